@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"freight/services"
+	"freight/utils"
+	"github.com/gorilla/mux"
+)
+
+type BidHandler struct {
+	service services.BidService
+}
+
+// NewBidHandler 创建竞价处理器
+func NewBidHandler(service services.BidService) *BidHandler {
+	return &BidHandler{service: service}
+}
+
+// PlaceBid 承运方对订单出价
+func (h *BidHandler) PlaceBid(w http.ResponseWriter, r *http.Request) {
+	orderID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "无效的订单ID")
+		return
+	}
+	userID, ok := actingUserID(r)
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+
+	var req services.BidRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "无效的请求数据")
+		return
+	}
+	defer r.Body.Close()
+
+	bid, err := h.service.PlaceBid(r.Context(), orderID, userID, req)
+	if err != nil {
+		writeBidError(w, err, "出价失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusCreated, map[string]interface{}{
+		"message": "出价成功",
+		"data":    bid,
+	})
+}
+
+// ListBids 查看订单的出价（发货方可见全部，承运方仅可见本人出价）
+func (h *BidHandler) ListBids(w http.ResponseWriter, r *http.Request) {
+	orderID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "无效的订单ID")
+		return
+	}
+	userID, ok := actingUserID(r)
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+
+	bids, err := h.service.ListBids(r.Context(), orderID, userID)
+	if err != nil {
+		writeBidError(w, err, "查询出价失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "查询出价成功",
+		"data":    bids,
+	})
+}
+
+// ListMyBids 查看本人的全部出价
+func (h *BidHandler) ListMyBids(w http.ResponseWriter, r *http.Request) {
+	userID, ok := actingUserID(r)
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+
+	bids, err := h.service.ListMyBids(r.Context(), userID)
+	if err != nil {
+		writeBidError(w, err, "查询出价失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "查询出价成功",
+		"data":    bids,
+	})
+}
+
+// AwardBid 发货方选定中标出价
+func (h *BidHandler) AwardBid(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	orderID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "无效的订单ID")
+		return
+	}
+	bidID, err := strconv.ParseUint(vars["bid_id"], 10, 64)
+	if err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "无效的出价ID")
+		return
+	}
+	userID, ok := actingUserID(r)
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+
+	order, err := h.service.AwardBid(r.Context(), orderID, bidID, userID)
+	if err != nil {
+		writeBidError(w, err, "定标失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "定标成功",
+		"data":    order,
+	})
+}
+
+// writeBidError 按竞价服务错误输出响应
+func writeBidError(w http.ResponseWriter, err error, failMsg string) {
+	var fieldErr *services.FreightFieldError
+	switch {
+	case errors.As(err, &fieldErr):
+		writeFieldError(w, fieldErr)
+	case errors.Is(err, services.ErrBidNotFound):
+		utils.ResponseError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrBiddingDisabled), errors.Is(err, services.ErrBiddingClosed):
+		utils.ResponseError(w, http.StatusConflict, err.Error())
+	default:
+		writeFreightError(w, err, failMsg)
+	}
+}
@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"freight/api/reqctx"
+	"freight/events"
+	"freight/models"
+	"freight/services"
+	"freight/utils"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// WebSocket 连接参数
+const (
+	chatWriteWait    = 10 * time.Second // 单次写入超时
+	chatPongWait     = 60 * time.Second // 超过该时间未收到客户端 pong 视为断线
+	chatPingPeriod   = chatPongWait * 9 / 10
+	chatMaxFrameSize = 16 << 10         // 客户端单帧上限
+	chatBodyBytes    = chatMaxFrameSize // REST 发送消息的请求体上限
+)
+
+// WebSocket 帧类型
+const (
+	chatFrameMessage = "message" // 客户端发送消息 / 服务端推送新消息
+	chatFrameRead    = "read"    // 客户端标记已读 / 服务端推送已读回执
+	chatFrameError   = "error"   // 服务端返回客户端帧的处理错误
+)
+
+// chatUpgrader 身份通过 JWT 校验而不是 Cookie，不存在跨站劫持连接的问题，因此不限制 Origin
+var chatUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// chatClientFrame 客户端发来的帧
+type chatClientFrame struct {
+	Type          string `json:"type"`
+	Kind          string `json:"kind"`
+	Body          string `json:"body"`
+	AttachmentURL string `json:"attachment_url"`
+	ClientID      string `json:"client_id"`
+	LastReadID    uint64 `json:"last_read_id"`
+}
+
+// chatServerFrame 服务端下发的帧
+type chatServerFrame struct {
+	Type     string      `json:"type"`
+	Data     interface{} `json:"data,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	Field    string      `json:"field,omitempty"`
+	ClientID string      `json:"client_id,omitempty"`
+}
+
+type ChatHandler struct {
+	service services.ChatService
+}
+
+// NewChatHandler 创建订单沟通处理器
+func NewChatHandler(service services.ChatService) *ChatHandler {
+	return &ChatHandler{service: service}
+}
+
+// chatRequest 解析订单ID和访问者
+func chatRequest(w http.ResponseWriter, r *http.Request) (uint64, services.ChatAccess, bool) {
+	orderID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "无效的订单ID")
+		return 0, services.ChatAccess{}, false
+	}
+	userID, ok := actingUserID(r)
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return 0, services.ChatAccess{}, false
+	}
+	role, _ := reqctx.Role(r.Context())
+	return orderID, services.ChatAccess{UserID: userID, Moderator: models.HasPermission(role, models.PermChatModerate)}, true
+}
+
+// ListMessages 查询消息历史：before_id 向前翻页，after_id 用于断线重连后补齐
+func (h *ChatHandler) ListMessages(w http.ResponseWriter, r *http.Request) {
+	orderID, access, ok := chatRequest(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	var params [3]int
+	for i, name := range []string{"before_id", "after_id", "limit"} {
+		n, paramErr := parsePositiveInt(query, name)
+		if paramErr != nil {
+			writeQueryParamError(w, paramErr)
+			return
+		}
+		params[i] = n
+	}
+
+	history, err := h.service.History(r.Context(), orderID, access, uint64(params[0]), uint64(params[1]), params[2])
+	if err != nil {
+		writeChatError(w, err, "查询消息失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "查询消息成功",
+		"data":    history,
+	})
+}
+
+// SendMessage 通过 REST 发送消息（不便使用 WebSocket 时）
+func (h *ChatHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
+	orderID, access, ok := chatRequest(w, r)
+	if !ok {
+		return
+	}
+	var msg models.ChatMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, chatBodyBytes)).Decode(&msg); err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "无效的请求数据")
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.service.Send(r.Context(), orderID, access, &msg); err != nil {
+		writeChatError(w, err, "发送消息失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusCreated, map[string]interface{}{
+		"message": "发送消息成功",
+		"data":    msg,
+	})
+}
+
+// MarkRead 标记已读，请求体 last_read_id 为空时标记全部已读
+func (h *ChatHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	orderID, access, ok := chatRequest(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		LastReadID uint64 `json:"last_read_id"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.ResponseError(w, http.StatusBadRequest, "无效的请求数据")
+			return
+		}
+		defer r.Body.Close()
+	}
+
+	receipt, err := h.service.MarkRead(r.Context(), orderID, access, req.LastReadID)
+	if err != nil {
+		writeChatError(w, err, "标记已读失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "标记已读成功",
+		"data":    receipt,
+	})
+}
+
+// chatUnread 单个订单的未读数
+type chatUnread struct {
+	OrderID uint64 `json:"order_id"`
+	Unread  int    `json:"unread"`
+}
+
+// UnreadCounts 当前用户各订单的未读消息数及合计
+func (h *ChatHandler) UnreadCounts(w http.ResponseWriter, r *http.Request) {
+	userID, ok := actingUserID(r)
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+	counts, err := h.service.UnreadCounts(r.Context(), userID)
+	if err != nil {
+		utils.ResponseError(w, http.StatusInternalServerError, "查询未读消息失败")
+		return
+	}
+
+	orders := make([]chatUnread, 0, len(counts))
+	total := 0
+	for orderID, n := range counts {
+		orders = append(orders, chatUnread{OrderID: orderID, Unread: n})
+		total += n
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].OrderID < orders[j].OrderID })
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "查询未读消息成功",
+		"data":    map[string]interface{}{"total": total, "orders": orders},
+	})
+}
+
+// Connect 建立订单沟通的 WebSocket 连接：推送新消息和已读回执，并接收客户端发送的消息和已读标记。
+// 权限在升级前校验，失败时按普通 HTTP 错误返回；连接断开期间的消息由客户端通过 after_id 查询历史补齐。
+func (h *ChatHandler) Connect(w http.ResponseWriter, r *http.Request) {
+	orderID, access, ok := chatRequest(w, r)
+	if !ok {
+		return
+	}
+	sub, err := h.service.Subscribe(r.Context(), orderID, access)
+	if err != nil {
+		writeChatError(w, err, "连接失败")
+		return
+	}
+	defer h.service.Unsubscribe(sub)
+
+	conn, err := chatUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade 已向客户端返回错误
+		return
+	}
+
+	// 读写分离：读循环处理客户端帧，回复经 replies 交给写循环，保证同一时刻只有一个写入者
+	replies := make(chan chatServerFrame, 16)
+	done, stop := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		h.readFrames(r, conn, orderID, access, func(frame chatServerFrame) {
+			select {
+			case replies <- frame:
+			case <-stop:
+			}
+		})
+	}()
+	h.writeFrames(conn, sub, replies, done)
+
+	// 写循环先退出时关闭连接，使读循环结束
+	close(stop)
+	conn.Close()
+	<-done
+}
+
+// readFrames 读取并处理客户端帧，连接断开或读取超时后返回
+func (h *ChatHandler) readFrames(r *http.Request, conn *websocket.Conn, orderID uint64, access services.ChatAccess, reply func(chatServerFrame)) {
+	conn.SetReadLimit(chatMaxFrameSize)
+	conn.SetReadDeadline(time.Now().Add(chatPongWait))
+	conn.SetPongHandler(func(string) error { return conn.SetReadDeadline(time.Now().Add(chatPongWait)) })
+
+	for {
+		var frame chatClientFrame
+		if err := conn.ReadJSON(&frame); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				reply(chatServerFrame{Type: chatFrameError, Error: "无效的消息格式"})
+				continue
+			}
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(chatPongWait))
+
+		var err error
+		switch frame.Type {
+		case chatFrameMessage:
+			err = h.service.Send(r.Context(), orderID, access, &models.ChatMessage{
+				Kind: frame.Kind, Body: frame.Body, AttachmentURL: frame.AttachmentURL, ClientID: frame.ClientID,
+			})
+		case chatFrameRead:
+			_, err = h.service.MarkRead(r.Context(), orderID, access, frame.LastReadID)
+		default:
+			reply(chatServerFrame{Type: chatFrameError, Error: "未知的消息类型", ClientID: frame.ClientID})
+			continue
+		}
+		if err != nil {
+			reply(chatErrorFrame(err, frame.ClientID))
+		}
+	}
+}
+
+// writeFrames 推送订阅事件、错误回复和心跳，读循环结束或订阅被断开后返回
+func (h *ChatHandler) writeFrames(conn *websocket.Conn, sub *events.Subscription, replies <-chan chatServerFrame, done <-chan struct{}) {
+	ticker := time.NewTicker(chatPingPeriod)
+	defer ticker.Stop()
+
+	write := func(frame chatServerFrame) error {
+		conn.SetWriteDeadline(time.Now().Add(chatWriteWait))
+		return conn.WriteJSON(frame)
+	}
+	for {
+		var err error
+		select {
+		case <-done:
+			return
+		case e, open := <-sub.C:
+			if !open {
+				// 客户端接收过慢被断开，重连后补齐
+				conn.SetWriteDeadline(time.Now().Add(chatWriteWait))
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "请重新连接"))
+				return
+			}
+			frameType := chatFrameMessage
+			if e.Type == events.TypeChatRead {
+				frameType = chatFrameRead
+			}
+			frame := chatServerFrame{Type: frameType, Data: e.Data}
+			if msg, ok := e.Data.(*models.ChatMessage); ok {
+				frame.ClientID = msg.ClientID
+			}
+			err = write(frame)
+		case frame := <-replies:
+			err = write(frame)
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(chatWriteWait))
+			err = conn.WriteMessage(websocket.PingMessage, nil)
+		}
+		if err != nil {
+			log.Printf("订单沟通连接写入失败: %v", err)
+			return
+		}
+	}
+}
+
+// chatErrorFrame 将服务层错误转换为错误帧
+func chatErrorFrame(err error, clientID string) chatServerFrame {
+	frame := chatServerFrame{Type: chatFrameError, Error: err.Error(), ClientID: clientID}
+	var fieldErr *services.FreightFieldError
+	if errors.As(err, &fieldErr) {
+		frame.Field = fieldErr.Field
+	} else if freightErrorStatus(err) == http.StatusInternalServerError && !errors.Is(err, services.ErrChatUnavailable) {
+		log.Printf("处理订单沟通消息失败: %v", err)
+		frame.Error = "处理失败，请稍后重试"
+	}
+	return frame
+}
+
+func writeChatError(w http.ResponseWriter, err error, failMsg string) {
+	var fieldErr *services.FreightFieldError
+	switch {
+	case errors.As(err, &fieldErr):
+		writeFieldError(w, fieldErr)
+	case errors.Is(err, services.ErrChatUnavailable):
+		utils.ResponseError(w, http.StatusConflict, err.Error())
+	default:
+		writeFreightError(w, err, failMsg)
+	}
+}
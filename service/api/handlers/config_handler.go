@@ -3,8 +3,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	"freight/api/reqctx"
 	_ "freight/models"
 	"freight/services"
 	"freight/utils"
@@ -21,7 +23,7 @@ func NewConfigHandler(configService services.ConfigService) *ConfigHandler {
 // CreateConfig 处理创建配置请求
 func (h *ConfigHandler) CreateConfig(w http.ResponseWriter, r *http.Request) {
 	// 从上下文中获取用户ID
-	userID, ok := r.Context().Value("user_id").(int64)
+	userID, ok := reqctx.UserID(r.Context())
 	if !ok {
 		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
 		return
@@ -46,6 +48,10 @@ func (h *ConfigHandler) CreateConfig(w http.ResponseWriter, r *http.Request) {
 
 	// 创建配置
 	if err := h.ConfigService.CreateConfig(req.Key, req.Value, req.Description); err != nil {
+		if errors.Is(err, services.ErrConfigExists) {
+			utils.ResponseError(w, http.StatusConflict, err.Error())
+			return
+		}
 		utils.ResponseError(w, http.StatusInternalServerError, "创建配置失败")
 		return
 	}
@@ -68,7 +74,7 @@ func (h *ConfigHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
 	// 获取配置
 	config, err := h.ConfigService.GetConfig(key)
 	if err != nil {
-		utils.ResponseError(w, http.StatusNotFound, "配置不存在")
+		writeConfigError(w, err, "获取配置失败")
 		return
 	}
 
@@ -82,7 +88,7 @@ func (h *ConfigHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
 // UpdateConfig 更新配置
 func (h *ConfigHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
 	// 从上下文中获取用户ID
-	userID, ok := r.Context().Value("user_id").(int64)
+	userID, ok := reqctx.UserID(r.Context())
 	if !ok {
 		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
 		return
@@ -97,7 +103,7 @@ func (h *ConfigHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
 	// 获取现有配置
 	config, err := h.ConfigService.GetConfig(key)
 	if err != nil {
-		utils.ResponseError(w, http.StatusNotFound, "配置不存在")
+		writeConfigError(w, err, "获取配置失败")
 		return
 	}
 
@@ -134,7 +140,7 @@ func (h *ConfigHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
 // DeleteConfig 删除配置
 func (h *ConfigHandler) DeleteConfig(w http.ResponseWriter, r *http.Request) {
 	// 从上下文中获取用户ID
-	userID, ok := r.Context().Value("user_id").(int64)
+	userID, ok := reqctx.UserID(r.Context())
 	if !ok {
 		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
 		return
@@ -148,7 +154,7 @@ func (h *ConfigHandler) DeleteConfig(w http.ResponseWriter, r *http.Request) {
 
 	// 删除配置
 	if err := h.ConfigService.DeleteConfig(key); err != nil {
-		utils.ResponseError(w, http.StatusNotFound, "配置不存在")
+		writeConfigError(w, err, "删除配置失败")
 		return
 	}
 
@@ -162,7 +168,7 @@ func (h *ConfigHandler) DeleteConfig(w http.ResponseWriter, r *http.Request) {
 // ListConfigs 获取配置列表
 func (h *ConfigHandler) ListConfigs(w http.ResponseWriter, r *http.Request) {
 	// 从上下文中获取用户ID
-	userID, ok := r.Context().Value("user_id").(int64)
+	userID, ok := reqctx.UserID(r.Context())
 	if !ok {
 		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
 		return
@@ -182,3 +188,12 @@ func (h *ConfigHandler) ListConfigs(w http.ResponseWriter, r *http.Request) {
 		"user_id": userID,
 	})
 }
+
+// writeConfigError 配置不存在返回404，其余返回500
+func writeConfigError(w http.ResponseWriter, err error, failMsg string) {
+	if errors.Is(err, services.ErrConfigNotFound) {
+		utils.ResponseError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	utils.ResponseError(w, http.StatusInternalServerError, failMsg)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"freight/api/reqctx"
+	"freight/events"
+	"freight/models"
+	"freight/utils"
+)
+
+// DefaultHeartbeat SSE 心跳间隔，避免代理因连接空闲而断开
+const DefaultHeartbeat = 15 * time.Second
+
+// 订阅主题
+const (
+	topicHall   = "hall"   // 货源大厅：新订单上架、待接单订单被接走/取消/过期
+	topicOrders = "orders" // 本人发布或承运的订单（可查看全部订单的角色为全部订单）
+)
+
+type EventHandler struct {
+	bus       *events.Bus
+	heartbeat time.Duration
+}
+
+// NewEventHandler 创建事件推送处理器，heartbeat 为0时使用默认心跳间隔
+func NewEventHandler(bus *events.Bus, heartbeat time.Duration) *EventHandler {
+	if heartbeat <= 0 {
+		heartbeat = DefaultHeartbeat
+	}
+	return &EventHandler{bus: bus, heartbeat: heartbeat}
+}
+
+// Stream 以 Server-Sent Events 推送订单事件。
+// topics 参数为 hall、orders 或两者（逗号分隔，默认两者）；
+// 断线重连时按 Last-Event-ID 请求头（或 last_event_id 参数）重放缓冲区中的事件，
+// 缓冲区已不完整时先发送 reset 事件，客户端应重新拉取列表。
+func (h *EventHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	userID, ok := actingUserID(r)
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+	role, _ := reqctx.Role(r.Context())
+	viewAll := models.HasPermission(role, models.PermFreightViewAll)
+
+	hall, orders := true, true
+	if raw := r.URL.Query().Get("topics"); raw != "" {
+		hall, orders = false, false
+		for _, topic := range strings.Split(raw, ",") {
+			switch strings.TrimSpace(topic) {
+			case topicHall:
+				hall = true
+			case topicOrders:
+				orders = true
+			default:
+				writeQueryParamError(w, &QueryParamError{Param: "topics", Reason: "只能为 hall 或 orders"})
+				return
+			}
+		}
+	}
+
+	lastIDStr := r.Header.Get("Last-Event-ID")
+	if lastIDStr == "" {
+		lastIDStr = r.URL.Query().Get("last_event_id")
+	}
+	var lastID uint64
+	if lastIDStr != "" {
+		id, err := strconv.ParseUint(lastIDStr, 10, 64)
+		if err != nil {
+			writeQueryParamError(w, &QueryParamError{Param: "last_event_id", Reason: "必须为非负整数"})
+			return
+		}
+		lastID = id
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		utils.ResponseError(w, http.StatusInternalServerError, "当前连接不支持事件推送")
+		return
+	}
+
+	// 按用户过滤：大厅事件对所有订阅大厅的用户可见，订单事件仅订单双方可见
+	match := func(e events.Event) bool {
+		if hall && e.Hall {
+			return true
+		}
+		return orders && (viewAll || e.ShipperID == userID || e.CarrierID == userID)
+	}
+	sub, replay, complete := h.bus.Subscribe(lastID, match)
+	defer h.bus.Unsubscribe(sub)
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no") // 关闭 nginx 缓冲
+	w.WriteHeader(http.StatusOK)
+
+	if !complete {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, e := range replay {
+		if err := writeEvent(w, e); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, open := <-sub.C:
+			if !open {
+				// 订阅被结束（客户端过慢或服务关闭），客户端重连后按 Last-Event-ID 补齐
+				return
+			}
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent 按 SSE 格式写出一条事件
+func writeEvent(w http.ResponseWriter, e events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("序列化事件 %d 失败: %v", e.ID, err)
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
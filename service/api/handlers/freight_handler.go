@@ -3,10 +3,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
-	"time"
+	"strings"
+	"unicode/utf8"
 
+	"freight/api/reqctx"
 	"freight/models"
 	"freight/services"
 	"freight/utils"
@@ -18,11 +23,6 @@ type FreightHandler struct {
 	logger  utils.Logger
 }
 
-// 新增：接单请求参数结构体
-type AcceptFreightRequest struct {
-	UserID uint64 `json:"user_id"` // 接单用户ID
-}
-
 // NewFreightHandler 创建处理器实例
 func NewFreightHandler(service services.FreightService) *FreightHandler {
 	return &FreightHandler{service: service}
@@ -37,7 +37,21 @@ func (h *FreightHandler) CreateFreight(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
+	// 发布人取自认证信息
+	userID, ok := actingUserID(r)
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+	freight.ShipperID = userID
+	freight.CarrierID = 0
+
 	if err := h.service.CreateFreight(r.Context(), &freight); err != nil {
+		var fieldErr *services.FreightFieldError
+		if errors.As(err, &fieldErr) {
+			writeFieldError(w, fieldErr)
+			return
+		}
 		utils.ResponseError(w, http.StatusInternalServerError, "创建货运订单失败")
 		return
 	}
@@ -76,57 +90,72 @@ func (h *FreightHandler) GetFreightByID(w http.ResponseWriter, r *http.Request)
 
 // ListFreights 处理列表请求
 func (h *FreightHandler) ListFreights(w http.ResponseWriter, r *http.Request) {
-	var filter models.FreightFilter
-
-	// 解析查询参数
-	if origin := r.URL.Query().Get("origin_location"); origin != "" {
-		filter.OriginLocation = origin
-	}
-
-	if destination := r.URL.Query().Get("destination_location"); destination != "" {
-		filter.DestinationLocation = destination
+	// 解析过滤、排序和分页参数
+	filter, paramErr := parseFreightFilter(r.URL.Query())
+	if paramErr != nil {
+		writeQueryParamError(w, paramErr)
+		return
 	}
 
-	if statusStr := r.URL.Query().Get("status"); statusStr != "" {
-		status, err := strconv.ParseUint(statusStr, 10, 8)
-		if err != nil {
-			utils.ResponseError(w, http.StatusBadRequest, "无效的状态值")
+	// 按车辆筛选时只能使用本人登记的车辆
+	if v := r.URL.Query().Get("vehicle_id"); v != "" {
+		vehicleID, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || vehicleID == 0 {
+			writeQueryParamError(w, &QueryParamError{Param: "vehicle_id", Reason: "必须是正整数"})
 			return
 		}
-		statusUint8 := uint8(status)
-		filter.Status = &statusUint8
+		filter.VehicleID = vehicleID
+		filter.VehicleOwnerID, _ = actingUserID(r)
+	}
+
+	// 调用服务层获取列表
+	page, err := h.service.ListFreights(r.Context(), filter)
+	if err != nil {
+		writeFreightError(w, err, "获取货运订单列表失败")
+		return
 	}
 
-	// 解析其他过滤参数...
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "获取货运订单列表成功",
+		"data":    page,
+	})
+}
 
-	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
-		page, err := strconv.Atoi(pageStr)
-		if err != nil {
-			utils.ResponseError(w, http.StatusBadRequest, "无效的页码")
-			return
-		}
-		filter.Page = page
+// maxSearchQueryLen 搜索关键词最大长度（字符数）
+const maxSearchQueryLen = 100
+
+// SearchFreights 按关键词全文检索订单，支持与列表相同的过滤和分页参数
+func (h *FreightHandler) SearchFreights(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		writeQueryParamError(w, &QueryParamError{Param: "q", Reason: "不能为空"})
+		return
+	}
+	if utf8.RuneCountInString(q) > maxSearchQueryLen {
+		writeQueryParamError(w, &QueryParamError{Param: "q", Reason: fmt.Sprintf("不能超过%d个字符", maxSearchQueryLen)})
+		return
+	}
+	if query.Has("cursor") {
+		writeQueryParamError(w, &QueryParamError{Param: "cursor", Reason: "搜索不支持游标分页"})
+		return
 	}
 
-	if pageSizeStr := r.URL.Query().Get("page_size"); pageSizeStr != "" {
-		pageSize, err := strconv.Atoi(pageSizeStr)
-		if err != nil {
-			utils.ResponseError(w, http.StatusBadRequest, "无效的每页大小")
-			return
-		}
-		filter.PageSize = pageSize
+	filter, paramErr := parseFreightFilter(query)
+	if paramErr != nil {
+		writeQueryParamError(w, paramErr)
+		return
 	}
 
-	// 调用服务层获取列表
-	freights, err := h.service.ListFreights(r.Context(), filter)
+	page, err := h.service.SearchFreights(r.Context(), q, filter)
 	if err != nil {
-		utils.ResponseError(w, http.StatusInternalServerError, "获取货运订单列表失败")
+		utils.ResponseError(w, http.StatusInternalServerError, "搜索货运订单失败")
 		return
 	}
 
 	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
-		"message": "获取货运订单列表成功",
-		"data":    freights,
+		"message": "搜索货运订单成功",
+		"data":    page,
 	})
 }
 
@@ -149,8 +178,14 @@ func (h *FreightHandler) UpdateFreight(w http.ResponseWriter, r *http.Request) {
 	// 设置ID
 	freight.ID = id
 
-	if err := h.service.UpdateFreight(r.Context(), &freight); err != nil {
-		utils.ResponseError(w, http.StatusInternalServerError, "更新货运订单失败")
+	userID, ok := actingUserID(r)
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+
+	if err := h.service.UpdateFreight(r.Context(), &freight, userID); err != nil {
+		writeFreightError(w, err, "更新货运订单失败")
 		return
 	}
 
@@ -169,8 +204,14 @@ func (h *FreightHandler) DeleteFreight(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.DeleteFreight(r.Context(), id); err != nil {
-		utils.ResponseError(w, http.StatusInternalServerError, "删除货运订单失败")
+	userID, ok := actingUserID(r)
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+
+	if err := h.service.DeleteFreight(r.Context(), id, userID); err != nil {
+		writeFreightError(w, err, "删除货运订单失败")
 		return
 	}
 
@@ -179,7 +220,7 @@ func (h *FreightHandler) DeleteFreight(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// AcceptFreight 处理接单请求（更新user_id、status和时间）
+// AcceptFreight 处理接单请求（记录承运方并改为已接单）
 func (h *FreightHandler) AcceptFreight(w http.ResponseWriter, r *http.Request) {
 	// 1. 解析路径中的订单ID
 	vars := mux.Vars(r)
@@ -189,32 +230,26 @@ func (h *FreightHandler) AcceptFreight(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 2. 解析请求体中的接单用户ID
-	var req AcceptFreightRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.ResponseError(w, http.StatusBadRequest, "无效的请求数据："+err.Error())
+	// 2. 接单用户取自认证信息，忽略请求体中的user_id
+	userID, ok := actingUserID(r)
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
 		return
 	}
-	defer r.Body.Close()
 
-	// 3. 验证用户ID是否有效
-	if req.UserID == 0 {
-		utils.ResponseError(w, http.StatusBadRequest, "接单用户ID不能为空")
-		return
+	// 3. 可选的承运车辆（请求体为空表示不指定）
+	var req struct {
+		VehicleID uint64 `json:"vehicle_id"`
 	}
-
-	// 4. 构建仅包含需更新字段的订单对象
-	// （复用UpdateFreight的服务层逻辑，仅更新必要字段）
-	freight := &models.FreightOrder{
-		ID:        orderID,                    // 订单ID（用于定位）
-		UserID:    req.UserID,                 // 更新接单用户ID
-		Status:    2,                          // 状态改为“运输中”（假设1=待接单，2=运输中）
-		UpdatedAt: utils.FromTime(time.Now()), // 更新时间
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.ResponseError(w, http.StatusBadRequest, "无效的请求数据")
+		return
 	}
+	defer r.Body.Close()
 
-	// 5. 调用服务层的Update方法更新订单
-	if err := h.service.AcceptOrder(r.Context(), freight.ID, freight.UserID); err != nil {
-		utils.ResponseError(w, http.StatusInternalServerError, "接单失败："+err.Error())
+	// 4. 调用服务层原子接单
+	if err := h.service.AcceptOrder(r.Context(), orderID, userID, req.VehicleID); err != nil {
+		writeFreightError(w, err, "接单失败")
 		return
 	}
 
@@ -222,8 +257,9 @@ func (h *FreightHandler) AcceptFreight(w http.ResponseWriter, r *http.Request) {
 	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
 		"message": "接单成功",
 		"data": map[string]uint64{
-			"order_id": orderID,
-			"user_id":  req.UserID,
+			"order_id":   orderID,
+			"user_id":    userID,
+			"vehicle_id": req.VehicleID,
 		},
 	})
 }
@@ -238,41 +274,33 @@ func (h *FreightHandler) ListFreightsByUser(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// 2. 解析查询参数（分页、筛选条件）
-	var filter models.FreightFilter
-
-	// 状态筛选（可选）
-	if statusStr := r.URL.Query().Get("status"); statusStr != "" {
-		status, err := strconv.ParseUint(statusStr, 10, 8)
-		if err != nil {
-			utils.ResponseError(w, http.StatusBadRequest, "无效的状态值")
+	// 仅本人或拥有查看全部订单权限的角色（调度、管理员）可查询
+	if actingID, _ := actingUserID(r); actingID != userID {
+		role, _ := reqctx.Role(r.Context())
+		if !models.HasPermission(role, models.PermFreightViewAll) {
+			utils.ResponseForbidden(w, "只能查询自己的订单")
 			return
 		}
-		statusUint8 := uint8(status)
-		filter.Status = &statusUint8
 	}
 
-	// 分页参数（可选）
-	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
-		page, err := strconv.Atoi(pageStr)
-		if err != nil {
-			utils.ResponseError(w, http.StatusBadRequest, "无效的页码")
-			return
-		}
-		filter.Page = page
+	// 2. 解析查询参数（分页、筛选条件）
+	filter, paramErr := parseFreightFilter(r.URL.Query())
+	if paramErr != nil {
+		writeQueryParamError(w, paramErr)
+		return
 	}
 
-	if pageSizeStr := r.URL.Query().Get("page_size"); pageSizeStr != "" {
-		pageSize, err := strconv.Atoi(pageSizeStr)
-		if err != nil {
-			utils.ResponseError(w, http.StatusBadRequest, "无效的每页大小")
-			return
-		}
-		filter.PageSize = pageSize
+	// 角色筛选（可选）：shipper 为发布的订单，carrier 为承运的订单
+	switch role := r.URL.Query().Get("role"); role {
+	case "", models.FreightRoleShipper, models.FreightRoleCarrier:
+		filter.Role = role
+	default:
+		writeQueryParamError(w, &QueryParamError{Param: "role", Reason: "必须是 shipper 或 carrier"})
+		return
 	}
 
 	// 3. 调用服务层方法查询用户订单
-	freights, err := h.service.ListByUserID(r.Context(), userID, filter)
+	page, err := h.service.ListByUserID(r.Context(), userID, filter)
 	if err != nil {
 		utils.ResponseError(w, http.StatusInternalServerError, "查询用户订单失败："+err.Error())
 		return
@@ -281,7 +309,7 @@ func (h *FreightHandler) ListFreightsByUser(w http.ResponseWriter, r *http.Reque
 	// 4. 返回成功响应
 	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
 		"message": "查询用户订单成功",
-		"data":    freights,
+		"data":    page,
 	})
 }
 
@@ -295,9 +323,44 @@ func (h *FreightHandler) CompleteOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 解析请求体（当前用户ID）
+	// 当前用户取自认证信息
+	userID, ok := actingUserID(r)
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+
+	// 调用服务层完成订单
+	if err := h.service.CompleteOrder(r.Context(), orderID, userID); err != nil {
+		writeFreightError(w, err, "完成订单失败")
+		return
+	}
+
+	// 返回成功响应
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message":  "订单已完成",
+		"order_id": orderID,
+	})
+}
+
+// TransitionFreight 按状态机变更订单状态（取货、发车、确认收货、取消、争议等）
+func (h *FreightHandler) TransitionFreight(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	orderID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "无效的订单ID")
+		return
+	}
+
+	userID, ok := actingUserID(r)
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+
 	var req struct {
-		UserID uint64 `json:"user_id"`
+		Status uint8  `json:"status"`
+		Reason string `json:"reason"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.ResponseError(w, http.StatusBadRequest, "无效的请求数据")
@@ -305,15 +368,93 @@ func (h *FreightHandler) CompleteOrder(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	// 调用服务层完成订单
-	if err := h.service.CompleteOrder(r.Context(), orderID, req.UserID); err != nil {
-		utils.ResponseError(w, http.StatusForbidden, err.Error())
+	if err := h.service.TransitionOrder(r.Context(), orderID, userID, req.Status, req.Reason); err != nil {
+		writeFreightError(w, err, "订单状态更新失败")
 		return
 	}
 
-	// 返回成功响应
 	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
-		"message":  "订单已完成",
+		"message":  "订单状态更新成功",
 		"order_id": orderID,
+		"status":   req.Status,
 	})
 }
+
+// GetFreightHistory 获取订单状态流转时间线
+func (h *FreightHandler) GetFreightHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	orderID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "无效的订单ID")
+		return
+	}
+	userID, ok := actingUserID(r)
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+	role, _ := reqctx.Role(r.Context())
+
+	events, err := h.service.GetOrderHistory(r.Context(), orderID, userID, models.HasPermission(role, models.PermFreightViewAll))
+	if err != nil {
+		writeFreightError(w, err, "获取订单时间线失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "获取订单时间线成功",
+		"data":    events,
+	})
+}
+
+// actingUserID 从请求上下文获取当前认证用户ID
+func actingUserID(r *http.Request) (uint64, bool) {
+	userID, ok := reqctx.UserID(r.Context())
+	return uint64(userID), ok
+}
+
+// writeFieldError 以400返回字段校验错误，同时在 field 字段给出字段名
+func writeFieldError(w http.ResponseWriter, err *services.FreightFieldError) {
+	utils.ResponseJSON(w, http.StatusBadRequest, map[string]string{
+		"error": err.Error(),
+		"field": err.Field,
+	})
+}
+
+// writeFreightError 按服务层错误输出响应：业务错误返回具体原因，未知错误只返回 failMsg
+func writeFreightError(w http.ResponseWriter, err error, failMsg string) {
+	switch status := freightErrorStatus(err); status {
+	case http.StatusForbidden:
+		utils.ResponseForbidden(w, err.Error())
+	case http.StatusInternalServerError:
+		utils.ResponseError(w, status, failMsg)
+	default:
+		utils.ResponseError(w, status, failMsg+"："+err.Error())
+	}
+}
+
+// freightErrorStatus 将服务层错误映射为HTTP状态码
+func freightErrorStatus(err error) int {
+	var illegal *services.IllegalTransitionError
+	var unknown *services.UnknownStatusError
+	var mismatch *services.VehicleMismatchError
+	switch {
+	case errors.Is(err, services.ErrFreightNotFound), errors.Is(err, services.ErrVehicleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrPermissionDenied):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrStatusNotEditable):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrOrderNotEditable), errors.Is(err, services.ErrOrderNotDeletable):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrStatusConflict), errors.Is(err, services.ErrOrderTaken), errors.Is(err, services.ErrBiddingOnly), errors.As(err, &illegal),
+		errors.Is(err, services.ErrPickupWindowClosed):
+		return http.StatusConflict
+	case errors.As(err, &unknown):
+		return http.StatusBadRequest
+	case errors.As(err, &mismatch):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
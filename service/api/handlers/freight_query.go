@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"freight/models"
+	"freight/utils"
+)
+
+// maxPickupWithinHours 大厅按取货时间筛选时最多查询未来30天
+const maxPickupWithinHours = 30 * 24
+
+// QueryParamError 查询参数无效（Param 为参数名，便于客户端定位）
+type QueryParamError struct {
+	Param  string
+	Reason string
+}
+
+func (e *QueryParamError) Error() string {
+	return fmt.Sprintf("参数 %s 无效：%s", e.Param, e.Reason)
+}
+
+// writeQueryParamError 以400返回参数错误，同时在 param 字段给出参数名
+func writeQueryParamError(w http.ResponseWriter, err *QueryParamError) {
+	utils.ResponseJSON(w, http.StatusBadRequest, map[string]string{
+		"error": err.Error(),
+		"param": err.Param,
+	})
+}
+
+// parseFreightFilter 从查询字符串解析订单过滤、排序和分页参数
+func parseFreightFilter(query url.Values) (models.FreightFilter, *QueryParamError) {
+	filter := models.FreightFilter{
+		OriginLocation:      query.Get("origin_location"),
+		OriginCode:          query.Get("origin_code"),
+		DestinationLocation: query.Get("destination_location"),
+		DestinationCode:     query.Get("destination_code"),
+	}
+
+	if v := query.Get("type_id"); v != "" {
+		typeID, err := strconv.ParseUint(v, 10, 8)
+		if err != nil || typeID == 0 {
+			return filter, &QueryParamError{Param: "type_id", Reason: "必须是1-255的整数"}
+		}
+		filter.TypeID = uint8(typeID)
+	}
+
+	if v := query.Get("status"); v != "" {
+		status, err := strconv.ParseUint(v, 10, 8)
+		if err != nil || status < models.FreightStatusPending || status > models.FreightStatusDisputed {
+			return filter, &QueryParamError{Param: "status", Reason: "不是有效的订单状态"}
+		}
+		s := uint8(status)
+		filter.Status = &s
+	}
+
+	var err *QueryParamError
+	if filter.MinPrice, err = parseNonNegative(query, "min_price"); err != nil {
+		return filter, err
+	}
+	if filter.MaxPrice, err = parseNonNegative(query, "max_price"); err != nil {
+		return filter, err
+	}
+	if filter.MinPrice > 0 && filter.MaxPrice > 0 && filter.MinPrice > filter.MaxPrice {
+		return filter, &QueryParamError{Param: "min_price", Reason: "不能大于 max_price"}
+	}
+
+	if filter.MinDistance, err = parseNonNegative(query, "min_distance"); err != nil {
+		return filter, err
+	}
+	if filter.MaxDistance, err = parseNonNegative(query, "max_distance"); err != nil {
+		return filter, err
+	}
+	if filter.MinDistance > 0 && filter.MaxDistance > 0 && filter.MinDistance > filter.MaxDistance {
+		return filter, &QueryParamError{Param: "min_distance", Reason: "不能大于 max_distance"}
+	}
+
+	if filter.MinWeight, err = parseNonNegative(query, "min_weight"); err != nil {
+		return filter, err
+	}
+	if filter.MaxWeight, err = parseNonNegative(query, "max_weight"); err != nil {
+		return filter, err
+	}
+	if filter.MinWeight > 0 && filter.MaxWeight > 0 && filter.MinWeight > filter.MaxWeight {
+		return filter, &QueryParamError{Param: "min_weight", Reason: "不能大于 max_weight"}
+	}
+	if filter.MaxVolume, err = parseNonNegative(query, "max_volume"); err != nil {
+		return filter, err
+	}
+	if filter.MaxLength, err = parseNonNegative(query, "max_length"); err != nil {
+		return filter, err
+	}
+	if filter.MaxWidth, err = parseNonNegative(query, "max_width"); err != nil {
+		return filter, err
+	}
+	if filter.MaxHeight, err = parseNonNegative(query, "max_height"); err != nil {
+		return filter, err
+	}
+
+	if filter.Hazmat, err = parseBool(query, "hazmat"); err != nil {
+		return filter, err
+	}
+	if v := query.Get("hazmat_class"); v != "" {
+		class, parseErr := strconv.ParseUint(v, 10, 8)
+		if parseErr != nil || class < 1 || class > 9 {
+			return filter, &QueryParamError{Param: "hazmat_class", Reason: "必须是1-9的整数"}
+		}
+		filter.HazmatClass = uint8(class)
+	}
+	if filter.ColdChain, err = parseBool(query, "cold_chain"); err != nil {
+		return filter, err
+	}
+
+	if filter.PickupWithinHours, err = parsePositiveInt(query, "pickup_within_hours"); err != nil {
+		return filter, err
+	}
+	if filter.PickupWithinHours > maxPickupWithinHours {
+		return filter, &QueryParamError{Param: "pickup_within_hours", Reason: fmt.Sprintf("不能超过%d", maxPickupWithinHours)}
+	}
+
+	if filter.IsUrgent, err = parseBool(query, "is_urgent"); err != nil {
+		return filter, err
+	}
+	if filter.HasInsurance, err = parseBool(query, "has_insurance"); err != nil {
+		return filter, err
+	}
+
+	if filter.OrderDate, err = parseDateParam(query, "order_date"); err != nil {
+		return filter, err
+	}
+	if filter.OrderDateFrom, err = parseDateParam(query, "order_date_from"); err != nil {
+		return filter, err
+	}
+	if filter.OrderDateTo, err = parseDateParam(query, "order_date_to"); err != nil {
+		return filter, err
+	}
+	if filter.OrderDateFrom != "" && filter.OrderDateTo != "" && filter.OrderDateFrom > filter.OrderDateTo {
+		return filter, &QueryParamError{Param: "order_date_from", Reason: "不能晚于 order_date_to"}
+	}
+
+	if v := query.Get("sort"); v != "" {
+		if !models.ValidFreightSortField(v) {
+			return filter, &QueryParamError{Param: "sort", Reason: "只能是 " + strings.Join(models.FreightSortFields, "、")}
+		}
+		filter.SortField = v
+	}
+	if v := strings.ToLower(query.Get("order")); v != "" {
+		if v != "asc" && v != "desc" {
+			return filter, &QueryParamError{Param: "order", Reason: "只能是 asc 或 desc"}
+		}
+		filter.SortOrder = v
+	}
+
+	if filter.Page, err = parsePositiveInt(query, "page"); err != nil {
+		return filter, err
+	}
+	if filter.PageSize, err = parsePositiveInt(query, "page_size"); err != nil {
+		return filter, err
+	}
+
+	// 带 cursor 参数即为游标模式（值为空表示第一页），按 (updated_at, id) 倒序，不能与排序或页码同时使用
+	if query.Has("cursor") {
+		if query.Get("sort") != "" || query.Get("order") != "" {
+			return filter, &QueryParamError{Param: "cursor", Reason: "不能与 sort、order 同时使用"}
+		}
+		if filter.Page > 0 {
+			return filter, &QueryParamError{Param: "cursor", Reason: "不能与 page 同时使用"}
+		}
+		filter.CursorMode = true
+		if v := query.Get("cursor"); v != "" {
+			after, decodeErr := models.DecodeFreightCursor(v)
+			if decodeErr != nil {
+				return filter, &QueryParamError{Param: "cursor", Reason: "格式不正确"}
+			}
+			filter.After = after
+		}
+	}
+
+	return filter, nil
+}
+
+func parseNonNegative(query url.Values, name string) (float64, *QueryParamError) {
+	v := query.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	price, err := strconv.ParseFloat(v, 64)
+	if err != nil || price < 0 {
+		return 0, &QueryParamError{Param: name, Reason: "必须是非负数"}
+	}
+	return price, nil
+}
+
+func parseBool(query url.Values, name string) (*bool, *QueryParamError) {
+	v := query.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, &QueryParamError{Param: name, Reason: "必须是 true 或 false"}
+	}
+	return &b, nil
+}
+
+func parseDateParam(query url.Values, name string) (string, *QueryParamError) {
+	v := query.Get(name)
+	if v == "" {
+		return "", nil
+	}
+	if _, err := utils.ParseDate(v); err != nil {
+		return "", &QueryParamError{Param: name, Reason: "日期格式必须是 YYYY-MM-DD"}
+	}
+	return v, nil
+}
+
+func parsePositiveInt(query url.Values, name string) (int, *QueryParamError) {
+	v := query.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, &QueryParamError{Param: name, Reason: "必须是正整数"}
+	}
+	return n, nil
+}
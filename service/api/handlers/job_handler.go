@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	"freight/scheduler"
+	"freight/utils"
+)
+
+// JobStatusProvider 提供定时任务运行状态（由 scheduler.Scheduler 实现）
+type JobStatusProvider interface {
+	Status() []scheduler.JobStatus
+}
+
+type JobHandler struct {
+	jobs JobStatusProvider
+}
+
+// NewJobHandler 创建定时任务处理器
+func NewJobHandler(jobs JobStatusProvider) *JobHandler {
+	return &JobHandler{jobs: jobs}
+}
+
+// ListJobs 返回全部定时任务的调度、最近一次执行时间和错误；未启用调度器时返回空列表
+func (h *JobHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	jobs := []scheduler.JobStatus{}
+	if h.jobs != nil {
+		jobs = h.jobs.Status()
+	}
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "获取定时任务状态成功",
+		"data":    jobs,
+	})
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"freight/models"
+	"freight/services"
+	"freight/utils"
+	"github.com/gorilla/mux"
+)
+
+type QuoteHandler struct {
+	service services.QuoteService
+}
+
+// NewQuoteHandler 创建报价处理器
+func NewQuoteHandler(service services.QuoteService) *QuoteHandler {
+	return &QuoteHandler{service: service}
+}
+
+// CreateQuote 计算运费报价
+func (h *QuoteHandler) CreateQuote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := actingUserID(r)
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+
+	var req services.QuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "无效的请求数据")
+		return
+	}
+	defer r.Body.Close()
+
+	quote, err := h.service.CreateQuote(r.Context(), userID, req)
+	if err != nil {
+		writeQuoteError(w, err, "计算报价失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusCreated, map[string]interface{}{
+		"message": "报价成功",
+		"data":    quote,
+	})
+}
+
+// GetQuote 查询报价
+func (h *QuoteHandler) GetQuote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := actingUserID(r)
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+
+	quote, err := h.service.GetQuote(r.Context(), mux.Vars(r)["id"], userID)
+	if err != nil {
+		writeQuoteError(w, err, "查询报价失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "查询报价成功",
+		"data":    quote,
+	})
+}
+
+// CreateOrderFromQuote 按报价发布订单，请求体为订单的其余字段（备注、下单日期等）
+func (h *QuoteHandler) CreateOrderFromQuote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := actingUserID(r)
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+
+	var order models.FreightOrder
+	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "无效的请求数据")
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.service.CreateOrderFromQuote(r.Context(), mux.Vars(r)["id"], userID, &order); err != nil {
+		writeQuoteError(w, err, "创建货运订单失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusCreated, map[string]interface{}{
+		"message": "货运订单创建成功",
+		"data":    order,
+	})
+}
+
+// writeQuoteError 按报价服务错误输出响应
+func writeQuoteError(w http.ResponseWriter, err error, failMsg string) {
+	var fieldErr *services.FreightFieldError
+	switch {
+	case errors.As(err, &fieldErr):
+		writeFieldError(w, fieldErr)
+	case errors.Is(err, services.ErrQuoteNotFound):
+		utils.ResponseError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrQuoteExpired):
+		utils.ResponseError(w, http.StatusGone, err.Error())
+	case errors.Is(err, services.ErrQuoteUsed):
+		utils.ResponseError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrRouteUnavailable):
+		utils.ResponseError(w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		writeFreightError(w, err, failMsg)
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+
+	"freight/region"
+	"freight/utils"
+)
+
+type RegionHandler struct {
+	regions *region.Tree
+}
+
+// NewRegionHandler 创建行政区划处理器
+func NewRegionHandler(regions *region.Tree) *RegionHandler {
+	return &RegionHandler{regions: regions}
+}
+
+// ListRegions 返回行政区划树；指定 parent 时只返回该区划的下级，供地址选择器逐级加载
+func (h *RegionHandler) ListRegions(w http.ResponseWriter, r *http.Request) {
+	regions := h.regions.Roots()
+	if parent := r.URL.Query().Get("parent"); parent != "" {
+		node := h.regions.Lookup(parent)
+		if node == nil {
+			utils.ResponseError(w, http.StatusNotFound, "行政区划不存在")
+			return
+		}
+		regions = node.Children
+		if regions == nil {
+			regions = []*region.Region{}
+		}
+	}
+
+	// 区划数据随版本发布更新，允许客户端缓存
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "获取行政区划成功",
+		"data":    regions,
+	})
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"freight/api/reqctx"
+	"freight/services"
+	"freight/utils"
+)
+
+// TokenHandler 令牌刷新与登出处理函数
+type TokenHandler struct {
+	TokenService services.TokenService
+}
+
+// NewTokenHandler 创建令牌处理函数实例
+func NewTokenHandler(tokenService services.TokenService) *TokenHandler {
+	return &TokenHandler{TokenService: tokenService}
+}
+
+// Refresh 使用刷新令牌换取新的令牌对
+func (h *TokenHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "请求格式错误")
+		return
+	}
+
+	tokens, err := h.TokenService.Refresh(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+			utils.ResponseError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		utils.ResponseError(w, http.StatusInternalServerError, "刷新令牌失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message":       "刷新成功",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+// Logout 登出：吊销当前访问令牌及对应的刷新令牌
+func (h *TokenHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	// 请求体可为空，仅吊销访问令牌
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.ResponseError(w, http.StatusBadRequest, "请求格式错误")
+			return
+		}
+	}
+
+	userID, _ := reqctx.UserID(r.Context())
+	jti, expiresAt, _ := reqctx.Token(r.Context())
+	if err := h.TokenService.Logout(userID, jti, expiresAt, req.RefreshToken); err != nil {
+		utils.ResponseError(w, http.StatusInternalServerError, "登出失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]string{
+		"message": "已登出",
+	})
+}
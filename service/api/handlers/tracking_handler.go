@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"freight/api/reqctx"
+	"freight/models"
+	"freight/services"
+	"freight/utils"
+	"github.com/gorilla/mux"
+)
+
+// maxPingBodyBytes 定位上报请求体上限（约500条定位）
+const maxPingBodyBytes = 256 << 10
+
+type TrackingHandler struct {
+	service services.TrackingService
+}
+
+// NewTrackingHandler 创建位置跟踪处理器
+func NewTrackingHandler(service services.TrackingService) *TrackingHandler {
+	return &TrackingHandler{service: service}
+}
+
+// trackingRequest 解析订单ID和当前用户；viewAll 表示当前角色可查看任意订单
+func trackingRequest(w http.ResponseWriter, r *http.Request) (orderID, userID uint64, viewAll, ok bool) {
+	orderID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "无效的订单ID")
+		return 0, 0, false, false
+	}
+	userID, ok = actingUserID(r)
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return 0, 0, false, false
+	}
+	role, _ := reqctx.Role(r.Context())
+	return orderID, userID, models.HasPermission(role, models.PermFreightViewAll), true
+}
+
+// RecordLocations 承运方上报定位：请求体为单条定位对象，或离线补传时的定位数组
+func (h *TrackingHandler) RecordLocations(w http.ResponseWriter, r *http.Request) {
+	orderID, userID, _, ok := trackingRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPingBodyBytes)).Decode(&raw); err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "无效的请求数据")
+		return
+	}
+	defer r.Body.Close()
+	var pings []*models.LocationPing
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &pings); err != nil {
+			utils.ResponseError(w, http.StatusBadRequest, "无效的请求数据")
+			return
+		}
+	} else {
+		var ping models.LocationPing
+		if err := json.Unmarshal(trimmed, &ping); err != nil {
+			utils.ResponseError(w, http.StatusBadRequest, "无效的请求数据")
+			return
+		}
+		pings = []*models.LocationPing{&ping}
+	}
+	for _, p := range pings {
+		if p == nil {
+			utils.ResponseError(w, http.StatusBadRequest, "无效的请求数据")
+			return
+		}
+	}
+
+	saved, err := h.service.RecordPings(r.Context(), orderID, userID, pings)
+	if err != nil {
+		writeTrackingError(w, err, "上报位置失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusCreated, map[string]interface{}{
+		"message": "上报位置成功",
+		"data": map[string]int{
+			"received": len(pings),
+			"saved":    saved, // 重复上报的定位不会重复保存
+		},
+	})
+}
+
+// GetLatestLocation 查询订单最新位置和预计到达时间
+func (h *TrackingHandler) GetLatestLocation(w http.ResponseWriter, r *http.Request) {
+	orderID, userID, viewAll, ok := trackingRequest(w, r)
+	if !ok {
+		return
+	}
+
+	position, err := h.service.LatestPosition(r.Context(), orderID, userID, viewAll)
+	if err != nil {
+		writeTrackingError(w, err, "查询位置失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "查询位置成功",
+		"data":    position,
+	})
+}
+
+// geoJSONFeature GeoJSON 要素（RFC 7946），坐标顺序为 [经度, 纬度]
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   geoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// GetLocationTrail 以 GeoJSON FeatureCollection 返回订单轨迹：至少两个定位时包含一条 LineString 轨迹
+// （properties.timestamps 与坐标一一对应），并以 Point 要素标出最新位置
+func (h *TrackingHandler) GetLocationTrail(w http.ResponseWriter, r *http.Request) {
+	orderID, userID, viewAll, ok := trackingRequest(w, r)
+	if !ok {
+		return
+	}
+
+	trail, err := h.service.Trail(r.Context(), orderID, userID, viewAll)
+	if err != nil {
+		writeTrackingError(w, err, "查询轨迹失败")
+		return
+	}
+
+	features := []geoJSONFeature{}
+	if len(trail) >= 2 {
+		coordinates := make([][2]float64, 0, len(trail))
+		timestamps := make([]string, 0, len(trail))
+		for _, p := range trail {
+			coordinates = append(coordinates, [2]float64{p.Lng, p.Lat})
+			timestamps = append(timestamps, p.RecordedAt.Format(time.RFC3339))
+		}
+		features = append(features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONGeometry{Type: "LineString", Coordinates: coordinates},
+			Properties: map[string]interface{}{"order_id": orderID, "timestamps": timestamps},
+		})
+	}
+	if len(trail) > 0 {
+		latest := trail[len(trail)-1]
+		features = append(features, geoJSONFeature{
+			Type:     "Feature",
+			Geometry: geoJSONGeometry{Type: "Point", Coordinates: [2]float64{latest.Lng, latest.Lat}},
+			Properties: map[string]interface{}{
+				"order_id":  orderID,
+				"timestamp": latest.RecordedAt.Format(time.RFC3339),
+				"speed":     latest.SpeedKmh,
+				"heading":   latest.Heading,
+			},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/geo+json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"type":     "FeatureCollection",
+		"features": features,
+	})
+}
+
+// writeTrackingError 按位置跟踪服务错误输出响应
+func writeTrackingError(w http.ResponseWriter, err error, failMsg string) {
+	var fieldErr *services.FreightFieldError
+	switch {
+	case errors.As(err, &fieldErr):
+		writeFieldError(w, fieldErr)
+	case errors.Is(err, services.ErrNoLocation):
+		utils.ResponseError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrTrackingInactive):
+		utils.ResponseError(w, http.StatusConflict, err.Error())
+	default:
+		writeFreightError(w, err, failMsg)
+	}
+}
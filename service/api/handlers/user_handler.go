@@ -2,20 +2,28 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 
+	"freight/api/reqctx"
 	"freight/services"
 	"freight/utils"
+
+	"github.com/gorilla/mux"
 )
 
 // UserHandler 用户处理函数
 type UserHandler struct {
-	UserService services.UserService
+	UserService  services.UserService
+	TokenService services.TokenService // 注销账号时吊销当前访问令牌，可为nil
+	// TrustedProxies 受信任的反向代理，为空时登录防护按连接地址统计IP
+	TrustedProxies utils.TrustedProxies
 }
 
 // NewUserHandler 创建用户处理函数实例
-func NewUserHandler(userService services.UserService) *UserHandler {
-	return &UserHandler{UserService: userService}
+func NewUserHandler(userService services.UserService, tokenService services.TokenService) *UserHandler {
+	return &UserHandler{UserService: userService, TokenService: tokenService}
 }
 
 // Register 用户注册
@@ -68,16 +76,27 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 验证登录（调用已支持邮箱的服务层）
-	user, token, err := h.UserService.Login(req.LoginID, req.Password)
+	user, tokens, err := h.UserService.Login(req.LoginID, req.Password, h.TrustedProxies.ClientIP(r))
 	if err != nil {
-		utils.ResponseError(w, http.StatusUnauthorized, err.Error())
+		switch {
+		case errors.Is(err, services.ErrInvalidCredentials):
+			utils.ResponseError(w, http.StatusUnauthorized, err.Error())
+		case errors.Is(err, services.ErrTooManyAttempts):
+			utils.ResponseError(w, http.StatusTooManyRequests, err.Error())
+		case errors.Is(err, services.ErrEmailNotVerified), errors.Is(err, services.ErrAccountDeactivated):
+			utils.ResponseError(w, http.StatusForbidden, err.Error())
+		default:
+			utils.ResponseError(w, http.StatusInternalServerError, "登录失败")
+		}
 		return
 	}
 
 	// 返回成功响应
 	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
-		"message": "登录成功",
-		"token":   token,
+		"message":       "登录成功",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
 		"user": map[string]interface{}{
 			"id":       user.ID,
 			"username": user.Username,
@@ -85,37 +104,35 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 			"avatar":   user.AvatarURL,
 			"role":     user.Role,
 			"six":      user.Six,
-			"token":    token,
+			"token":    tokens.AccessToken,
 		},
 	})
 }
 
 // UpdateGender 更新用户性别（仅支持更新为man或women）
 func (h *UserHandler) UpdateGender(w http.ResponseWriter, r *http.Request) {
-	// 1. 解析请求体中的性别参数
+	// 1. 从认证信息获取当前用户ID（忽略请求体中的user_id）
+	userID, ok := reqctx.UserID(r.Context())
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+
+	// 2. 解析请求体中的性别参数并校验
 	var req struct {
-		UserId int64  `json:"user_id"`
 		Gender string `json:"gender"` // 只能是"man"或"women"
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.ResponseError(w, http.StatusBadRequest, "请求格式错误")
 		return
 	}
-
-	// 2. 从中间件处理后的上下文获取用户ID
-	// （假设中间件已将用户ID存入r.Context()的"userID"键）
-	// 2. 参数校验
-	if req.UserId <= 0 {
-		utils.ResponseError(w, http.StatusBadRequest, "无效的用户ID")
-		return
-	}
 	if req.Gender != "man" && req.Gender != "women" {
 		utils.ResponseError(w, http.StatusBadRequest, "性别参数无效，必须是'man'或'women'")
 		return
 	}
 
 	// 3. 调用服务层更新性别
-	updatedUser, err := h.UserService.UpdateGender(req.UserId, req.Gender)
+	updatedUser, err := h.UserService.UpdateGender(userID, req.Gender)
 	if err != nil {
 		utils.ResponseError(w, http.StatusBadRequest, err.Error())
 		return
@@ -131,3 +148,293 @@ func (h *UserHandler) UpdateGender(w http.ResponseWriter, r *http.Request) {
 		},
 	})
 }
+
+// UpdateRole 管理员为用户分配角色
+func (h *UserHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || userID <= 0 {
+		utils.ResponseError(w, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "请求格式错误")
+		return
+	}
+
+	updatedUser, err := h.UserService.UpdateRole(userID, req.Role)
+	if err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "角色更新成功",
+		"user": map[string]interface{}{
+			"id":       updatedUser.ID,
+			"username": updatedUser.Username,
+			"role":     updatedUser.Role,
+		},
+	})
+}
+
+// GetMe 获取当前用户资料
+func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := reqctx.UserID(r.Context())
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+
+	user, err := h.UserService.GetUserByID(userID)
+	if err != nil {
+		writeProfileError(w, err, "获取用户资料失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"user": user,
+	})
+}
+
+// UpdateMe 修改当前用户资料（用户名、手机号、头像，未提供的字段不修改）
+func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := reqctx.UserID(r.Context())
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+
+	var req services.ProfileUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "请求格式错误")
+		return
+	}
+
+	user, err := h.UserService.UpdateProfile(userID, req)
+	if err != nil {
+		writeProfileError(w, err, "更新资料失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "资料更新成功",
+		"user":    user,
+	})
+}
+
+// ChangePassword 校验原密码后修改密码
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := reqctx.UserID(r.Context())
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+
+	var req struct {
+		OldPassword string `json:"old_password"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "请求格式错误")
+		return
+	}
+	if req.OldPassword == "" || req.NewPassword == "" {
+		utils.ResponseError(w, http.StatusBadRequest, "原密码和新密码不能为空")
+		return
+	}
+
+	if err := h.UserService.ChangePassword(userID, req.OldPassword, req.NewPassword); err != nil {
+		writeProfileError(w, err, "修改密码失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "密码修改成功，其他设备需重新登录",
+	})
+}
+
+// Deactivate 注销当前账号（需再次输入密码）
+func (h *UserHandler) Deactivate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := reqctx.UserID(r.Context())
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" {
+		utils.ResponseError(w, http.StatusBadRequest, "密码不能为空")
+		return
+	}
+
+	if err := h.UserService.Deactivate(userID, req.Password); err != nil {
+		writeProfileError(w, err, "注销账号失败")
+		return
+	}
+
+	// 当前访问令牌立即失效
+	if h.TokenService != nil {
+		if jti, expiresAt, ok := reqctx.Token(r.Context()); ok {
+			if err := h.TokenService.Logout(userID, jti, expiresAt, ""); err != nil {
+				utils.ResponseError(w, http.StatusInternalServerError, "注销账号失败")
+				return
+			}
+		}
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "账号已注销",
+	})
+}
+
+// writeProfileError 资料相关错误映射为HTTP状态码
+func writeProfileError(w http.ResponseWriter, err error, failMsg string) {
+	var fieldErr *services.ProfileFieldError
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		utils.ResponseError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrUsernameTaken):
+		utils.ResponseError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrWrongPassword), errors.Is(err, services.ErrPasswordTooShort), errors.As(err, &fieldErr):
+		utils.ResponseError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrTooManyAttempts):
+		utils.ResponseError(w, http.StatusTooManyRequests, err.Error())
+	default:
+		utils.ResponseError(w, http.StatusInternalServerError, failMsg)
+	}
+}
+
+// UnlockAccount 管理员解除账号的登录锁定
+func (h *UserHandler) UnlockAccount(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := reqctx.UserID(r.Context())
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+	userID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || userID <= 0 {
+		utils.ResponseError(w, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	unlocked, err := h.UserService.UnlockAccount(userID, adminID)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			utils.ResponseError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ResponseError(w, http.StatusInternalServerError, "解除锁定失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message":  "账号已解除锁定",
+		"unlocked": unlocked,
+	})
+}
+
+// VerifyEmail 验证邮箱（令牌可来自查询参数，便于邮件链接直接打开；也可来自请求体）
+func (h *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" && r.Method == http.MethodPost {
+		var req struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.ResponseError(w, http.StatusBadRequest, "请求格式错误")
+			return
+		}
+		token = req.Token
+	}
+
+	if err := h.UserService.VerifyEmail(token); err != nil {
+		writeAccountError(w, err, "邮箱验证失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "邮箱验证成功",
+	})
+}
+
+// ResendVerification 重新发送验证邮件
+func (h *UserHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		utils.ResponseError(w, http.StatusBadRequest, "邮箱不能为空")
+		return
+	}
+
+	if err := h.UserService.ResendVerification(req.Email); err != nil {
+		utils.ResponseError(w, http.StatusInternalServerError, "发送验证邮件失败")
+		return
+	}
+
+	// 无论邮箱是否存在都返回相同响应
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "如果该邮箱已注册且未验证，验证邮件已发送",
+	})
+}
+
+// ForgotPassword 申请重置密码
+func (h *UserHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		utils.ResponseError(w, http.StatusBadRequest, "邮箱不能为空")
+		return
+	}
+
+	if err := h.UserService.ForgotPassword(req.Email); err != nil {
+		utils.ResponseError(w, http.StatusInternalServerError, "发送重置邮件失败")
+		return
+	}
+
+	// 无论邮箱是否存在都返回相同响应
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "如果该邮箱已注册，重置邮件已发送",
+	})
+}
+
+// ResetPassword 使用重置令牌设置新密码
+func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "请求格式错误")
+		return
+	}
+	if req.Token == "" || req.Password == "" {
+		utils.ResponseError(w, http.StatusBadRequest, "令牌和新密码不能为空")
+		return
+	}
+
+	if err := h.UserService.ResetPassword(req.Token, req.Password); err != nil {
+		writeAccountError(w, err, "重置密码失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "密码重置成功",
+	})
+}
+
+// writeAccountError 令牌无效等业务错误返回400，其余返回500
+func writeAccountError(w http.ResponseWriter, err error, failMsg string) {
+	if errors.Is(err, services.ErrInvalidUserToken) || errors.Is(err, services.ErrPasswordTooShort) {
+		utils.ResponseError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.ResponseError(w, http.StatusInternalServerError, failMsg)
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"freight/models"
+	"freight/services"
+	"freight/utils"
+	"github.com/gorilla/mux"
+)
+
+type VehicleHandler struct {
+	service services.VehicleService
+}
+
+// NewVehicleHandler 创建车辆处理器
+func NewVehicleHandler(service services.VehicleService) *VehicleHandler {
+	return &VehicleHandler{service: service}
+}
+
+// ListVehicles 列出本人登记的车辆
+func (h *VehicleHandler) ListVehicles(w http.ResponseWriter, r *http.Request) {
+	userID, ok := actingUserID(r)
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+
+	vehicles, err := h.service.ListVehicles(r.Context(), userID)
+	if err != nil {
+		writeVehicleError(w, err, "查询车辆失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "查询车辆成功",
+		"data":    vehicles,
+	})
+}
+
+// CreateVehicle 登记车辆
+func (h *VehicleHandler) CreateVehicle(w http.ResponseWriter, r *http.Request) {
+	userID, ok := actingUserID(r)
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+
+	var vehicle models.Vehicle
+	if err := json.NewDecoder(r.Body).Decode(&vehicle); err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "无效的请求数据")
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.service.CreateVehicle(r.Context(), userID, &vehicle); err != nil {
+		writeVehicleError(w, err, "登记车辆失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusCreated, map[string]interface{}{
+		"message": "登记车辆成功",
+		"data":    vehicle,
+	})
+}
+
+// GetVehicle 查询本人的车辆
+func (h *VehicleHandler) GetVehicle(w http.ResponseWriter, r *http.Request) {
+	vehicleID, userID, ok := vehicleRequest(w, r)
+	if !ok {
+		return
+	}
+
+	vehicle, err := h.service.GetVehicle(r.Context(), vehicleID, userID)
+	if err != nil {
+		writeVehicleError(w, err, "查询车辆失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "查询车辆成功",
+		"data":    vehicle,
+	})
+}
+
+// UpdateVehicle 修改本人的车辆
+func (h *VehicleHandler) UpdateVehicle(w http.ResponseWriter, r *http.Request) {
+	vehicleID, userID, ok := vehicleRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var vehicle models.Vehicle
+	if err := json.NewDecoder(r.Body).Decode(&vehicle); err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "无效的请求数据")
+		return
+	}
+	defer r.Body.Close()
+	vehicle.ID = vehicleID
+
+	if err := h.service.UpdateVehicle(r.Context(), userID, &vehicle); err != nil {
+		writeVehicleError(w, err, "修改车辆失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "修改车辆成功",
+		"data":    vehicle,
+	})
+}
+
+// DeleteVehicle 删除本人的车辆
+func (h *VehicleHandler) DeleteVehicle(w http.ResponseWriter, r *http.Request) {
+	vehicleID, userID, ok := vehicleRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteVehicle(r.Context(), vehicleID, userID); err != nil {
+		writeVehicleError(w, err, "删除车辆失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]string{
+		"message": "删除车辆成功",
+	})
+}
+
+// vehicleRequest 解析路径中的车辆ID和当前用户，失败时已写入响应
+func vehicleRequest(w http.ResponseWriter, r *http.Request) (vehicleID, userID uint64, ok bool) {
+	vehicleID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "无效的车辆ID")
+		return 0, 0, false
+	}
+	userID, ok = actingUserID(r)
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return 0, 0, false
+	}
+	return vehicleID, userID, true
+}
+
+// writeVehicleError 按车辆服务错误输出响应
+func writeVehicleError(w http.ResponseWriter, err error, failMsg string) {
+	var fieldErr *services.FreightFieldError
+	switch {
+	case errors.As(err, &fieldErr):
+		writeFieldError(w, fieldErr)
+	case errors.Is(err, services.ErrVehicleNotFound):
+		utils.ResponseError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrPlateTaken):
+		utils.ResponseError(w, http.StatusConflict, err.Error())
+	default:
+		utils.ResponseError(w, http.StatusInternalServerError, failMsg)
+	}
+}
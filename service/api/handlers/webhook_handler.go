@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"freight/api/reqctx"
+	"freight/models"
+	"freight/services"
+	"freight/utils"
+	"github.com/gorilla/mux"
+)
+
+type WebhookHandler struct {
+	service services.WebhookService
+}
+
+// NewWebhookHandler 创建 Webhook 处理器
+func NewWebhookHandler(service services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+// webhookActor 当前用户及其订阅权限，未认证时已写入响应
+func webhookActor(w http.ResponseWriter, r *http.Request) (services.WebhookActor, bool) {
+	userID, ok := actingUserID(r)
+	if !ok {
+		utils.ResponseError(w, http.StatusUnauthorized, "用户未认证")
+		return services.WebhookActor{}, false
+	}
+	role, _ := reqctx.Role(r.Context())
+	return services.WebhookActor{
+		UserID:  userID,
+		ViewAll: models.HasPermission(role, models.PermFreightViewAll),
+		Admin:   models.HasPermission(role, models.PermWebhookAdmin),
+	}, true
+}
+
+// webhookRequest 解析路径中的订阅ID和当前用户，失败时已写入响应
+func webhookRequest(w http.ResponseWriter, r *http.Request) (uint64, services.WebhookActor, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "无效的订阅ID")
+		return 0, services.WebhookActor{}, false
+	}
+	actor, ok := webhookActor(w, r)
+	return id, actor, ok
+}
+
+// CreateWebhook 创建订阅，响应中包含签名密钥（之后不再返回）
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	actor, ok := webhookActor(w, r)
+	if !ok {
+		return
+	}
+	var req services.WebhookSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "无效的请求数据")
+		return
+	}
+	defer r.Body.Close()
+
+	sub, err := h.service.CreateSubscription(r.Context(), actor, req)
+	if err != nil {
+		writeWebhookError(w, err, "创建订阅失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusCreated, map[string]interface{}{
+		"message": "创建订阅成功",
+		"data":    sub,
+	})
+}
+
+// ListWebhooks 本人的订阅
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	actor, ok := webhookActor(w, r)
+	if !ok {
+		return
+	}
+	subs, err := h.service.ListSubscriptions(r.Context(), actor)
+	if err != nil {
+		writeWebhookError(w, err, "查询订阅失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "查询订阅成功",
+		"data":    subs,
+	})
+}
+
+// GetWebhook 查询订阅
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id, actor, ok := webhookRequest(w, r)
+	if !ok {
+		return
+	}
+	sub, err := h.service.GetSubscription(r.Context(), actor, id)
+	if err != nil {
+		writeWebhookError(w, err, "查询订阅失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "查询订阅成功",
+		"data":    sub,
+	})
+}
+
+// UpdateWebhook 修改订阅（密钥不可修改，需要更换时删除后重新创建）
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, actor, ok := webhookRequest(w, r)
+	if !ok {
+		return
+	}
+	var req services.WebhookSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "无效的请求数据")
+		return
+	}
+	defer r.Body.Close()
+
+	sub, err := h.service.UpdateSubscription(r.Context(), actor, id, req)
+	if err != nil {
+		writeWebhookError(w, err, "修改订阅失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "修改订阅成功",
+		"data":    sub,
+	})
+}
+
+// DeleteWebhook 删除订阅
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, actor, ok := webhookRequest(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteSubscription(r.Context(), actor, id); err != nil {
+		writeWebhookError(w, err, "删除订阅失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]string{
+		"message": "删除订阅成功",
+	})
+}
+
+// ListDeliveries 投递记录（管理员），可按 subscription_id、status 过滤，按 before_id 向前翻页
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	h.listDeliveries(w, r, r.URL.Query().Get("status"))
+}
+
+// ListDeadLetters 重试次数用尽的投递（管理员）
+func (h *WebhookHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	h.listDeliveries(w, r, models.WebhookDead)
+}
+
+func (h *WebhookHandler) listDeliveries(w http.ResponseWriter, r *http.Request, status string) {
+	switch status {
+	case "", models.WebhookPending, models.WebhookDelivered, models.WebhookDead:
+	default:
+		writeQueryParamError(w, &QueryParamError{Param: "status", Reason: "只能为 pending、delivered 或 dead"})
+		return
+	}
+	query := r.URL.Query()
+	var params [3]int
+	for i, name := range []string{"subscription_id", "before_id", "limit"} {
+		n, paramErr := parsePositiveInt(query, name)
+		if paramErr != nil {
+			writeQueryParamError(w, paramErr)
+			return
+		}
+		params[i] = n
+	}
+
+	deliveries, err := h.service.ListDeliveries(r.Context(), models.WebhookDeliveryFilter{
+		SubscriptionID: uint64(params[0]), Status: status, BeforeID: uint64(params[1]), Limit: params[2],
+	})
+	if err != nil {
+		writeWebhookError(w, err, "查询投递记录失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "查询投递记录成功",
+		"data":    deliveries,
+	})
+}
+
+// Redeliver 立即重新投递（管理员），返回本次投递结果
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ResponseError(w, http.StatusBadRequest, "无效的投递ID")
+		return
+	}
+
+	delivery, err := h.service.Redeliver(r.Context(), id)
+	if err != nil {
+		writeWebhookError(w, err, "重新投递失败")
+		return
+	}
+
+	utils.ResponseJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "已重新投递",
+		"data":    delivery,
+	})
+}
+
+// writeWebhookError 按 Webhook 服务错误输出响应
+func writeWebhookError(w http.ResponseWriter, err error, failMsg string) {
+	var fieldErr *services.FreightFieldError
+	switch {
+	case errors.As(err, &fieldErr):
+		writeFieldError(w, fieldErr)
+	case errors.Is(err, services.ErrWebhookNotFound), errors.Is(err, services.ErrDeliveryNotFound):
+		utils.ResponseError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrPermissionDenied):
+		utils.ResponseForbidden(w, err.Error())
+	default:
+		utils.ResponseError(w, http.StatusInternalServerError, failMsg)
+	}
+}
@@ -1,21 +1,30 @@
 package middleware
 
 import (
-	"context"
+	"math"
 	"net/http"
 	"strings"
+	"time"
 
+	"freight/api/reqctx"
+	"freight/models"
 	"freight/utils"
 )
 
+// TokenRevocationChecker 判断访问令牌是否已被吊销（按 jti 单独吊销，或按用户吊销签发时间不晚于某时间点的令牌）
+type TokenRevocationChecker interface {
+	IsRevoked(jti string, userID int64, issuedAt time.Time) (bool, error)
+}
+
 // AuthMiddleware JWT认证中间件
 type AuthMiddleware struct {
-	JwtSecret string
+	JwtSecret   string
+	Revocations TokenRevocationChecker // 为nil时不检查吊销
 }
 
 // NewAuthMiddleware 创建认证中间件实例
-func NewAuthMiddleware(jwtSecret string) *AuthMiddleware {
-	return &AuthMiddleware{JwtSecret: jwtSecret}
+func NewAuthMiddleware(jwtSecret string, revocations TokenRevocationChecker) *AuthMiddleware {
+	return &AuthMiddleware{JwtSecret: jwtSecret, Revocations: revocations}
 }
 
 // Handler 中间件处理函数
@@ -57,12 +66,47 @@ func (m *AuthMiddleware) Handler(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
+		// 检查令牌是否已被吊销（登出、角色变更、注销账号）；旧token不含 iat 时按最早签发处理
+		jti, _ := claims["jti"].(string)
+		if m.Revocations != nil {
+			iat, _ := claims["iat"].(float64)
+			revoked, err := m.Revocations.IsRevoked(jti, int64(userID), time.UnixMilli(int64(math.Round(iat*1000))))
+			if err != nil {
+				utils.ResponseError(w, http.StatusInternalServerError, "校验token失败")
+				return
+			}
+			if revoked {
+				utils.ResponseError(w, http.StatusUnauthorized, "token已失效")
+				return
+			}
+		}
+		exp, _ := claims["exp"].(float64)
+
+		// 角色（旧token不含角色时按普通用户处理）
+		role, _ := claims["role"].(string)
+		if role == "" {
+			role = models.RoleUser
+		}
+
 		// 将用户信息添加到请求上下文中
-		ctx := context.WithValue(r.Context(), "user_id", int64(userID))
-		ctx = context.WithValue(ctx, "username", username)
+		ctx := reqctx.WithUser(r.Context(), int64(userID), username)
+		ctx = reqctx.WithRole(ctx, role)
+		ctx = reqctx.WithToken(ctx, jti, time.Unix(int64(exp), 0))
 		r = r.WithContext(ctx)
 
 		// 继续处理请求
 		next(w, r)
 	}
 }
+
+// QueryTokenHandler 与 Handler 相同，但请求头没有认证信息时从 access_token 查询参数读取令牌。
+// 仅用于浏览器无法设置请求头的 WebSocket 连接。
+func (m *AuthMiddleware) QueryTokenHandler(next http.HandlerFunc) http.HandlerFunc {
+	handler := m.Handler(next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token := r.URL.Query().Get("access_token"); token != "" && r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		handler(w, r)
+	}
+}
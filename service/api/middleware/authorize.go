@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"freight/api/reqctx"
+	"freight/models"
+	"freight/utils"
+)
+
+// RequireRoles 要求当前用户为指定角色之一（管理员始终放行），需在 AuthMiddleware 之后使用
+func RequireRoles(roles ...string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			role, ok := reqctx.Role(r.Context())
+			if !ok {
+				utils.ResponseForbidden(w, "缺少角色信息")
+				return
+			}
+			if role == models.RoleAdmin {
+				next(w, r)
+				return
+			}
+			for _, allowed := range roles {
+				if role == allowed {
+					next(w, r)
+					return
+				}
+			}
+			utils.ResponseForbidden(w, "需要角色："+strings.Join(roles, "/"))
+		}
+	}
+}
+
+// RequirePermissions 要求当前用户角色拥有全部指定权限，需在 AuthMiddleware 之后使用
+func RequirePermissions(perms ...string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			role, ok := reqctx.Role(r.Context())
+			if !ok {
+				utils.ResponseForbidden(w, "缺少角色信息")
+				return
+			}
+			for _, perm := range perms {
+				if !models.HasPermission(role, perm) {
+					utils.ResponseForbidden(w, "需要权限："+perm)
+					return
+				}
+			}
+			next(w, r)
+		}
+	}
+}
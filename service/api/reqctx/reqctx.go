@@ -0,0 +1,72 @@
+// Package reqctx 请求上下文辅助函数，使用私有类型的key避免与其他包冲突
+package reqctx
+
+import (
+	"context"
+	"time"
+)
+
+// contextKey 上下文key类型（不导出，外部只能通过本包函数读写）
+type contextKey int
+
+const (
+	userIDKey contextKey = iota
+	usernameKey
+	roleKey
+	tokenIDKey
+	tokenExpiryKey
+	freightIDKey
+)
+
+// WithUser 将认证用户信息写入上下文
+func WithUser(ctx context.Context, userID int64, username string) context.Context {
+	ctx = context.WithValue(ctx, userIDKey, userID)
+	return context.WithValue(ctx, usernameKey, username)
+}
+
+// UserID 获取当前认证用户ID
+func UserID(ctx context.Context) (int64, bool) {
+	userID, ok := ctx.Value(userIDKey).(int64)
+	return userID, ok && userID > 0
+}
+
+// Username 获取当前认证用户名
+func Username(ctx context.Context) (string, bool) {
+	username, ok := ctx.Value(usernameKey).(string)
+	return username, ok
+}
+
+// WithRole 将认证用户角色写入上下文
+func WithRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, roleKey, role)
+}
+
+// Role 获取当前认证用户角色
+func Role(ctx context.Context) (string, bool) {
+	role, ok := ctx.Value(roleKey).(string)
+	return role, ok && role != ""
+}
+
+// WithToken 将访问令牌ID（jti）及过期时间写入上下文（用于登出吊销）
+func WithToken(ctx context.Context, jti string, expiresAt time.Time) context.Context {
+	ctx = context.WithValue(ctx, tokenIDKey, jti)
+	return context.WithValue(ctx, tokenExpiryKey, expiresAt)
+}
+
+// Token 获取当前访问令牌ID及过期时间
+func Token(ctx context.Context) (string, time.Time, bool) {
+	jti, ok := ctx.Value(tokenIDKey).(string)
+	expiresAt, _ := ctx.Value(tokenExpiryKey).(time.Time)
+	return jti, expiresAt, ok && jti != ""
+}
+
+// WithFreightID 将路径中的订单ID写入上下文
+func WithFreightID(ctx context.Context, freightID string) context.Context {
+	return context.WithValue(ctx, freightIDKey, freightID)
+}
+
+// FreightID 获取路径中的订单ID
+func FreightID(ctx context.Context) (string, bool) {
+	freightID, ok := ctx.Value(freightIDKey).(string)
+	return freightID, ok
+}
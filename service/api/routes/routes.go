@@ -1,65 +1,140 @@
 package routes
 
 import (
-	"context"
 	"net/http"
 
 	"freight/api/handlers"
 	"freight/api/middleware"
+	"freight/api/reqctx"
+	"freight/models"
+	"freight/region"
 	"freight/services"
+	"freight/utils"
 
 	"github.com/gorilla/mux" // 引入gorilla/mux
 )
 
-func SetupRoutes(
-	userService services.UserService,
-	configService services.ConfigService,
-	freightService services.FreightService,
-	authMiddleware *middleware.AuthMiddleware,
-) http.Handler {
+// Deps 路由依赖，未设置的服务对应的路由仍会注册（测试中按需设置）
+type Deps struct {
+	UserService     services.UserService
+	ConfigService   services.ConfigService
+	FreightService  services.FreightService
+	TokenService    services.TokenService
+	QuoteService    services.QuoteService
+	BidService      services.BidService
+	VehicleService  services.VehicleService
+	TrackingService services.TrackingService
+	ChatService     services.ChatService
+	WebhookService  services.WebhookService
+	Jobs            handlers.JobStatusProvider
+	EventHandler    *handlers.EventHandler // 为空时不注册订单事件推送
+	Regions         *region.Tree           // 为空时使用内置区划数据
+	AuthMiddleware  *middleware.AuthMiddleware
+	TrustedProxies  utils.TrustedProxies // 登录防护按客户端IP统计时信任其请求头的反向代理
+}
+
+func SetupRoutes(deps Deps) http.Handler {
 	r := mux.NewRouter() // 使用gorilla/mux的路由器
 
+	regions := deps.Regions
+	if regions == nil {
+		regions = region.Default()
+	}
+	authMiddleware := deps.AuthMiddleware
+	eventHandler := deps.EventHandler
+
 	// 创建处理器实例
-	userHandler := handlers.NewUserHandler(userService)
-	configHandler := handlers.NewConfigHandler(configService)
-	freightHandler := handlers.NewFreightHandler(freightService)
+	userHandler := handlers.NewUserHandler(deps.UserService, deps.TokenService)
+	userHandler.TrustedProxies = deps.TrustedProxies
+	configHandler := handlers.NewConfigHandler(deps.ConfigService)
+	freightHandler := handlers.NewFreightHandler(deps.FreightService)
+	tokenHandler := handlers.NewTokenHandler(deps.TokenService)
+	regionHandler := handlers.NewRegionHandler(regions)
+	quoteHandler := handlers.NewQuoteHandler(deps.QuoteService)
+	bidHandler := handlers.NewBidHandler(deps.BidService)
+	vehicleHandler := handlers.NewVehicleHandler(deps.VehicleService)
+	trackingHandler := handlers.NewTrackingHandler(deps.TrackingService)
+	jobHandler := handlers.NewJobHandler(deps.Jobs)
+	chatHandler := handlers.NewChatHandler(deps.ChatService)
+	webhookHandler := handlers.NewWebhookHandler(deps.WebhookService)
 
 	// 用户路由
 	r.HandleFunc("/api/users/register", userHandler.Register).Methods("POST")
 	r.HandleFunc("/api/users/login", userHandler.Login).Methods("POST")
-	r.HandleFunc("/api/users/gender", userHandler.UpdateGender).Methods("PATCH")
+	r.HandleFunc("/api/users/refresh", tokenHandler.Refresh).Methods("POST")
+	r.HandleFunc("/api/users/logout", authMiddleware.Handler(tokenHandler.Logout)).Methods("POST")
+	r.HandleFunc("/api/users/email/verify", userHandler.VerifyEmail).Methods("GET", "POST")
+	r.HandleFunc("/api/users/email/resend", userHandler.ResendVerification).Methods("POST")
+	r.HandleFunc("/api/users/password/forgot", userHandler.ForgotPassword).Methods("POST")
+	r.HandleFunc("/api/users/password/reset", userHandler.ResetPassword).Methods("POST")
+	r.HandleFunc("/api/users/gender", authMiddleware.Handler(userHandler.UpdateGender)).Methods("PATCH")
+
+	// 当前用户资料
+	r.HandleFunc("/api/users/me", authMiddleware.Handler(userHandler.GetMe)).Methods("GET")
+	r.HandleFunc("/api/users/me", authMiddleware.Handler(userHandler.UpdateMe)).Methods("PATCH")
+	r.HandleFunc("/api/users/me/password", authMiddleware.Handler(userHandler.ChangePassword)).Methods("POST")
+	r.HandleFunc("/api/users/me/deactivate", authMiddleware.Handler(userHandler.Deactivate)).Methods("POST")
+
+	// 权限声明：在认证之后按角色/权限校验
+	requirePerm := func(perm string, next http.HandlerFunc) http.HandlerFunc {
+		return authMiddleware.Handler(middleware.RequirePermissions(perm)(next))
+	}
+
+	// 管理员为用户分配角色
+	r.HandleFunc("/api/users/{id:[0-9]+}/role", requirePerm(models.PermUserManage, userHandler.UpdateRole)).Methods("PATCH")
+	// 管理员解除登录锁定
+	r.HandleFunc("/api/users/{id:[0-9]+}/unlock", requirePerm(models.PermUserManage, userHandler.UnlockAccount)).Methods("POST")
+
+	// 管理员查看定时任务运行状态
+	r.HandleFunc("/api/admin/jobs", requirePerm(models.PermSystemMonitor, jobHandler.ListJobs)).Methods("GET")
 
-	// 配置路由
+	// Webhook 订阅（外部系统接收订单事件），投递记录和重新投递仅管理员
+	r.HandleFunc("/api/webhooks", authMiddleware.Handler(webhookHandler.ListWebhooks)).Methods("GET")
+	r.HandleFunc("/api/webhooks", authMiddleware.Handler(webhookHandler.CreateWebhook)).Methods("POST")
+	r.HandleFunc("/api/webhooks/{id:[0-9]+}", authMiddleware.Handler(webhookHandler.GetWebhook)).Methods("GET")
+	r.HandleFunc("/api/webhooks/{id:[0-9]+}", authMiddleware.Handler(webhookHandler.UpdateWebhook)).Methods("PUT")
+	r.HandleFunc("/api/webhooks/{id:[0-9]+}", authMiddleware.Handler(webhookHandler.DeleteWebhook)).Methods("DELETE")
+	r.HandleFunc("/api/admin/webhooks/deliveries", requirePerm(models.PermWebhookAdmin, webhookHandler.ListDeliveries)).Methods("GET")
+	r.HandleFunc("/api/admin/webhooks/dead-letters", requirePerm(models.PermWebhookAdmin, webhookHandler.ListDeadLetters)).Methods("GET")
+	r.HandleFunc("/api/admin/webhooks/deliveries/{id:[0-9]+}/redeliver", requirePerm(models.PermWebhookAdmin, webhookHandler.Redeliver)).Methods("POST")
+
+	// 配置路由（读取需登录，写操作仅管理员）
 	configRouter := r.PathPrefix("/api/configs").Subrouter()
-	configRouter.HandleFunc("", authMiddleware.Handler(func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodPost:
-			configHandler.CreateConfig(w, r)
-		case http.MethodGet:
-			configHandler.GetConfig(w, r)
-		case http.MethodPut:
-			configHandler.UpdateConfig(w, r)
-		case http.MethodDelete:
-			configHandler.DeleteConfig(w, r)
-		default:
-			http.Error(w, "方法不允许", http.StatusMethodNotAllowed)
-		}
-	})).Methods("POST", "GET", "PUT", "DELETE")
+	configRouter.HandleFunc("", authMiddleware.Handler(configHandler.GetConfig)).Methods("GET")
+	configRouter.HandleFunc("", requirePerm(models.PermConfigWrite, configHandler.CreateConfig)).Methods("POST")
+	configRouter.HandleFunc("", requirePerm(models.PermConfigWrite, configHandler.UpdateConfig)).Methods("PUT")
+	configRouter.HandleFunc("", requirePerm(models.PermConfigWrite, configHandler.DeleteConfig)).Methods("DELETE")
 
 	configRouter.HandleFunc("/list", authMiddleware.Handler(configHandler.ListConfigs)).Methods("GET")
 
+	// 行政区划（公开数据，无需登录）
+	r.HandleFunc("/api/regions", regionHandler.ListRegions).Methods("GET")
+
+	// 运费报价（发货方）
+	r.HandleFunc("/api/quotes", requirePerm(models.PermFreightPublish, quoteHandler.CreateQuote)).Methods("POST")
+	r.HandleFunc("/api/quotes/{id:[0-9a-f]+}", authMiddleware.Handler(quoteHandler.GetQuote)).Methods("GET")
+	r.HandleFunc("/api/quotes/{id:[0-9a-f]+}/order", requirePerm(models.PermFreightPublish, quoteHandler.CreateOrderFromQuote)).Methods("POST")
+
+	// 承运方查看本人出价
+	r.HandleFunc("/api/bids/mine", authMiddleware.Handler(bidHandler.ListMyBids)).Methods("GET")
+
+	// 承运方车辆登记
+	r.HandleFunc("/api/vehicles", requirePerm(models.PermFreightAccept, vehicleHandler.ListVehicles)).Methods("GET")
+	r.HandleFunc("/api/vehicles", requirePerm(models.PermFreightAccept, vehicleHandler.CreateVehicle)).Methods("POST")
+	r.HandleFunc("/api/vehicles/{id:[0-9]+}", requirePerm(models.PermFreightAccept, vehicleHandler.GetVehicle)).Methods("GET")
+	r.HandleFunc("/api/vehicles/{id:[0-9]+}", requirePerm(models.PermFreightAccept, vehicleHandler.UpdateVehicle)).Methods("PUT")
+	r.HandleFunc("/api/vehicles/{id:[0-9]+}", requirePerm(models.PermFreightAccept, vehicleHandler.DeleteVehicle)).Methods("DELETE")
+
 	// 货运路由
+	// 订单事件推送（SSE）：货源大厅及本人相关订单的实时变化，未启用事件总线时不注册
+	if eventHandler != nil {
+		r.HandleFunc("/api/events", authMiddleware.Handler(eventHandler.Stream)).Methods("GET")
+	}
+
 	freightRouter := r.PathPrefix("/api/freights").Subrouter()
-	freightRouter.HandleFunc("", authMiddleware.Handler(func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodPost:
-			freightHandler.CreateFreight(w, r)
-		case http.MethodGet:
-			freightHandler.ListFreights(w, r)
-		default:
-			http.Error(w, "方法不允许", http.StatusMethodNotAllowed)
-		}
-	})).Methods("POST", "GET")
+	freightRouter.HandleFunc("", authMiddleware.Handler(freightHandler.ListFreights)).Methods("GET")
+	freightRouter.HandleFunc("", requirePerm(models.PermFreightPublish, freightHandler.CreateFreight)).Methods("POST")
+	freightRouter.HandleFunc("/search", authMiddleware.Handler(freightHandler.SearchFreights)).Methods("GET")
 
 	// 使用gorilla/mux的正则表达式路径参数
 	freightRouter.HandleFunc("/{id:[0-9]+}", authMiddleware.Handler(func(w http.ResponseWriter, r *http.Request) {
@@ -67,8 +142,7 @@ func SetupRoutes(
 		idStr := vars["id"]
 
 		// 将ID添加到请求上下文
-		ctx := context.WithValue(r.Context(), "freightID", idStr)
-		r = r.WithContext(ctx)
+		r = r.WithContext(reqctx.WithFreightID(r.Context(), idStr))
 
 		switch r.Method {
 		case http.MethodGet:
@@ -85,8 +159,56 @@ func SetupRoutes(
 	// 新增：接单路由（需认证）
 	freightRouter.HandleFunc(
 		"/{id:[0-9]+}/accept",
-		authMiddleware.Handler(freightHandler.AcceptFreight),
+		requirePerm(models.PermFreightAccept, freightHandler.AcceptFreight),
+	).Methods("POST")
+
+	// 竞价：承运方出价，发货方查看并定标
+	freightRouter.HandleFunc(
+		"/{id:[0-9]+}/bids",
+		requirePerm(models.PermFreightAccept, bidHandler.PlaceBid),
+	).Methods("POST")
+	freightRouter.HandleFunc(
+		"/{id:[0-9]+}/bids",
+		authMiddleware.Handler(bidHandler.ListBids),
+	).Methods("GET")
+	freightRouter.HandleFunc(
+		"/{id:[0-9]+}/bids/{bid_id:[0-9]+}/award",
+		requirePerm(models.PermFreightPublish, bidHandler.AwardBid),
+	).Methods("POST")
+
+	// 位置跟踪：承运方上报定位，订单双方查看最新位置和轨迹
+	freightRouter.HandleFunc(
+		"/{id:[0-9]+}/locations",
+		requirePerm(models.PermFreightAccept, trackingHandler.RecordLocations),
 	).Methods("POST")
+	freightRouter.HandleFunc(
+		"/{id:[0-9]+}/locations",
+		authMiddleware.Handler(trackingHandler.GetLocationTrail),
+	).Methods("GET")
+	freightRouter.HandleFunc(
+		"/{id:[0-9]+}/locations/latest",
+		authMiddleware.Handler(trackingHandler.GetLatestLocation),
+	).Methods("GET")
+
+	// 订单沟通：接单后发货方与承运方（及管理员）收发消息，WebSocket 实时推送，REST 查询历史和未读
+	r.HandleFunc("/api/chat/unread", authMiddleware.Handler(chatHandler.UnreadCounts)).Methods("GET")
+	freightRouter.HandleFunc(
+		"/{id:[0-9]+}/chat/ws",
+		authMiddleware.QueryTokenHandler(chatHandler.Connect),
+	).Methods("GET")
+	freightRouter.HandleFunc(
+		"/{id:[0-9]+}/chat/messages",
+		authMiddleware.Handler(chatHandler.ListMessages),
+	).Methods("GET")
+	freightRouter.HandleFunc(
+		"/{id:[0-9]+}/chat/messages",
+		authMiddleware.Handler(chatHandler.SendMessage),
+	).Methods("POST")
+	freightRouter.HandleFunc(
+		"/{id:[0-9]+}/chat/read",
+		authMiddleware.Handler(chatHandler.MarkRead),
+	).Methods("POST")
+
 	freightRouter.HandleFunc(
 		"/user/{user_id:[0-9]+}",
 		authMiddleware.Handler(freightHandler.ListFreightsByUser),
@@ -98,5 +220,15 @@ func SetupRoutes(
 		authMiddleware.Handler(freightHandler.CompleteOrder),
 	).Methods("POST")
 
+	// 订单状态流转及时间线（需认证）
+	freightRouter.HandleFunc(
+		"/{id:[0-9]+}/status",
+		authMiddleware.Handler(freightHandler.TransitionFreight),
+	).Methods("POST")
+	freightRouter.HandleFunc(
+		"/{id:[0-9]+}/history",
+		authMiddleware.Handler(freightHandler.GetFreightHistory),
+	).Methods("GET")
+
 	return r // 返回gorilla/mux的路由器
 }
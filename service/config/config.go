@@ -21,16 +21,99 @@ type DBConfig struct {
 	Loc             string `yaml:"loc"`
 }
 
+// MailConfig 邮件配置
+type MailConfig struct {
+	Driver  string `yaml:"driver"`   // smtp 或 log
+	From    string `yaml:"from"`     // 发件人地址
+	LogFile string `yaml:"log_file"` // log 驱动写入的文件，为空时写标准日志
+	SMTP    struct {
+		Host     string `yaml:"host"`
+		Port     int    `yaml:"port"`
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+	} `yaml:"smtp"`
+}
+
+// AccountConfig 账号安全配置
+type AccountConfig struct {
+	RequireEmailVerification bool   `yaml:"require_email_verification"` // 未验证邮箱时禁止登录
+	VerifyTokenExpiry        int    `yaml:"verify_token_expiry"`        // 邮箱验证令牌有效期（秒）
+	ResetTokenExpiry         int    `yaml:"reset_token_expiry"`         // 密码重置令牌有效期（秒）
+	AppBaseURL               string `yaml:"app_base_url"`               // 邮件中链接的地址前缀
+	MaxLoginFailures         int    `yaml:"max_login_failures"`         // 窗口内单个账号允许的登录失败次数
+	MaxIPLoginFailures       int    `yaml:"max_ip_login_failures"`      // 窗口内单个IP允许的登录失败次数
+	LoginFailureWindow       int    `yaml:"login_failure_window"`       // 登录失败计数窗口（秒）
+	LockoutDuration          int    `yaml:"lockout_duration"`           // 账号锁定时长（秒）
+}
+
+// RegionConfig 行政区划配置
+type RegionConfig struct {
+	DataFile string `yaml:"data_file"` // 外部区划数据文件（格式同内置 regions.json），为空时使用内置数据
+}
+
+// RouteConfig 路线估算配置
+type RouteConfig struct {
+	DetourFactor    float64 `yaml:"detour_factor"`     // 绕行系数：公路里程 = 直线距离 × 绕行系数
+	AverageSpeedKmh float64 `yaml:"average_speed_kmh"` // 货车平均行驶速度（公里/小时）
+}
+
+// TrackingConfig 位置跟踪配置
+type TrackingConfig struct {
+	RetentionDays int `yaml:"retention_days"` // 订单结束（确认收货、取消、过期）后定位轨迹保留的天数
+}
+
+// EventsConfig 订单事件推送配置
+type EventsConfig struct {
+	BufferSize       int `yaml:"buffer_size"`       // 保留用于断线重放的最近事件数
+	HeartbeatSeconds int `yaml:"heartbeat_seconds"` // SSE 心跳间隔（秒）
+}
+
+// WebhookConfig Webhook 投递配置，未填写的项使用内置默认值
+type WebhookConfig struct {
+	MaxAttempts       int `yaml:"max_attempts"`        // 最多投递次数，用尽后转为死信
+	BackoffSeconds    int `yaml:"backoff_seconds"`     // 首次重试间隔（秒），之后每次翻倍
+	MaxBackoffSeconds int `yaml:"max_backoff_seconds"` // 重试间隔上限（秒）
+	TimeoutSeconds    int `yaml:"timeout_seconds"`     // 单次请求超时（秒）
+	QueueSize         int `yaml:"queue_size"`          // 等待保存投递记录的事件数
+	CacheSeconds      int `yaml:"cache_seconds"`       // 启用订阅的缓存时长（秒）
+	// AllowPrivateNetworks 允许投递到本机和内网地址，默认拒绝以防订阅地址被用于访问内网服务
+	AllowPrivateNetworks bool `yaml:"allow_private_networks"`
+}
+
+// JobConfig 单个定时任务的配置，未填写的项使用内置默认值
+type JobConfig struct {
+	Schedule string `yaml:"schedule"` // cron 表达式（分 时 日 月 周）或 "@every 5m"
+	Timeout  int    `yaml:"timeout"`  // 单次执行超时（秒）
+	Disabled bool   `yaml:"disabled"`
+}
+
+// SchedulerConfig 定时任务配置
+type SchedulerConfig struct {
+	Disabled bool                 `yaml:"disabled"` // 关闭本实例的全部定时任务
+	Lease    bool                 `yaml:"lease"`    // 多实例部署时开启，通过数据库租约保证每个任务只有一个实例执行
+	Jobs     map[string]JobConfig `yaml:"jobs"`     // 按任务名覆盖默认配置
+}
+
 // Config 应用配置结构
 type Config struct {
 	Server struct {
-		Port string `yaml:"port"`
+		Port           string   `yaml:"port"`
+		TrustedProxies []string `yaml:"trusted_proxies"` // 受信任的反向代理（IP 或 CIDR），仅信任其设置的 X-Forwarded-For/X-Real-IP
 	} `yaml:"server"`
 	DB  DBConfig `yaml:"db"`
 	JWT struct {
-		Secret string `yaml:"secret"`
-		Expiry int    `yaml:"expiry"`
+		Secret        string `yaml:"secret"`
+		Expiry        int    `yaml:"expiry"`         // 访问令牌有效期（秒）
+		RefreshExpiry int    `yaml:"refresh_expiry"` // 刷新令牌有效期（秒）
 	} `yaml:"jwt"`
+	Mail      MailConfig      `yaml:"mail"`
+	Account   AccountConfig   `yaml:"account"`
+	Region    RegionConfig    `yaml:"region"`
+	Route     RouteConfig     `yaml:"route"`
+	Tracking  TrackingConfig  `yaml:"tracking"`
+	Scheduler SchedulerConfig `yaml:"scheduler"`
+	Events    EventsConfig    `yaml:"events"`
+	Webhook   WebhookConfig   `yaml:"webhook"`
 }
 
 var appConfig Config
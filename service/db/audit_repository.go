@@ -0,0 +1,63 @@
+package db
+
+import (
+	"database/sql"
+
+	"freight/models"
+)
+
+// AuditRepositoryImpl 审计记录数据访问实现
+type AuditRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewAuditRepository 创建审计记录数据访问实例
+func NewAuditRepository(db *sql.DB) models.AuditRepository {
+	return &AuditRepositoryImpl{db: db}
+}
+
+// Create 写入审计记录
+func (r *AuditRepositoryImpl) Create(log *models.AuditLog) error {
+	query := `INSERT INTO audit_logs (action, actor_id, subject, ip, detail, created_at)
+              VALUES (?, ?, ?, ?, ?, NOW())`
+
+	result, err := r.db.Exec(query, log.Action, log.ActorID, log.Subject, log.IP, log.Detail)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	log.ID = id
+	return nil
+}
+
+// List 按时间倒序列出审计记录
+func (r *AuditRepositoryImpl) List(action string, limit int) ([]*models.AuditLog, error) {
+	query := `SELECT id, action, actor_id, subject, ip, detail, created_at FROM audit_logs`
+	var args []interface{}
+	if action != "" {
+		query += ` WHERE action = ?`
+		args = append(args, action)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logs []*models.AuditLog
+	for rows.Next() {
+		var log models.AuditLog
+		if err := rows.Scan(&log.ID, &log.Action, &log.ActorID, &log.Subject, &log.IP, &log.Detail, &log.CreatedAt); err != nil {
+			return nil, err
+		}
+		logs = append(logs, &log)
+	}
+	return logs, rows.Err()
+}
@@ -0,0 +1,185 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"freight/models"
+)
+
+// BidRepository 竞价出价数据访问接口（出价与订单在同一事务内更新，故与订单仓储放在一起）
+type BidRepository interface {
+	// Place 出价：承运方已有出价时覆盖金额、到达时间和备注；仅当订单仍为待接单时写入，返回是否写入成功
+	Place(ctx context.Context, bid *models.Bid) (bool, error)
+	// GetByID 不存在时返回 nil, nil
+	GetByID(ctx context.Context, id uint64) (*models.Bid, error)
+	// ListByOrder 按金额从低到高列出订单的全部出价
+	ListByOrder(ctx context.Context, orderID uint64) ([]*models.Bid, error)
+	// ListByCarrier 按更新时间倒序列出承运方的全部出价
+	ListByCarrier(ctx context.Context, carrierID uint64) ([]*models.Bid, error)
+	// Award 原子定标：订单仍为待接单时改为已接单，承运方和价格取自中标出价，其余待定出价改为未中标；返回是否定标成功
+	Award(ctx context.Context, bidID, actorID uint64) (bool, error)
+}
+
+// MySQLBidRepository MySQL实现
+type MySQLBidRepository struct {
+	db *sql.DB
+}
+
+var _ BidRepository = (*MySQLBidRepository)(nil)
+
+// NewBidRepository 创建竞价仓储实例
+func NewBidRepository(db *sql.DB) BidRepository {
+	return &MySQLBidRepository{db: db}
+}
+
+const bidColumns = `id, order_id, carrier_id, amount, eta, note, status, created_at, updated_at`
+
+func scanBid(row rowScanner) (*models.Bid, error) {
+	var bid models.Bid
+	if err := row.Scan(&bid.ID, &bid.OrderID, &bid.CarrierID, &bid.Amount, &bid.ETA, &bid.Note,
+		&bid.Status, &bid.CreatedAt, &bid.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &bid, nil
+}
+
+// Place 锁定订单行后写入出价，新出价同时累加订单的出价数量
+func (r *MySQLBidRepository) Place(ctx context.Context, bid *models.Bid) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var status uint8
+	err = tx.QueryRowContext(ctx, `SELECT status FROM freight_orders WHERE id = ? FOR UPDATE`, bid.OrderID).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && status != models.FreightStatusPending) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// 新插入时影响行数为1，覆盖已有出价时为2
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO freight_bids (order_id, carrier_id, amount, eta, note, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, NOW(), NOW())
+		ON DUPLICATE KEY UPDATE amount = VALUES(amount), eta = VALUES(eta), note = VALUES(note), updated_at = NOW()
+	`, bid.OrderID, bid.CarrierID, bid.Amount, bid.ETA, bid.Note, models.BidStatusPending)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 1 {
+		if _, err := tx.ExecContext(ctx, `UPDATE freight_orders SET bid_count = bid_count + 1 WHERE id = ?`, bid.OrderID); err != nil {
+			return false, err
+		}
+	}
+
+	stored, err := scanBid(tx.QueryRowContext(ctx,
+		"SELECT "+bidColumns+" FROM freight_bids WHERE order_id = ? AND carrier_id = ?", bid.OrderID, bid.CarrierID))
+	if err != nil {
+		return false, err
+	}
+	*bid = *stored
+	return true, tx.Commit()
+}
+
+// GetByID 查找出价，不存在返回nil
+func (r *MySQLBidRepository) GetByID(ctx context.Context, id uint64) (*models.Bid, error) {
+	bid, err := scanBid(r.db.QueryRowContext(ctx, "SELECT "+bidColumns+" FROM freight_bids WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return bid, err
+}
+
+// ListByOrder 列出订单的出价
+func (r *MySQLBidRepository) ListByOrder(ctx context.Context, orderID uint64) ([]*models.Bid, error) {
+	return r.list(ctx, "SELECT "+bidColumns+" FROM freight_bids WHERE order_id = ? ORDER BY amount ASC, id ASC", orderID)
+}
+
+// ListByCarrier 列出承运方的出价
+func (r *MySQLBidRepository) ListByCarrier(ctx context.Context, carrierID uint64) ([]*models.Bid, error) {
+	return r.list(ctx, "SELECT "+bidColumns+" FROM freight_bids WHERE carrier_id = ? ORDER BY updated_at DESC, id DESC", carrierID)
+}
+
+func (r *MySQLBidRepository) list(ctx context.Context, query string, args ...interface{}) ([]*models.Bid, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bids := []*models.Bid{}
+	for rows.Next() {
+		bid, err := scanBid(rows)
+		if err != nil {
+			return nil, err
+		}
+		bids = append(bids, bid)
+	}
+	return bids, rows.Err()
+}
+
+// Award 在同一事务内完成接单、写入流转记录和更新出价状态，依赖 WHERE status = 待接单 保证只有一个出价中标
+func (r *MySQLBidRepository) Award(ctx context.Context, bidID, actorID uint64) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	bid, err := scanBid(tx.QueryRowContext(ctx, "SELECT "+bidColumns+" FROM freight_bids WHERE id = ? FOR UPDATE", bidID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if bid.Status != models.BidStatusPending {
+		return false, nil
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE freight_orders
+		SET carrier_id = ?, price = ?, status = ?, updated_at = NOW()
+		WHERE id = ? AND status = ?
+	`, bid.CarrierID, bid.Amount, models.FreightStatusAccepted, bid.OrderID, models.FreightStatusPending)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	event := &models.FreightOrderEvent{
+		OrderID:    bid.OrderID,
+		FromStatus: models.FreightStatusPending,
+		ToStatus:   models.FreightStatusAccepted,
+		ActorID:    actorID,
+		Reason:     "竞价中标",
+	}
+	if err := insertEvent(ctx, tx, event); err != nil {
+		return false, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE freight_bids
+		SET status = CASE WHEN id = ? THEN ? ELSE ? END, updated_at = NOW()
+		WHERE order_id = ? AND status = ?
+	`, bidID, models.BidStatusAwarded, models.BidStatusRejected, bid.OrderID, models.BidStatusPending); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
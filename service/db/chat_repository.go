@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"freight/models"
+)
+
+// ChatRepositoryImpl 订单沟通数据访问实现
+type ChatRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewChatRepository 创建订单沟通仓储实例
+func NewChatRepository(db *sql.DB) models.ChatRepository {
+	return &ChatRepositoryImpl{db: db}
+}
+
+const chatMessageColumns = `id, order_id, sender_id, kind, body, attachment_url, created_at`
+
+func scanChatMessage(row rowScanner) (*models.ChatMessage, error) {
+	var m models.ChatMessage
+	if err := row.Scan(&m.ID, &m.OrderID, &m.SenderID, &m.Kind, &m.Body, &m.AttachmentURL, &m.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// AddMessage 插入消息
+func (r *ChatRepositoryImpl) AddMessage(ctx context.Context, msg *models.ChatMessage) error {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO chat_messages (order_id, sender_id, kind, body, attachment_url, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		msg.OrderID, msg.SenderID, msg.Kind, msg.Body, msg.AttachmentURL, msg.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	msg.ID = uint64(id)
+	return nil
+}
+
+// ListMessages 向前翻页时倒序取 limit 条后再按ID升序返回
+func (r *ChatRepositoryImpl) ListMessages(ctx context.Context, orderID, beforeID, afterID uint64, limit int) ([]*models.ChatMessage, error) {
+	var rows *sql.Rows
+	var err error
+	switch {
+	case afterID > 0:
+		rows, err = r.db.QueryContext(ctx, "SELECT "+chatMessageColumns+` FROM chat_messages
+			WHERE order_id = ? AND id > ? ORDER BY id LIMIT ?`, orderID, afterID, limit)
+	case beforeID > 0:
+		rows, err = r.db.QueryContext(ctx, "SELECT "+chatMessageColumns+" FROM (SELECT "+chatMessageColumns+` FROM chat_messages
+			WHERE order_id = ? AND id < ? ORDER BY id DESC LIMIT ?) page ORDER BY id`, orderID, beforeID, limit)
+	default:
+		rows, err = r.db.QueryContext(ctx, "SELECT "+chatMessageColumns+" FROM (SELECT "+chatMessageColumns+` FROM chat_messages
+			WHERE order_id = ? ORDER BY id DESC LIMIT ?) page ORDER BY id`, orderID, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []*models.ChatMessage{}
+	for rows.Next() {
+		m, err := scanChatMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// MarkRead 以 upToID 及之前的最新消息ID为已读位置做 upsert，只在位置推进时更新已读时间
+func (r *ChatRepositoryImpl) MarkRead(ctx context.Context, orderID, userID, upToID uint64, at time.Time) (*models.ChatReadReceipt, bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO chat_reads (order_id, user_id, last_read_id, read_at)
+		SELECT ?, ?, COALESCE(MAX(id), 0), ? FROM chat_messages WHERE order_id = ? AND id <= ?
+		ON DUPLICATE KEY UPDATE
+			read_at = IF(VALUES(last_read_id) > last_read_id, VALUES(read_at), read_at),
+			last_read_id = GREATEST(last_read_id, VALUES(last_read_id))`,
+		orderID, userID, at, orderID, upToID)
+	if err != nil {
+		return nil, false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, err
+	}
+
+	var receipt models.ChatReadReceipt
+	err = r.db.QueryRowContext(ctx, `
+		SELECT order_id, user_id, last_read_id, read_at FROM chat_reads WHERE order_id = ? AND user_id = ?`,
+		orderID, userID).Scan(&receipt.OrderID, &receipt.UserID, &receipt.LastReadID, &receipt.ReadAt)
+	if err != nil {
+		return nil, false, err
+	}
+	return &receipt, affected > 0 && receipt.LastReadID > 0, nil
+}
+
+// ReadReceipts 查询订单的已读回执
+func (r *ChatRepositoryImpl) ReadReceipts(ctx context.Context, orderID uint64) ([]*models.ChatReadReceipt, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT order_id, user_id, last_read_id, read_at FROM chat_reads
+		WHERE order_id = ? AND last_read_id > 0 ORDER BY user_id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	receipts := []*models.ChatReadReceipt{}
+	for rows.Next() {
+		var receipt models.ChatReadReceipt
+		if err := rows.Scan(&receipt.OrderID, &receipt.UserID, &receipt.LastReadID, &receipt.ReadAt); err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, &receipt)
+	}
+	return receipts, rows.Err()
+}
+
+// UnreadCounts 统计用户参与订单中他人发送、ID大于已读位置的消息
+func (r *ChatRepositoryImpl) UnreadCounts(ctx context.Context, userID uint64) (map[uint64]int, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT m.order_id, COUNT(*) FROM chat_messages m
+		JOIN freight_orders o ON o.id = m.order_id AND (o.shipper_id = ? OR o.carrier_id = ?)
+		LEFT JOIN chat_reads cr ON cr.order_id = m.order_id AND cr.user_id = ?
+		WHERE m.sender_id <> ? AND m.id > COALESCE(cr.last_read_id, 0)
+		GROUP BY m.order_id`, userID, userID, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[uint64]int)
+	for rows.Next() {
+		var orderID uint64
+		var n int
+		if err := rows.Scan(&orderID, &n); err != nil {
+			return nil, err
+		}
+		counts[orderID] = n
+	}
+	return counts, rows.Err()
+}
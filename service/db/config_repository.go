@@ -2,10 +2,13 @@ package db
 
 import (
 	"database/sql"
-	"errors"
 	"freight/models"
+	"time"
 )
 
+// configColumns 配置查询字段（key、value 是MySQL保留字，需要加反引号）
+const configColumns = "id, `key`, `value`, description, created_at, updated_at"
+
 // ConfigRepositoryImpl 配置数据访问实现
 type ConfigRepositoryImpl struct {
 	db *sql.DB
@@ -18,17 +21,28 @@ func NewConfigRepository(db *sql.DB) models.ConfigRepository {
 
 // Create 创建配置
 func (r *ConfigRepositoryImpl) Create(config *models.Config) error {
-	query := `INSERT INTO configs (key, value, description, created_at, updated_at)
-              VALUES (?, ?, ?, ?, ?)`
+	now := time.Now()
+	config.CreatedAt = now
+	config.UpdatedAt = now
 
-	_, err := r.db.Exec(query, config.Key, config.Value, config.Description, config.CreatedAt, config.UpdatedAt)
-	return err
+	query := "INSERT INTO configs (`key`, `value`, description, created_at, updated_at) VALUES (?, ?, ?, ?, ?)"
+
+	result, err := r.db.Exec(query, config.Key, config.Value, config.Description, config.CreatedAt, config.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	config.ID = id
+	return nil
 }
 
-// GetByKey 通过键获取配置
+// GetByKey 通过键获取配置，不存在返回nil
 func (r *ConfigRepositoryImpl) GetByKey(key string) (*models.Config, error) {
-	query := `SELECT id, key, value, description, created_at, updated_at 
-              FROM configs WHERE key = ?`
+	query := "SELECT " + configColumns + " FROM configs WHERE `key` = ?"
 
 	var config models.Config
 	err := r.db.QueryRow(query, key).Scan(
@@ -36,7 +50,7 @@ func (r *ConfigRepositoryImpl) GetByKey(key string) (*models.Config, error) {
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("配置不存在")
+			return nil, nil
 		}
 		return nil, err
 	}
@@ -46,30 +60,52 @@ func (r *ConfigRepositoryImpl) GetByKey(key string) (*models.Config, error) {
 
 // Update 更新配置
 func (r *ConfigRepositoryImpl) Update(config *models.Config) error {
-	query := `UPDATE configs SET value = ?, description = ?, updated_at = ? 
-              WHERE key = ?`
+	config.UpdatedAt = time.Now()
+
+	query := "UPDATE configs SET `value` = ?, description = ?, updated_at = ? WHERE `key` = ?"
 
 	_, err := r.db.Exec(query, config.Value, config.Description, config.UpdatedAt, config.Key)
 	return err
 }
 
 // Delete 删除配置
-func (r *ConfigRepositoryImpl) Delete(key string) error {
-	query := `DELETE FROM configs WHERE key = ?`
+func (r *ConfigRepositoryImpl) Delete(key string) (bool, error) {
+	query := "DELETE FROM configs WHERE `key` = ?"
 
 	result, err := r.db.Exec(query, key)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return err
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// List 按创建时间倒序列出全部配置
+func (r *ConfigRepositoryImpl) List() ([]*models.Config, error) {
+	rows, err := r.db.Query("SELECT " + configColumns + " FROM configs ORDER BY created_at DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var configs []*models.Config
+	for rows.Next() {
+		var config models.Config
+		if err := rows.Scan(
+			&config.ID, &config.Key, &config.Value, &config.Description, &config.CreatedAt, &config.UpdatedAt); err != nil {
+			return nil, err
+		}
+		configs = append(configs, &config)
 	}
 
-	if rowsAffected == 0 {
-		return errors.New("配置不存在")
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return configs, nil
 }
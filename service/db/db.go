@@ -15,7 +15,8 @@ var dbInstance *sql.DB
 
 // Init 初始化数据库连接
 func Init(cfg config.DBConfig) error {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=true&loc=%s",
+	// clientFoundRows 让 RowsAffected 返回匹配行数，条件更新在字段值未变时也能判断是否命中
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=true&clientFoundRows=true&loc=%s",
 		cfg.Username, cfg.Password, cfg.Host, cfg.Port, cfg.Name, cfg.Charset, url.QueryEscape(cfg.Loc))
 
 	var err error
@@ -0,0 +1,265 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+
+	"freight/models"
+)
+
+// freightColumns 订单查询字段（与 scanFreight 顺序一致）
+const freightColumns = `id, origin_location, destination_location, origin_code, destination_code, type, typeid, remark,
+       order_date, price, status, is_urgent, has_insurance,
+       created_at, updated_at, email, shipper_id, carrier_id, distance_km, estimated_hours,
+       bidding_enabled, bid_count, weight_kg, volume_m3, pieces, length_m, width_m, height_m,
+       hazmat_class, temp_min_c, temp_max_c, vehicle_id,
+       pickup_earliest, pickup_latest, delivery_earliest, delivery_latest`
+
+// freightQuery 订单列表查询构建器：先收集全部 WHERE 条件，最后统一拼接 ORDER BY 和 LIMIT
+type freightQuery struct {
+	where []string
+	args  []interface{}
+
+	rank     string // 相关度表达式，未指定排序字段时按其倒序排列
+	rankArgs []interface{}
+}
+
+// and 追加一个 AND 条件
+func (q *freightQuery) and(clause string, args ...interface{}) {
+	q.where = append(q.where, clause)
+	q.args = append(q.args, args...)
+}
+
+// rankBy 设置相关度排序表达式
+func (q *freightQuery) rankBy(expr string, args ...interface{}) {
+	q.rank = expr
+	q.rankArgs = args
+}
+
+// applyFilter 按过滤条件追加 WHERE 条件（零值字段不参与过滤）
+func (q *freightQuery) applyFilter(filter models.FreightFilter) {
+	if filter.OriginLocation != "" {
+		q.and("origin_location = ?", filter.OriginLocation)
+	}
+	if filter.OriginCode != "" {
+		q.and("origin_code = ?", filter.OriginCode)
+	}
+	if filter.DestinationLocation != "" {
+		q.and("destination_location = ?", filter.DestinationLocation)
+	}
+	if filter.DestinationCode != "" {
+		q.and("destination_code = ?", filter.DestinationCode)
+	}
+	if filter.OriginCodePrefix != "" {
+		q.and("origin_code LIKE ?", filter.OriginCodePrefix+"%")
+	}
+	if filter.DestinationCodePrefix != "" {
+		q.and("destination_code LIKE ?", filter.DestinationCodePrefix+"%")
+	}
+	if filter.TypeID != 0 {
+		q.and("typeid = ?", filter.TypeID)
+	}
+	if filter.Status != nil {
+		q.and("status = ?", *filter.Status)
+	}
+	if filter.MinPrice > 0 {
+		q.and("price >= ?", filter.MinPrice)
+	}
+	if filter.MaxPrice > 0 {
+		q.and("price <= ?", filter.MaxPrice)
+	}
+	if filter.IsUrgent != nil {
+		q.and("is_urgent = ?", *filter.IsUrgent)
+	}
+	if filter.HasInsurance != nil {
+		q.and("has_insurance = ?", *filter.HasInsurance)
+	}
+	if filter.OrderDate != "" {
+		q.and("order_date = ?", filter.OrderDate)
+	}
+	if filter.OrderDateFrom != "" {
+		q.and("order_date >= ?", filter.OrderDateFrom)
+	}
+	if filter.OrderDateTo != "" {
+		q.and("order_date <= ?", filter.OrderDateTo)
+	}
+	if filter.MinDistance > 0 {
+		q.and("distance_km >= ?", filter.MinDistance)
+	}
+	if filter.MaxDistance > 0 {
+		q.and("distance_km <= ?", filter.MaxDistance)
+	}
+	if filter.MinWeight > 0 {
+		q.and("weight_kg >= ?", filter.MinWeight)
+	}
+	if filter.MaxWeight > 0 {
+		q.and("weight_kg <= ?", filter.MaxWeight)
+	}
+	if filter.MaxVolume > 0 {
+		q.and("volume_m3 <= ?", filter.MaxVolume)
+	}
+	if filter.MaxLength > 0 {
+		q.and("length_m <= ?", filter.MaxLength)
+	}
+	if filter.MaxWidth > 0 {
+		q.and("width_m <= ?", filter.MaxWidth)
+	}
+	if filter.MaxHeight > 0 {
+		q.and("height_m <= ?", filter.MaxHeight)
+	}
+	if filter.Hazmat != nil {
+		if *filter.Hazmat {
+			q.and("hazmat_class > 0")
+		} else {
+			q.and("hazmat_class = 0")
+		}
+	}
+	if filter.HazmatClass != 0 {
+		q.and("hazmat_class = ?", filter.HazmatClass)
+	}
+	if filter.PickupFrom != nil && filter.PickupTo != nil {
+		q.and("pickup_earliest <= ? AND pickup_latest >= ?", *filter.PickupTo, *filter.PickupFrom)
+	}
+	if filter.ColdChain != nil {
+		if *filter.ColdChain {
+			q.and("(temp_min_c IS NOT NULL OR temp_max_c IS NOT NULL)")
+		} else {
+			q.and("temp_min_c IS NULL AND temp_max_c IS NULL")
+		}
+	}
+}
+
+// count 生成统计总数的SQL（不含游标条件，total 始终是满足过滤条件的全部订单数）
+func (q *freightQuery) count() (string, []interface{}) {
+	query := "SELECT COUNT(*) FROM freight_orders"
+	if len(q.where) > 0 {
+		query += " WHERE " + strings.Join(q.where, " AND ")
+	}
+	return query, q.args
+}
+
+// build 生成完整SQL；排序字段不在白名单时使用 defaultSort，始终以 id 作为次级排序保证结果稳定。
+// 游标模式固定按 (updated_at, id) 倒序，从游标位置之后取 PageSize 条
+func (q *freightQuery) build(filter models.FreightFilter, defaultSort string) (string, []interface{}) {
+	where := q.where
+	args := append([]interface{}{}, q.args...)
+	if filter.CursorMode && filter.After != nil {
+		after := filter.After.UpdatedAt
+		where = append(where[:len(where):len(where)], "(updated_at < ? OR (updated_at = ? AND id < ?))")
+		args = append(args, after, after, filter.After.ID)
+	}
+
+	var b strings.Builder
+	b.WriteString("SELECT ")
+	b.WriteString(freightColumns)
+	b.WriteString(" FROM freight_orders")
+	if len(where) > 0 {
+		b.WriteString(" WHERE ")
+		b.WriteString(strings.Join(where, " AND "))
+	}
+
+	if q.rank != "" && filter.SortField == "" {
+		b.WriteString(" ORDER BY " + q.rank + " DESC, id DESC")
+		args = append(args, q.rankArgs...)
+	} else {
+		field, order := freightSort(filter, defaultSort)
+		b.WriteString(" ORDER BY " + field + " " + order + ", id " + order)
+	}
+
+	switch {
+	case filter.CursorMode && filter.PageSize > 0:
+		b.WriteString(" LIMIT ?")
+		args = append(args, filter.PageSize)
+	case filter.Page > 0 && filter.PageSize > 0:
+		b.WriteString(" LIMIT ? OFFSET ?")
+		args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	}
+	return b.String(), args
+}
+
+// freightSort 返回白名单内的排序字段和方向（默认降序）；游标模式固定为 updated_at 倒序
+func freightSort(filter models.FreightFilter, defaultSort string) (string, string) {
+	if filter.CursorMode {
+		return "updated_at", "DESC"
+	}
+	field := defaultSort
+	if models.ValidFreightSortField(filter.SortField) {
+		field = filter.SortField
+	}
+	order := "DESC"
+	if strings.EqualFold(filter.SortOrder, "asc") {
+		order = "ASC"
+	}
+	return field, order
+}
+
+// rowScanner 兼容 *sql.Row 和 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanFreight 按 freightColumns 的顺序读取一条订单
+func scanFreight(row rowScanner) (*models.FreightOrder, error) {
+	var freight models.FreightOrder
+	if err := row.Scan(
+		&freight.ID,                  // 1. id
+		&freight.OriginLocation,      // 2. origin_location
+		&freight.DestinationLocation, // 3. destination_location
+		&freight.OriginCode,          // 4. origin_code
+		&freight.DestinationCode,     // 5. destination_code
+		&freight.Type,                // 6. type
+		&freight.TypeID,              // 7. typeid
+		&freight.Remark,              // 8. remark
+		&freight.OrderDate,           // 9. order_date
+		&freight.Price,               // 10. price
+		&freight.Status,              // 11. status
+		&freight.IsUrgent,            // 12. is_urgent
+		&freight.HasInsurance,        // 13. has_insurance
+		&freight.CreatedAt,           // 14. created_at
+		&freight.UpdatedAt,           // 15. updated_at
+		&freight.Email,               // 16. email
+		&freight.ShipperID,           // 17. shipper_id
+		&freight.CarrierID,           // 18. carrier_id
+		&freight.DistanceKm,          // 19. distance_km
+		&freight.EstimatedHours,      // 20. estimated_hours
+		&freight.BiddingEnabled,      // 21. bidding_enabled
+		&freight.BidCount,            // 22. bid_count
+		&freight.WeightKg,            // 23. weight_kg
+		&freight.VolumeM3,            // 24. volume_m3
+		&freight.Pieces,              // 25. pieces
+		&freight.LengthM,             // 26. length_m
+		&freight.WidthM,              // 27. width_m
+		&freight.HeightM,             // 28. height_m
+		&freight.HazmatClass,         // 29. hazmat_class
+		&freight.TempMinC,            // 30. temp_min_c
+		&freight.TempMaxC,            // 31. temp_max_c
+		&freight.VehicleID,           // 32. vehicle_id
+		&freight.PickupEarliest,      // 33. pickup_earliest
+		&freight.PickupLatest,        // 34. pickup_latest
+		&freight.DeliveryEarliest,    // 35. delivery_earliest
+		&freight.DeliveryLatest,      // 36. delivery_latest
+	); err != nil {
+		return nil, err
+	}
+	return &freight, nil
+}
+
+// scanFreights 读取订单列表结果
+func scanFreights(rows *sql.Rows) ([]*models.FreightOrder, error) {
+	defer rows.Close()
+
+	var freights []*models.FreightOrder
+	for rows.Next() {
+		freight, err := scanFreight(rows)
+		if err != nil {
+			return nil, err
+		}
+		freights = append(freights, freight)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return freights, nil
+}
@@ -6,17 +6,29 @@ import (
 	"errors"
 	"fmt"
 	"freight/models"
+	"freight/utils"
 	"strings"
+	"time"
 )
 
 // FreightRepository 货运订单数据访问接口
 type FreightRepository interface {
 	Create(ctx context.Context, freight *models.FreightOrder) error
 	GetByID(ctx context.Context, id uint64) (*models.FreightOrder, error)
-	List(ctx context.Context, filter models.FreightFilter) ([]*models.FreightOrder, error)
-	Update(ctx context.Context, freight *models.FreightOrder) error
-	Delete(ctx context.Context, id uint64) error
-	ListByUserID(ctx context.Context, userID uint64, filter models.FreightFilter) ([]*models.FreightOrder, error)
+	// List 返回当前页订单和满足过滤条件的订单总数
+	List(ctx context.Context, filter models.FreightFilter) ([]*models.FreightOrder, int64, error)
+	// Update 仅当订单仍为待接单时更新非空字段；返回是否更新成功
+	Update(ctx context.Context, freight *models.FreightOrder) (bool, error)
+	// Delete 仅当订单仍为待接单时软删除；返回是否删除成功
+	Delete(ctx context.Context, id uint64) (bool, error)
+	ListByUserID(ctx context.Context, userID uint64, filter models.FreightFilter) ([]*models.FreightOrder, int64, error)
+	// UpdateStatus 仅当订单当前状态为 event.FromStatus 时更新为 event.ToStatus，并写入流转记录；返回是否更新成功
+	UpdateStatus(ctx context.Context, event *models.FreightOrderEvent) (bool, error)
+	ListEvents(ctx context.Context, orderID uint64) ([]*models.FreightOrderEvent, error)
+	// AcceptPending 原子接单：仅当订单仍为待接单时记录接单用户、承运车辆并改为已接单；返回是否抢单成功
+	AcceptPending(ctx context.Context, orderID, userID, vehicleID uint64) (bool, error)
+	// ListPickupOverdue 列出取货时间窗口已在 now 之前结束、仍待接单的订单，最多 limit 条
+	ListPickupOverdue(ctx context.Context, now time.Time, limit int) ([]*models.FreightOrder, error)
 }
 
 // MySQLFreightRepository MySQL实现
@@ -35,8 +47,10 @@ func (r *MySQLFreightRepository) Create(ctx context.Context, freight *models.Fre
 		INSERT INTO freight_orders (
 			origin_location, destination_location, origin_code, destination_code, 
 			type, typeid, remark, order_date, price, 
-			is_urgent, has_insurance, email, user_id, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())
+			is_urgent, has_insurance, email, user_id, shipper_id, distance_km, estimated_hours, bidding_enabled,
+			weight_kg, volume_m3, pieces, length_m, width_m, height_m, hazmat_class, temp_min_c, temp_max_c,
+			pickup_earliest, pickup_latest, delivery_earliest, delivery_latest, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())
 	`
 
 	fmt.Println("sql:", query)
@@ -52,10 +66,27 @@ func (r *MySQLFreightRepository) Create(ctx context.Context, freight *models.Fre
 		freight.OrderDate,           // 对应 order_date
 		freight.Price,               // 对应 price
 		//freight.Status,              // 对应 status
-		freight.IsUrgent,     // 对应 is_urgent
-		freight.HasInsurance, // 对应 has_insurance
-		freight.Email,        // 对应 email
-		freight.UserID,       // 对应 user_id
+		freight.IsUrgent,         // 对应 is_urgent
+		freight.HasInsurance,     // 对应 has_insurance
+		freight.Email,            // 对应 email
+		freight.ShipperID,        // 对应 user_id（历史字段，保持为发布人）
+		freight.ShipperID,        // 对应 shipper_id
+		freight.DistanceKm,       // 对应 distance_km
+		freight.EstimatedHours,   // 对应 estimated_hours
+		freight.BiddingEnabled,   // 对应 bidding_enabled
+		freight.WeightKg,         // 对应 weight_kg
+		freight.VolumeM3,         // 对应 volume_m3
+		freight.Pieces,           // 对应 pieces
+		freight.LengthM,          // 对应 length_m
+		freight.WidthM,           // 对应 width_m
+		freight.HeightM,          // 对应 height_m
+		freight.HazmatClass,      // 对应 hazmat_class
+		freight.TempMinC,         // 对应 temp_min_c
+		freight.TempMaxC,         // 对应 temp_max_c
+		freight.PickupEarliest,   // 对应 pickup_earliest
+		freight.PickupLatest,     // 对应 pickup_latest
+		freight.DeliveryEarliest, // 对应 delivery_earliest
+		freight.DeliveryLatest,   // 对应 delivery_latest
 	)
 	fmt.Println("result:", result)
 	fmt.Println("err:", err)
@@ -74,35 +105,9 @@ func (r *MySQLFreightRepository) Create(ctx context.Context, freight *models.Fre
 
 // GetByID 获取货运订单
 func (r *MySQLFreightRepository) GetByID(ctx context.Context, id uint64) (*models.FreightOrder, error) {
-	query := `
-    SELECT id, origin_location, destination_location, origin_code, destination_code, type, typeid, remark, 
-           order_date, price, status, is_urgent, has_insurance,
-           created_at, updated_at, email, user_id
-    FROM freight_orders
-    WHERE id = ? AND status != 0
-`
-
-	var freight models.FreightOrder
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&freight.ID,                  // 1. id
-		&freight.OriginLocation,      // 2. origin_location
-		&freight.DestinationLocation, // 3. destination_location
-		&freight.OriginCode,          // 4. origin_code
-		&freight.DestinationCode,     // 5. destination_code
-		&freight.Type,                // 6. type
-		&freight.TypeID,              // 7. typeid
-		&freight.Remark,              // 8. remark
-		&freight.OrderDate,           // 9. order_date
-		&freight.Price,               // 10. price
-		&freight.Status,              // 11. status
-		&freight.IsUrgent,            // 12. is_urgent
-		&freight.HasInsurance,        // 13. has_insurance
-		&freight.CreatedAt,           // 14. created_at
-		&freight.UpdatedAt,           // 15. updated_at
-		&freight.Email,               // 16. email
-		&freight.UserID,              // 17. user_id（添加 & 符号）
-	)
+	query := "SELECT " + freightColumns + " FROM freight_orders WHERE id = ? AND status != 0"
 
+	freight, err := scanFreight(r.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -110,83 +115,20 @@ func (r *MySQLFreightRepository) GetByID(ctx context.Context, id uint64) (*model
 		return nil, err
 	}
 
-	return &freight, nil
+	return freight, nil
 }
 
-// List 列出货运订单
-func (r *MySQLFreightRepository) List(ctx context.Context, filter models.FreightFilter) ([]*models.FreightOrder, error) {
-	// 构建查询SQL和参数
-	query := `
-    SELECT id, origin_location, destination_location, origin_code, destination_code, type, typeid, remark, 
-           order_date, price, status, is_urgent, has_insurance,
-           created_at, updated_at, email, user_id
-    FROM freight_orders
-    WHERE status = 1
-    ORDER BY updated_at desc 
-`
-
-	var args []interface{}
-
-	// 添加过滤条件
-	if filter.OriginLocation != "" {
-		query += " AND origin_location = ?"
-		args = append(args, filter.OriginLocation)
-	}
-
-	if filter.DestinationLocation != "" {
-		query += " AND destination_location = ?"
-		args = append(args, filter.DestinationLocation)
-	}
-
-	if filter.Status != nil {
-		query += " AND status = ?"
-		args = append(args, *filter.Status)
-	}
-
-	// 添加分页
-	if filter.Page > 0 && filter.PageSize > 0 {
-		query += " LIMIT ? OFFSET ?"
-		args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
-	}
-
-	rows, err := r.db.QueryContext(ctx, query, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var freights []*models.FreightOrder
-	for rows.Next() {
-		var freight models.FreightOrder
-		if err := rows.Scan(
-			&freight.ID,                  // 1. id
-			&freight.OriginLocation,      // 2. origin_location
-			&freight.DestinationLocation, // 3. destination_location
-			&freight.OriginCode,          // 4. origin_code
-			&freight.DestinationCode,     // 5. destination_code
-			&freight.Type,                // 6. type
-			&freight.TypeID,              // 7. typeid
-			&freight.Remark,              // 8. remark
-			&freight.OrderDate,           // 9. order_date
-			&freight.Price,               // 10. price
-			&freight.Status,              // 11. status
-			&freight.IsUrgent,            // 12. is_urgent
-			&freight.HasInsurance,        // 13. has_insurance
-			&freight.CreatedAt,           // 14. created_at
-			&freight.UpdatedAt,           // 15. updated_at
-			&freight.Email,               // 16. email
-			&freight.UserID,              // 17. user_id（添加 & 符号）
-		); err != nil {
-			return nil, err
-		}
-		freights = append(freights, &freight)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, err
+// List 列出货运订单（默认只列出待接单的订单，按更新时间倒序）
+func (r *MySQLFreightRepository) List(ctx context.Context, filter models.FreightFilter) ([]*models.FreightOrder, int64, error) {
+	var q freightQuery
+	if filter.Status == nil {
+		q.and("status = ?", models.FreightStatusPending)
+	} else {
+		q.and("status != 0")
 	}
+	q.applyFilter(filter)
 
-	return freights, nil
+	return r.listPage(ctx, &q, filter, "updated_at")
 }
 
 // Update 更新货运订单
@@ -220,8 +162,8 @@ func (r *MySQLFreightRepository) List(ctx context.Context, filter models.Freight
 //	return err
 //}
 
-// Update 更新货运订单（仅更新非空字段）
-func (r *MySQLFreightRepository) Update(ctx context.Context, freight *models.FreightOrder) error {
+// Update 更新货运订单（仅更新非空字段），依赖 WHERE status = 待接单 避免覆盖并发中已被接走的订单
+func (r *MySQLFreightRepository) Update(ctx context.Context, freight *models.FreightOrder) (bool, error) {
 	var (
 		setClauses []string
 		args       []interface{}
@@ -236,14 +178,15 @@ func (r *MySQLFreightRepository) Update(ctx context.Context, freight *models.Fre
 		setClauses = append(setClauses, "destination_location = ?")
 		args = append(args, freight.DestinationLocation)
 	}
+	// 路线按编码整体更新，里程和时长随之替换（估算失败时为0）
+	if freight.OriginCode != "" || freight.DestinationCode != "" {
+		setClauses = append(setClauses, "origin_code = ?", "destination_code = ?", "distance_km = ?", "estimated_hours = ?")
+		args = append(args, freight.OriginCode, freight.DestinationCode, freight.DistanceKm, freight.EstimatedHours)
+	}
 	if freight.Status != 0 {
 		setClauses = append(setClauses, "status = ?")
 		args = append(args, freight.Status)
 	}
-	if freight.UserID != 0 {
-		setClauses = append(setClauses, "user_id = ?")
-		args = append(args, freight.UserID)
-	}
 	// 其他字段...
 
 	// 必须更新的字段
@@ -253,117 +196,237 @@ func (r *MySQLFreightRepository) Update(ctx context.Context, freight *models.Fre
 	query := fmt.Sprintf(`
 		UPDATE freight_orders
 		SET %s
-		WHERE id = ? AND status != 0
+		WHERE id = ? AND status = ?
 	`, strings.Join(setClauses, ", "))
 
-	// 添加WHERE条件的ID
-	args = append(args, freight.ID)
+	// 添加WHERE条件的ID和状态
+	args = append(args, freight.ID, models.FreightStatusPending)
 
-	_, err := r.db.ExecContext(ctx, query, args...)
-	return err
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected == 1, nil
 }
 
-// Delete 删除货运订单
-func (r *MySQLFreightRepository) Delete(ctx context.Context, id uint64) error {
+// Delete 删除货运订单，依赖 WHERE status = 待接单 避免删除并发中已被接走的订单
+func (r *MySQLFreightRepository) Delete(ctx context.Context, id uint64) (bool, error) {
 	query := `
         UPDATE freight_orders
         SET status = 0, updated_at = NOW()
-        WHERE id = ? AND status != 0
+        WHERE id = ? AND status = ?
     `
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := r.db.ExecContext(ctx, query, id, models.FreightStatusPending)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return err
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
+
+// ListByUserID 根据用户ID列出货运订单（filter.Role 区分发货方/承运方，为空时两者都包含）
+func (r *MySQLFreightRepository) ListByUserID(ctx context.Context, userID uint64, filter models.FreightFilter) ([]*models.FreightOrder, int64, error) {
+	var q freightQuery
+	q.and("status != 0")
+
+	// 按角色关联 shipper_id / carrier_id
+	switch filter.Role {
+	case models.FreightRoleShipper:
+		q.and("shipper_id = ?", userID)
+	case models.FreightRoleCarrier:
+		q.and("carrier_id = ?", userID)
+	default:
+		q.and("(shipper_id = ? OR carrier_id = ?)", userID, userID)
+	}
+	q.applyFilter(filter)
+
+	return r.listPage(ctx, &q, filter, "created_at")
+}
+
+// listPage 统计总数并查询当前页
+func (r *MySQLFreightRepository) listPage(ctx context.Context, q *freightQuery, filter models.FreightFilter, defaultSort string) ([]*models.FreightOrder, int64, error) {
+	var total int64
+	countQuery, countArgs := q.count()
+	if err := r.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return nil, 0, nil
+	}
+
+	query, args := q.build(filter, defaultSort)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
 	}
+	freights, err := scanFreights(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return freights, total, nil
+}
 
+// ListPickupOverdue 列出取货已超时的待接单订单（使用 status, pickup_latest 索引）
+func (r *MySQLFreightRepository) ListPickupOverdue(ctx context.Context, now time.Time, limit int) ([]*models.FreightOrder, error) {
+	query := "SELECT " + freightColumns + " FROM freight_orders WHERE status = ? AND pickup_latest < ? ORDER BY pickup_latest LIMIT ?"
+	rows, err := r.db.QueryContext(ctx, query, models.FreightStatusPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanFreights(rows)
+}
+
+// UpdateStatus 条件更新订单状态，并在同一事务中写入流转记录
+func (r *MySQLFreightRepository) UpdateStatus(ctx context.Context, event *models.FreightOrderEvent) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE freight_orders
+		SET status = ?, updated_at = NOW()
+		WHERE id = ? AND status = ?
+	`, event.ToStatus, event.OrderID, event.FromStatus)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
 	if rowsAffected == 0 {
-		return errors.New("货运订单不存在")
+		return false, nil
 	}
 
-	return nil
+	if err := insertEvent(ctx, tx, event); err != nil {
+		return false, err
+	}
+
+	if closesBids(event.ToStatus) {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE freight_bids
+			SET status = ?, updated_at = NOW()
+			WHERE order_id = ? AND status = ?
+		`, models.BidStatusClosed, event.OrderID, models.BidStatusPending); err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit()
 }
 
-// ListByUserID 根据用户ID列出货运订单（兼容无SortField的情况）
-func (r *MySQLFreightRepository) ListByUserID(ctx context.Context, userID uint64, filter models.FreightFilter) ([]*models.FreightOrder, error) {
-	// 构建基础查询SQL（强制关联 user_id）
-	query := `
-		SELECT id, origin_location, destination_location, origin_code, destination_code, type, typeid, remark, 
-			   order_date, price, status, is_urgent, has_insurance,
-			   created_at, updated_at, email, user_id
-		FROM freight_orders
-		WHERE user_id = ? AND status != 0
-	`
+// closesBids 订单取消或过期时，其未定标的出价在同一事务内关闭
+func closesBids(status uint8) bool {
+	return status == models.FreightStatusCancelled || status == models.FreightStatusExpired
+}
 
-	var args []interface{}
-	args = append(args, userID) // 第一个参数：user_id
+// AcceptPending 原子接单，依赖 WHERE status = 待接单 保证并发下只有一个请求成功
+func (r *MySQLFreightRepository) AcceptPending(ctx context.Context, orderID, userID, vehicleID uint64) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
 
-	// 添加其他过滤条件（复用原有逻辑）
-	if filter.OriginLocation != "" {
-		query += " AND origin_location = ?"
-		args = append(args, filter.OriginLocation)
+	result, err := tx.ExecContext(ctx, `
+		UPDATE freight_orders
+		SET carrier_id = ?, vehicle_id = ?, status = ?, updated_at = NOW()
+		WHERE id = ? AND status = ?
+	`, userID, vehicleID, models.FreightStatusAccepted, orderID, models.FreightStatusPending)
+	if err != nil {
+		return false, err
 	}
 
-	if filter.DestinationLocation != "" {
-		query += " AND destination_location = ?"
-		args = append(args, filter.DestinationLocation)
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
 	}
 
-	if filter.Status != nil {
-		query += " AND status = ?"
-		args = append(args, *filter.Status)
+	event := &models.FreightOrderEvent{
+		OrderID:    orderID,
+		FromStatus: models.FreightStatusPending,
+		ToStatus:   models.FreightStatusAccepted,
+		ActorID:    userID,
+		Reason:     "接单",
+	}
+	if err := insertEvent(ctx, tx, event); err != nil {
+		return false, err
 	}
 
-	// 排序：使用默认排序（不依赖SortField）
-	query += " ORDER BY created_at DESC" // 默认按创建时间降序
+	return true, tx.Commit()
+}
 
-	// 添加分页
-	if filter.Page > 0 && filter.PageSize > 0 {
-		query += " LIMIT ? OFFSET ?"
-		args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+// insertEvent 写入订单状态流转记录
+func insertEvent(ctx context.Context, tx *sql.Tx, event *models.FreightOrderEvent) error {
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO freight_order_events (order_id, from_status, to_status, actor_id, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, event.OrderID, event.FromStatus, event.ToStatus, event.ActorID, event.Reason, now)
+	if err != nil {
+		return err
 	}
 
-	// 后续查询逻辑不变...
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	event.ID = uint64(id)
+	event.CreatedAt = utils.FromTime(now)
+	return nil
+}
+
+// ListEvents 按时间顺序列出订单的状态流转记录
+func (r *MySQLFreightRepository) ListEvents(ctx context.Context, orderID uint64) ([]*models.FreightOrderEvent, error) {
+	query := `
+		SELECT id, order_id, from_status, to_status, actor_id, reason, created_at
+		FROM freight_order_events
+		WHERE order_id = ?
+		ORDER BY id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orderID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var freights []*models.FreightOrder
+	var events []*models.FreightOrderEvent
 	for rows.Next() {
-		var freight models.FreightOrder
+		var event models.FreightOrderEvent
 		if err := rows.Scan(
-			&freight.ID,
-			&freight.OriginLocation,
-			&freight.DestinationLocation,
-			&freight.OriginCode,
-			&freight.DestinationCode,
-			&freight.Type,
-			&freight.TypeID,
-			&freight.Remark,
-			&freight.OrderDate,
-			&freight.Price,
-			&freight.Status,
-			&freight.IsUrgent,
-			&freight.HasInsurance,
-			&freight.CreatedAt,
-			&freight.UpdatedAt,
-			&freight.Email,
-			&freight.UserID,
+			&event.ID,
+			&event.OrderID,
+			&event.FromStatus,
+			&event.ToStatus,
+			&event.ActorID,
+			&event.Reason,
+			&event.CreatedAt,
 		); err != nil {
 			return nil, err
 		}
-		freights = append(freights, &freight)
+		events = append(events, &event)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return freights, nil
+	return events, nil
 }
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"freight/models"
+)
+
+// FreightSearchIndex 订单全文检索接口：按出发地、目的地、货物类型和备注匹配关键词，结果按相关度排序。
+// 过滤条件与 FreightRepository.List 一致（未指定状态时只返回待接单订单）
+type FreightSearchIndex interface {
+	Search(ctx context.Context, query string, filter models.FreightFilter) ([]*models.FreightOrder, int64, error)
+}
+
+// freightMatch 全文索引匹配表达式（列顺序须与 ft_freight_search 索引一致）
+const freightMatch = "MATCH(origin_location, destination_location, type, remark) AGAINST(? IN NATURAL LANGUAGE MODE)"
+
+// MySQLFreightSearchIndex 基于 MySQL FULLTEXT（ngram 分词）的实现，索引由 InnoDB 在写入时维护
+type MySQLFreightSearchIndex struct {
+	repo *MySQLFreightRepository
+}
+
+var _ FreightSearchIndex = (*MySQLFreightSearchIndex)(nil)
+
+// NewFreightSearchIndex 创建订单全文检索实例
+func NewFreightSearchIndex(db *sql.DB) FreightSearchIndex {
+	return &MySQLFreightSearchIndex{repo: &MySQLFreightRepository{db: db}}
+}
+
+// Search 全文检索订单
+func (s *MySQLFreightSearchIndex) Search(ctx context.Context, query string, filter models.FreightFilter) ([]*models.FreightOrder, int64, error) {
+	var q freightQuery
+	if filter.Status == nil {
+		q.and("status = ?", models.FreightStatusPending)
+	} else {
+		q.and("status != 0")
+	}
+	q.and(freightMatch, query)
+	q.applyFilter(filter)
+	q.rankBy(freightMatch, query)
+
+	return s.repo.listPage(ctx, &q, filter, "updated_at")
+}
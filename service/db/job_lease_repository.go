@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"freight/scheduler"
+)
+
+// MySQLJobLease 基于数据库的定时任务租约，多个实例共享同一张 job_leases 表
+type MySQLJobLease struct {
+	db *sql.DB
+}
+
+var _ scheduler.Locker = (*MySQLJobLease)(nil)
+
+// NewJobLease 创建数据库租约实例
+func NewJobLease(db *sql.DB) *MySQLJobLease {
+	return &MySQLJobLease{db: db}
+}
+
+// TryAcquire 插入或接管租约：仅当租约已过期或本来就由 holder 持有时才更新
+// （ON DUPLICATE KEY UPDATE 按顺序赋值，expires_at 的条件使用更新后的 holder）
+func (l *MySQLJobLease) TryAcquire(ctx context.Context, name, holder string, until time.Time) (bool, error) {
+	now := time.Now()
+	_, err := l.db.ExecContext(ctx, `
+		INSERT INTO job_leases (name, holder, expires_at) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			holder = IF(expires_at < ? OR holder = VALUES(holder), VALUES(holder), holder),
+			expires_at = IF(holder = VALUES(holder), VALUES(expires_at), expires_at)`,
+		name, holder, until, now)
+	if err != nil {
+		return false, err
+	}
+
+	var current string
+	if err := l.db.QueryRowContext(ctx, `SELECT holder FROM job_leases WHERE name = ?`, name).Scan(&current); err != nil {
+		return false, err
+	}
+	return current == holder, nil
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"freight/models"
+)
+
+// LocationRepositoryImpl 定位数据访问实现
+type LocationRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewLocationRepository 创建定位仓储实例
+func NewLocationRepository(db *sql.DB) models.LocationRepository {
+	return &LocationRepositoryImpl{db: db}
+}
+
+const locationColumns = `id, order_id, carrier_id, lat, lng, speed_kmh, heading, recorded_at`
+
+func scanLocation(row rowScanner) (*models.LocationPing, error) {
+	var p models.LocationPing
+	if err := row.Scan(&p.ID, &p.OrderID, &p.CarrierID, &p.Lat, &p.Lng, &p.SpeedKmh, &p.Heading, &p.RecordedAt); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// AddPings 多行 INSERT IGNORE，依赖 (order_id, recorded_at) 唯一索引去重
+func (r *LocationRepositoryImpl) AddPings(ctx context.Context, pings []*models.LocationPing) (int, error) {
+	if len(pings) == 0 {
+		return 0, nil
+	}
+	placeholders := make([]string, 0, len(pings))
+	args := make([]interface{}, 0, len(pings)*7)
+	for _, p := range pings {
+		placeholders = append(placeholders, "(?, ?, ?, ?, ?, ?, ?, NOW())")
+		args = append(args, p.OrderID, p.CarrierID, p.Lat, p.Lng, p.SpeedKmh, p.Heading, p.RecordedAt)
+	}
+	result, err := r.db.ExecContext(ctx, `
+		INSERT IGNORE INTO freight_locations (order_id, carrier_id, lat, lng, speed_kmh, heading, recorded_at, created_at)
+		VALUES `+strings.Join(placeholders, ", "), args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// Latest 查询订单最新定位
+func (r *LocationRepositoryImpl) Latest(ctx context.Context, orderID uint64) (*models.LocationPing, error) {
+	p, err := scanLocation(r.db.QueryRowContext(ctx,
+		"SELECT "+locationColumns+" FROM freight_locations WHERE order_id = ? ORDER BY recorded_at DESC LIMIT 1", orderID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return p, err
+}
+
+// Trail 取最近 limit 条后按时间升序返回
+func (r *LocationRepositoryImpl) Trail(ctx context.Context, orderID uint64, limit int) ([]*models.LocationPing, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+locationColumns+` FROM (
+			SELECT `+locationColumns+` FROM freight_locations
+			WHERE order_id = ? ORDER BY recorded_at DESC LIMIT ?
+		) recent ORDER BY recorded_at`, orderID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pings := []*models.LocationPing{}
+	for rows.Next() {
+		p, err := scanLocation(rows)
+		if err != nil {
+			return nil, err
+		}
+		pings = append(pings, p)
+	}
+	return pings, rows.Err()
+}
+
+// PurgeFinished 删除已结束订单的定位
+func (r *LocationRepositoryImpl) PurgeFinished(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE l FROM freight_locations l
+		JOIN freight_orders o ON o.id = l.order_id
+		WHERE o.status IN (0, ?, ?, ?) AND o.updated_at < ?`,
+		models.FreightStatusConfirmed, models.FreightStatusCancelled, models.FreightStatusExpired, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
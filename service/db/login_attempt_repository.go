@@ -0,0 +1,92 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"freight/models"
+)
+
+// LoginAttemptRepositoryImpl 登录失败记录数据访问实现
+type LoginAttemptRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewLoginAttemptRepository 创建登录失败记录数据访问实例
+func NewLoginAttemptRepository(db *sql.DB) models.LoginAttemptRepository {
+	return &LoginAttemptRepositoryImpl{db: db}
+}
+
+// RecordFailure 记录一次登录失败
+func (r *LoginAttemptRepositoryImpl) RecordFailure(scope, subject string, at time.Time) error {
+	_, err := r.db.Exec(`INSERT INTO login_failures (scope, subject, created_at) VALUES (?, ?, ?)`, scope, subject, at)
+	return err
+}
+
+// CountFailures 统计 since 之后的失败次数
+func (r *LoginAttemptRepositoryImpl) CountFailures(scope, subject string, since time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM login_failures WHERE scope = ? AND subject = ? AND created_at > ?`,
+		scope, subject, since).Scan(&count)
+	return count, err
+}
+
+// ClearFailures 清除失败记录（登录成功或锁定后重新计数）
+func (r *LoginAttemptRepositoryImpl) ClearFailures(scope, subject string) error {
+	_, err := r.db.Exec(`DELETE FROM login_failures WHERE scope = ? AND subject = ?`, scope, subject)
+	return err
+}
+
+// Lock 锁定账号到指定时间（已锁定时覆盖截止时间）
+func (r *LoginAttemptRepositoryImpl) Lock(subject string, until time.Time) error {
+	_, err := r.db.Exec(`INSERT INTO account_locks (subject, locked_until, created_at) VALUES (?, ?, NOW())
+              ON DUPLICATE KEY UPDATE locked_until = VALUES(locked_until)`, subject, until)
+	return err
+}
+
+// LockedUntil 查询账号锁定截止时间
+func (r *LoginAttemptRepositoryImpl) LockedUntil(subject string) (time.Time, bool, error) {
+	var until time.Time
+	err := r.db.QueryRow(`SELECT locked_until FROM account_locks WHERE subject = ?`, subject).Scan(&until)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, err
+	}
+	return until, true, nil
+}
+
+// Unlock 解除账号锁定
+func (r *LoginAttemptRepositoryImpl) Unlock(subject string) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM account_locks WHERE subject = ?`, subject)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// PurgeFailures 清理过期的失败记录和锁定
+func (r *LoginAttemptRepositoryImpl) PurgeFailures(before time.Time) (int64, error) {
+	var total int64
+	for _, query := range []string{
+		`DELETE FROM login_failures WHERE created_at < ?`,
+		`DELETE FROM account_locks WHERE locked_until < ?`,
+	} {
+		result, err := r.db.Exec(query, before)
+		if err != nil {
+			return total, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
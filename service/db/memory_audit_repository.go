@@ -0,0 +1,52 @@
+package db
+
+import (
+	"sync"
+	"time"
+
+	"freight/models"
+	"freight/utils"
+)
+
+// MemoryAuditRepository 审计记录内存实现（用于测试和本地开发）
+type MemoryAuditRepository struct {
+	mu     sync.Mutex
+	nextID int64
+	logs   []*models.AuditLog
+}
+
+var _ models.AuditRepository = (*MemoryAuditRepository)(nil)
+
+// NewMemoryAuditRepository 创建内存审计记录仓储实例
+func NewMemoryAuditRepository() *MemoryAuditRepository {
+	return &MemoryAuditRepository{}
+}
+
+// Create 写入审计记录
+func (r *MemoryAuditRepository) Create(log *models.AuditLog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	log.ID = r.nextID
+	log.CreatedAt = utils.FromTime(time.Now())
+	stored := *log
+	r.logs = append(r.logs, &stored)
+	return nil
+}
+
+// List 按时间倒序列出审计记录
+func (r *MemoryAuditRepository) List(action string, limit int) ([]*models.AuditLog, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result []*models.AuditLog
+	for i := len(r.logs) - 1; i >= 0 && len(result) < limit; i-- {
+		if action != "" && r.logs[i].Action != action {
+			continue
+		}
+		copied := *r.logs[i]
+		result = append(result, &copied)
+	}
+	return result, nil
+}
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"freight/models"
+	"freight/utils"
+)
+
+// MemoryBidRepository 竞价仓储内存实现（用于测试和本地开发），与内存订单仓储共享订单数据以保证定标的原子性
+type MemoryBidRepository struct {
+	mu       sync.Mutex
+	nextID   uint64
+	bids     map[uint64]*models.Bid
+	freights *MemoryFreightRepository
+}
+
+var _ BidRepository = (*MemoryBidRepository)(nil)
+
+// NewMemoryBidRepository 创建内存竞价仓储实例，并关联到订单仓储（订单取消或过期时关闭出价）
+func NewMemoryBidRepository(freights *MemoryFreightRepository) *MemoryBidRepository {
+	r := &MemoryBidRepository{bids: make(map[uint64]*models.Bid), freights: freights}
+	freights.mu.Lock()
+	freights.bids = r
+	freights.mu.Unlock()
+	return r
+}
+
+// closeOrder 关闭订单的待定出价，调用方须持有订单仓储的锁
+func (r *MemoryBidRepository) closeOrder(orderID uint64, now utils.CustomNullTime) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, b := range r.bids {
+		if b.OrderID == orderID && b.Status == models.BidStatusPending {
+			b.Status = models.BidStatusClosed
+			b.UpdatedAt = now
+		}
+	}
+}
+
+// Place 出价（加锁顺序：订单仓储 → 竞价仓储）
+func (r *MemoryBidRepository) Place(ctx context.Context, bid *models.Bid) (bool, error) {
+	r.freights.mu.Lock()
+	defer r.freights.mu.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order, ok := r.freights.orders[bid.OrderID]
+	if !ok || order.Status != models.FreightStatusPending {
+		return false, nil
+	}
+
+	now := utils.FromTime(time.Now())
+	for _, existing := range r.bids {
+		if existing.OrderID == bid.OrderID && existing.CarrierID == bid.CarrierID {
+			existing.Amount = bid.Amount
+			existing.ETA = bid.ETA
+			existing.Note = bid.Note
+			existing.UpdatedAt = now
+			*bid = *existing
+			return true, nil
+		}
+	}
+
+	r.nextID++
+	bid.ID = r.nextID
+	bid.Status = models.BidStatusPending
+	bid.CreatedAt = now
+	bid.UpdatedAt = now
+	stored := *bid
+	r.bids[bid.ID] = &stored
+	order.BidCount++
+	return true, nil
+}
+
+// GetByID 查找出价，不存在返回nil
+func (r *MemoryBidRepository) GetByID(ctx context.Context, id uint64) (*models.Bid, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bid, ok := r.bids[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *bid
+	return &copied, nil
+}
+
+// ListByOrder 按金额从低到高列出订单的出价
+func (r *MemoryBidRepository) ListByOrder(ctx context.Context, orderID uint64) ([]*models.Bid, error) {
+	bids := r.list(func(b *models.Bid) bool { return b.OrderID == orderID })
+	sort.Slice(bids, func(i, j int) bool {
+		if bids[i].Amount != bids[j].Amount {
+			return bids[i].Amount < bids[j].Amount
+		}
+		return bids[i].ID < bids[j].ID
+	})
+	return bids, nil
+}
+
+// ListByCarrier 按更新时间倒序列出承运方的出价
+func (r *MemoryBidRepository) ListByCarrier(ctx context.Context, carrierID uint64) ([]*models.Bid, error) {
+	bids := r.list(func(b *models.Bid) bool { return b.CarrierID == carrierID })
+	sort.Slice(bids, func(i, j int) bool {
+		if !bids[i].UpdatedAt.Time.Equal(bids[j].UpdatedAt.Time) {
+			return bids[i].UpdatedAt.Time.After(bids[j].UpdatedAt.Time)
+		}
+		return bids[i].ID > bids[j].ID
+	})
+	return bids, nil
+}
+
+func (r *MemoryBidRepository) list(match func(*models.Bid) bool) []*models.Bid {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bids := []*models.Bid{}
+	for _, b := range r.bids {
+		if match(b) {
+			copied := *b
+			bids = append(bids, &copied)
+		}
+	}
+	return bids
+}
+
+// Award 原子定标
+func (r *MemoryBidRepository) Award(ctx context.Context, bidID, actorID uint64) (bool, error) {
+	r.freights.mu.Lock()
+	defer r.freights.mu.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bid, ok := r.bids[bidID]
+	if !ok || bid.Status != models.BidStatusPending {
+		return false, nil
+	}
+	order, ok := r.freights.orders[bid.OrderID]
+	if !ok || order.Status != models.FreightStatusPending {
+		return false, nil
+	}
+
+	now := utils.FromTime(time.Now())
+	order.CarrierID = bid.CarrierID
+	order.Price = bid.Amount
+	order.Status = models.FreightStatusAccepted
+	order.UpdatedAt = now
+	r.freights.appendEvent(&models.FreightOrderEvent{
+		OrderID:    order.ID,
+		FromStatus: models.FreightStatusPending,
+		ToStatus:   models.FreightStatusAccepted,
+		ActorID:    actorID,
+		Reason:     "竞价中标",
+	})
+
+	for _, b := range r.bids {
+		if b.OrderID != order.ID || b.Status != models.BidStatusPending {
+			continue
+		}
+		b.Status = models.BidStatusRejected
+		if b.ID == bidID {
+			b.Status = models.BidStatusAwarded
+		}
+		b.UpdatedAt = now
+	}
+	return true, nil
+}
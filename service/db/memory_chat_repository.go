@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"freight/models"
+)
+
+type chatReadKey struct {
+	orderID, userID uint64
+}
+
+// MemoryChatRepository 订单沟通仓储内存实现（用于测试和本地开发）
+type MemoryChatRepository struct {
+	freights *MemoryFreightRepository
+
+	mu       sync.Mutex
+	nextID   uint64
+	messages map[uint64][]*models.ChatMessage // 按订单分组，按ID升序
+	reads    map[chatReadKey]*models.ChatReadReceipt
+}
+
+var _ models.ChatRepository = (*MemoryChatRepository)(nil)
+
+// NewMemoryChatRepository 创建内存订单沟通仓储实例，统计未读时通过订单仓储判断用户参与的订单
+func NewMemoryChatRepository(freights *MemoryFreightRepository) *MemoryChatRepository {
+	return &MemoryChatRepository{
+		freights: freights,
+		messages: make(map[uint64][]*models.ChatMessage),
+		reads:    make(map[chatReadKey]*models.ChatReadReceipt),
+	}
+}
+
+// AddMessage 保存消息副本
+func (r *MemoryChatRepository) AddMessage(ctx context.Context, msg *models.ChatMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	msg.ID = r.nextID
+	stored := *msg
+	stored.ClientID = ""
+	r.messages[msg.OrderID] = append(r.messages[msg.OrderID], &stored)
+	return nil
+}
+
+// ListMessages 按ID升序返回一页消息
+func (r *MemoryChatRepository) ListMessages(ctx context.Context, orderID, beforeID, afterID uint64, limit int) ([]*models.ChatMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	all := r.messages[orderID]
+	var page []*models.ChatMessage
+	if afterID > 0 {
+		i := sort.Search(len(all), func(i int) bool { return all[i].ID > afterID })
+		page = all[i:]
+		if len(page) > limit {
+			page = page[:limit]
+		}
+	} else {
+		end := len(all)
+		if beforeID > 0 {
+			end = sort.Search(len(all), func(i int) bool { return all[i].ID >= beforeID })
+		}
+		start := end - limit
+		if start < 0 {
+			start = 0
+		}
+		page = all[start:end]
+	}
+
+	messages := make([]*models.ChatMessage, 0, len(page))
+	for _, m := range page {
+		copied := *m
+		messages = append(messages, &copied)
+	}
+	return messages, nil
+}
+
+// MarkRead 推进已读位置
+func (r *MemoryChatRepository) MarkRead(ctx context.Context, orderID, userID, upToID uint64, at time.Time) (*models.ChatReadReceipt, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var latest uint64
+	for _, m := range r.messages[orderID] {
+		if m.ID > upToID {
+			break
+		}
+		latest = m.ID
+	}
+	key := chatReadKey{orderID, userID}
+	receipt, ok := r.reads[key]
+	if !ok {
+		receipt = &models.ChatReadReceipt{OrderID: orderID, UserID: userID, ReadAt: at}
+		r.reads[key] = receipt
+	}
+	advanced := latest > receipt.LastReadID
+	if advanced {
+		receipt.LastReadID, receipt.ReadAt = latest, at
+	}
+	copied := *receipt
+	return &copied, advanced, nil
+}
+
+// ReadReceipts 返回订单的已读回执（按用户ID升序）
+func (r *MemoryChatRepository) ReadReceipts(ctx context.Context, orderID uint64) ([]*models.ChatReadReceipt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	receipts := []*models.ChatReadReceipt{}
+	for key, receipt := range r.reads {
+		if key.orderID == orderID && receipt.LastReadID > 0 {
+			copied := *receipt
+			receipts = append(receipts, &copied)
+		}
+	}
+	sort.Slice(receipts, func(i, j int) bool { return receipts[i].UserID < receipts[j].UserID })
+	return receipts, nil
+}
+
+// UnreadCounts 统计用户参与订单中的未读消息
+func (r *MemoryChatRepository) UnreadCounts(ctx context.Context, userID uint64) (map[uint64]int, error) {
+	r.freights.mu.Lock()
+	defer r.freights.mu.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := make(map[uint64]int)
+	for orderID, messages := range r.messages {
+		order, ok := r.freights.orders[orderID]
+		if !ok || (order.ShipperID != userID && order.CarrierID != userID) {
+			continue
+		}
+		var lastRead uint64
+		if receipt, ok := r.reads[chatReadKey{orderID, userID}]; ok {
+			lastRead = receipt.LastReadID
+		}
+		for _, m := range messages {
+			if m.ID > lastRead && m.SenderID != userID {
+				counts[orderID]++
+			}
+		}
+	}
+	return counts, nil
+}
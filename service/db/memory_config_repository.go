@@ -0,0 +1,100 @@
+package db
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"freight/models"
+)
+
+// MemoryConfigRepository 配置仓储内存实现（用于测试和本地开发）
+type MemoryConfigRepository struct {
+	mu      sync.Mutex
+	nextID  int64
+	configs map[string]*models.Config
+}
+
+var _ models.ConfigRepository = (*MemoryConfigRepository)(nil)
+
+// NewMemoryConfigRepository 创建内存配置仓储实例
+func NewMemoryConfigRepository() *MemoryConfigRepository {
+	return &MemoryConfigRepository{configs: make(map[string]*models.Config)}
+}
+
+// Create 创建配置（键唯一，与数据库唯一索引一致）
+func (r *MemoryConfigRepository) Create(config *models.Config) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.configs[config.Key]; ok {
+		return errors.New("配置键重复")
+	}
+	now := time.Now()
+	r.nextID++
+	config.ID = r.nextID
+	config.CreatedAt = now
+	config.UpdatedAt = now
+	stored := *config
+	r.configs[config.Key] = &stored
+	return nil
+}
+
+// GetByKey 通过键获取配置
+func (r *MemoryConfigRepository) GetByKey(key string) (*models.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	config, ok := r.configs[key]
+	if !ok {
+		return nil, nil
+	}
+	copied := *config
+	return &copied, nil
+}
+
+// Update 更新配置
+func (r *MemoryConfigRepository) Update(config *models.Config) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.configs[config.Key]
+	if !ok {
+		return nil
+	}
+	config.UpdatedAt = time.Now()
+	stored.Value = config.Value
+	stored.Description = config.Description
+	stored.UpdatedAt = config.UpdatedAt
+	return nil
+}
+
+// Delete 删除配置
+func (r *MemoryConfigRepository) Delete(key string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.configs[key]
+	delete(r.configs, key)
+	return ok, nil
+}
+
+// List 按创建时间倒序列出全部配置
+func (r *MemoryConfigRepository) List() ([]*models.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	configs := make([]*models.Config, 0, len(r.configs))
+	for _, config := range r.configs {
+		copied := *config
+		configs = append(configs, &copied)
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		if !configs[i].CreatedAt.Equal(configs[j].CreatedAt) {
+			return configs[i].CreatedAt.After(configs[j].CreatedAt)
+		}
+		return configs[i].ID > configs[j].ID
+	})
+	return configs, nil
+}
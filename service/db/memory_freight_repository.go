@@ -0,0 +1,351 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"freight/models"
+	"freight/utils"
+)
+
+// MemoryFreightRepository 内存实现（用于测试和本地开发，不依赖MySQL）
+type MemoryFreightRepository struct {
+	mu      sync.Mutex
+	nextID  uint64
+	orders  map[uint64]*models.FreightOrder
+	events  map[uint64][]*models.FreightOrderEvent
+	eventID uint64
+	index   *invertedIndex       // 全文检索倒排索引，随订单写入同步更新
+	bids    *MemoryBidRepository // 共享订单数据的竞价仓储，订单取消或过期时关闭出价（可为空）
+}
+
+var _ FreightRepository = (*MemoryFreightRepository)(nil)
+
+// NewMemoryFreightRepository 创建内存货运订单仓储实例
+func NewMemoryFreightRepository() *MemoryFreightRepository {
+	return &MemoryFreightRepository{
+		orders: make(map[uint64]*models.FreightOrder),
+		events: make(map[uint64][]*models.FreightOrderEvent),
+		index:  newInvertedIndex(),
+	}
+}
+
+// Create 创建货运订单
+func (r *MemoryFreightRepository) Create(ctx context.Context, freight *models.FreightOrder) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	now := utils.FromTime(time.Now())
+	freight.ID = r.nextID
+	freight.Status = models.FreightStatusPending
+	freight.BidCount = 0
+	freight.VehicleID = 0
+	freight.CreatedAt = now
+	freight.UpdatedAt = now
+
+	stored := *freight
+	r.orders[freight.ID] = &stored
+	r.index.add(&stored)
+	return nil
+}
+
+// GetByID 获取货运订单（已删除返回nil）
+func (r *MemoryFreightRepository) GetByID(ctx context.Context, id uint64) (*models.FreightOrder, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order, ok := r.orders[id]
+	if !ok || order.Status == 0 {
+		return nil, nil
+	}
+	copied := *order
+	return &copied, nil
+}
+
+// List 列出待接单的货运订单
+func (r *MemoryFreightRepository) List(ctx context.Context, filter models.FreightFilter) ([]*models.FreightOrder, int64, error) {
+	freights, total := r.list(func(o *models.FreightOrder) bool {
+		if filter.Status == nil {
+			return o.Status == models.FreightStatusPending
+		}
+		return o.Status != 0
+	}, filter, "updated_at")
+	return freights, total, nil
+}
+
+// Update 更新货运订单（仅更新非空字段，与MySQL实现保持一致）
+func (r *MemoryFreightRepository) Update(ctx context.Context, freight *models.FreightOrder) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order, ok := r.orders[freight.ID]
+	if !ok || order.Status != models.FreightStatusPending {
+		return false, nil
+	}
+	if freight.OriginLocation != "" {
+		order.OriginLocation = freight.OriginLocation
+	}
+	if freight.DestinationLocation != "" {
+		order.DestinationLocation = freight.DestinationLocation
+	}
+	if freight.OriginCode != "" || freight.DestinationCode != "" {
+		order.OriginCode, order.DestinationCode = freight.OriginCode, freight.DestinationCode
+		order.DistanceKm, order.EstimatedHours = freight.DistanceKm, freight.EstimatedHours
+	}
+	if freight.Status != 0 {
+		order.Status = freight.Status
+	}
+	order.UpdatedAt = utils.FromTime(time.Now())
+	r.index.add(order)
+	return true, nil
+}
+
+// Delete 软删除待接单的货运订单
+func (r *MemoryFreightRepository) Delete(ctx context.Context, id uint64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order, ok := r.orders[id]
+	if !ok || order.Status != models.FreightStatusPending {
+		return false, nil
+	}
+	order.Status = 0
+	order.UpdatedAt = utils.FromTime(time.Now())
+	r.index.remove(id)
+	return true, nil
+}
+
+// ListByUserID 根据用户ID及角色列出货运订单
+func (r *MemoryFreightRepository) ListByUserID(ctx context.Context, userID uint64, filter models.FreightFilter) ([]*models.FreightOrder, int64, error) {
+	freights, total := r.list(func(o *models.FreightOrder) bool {
+		if o.Status == 0 {
+			return false
+		}
+		switch filter.Role {
+		case models.FreightRoleShipper:
+			return o.ShipperID == userID
+		case models.FreightRoleCarrier:
+			return o.CarrierID == userID
+		default:
+			return o.ShipperID == userID || o.CarrierID == userID
+		}
+	}, filter, "created_at")
+	return freights, total, nil
+}
+
+// UpdateStatus 条件更新订单状态并写入流转记录
+func (r *MemoryFreightRepository) UpdateStatus(ctx context.Context, event *models.FreightOrderEvent) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order, ok := r.orders[event.OrderID]
+	if !ok || order.Status != event.FromStatus {
+		return false, nil
+	}
+	order.Status = event.ToStatus
+	order.UpdatedAt = utils.FromTime(time.Now())
+	r.appendEvent(event)
+	if closesBids(event.ToStatus) && r.bids != nil {
+		r.bids.closeOrder(order.ID, order.UpdatedAt)
+	}
+	return true, nil
+}
+
+// ListEvents 按时间顺序列出订单的状态流转记录
+func (r *MemoryFreightRepository) ListEvents(ctx context.Context, orderID uint64) ([]*models.FreightOrderEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := make([]*models.FreightOrderEvent, 0, len(r.events[orderID]))
+	for _, e := range r.events[orderID] {
+		copied := *e
+		events = append(events, &copied)
+	}
+	return events, nil
+}
+
+// AcceptPending 原子接单
+func (r *MemoryFreightRepository) AcceptPending(ctx context.Context, orderID, userID, vehicleID uint64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order, ok := r.orders[orderID]
+	if !ok || order.Status != models.FreightStatusPending {
+		return false, nil
+	}
+	order.CarrierID = userID
+	order.VehicleID = vehicleID
+	order.Status = models.FreightStatusAccepted
+	order.UpdatedAt = utils.FromTime(time.Now())
+	r.appendEvent(&models.FreightOrderEvent{
+		OrderID:    orderID,
+		FromStatus: models.FreightStatusPending,
+		ToStatus:   models.FreightStatusAccepted,
+		ActorID:    userID,
+		Reason:     "接单",
+	})
+	return true, nil
+}
+
+// ListPickupOverdue 列出取货已超时的待接单订单，按取货截止时间升序
+func (r *MemoryFreightRepository) ListPickupOverdue(ctx context.Context, now time.Time, limit int) ([]*models.FreightOrder, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var overdue []*models.FreightOrder
+	for _, o := range r.orders {
+		if o.Status == models.FreightStatusPending && o.PickupLatest != nil && o.PickupLatest.Before(now) {
+			copied := *o
+			overdue = append(overdue, &copied)
+		}
+	}
+	sort.Slice(overdue, func(i, j int) bool { return overdue[i].PickupLatest.Before(*overdue[j].PickupLatest) })
+	if len(overdue) > limit {
+		overdue = overdue[:limit]
+	}
+	return overdue, nil
+}
+
+// appendEvent 记录流转事件（调用方需持有锁）
+func (r *MemoryFreightRepository) appendEvent(event *models.FreightOrderEvent) {
+	r.eventID++
+	event.ID = r.eventID
+	event.CreatedAt = utils.FromTime(time.Now())
+	stored := *event
+	r.events[event.OrderID] = append(r.events[event.OrderID], &stored)
+}
+
+// list 按条件筛选订单，按白名单排序字段（默认 defaultSort）排序并分页，与MySQL实现保持一致；同时返回过滤后的总数
+func (r *MemoryFreightRepository) list(match func(*models.FreightOrder) bool, filter models.FreightFilter, defaultSort string) ([]*models.FreightOrder, int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var freights []*models.FreightOrder
+	var total int64
+	for _, o := range r.orders {
+		if !match(o) || !matchFreightFilter(o, filter) {
+			continue
+		}
+		total++
+		if filter.CursorMode && filter.After != nil && !beforeCursor(o, filter.After) {
+			continue
+		}
+		copied := *o
+		freights = append(freights, &copied)
+	}
+
+	field, order := freightSort(filter, defaultSort)
+	sort.Slice(freights, func(i, j int) bool {
+		c := compareFreight(freights[i], freights[j], field)
+		if c == 0 {
+			c = compareUint64(freights[i].ID, freights[j].ID)
+		}
+		if order == "ASC" {
+			return c < 0
+		}
+		return c > 0
+	})
+
+	switch {
+	case filter.CursorMode && filter.PageSize > 0:
+		if len(freights) > filter.PageSize {
+			freights = freights[:filter.PageSize]
+		}
+	case filter.Page > 0 && filter.PageSize > 0:
+		start := (filter.Page - 1) * filter.PageSize
+		if start >= len(freights) {
+			return nil, total
+		}
+		end := start + filter.PageSize
+		if end > len(freights) {
+			end = len(freights)
+		}
+		freights = freights[start:end]
+	}
+	return freights, total
+}
+
+// beforeCursor 判断订单是否排在游标之后（按 (updated_at, id) 倒序）
+func beforeCursor(o *models.FreightOrder, after *models.FreightCursor) bool {
+	c := o.UpdatedAt.Time.Compare(after.UpdatedAt)
+	return c < 0 || c == 0 && o.ID < after.ID
+}
+
+// matchFreightFilter 判断订单是否满足过滤条件（零值字段不参与过滤）
+func matchFreightFilter(o *models.FreightOrder, filter models.FreightFilter) bool {
+	orderDate := o.OrderDate.String()
+	switch {
+	case filter.OriginLocation != "" && o.OriginLocation != filter.OriginLocation,
+		filter.OriginCode != "" && o.OriginCode != filter.OriginCode,
+		filter.DestinationLocation != "" && o.DestinationLocation != filter.DestinationLocation,
+		filter.DestinationCode != "" && o.DestinationCode != filter.DestinationCode,
+		filter.OriginCodePrefix != "" && !strings.HasPrefix(o.OriginCode, filter.OriginCodePrefix),
+		filter.DestinationCodePrefix != "" && !strings.HasPrefix(o.DestinationCode, filter.DestinationCodePrefix),
+		filter.TypeID != 0 && o.TypeID != filter.TypeID,
+		filter.Status != nil && o.Status != *filter.Status,
+		filter.MinPrice > 0 && o.Price < filter.MinPrice,
+		filter.MaxPrice > 0 && o.Price > filter.MaxPrice,
+		filter.IsUrgent != nil && o.IsUrgent != *filter.IsUrgent,
+		filter.HasInsurance != nil && o.HasInsurance != *filter.HasInsurance,
+		filter.OrderDate != "" && orderDate != filter.OrderDate,
+		filter.OrderDateFrom != "" && orderDate < filter.OrderDateFrom,
+		filter.OrderDateTo != "" && orderDate > filter.OrderDateTo,
+		filter.MinDistance > 0 && o.DistanceKm < filter.MinDistance,
+		filter.MaxDistance > 0 && o.DistanceKm > filter.MaxDistance,
+		filter.MinWeight > 0 && o.WeightKg < filter.MinWeight,
+		filter.MaxWeight > 0 && o.WeightKg > filter.MaxWeight,
+		filter.MaxVolume > 0 && o.VolumeM3 > filter.MaxVolume,
+		filter.MaxLength > 0 && o.LengthM > filter.MaxLength,
+		filter.MaxWidth > 0 && o.WidthM > filter.MaxWidth,
+		filter.MaxHeight > 0 && o.HeightM > filter.MaxHeight,
+		filter.Hazmat != nil && (o.HazmatClass > 0) != *filter.Hazmat,
+		filter.HazmatClass != 0 && o.HazmatClass != filter.HazmatClass,
+		filter.ColdChain != nil && (o.TempMinC != nil || o.TempMaxC != nil) != *filter.ColdChain,
+		filter.PickupFrom != nil && filter.PickupTo != nil && (o.PickupEarliest == nil || o.PickupLatest == nil ||
+			o.PickupEarliest.After(*filter.PickupTo) || o.PickupLatest.Before(*filter.PickupFrom)):
+		return false
+	}
+	return true
+}
+
+// compareFreight 按排序字段比较两个订单
+func compareFreight(a, b *models.FreightOrder, field string) int {
+	switch field {
+	case "price":
+		switch {
+		case a.Price < b.Price:
+			return -1
+		case a.Price > b.Price:
+			return 1
+		}
+		return 0
+	case "order_date":
+		return a.OrderDate.Time.Compare(b.OrderDate.Time)
+	case "distance_km":
+		switch {
+		case a.DistanceKm < b.DistanceKm:
+			return -1
+		case a.DistanceKm > b.DistanceKm:
+			return 1
+		}
+		return 0
+	case "created_at":
+		return a.CreatedAt.Time.Compare(b.CreatedAt.Time)
+	default:
+		return a.UpdatedAt.Time.Compare(b.UpdatedAt.Time)
+	}
+}
+
+func compareUint64(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
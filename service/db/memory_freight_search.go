@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"freight/models"
+)
+
+var _ FreightSearchIndex = (*MemoryFreightRepository)(nil)
+
+// invertedIndex 进程内倒排索引，分词方式与 MySQL ngram 解析器一致（按字母数字连续片段切分为二元组）
+type invertedIndex struct {
+	postings map[string]map[uint64]int // 词 -> 订单ID -> 词频
+	docs     map[uint64][]string       // 订单ID -> 已索引的词，用于重建索引时移除
+}
+
+func newInvertedIndex() *invertedIndex {
+	return &invertedIndex{
+		postings: make(map[string]map[uint64]int),
+		docs:     make(map[uint64][]string),
+	}
+}
+
+// add 索引订单的出发地、目的地、货物类型和备注（已索引的订单先移除再重建）
+func (idx *invertedIndex) add(o *models.FreightOrder) {
+	idx.remove(o.ID)
+	var terms []string
+	for _, field := range []string{o.OriginLocation, o.DestinationLocation, o.Type, o.Remark} {
+		terms = append(terms, ngrams(field)...)
+	}
+	for _, term := range terms {
+		docs, ok := idx.postings[term]
+		if !ok {
+			docs = make(map[uint64]int)
+			idx.postings[term] = docs
+		}
+		docs[o.ID]++
+	}
+	idx.docs[o.ID] = terms
+}
+
+// remove 从索引中移除订单
+func (idx *invertedIndex) remove(id uint64) {
+	for _, term := range idx.docs[id] {
+		docs := idx.postings[term]
+		delete(docs, id)
+		if len(docs) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	delete(idx.docs, id)
+}
+
+// score 计算查询与各订单的相关度（TF-IDF 累加），只返回至少匹配一个词的订单
+func (idx *invertedIndex) score(query string) map[uint64]float64 {
+	scores := make(map[uint64]float64)
+	seen := make(map[string]bool)
+	total := float64(len(idx.docs))
+	for _, term := range ngrams(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		docs := idx.postings[term]
+		if len(docs) == 0 {
+			continue
+		}
+		idf := math.Log(1 + total/float64(len(docs)))
+		for id, tf := range docs {
+			scores[id] += float64(tf) * idf
+		}
+	}
+	return scores
+}
+
+// ngrams 将文本按非字母数字字符切分，每个片段再切为相邻两个字符的二元组（单字片段原样保留）
+func ngrams(text string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		runes := []rune(word)
+		if len(runes) == 1 {
+			terms = append(terms, word)
+			continue
+		}
+		for i := 0; i+1 < len(runes); i++ {
+			terms = append(terms, string(runes[i:i+2]))
+		}
+	}
+	return terms
+}
+
+// Search 基于倒排索引检索订单，过滤条件和排序规则与MySQL实现保持一致
+func (r *MemoryFreightRepository) Search(ctx context.Context, query string, filter models.FreightFilter) ([]*models.FreightOrder, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	scores := r.index.score(query)
+	var freights []*models.FreightOrder
+	for id := range scores {
+		o := r.orders[id]
+		if filter.Status == nil && o.Status != models.FreightStatusPending || o.Status == 0 || !matchFreightFilter(o, filter) {
+			continue
+		}
+		copied := *o
+		freights = append(freights, &copied)
+	}
+	total := int64(len(freights))
+
+	field, order := freightSort(filter, "updated_at")
+	sort.Slice(freights, func(i, j int) bool {
+		a, b := freights[i], freights[j]
+		if filter.SortField == "" {
+			if sa, sb := scores[a.ID], scores[b.ID]; sa != sb {
+				return sa > sb
+			}
+			return a.ID > b.ID
+		}
+		c := compareFreight(a, b, field)
+		if c == 0 {
+			c = compareUint64(a.ID, b.ID)
+		}
+		if order == "ASC" {
+			return c < 0
+		}
+		return c > 0
+	})
+
+	if filter.Page > 0 && filter.PageSize > 0 {
+		start := (filter.Page - 1) * filter.PageSize
+		if start >= len(freights) {
+			return nil, total, nil
+		}
+		end := start + filter.PageSize
+		if end > len(freights) {
+			end = len(freights)
+		}
+		freights = freights[start:end]
+	}
+	return freights, total, nil
+}
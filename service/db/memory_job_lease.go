@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"freight/scheduler"
+)
+
+type memoryLease struct {
+	holder    string
+	expiresAt time.Time
+}
+
+// MemoryJobLease 定时任务租约内存实现（用于测试，模拟多个实例共享租约）
+type MemoryJobLease struct {
+	mu     sync.Mutex
+	leases map[string]memoryLease
+}
+
+var _ scheduler.Locker = (*MemoryJobLease)(nil)
+
+// NewMemoryJobLease 创建内存租约实例
+func NewMemoryJobLease() *MemoryJobLease {
+	return &MemoryJobLease{leases: make(map[string]memoryLease)}
+}
+
+// TryAcquire 租约空闲、已过期或已由 holder 持有时交给 holder
+func (l *MemoryJobLease) TryAcquire(ctx context.Context, name, holder string, until time.Time) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	current, ok := l.leases[name]
+	if ok && current.holder != holder && !current.expiresAt.Before(time.Now()) {
+		return false, nil
+	}
+	l.leases[name] = memoryLease{holder: holder, expiresAt: until}
+	return true, nil
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"freight/models"
+)
+
+// MemoryLocationRepository 定位仓储内存实现（用于测试和本地开发）
+type MemoryLocationRepository struct {
+	freights *MemoryFreightRepository
+
+	mu     sync.Mutex
+	nextID uint64
+	pings  map[uint64][]*models.LocationPing // 按订单分组，按定位时间升序
+}
+
+var _ models.LocationRepository = (*MemoryLocationRepository)(nil)
+
+// NewMemoryLocationRepository 创建内存定位仓储实例，清理时通过订单仓储判断订单状态
+func NewMemoryLocationRepository(freights *MemoryFreightRepository) *MemoryLocationRepository {
+	return &MemoryLocationRepository{freights: freights, pings: make(map[uint64][]*models.LocationPing)}
+}
+
+// AddPings 保存定位并保持按时间排序，重复的定位时间忽略
+func (r *MemoryLocationRepository) AddPings(ctx context.Context, pings []*models.LocationPing) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	added := 0
+	for _, p := range pings {
+		trail := r.pings[p.OrderID]
+		i := sort.Search(len(trail), func(i int) bool { return !trail[i].RecordedAt.Before(p.RecordedAt) })
+		if i < len(trail) && trail[i].RecordedAt.Equal(p.RecordedAt) {
+			continue
+		}
+		r.nextID++
+		stored := *p
+		stored.ID = r.nextID
+		trail = append(trail, nil)
+		copy(trail[i+1:], trail[i:])
+		trail[i] = &stored
+		r.pings[p.OrderID] = trail
+		added++
+	}
+	return added, nil
+}
+
+// Latest 返回最新定位
+func (r *MemoryLocationRepository) Latest(ctx context.Context, orderID uint64) (*models.LocationPing, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	trail := r.pings[orderID]
+	if len(trail) == 0 {
+		return nil, nil
+	}
+	latest := *trail[len(trail)-1]
+	return &latest, nil
+}
+
+// Trail 返回最近 limit 条定位（升序）
+func (r *MemoryLocationRepository) Trail(ctx context.Context, orderID uint64, limit int) ([]*models.LocationPing, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	trail := r.pings[orderID]
+	if len(trail) > limit {
+		trail = trail[len(trail)-limit:]
+	}
+	pings := make([]*models.LocationPing, 0, len(trail))
+	for _, p := range trail {
+		copied := *p
+		pings = append(pings, &copied)
+	}
+	return pings, nil
+}
+
+// PurgeFinished 删除已结束订单的定位
+func (r *MemoryLocationRepository) PurgeFinished(ctx context.Context, before time.Time) (int64, error) {
+	r.freights.mu.Lock()
+	defer r.freights.mu.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var purged int64
+	for orderID, trail := range r.pings {
+		order, ok := r.freights.orders[orderID]
+		if !ok || !order.UpdatedAt.Time.Before(before) {
+			continue
+		}
+		switch order.Status {
+		case 0, models.FreightStatusConfirmed, models.FreightStatusCancelled, models.FreightStatusExpired:
+			purged += int64(len(trail))
+			delete(r.pings, orderID)
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"sync"
+	"time"
+
+	"freight/models"
+)
+
+// MemoryLoginAttemptRepository 登录失败记录内存实现（用于测试和本地开发）
+type MemoryLoginAttemptRepository struct {
+	mu       sync.Mutex
+	failures map[string][]time.Time
+	locks    map[string]time.Time
+}
+
+var _ models.LoginAttemptRepository = (*MemoryLoginAttemptRepository)(nil)
+
+// NewMemoryLoginAttemptRepository 创建内存登录失败记录仓储实例
+func NewMemoryLoginAttemptRepository() *MemoryLoginAttemptRepository {
+	return &MemoryLoginAttemptRepository{
+		failures: make(map[string][]time.Time),
+		locks:    make(map[string]time.Time),
+	}
+}
+
+// RecordFailure 记录一次登录失败
+func (r *MemoryLoginAttemptRepository) RecordFailure(scope, subject string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := scope + "|" + subject
+	r.failures[key] = append(r.failures[key], at)
+	return nil
+}
+
+// CountFailures 统计 since 之后的失败次数
+func (r *MemoryLoginAttemptRepository) CountFailures(scope, subject string, since time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := 0
+	for _, at := range r.failures[scope+"|"+subject] {
+		if at.After(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ClearFailures 清除失败记录
+func (r *MemoryLoginAttemptRepository) ClearFailures(scope, subject string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.failures, scope+"|"+subject)
+	return nil
+}
+
+// Lock 锁定账号到指定时间
+func (r *MemoryLoginAttemptRepository) Lock(subject string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.locks[subject] = until
+	return nil
+}
+
+// LockedUntil 查询账号锁定截止时间
+func (r *MemoryLoginAttemptRepository) LockedUntil(subject string) (time.Time, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	until, ok := r.locks[subject]
+	return until, ok, nil
+}
+
+// Unlock 解除账号锁定
+func (r *MemoryLoginAttemptRepository) Unlock(subject string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.locks[subject]
+	delete(r.locks, subject)
+	return ok, nil
+}
+
+// PurgeFailures 清理过期的失败记录和锁定
+func (r *MemoryLoginAttemptRepository) PurgeFailures(before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var total int64
+	for key, times := range r.failures {
+		kept := times[:0]
+		for _, at := range times {
+			if at.Before(before) {
+				total++
+				continue
+			}
+			kept = append(kept, at)
+		}
+		if len(kept) == 0 {
+			delete(r.failures, key)
+		} else {
+			r.failures[key] = kept
+		}
+	}
+	for subject, until := range r.locks {
+		if until.Before(before) {
+			delete(r.locks, subject)
+			total++
+		}
+	}
+	return total, nil
+}
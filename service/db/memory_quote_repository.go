@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"freight/models"
+	"freight/utils"
+)
+
+// MemoryQuoteRepository 报价仓储内存实现（用于测试和本地开发）
+type MemoryQuoteRepository struct {
+	mu     sync.Mutex
+	quotes map[string]*models.Quote
+}
+
+var _ models.QuoteRepository = (*MemoryQuoteRepository)(nil)
+
+// NewMemoryQuoteRepository 创建内存报价仓储实例
+func NewMemoryQuoteRepository() *MemoryQuoteRepository {
+	return &MemoryQuoteRepository{quotes: make(map[string]*models.Quote)}
+}
+
+// Create 保存报价
+func (r *MemoryQuoteRepository) Create(ctx context.Context, quote *models.Quote) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	quote.CreatedAt = utils.FromTime(time.Now())
+	stored := *quote
+	stored.Items = append([]models.QuoteItem(nil), quote.Items...)
+	r.quotes[quote.ID] = &stored
+	return nil
+}
+
+// FindByID 查找报价，不存在返回nil
+func (r *MemoryQuoteRepository) FindByID(ctx context.Context, id string) (*models.Quote, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	quote, ok := r.quotes[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *quote
+	copied.Items = append([]models.QuoteItem(nil), quote.Items...)
+	return &copied, nil
+}
+
+// Claim 占用未使用且未过期的报价
+func (r *MemoryQuoteRepository) Claim(ctx context.Context, id string, now time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	quote, ok := r.quotes[id]
+	if !ok || quote.UsedAt.Valid || !quote.ExpiresAt.After(now) {
+		return false, nil
+	}
+	quote.UsedAt = utils.FromTime(now)
+	return true, nil
+}
+
+// Release 释放占用（仅限尚未关联订单的报价）
+func (r *MemoryQuoteRepository) Release(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if quote, ok := r.quotes[id]; ok && quote.OrderID == 0 {
+		quote.UsedAt = utils.CustomNullTime{}
+	}
+	return nil
+}
+
+// AttachOrder 记录据此报价发布的订单
+func (r *MemoryQuoteRepository) AttachOrder(ctx context.Context, id string, orderID uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if quote, ok := r.quotes[id]; ok {
+		quote.OrderID = orderID
+	}
+	return nil
+}
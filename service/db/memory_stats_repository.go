@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"freight/models"
+	"freight/utils"
+)
+
+// MemoryStatsRepository 统计仓储内存实现，基于内存订单仓储汇总，结果不保存（用于测试和本地开发）
+type MemoryStatsRepository struct {
+	freights *MemoryFreightRepository
+}
+
+var _ models.StatsRepository = (*MemoryStatsRepository)(nil)
+
+// NewMemoryStatsRepository 创建内存统计仓储实例
+func NewMemoryStatsRepository(freights *MemoryFreightRepository) *MemoryStatsRepository {
+	return &MemoryStatsRepository{freights: freights}
+}
+
+// RollupDaily 汇总 [from, to) 内的订单数据
+func (r *MemoryStatsRepository) RollupDaily(ctx context.Context, date utils.Date, from, to time.Time) (*models.DailyStats, error) {
+	within := func(t utils.CustomNullTime) bool {
+		return t.Valid && !t.Time.Before(from) && t.Time.Before(to)
+	}
+	stats := &models.DailyStats{Date: date}
+	counts := make(map[uint8]int)
+
+	r.freights.mu.Lock()
+	for _, o := range r.freights.orders {
+		if within(o.CreatedAt) {
+			stats.OrdersCreated++
+		}
+	}
+	for orderID, events := range r.freights.events {
+		for _, e := range events {
+			if !within(e.CreatedAt) {
+				continue
+			}
+			counts[e.ToStatus]++
+			if e.ToStatus == models.FreightStatusConfirmed {
+				stats.GMV += r.freights.orders[orderID].Price
+			}
+		}
+	}
+	r.freights.mu.Unlock()
+
+	applyStatusCounts(stats, counts)
+	stats.UpdatedAt = utils.FromTime(time.Now())
+	return stats, nil
+}
@@ -0,0 +1,213 @@
+package db
+
+import (
+	"sync"
+	"time"
+
+	"freight/models"
+	"freight/utils"
+)
+
+// MemoryTokenRepository 令牌仓储内存实现（用于测试和本地开发）
+type MemoryTokenRepository struct {
+	mu      sync.Mutex
+	nextID  int64
+	refresh map[int64]*models.RefreshToken
+	revoked map[string]time.Time
+	cutoffs map[int64]tokenCutoff
+	once    map[int64]*models.UserToken
+}
+
+// tokenCutoff 用户访问令牌的吊销时间点
+type tokenCutoff struct {
+	before    time.Time
+	expiresAt time.Time
+}
+
+var _ models.TokenRepository = (*MemoryTokenRepository)(nil)
+
+// NewMemoryTokenRepository 创建内存令牌仓储实例
+func NewMemoryTokenRepository() *MemoryTokenRepository {
+	return &MemoryTokenRepository{
+		refresh: make(map[int64]*models.RefreshToken),
+		revoked: make(map[string]time.Time),
+		cutoffs: make(map[int64]tokenCutoff),
+		once:    make(map[int64]*models.UserToken),
+	}
+}
+
+// CreateRefreshToken 保存刷新令牌
+func (r *MemoryTokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	token.ID = r.nextID
+	token.CreatedAt = utils.FromTime(time.Now())
+	stored := *token
+	r.refresh[token.ID] = &stored
+	return nil
+}
+
+// FindRefreshToken 通过哈希查找刷新令牌
+func (r *MemoryTokenRepository) FindRefreshToken(tokenHash string) (*models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.refresh {
+		if token.TokenHash == tokenHash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+// RevokeRefreshToken 条件吊销刷新令牌
+func (r *MemoryTokenRepository) RevokeRefreshToken(id int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.refresh[id]
+	if !ok || token.RevokedAt.Valid {
+		return false, nil
+	}
+	token.RevokedAt = utils.FromTime(time.Now())
+	return true, nil
+}
+
+// RevokeFamily 吊销同一 family 下的全部刷新令牌
+func (r *MemoryTokenRepository) RevokeFamily(familyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := utils.FromTime(time.Now())
+	for _, token := range r.refresh {
+		if token.FamilyID == familyID && !token.RevokedAt.Valid {
+			token.RevokedAt = now
+		}
+	}
+	return nil
+}
+
+// RevokeUserRefreshTokens 吊销用户的全部刷新令牌
+func (r *MemoryTokenRepository) RevokeUserRefreshTokens(userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := utils.FromTime(time.Now())
+	for _, token := range r.refresh {
+		if token.UserID == userID && !token.RevokedAt.Valid {
+			token.RevokedAt = now
+		}
+	}
+	return nil
+}
+
+// RevokeAccessToken 记录被吊销的访问令牌ID
+func (r *MemoryTokenRepository) RevokeAccessToken(jti string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.revoked[jti] = expiresAt
+	return nil
+}
+
+// IsAccessTokenRevoked 判断访问令牌是否已被吊销
+func (r *MemoryTokenRepository) IsAccessTokenRevoked(jti string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.revoked[jti]
+	return ok, nil
+}
+
+// RevokeUserAccessTokens 记录用户访问令牌的吊销时间点（只会后移）
+func (r *MemoryTokenRepository) RevokeUserAccessTokens(userID int64, before, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cutoff := r.cutoffs[userID]
+	if before.After(cutoff.before) {
+		cutoff.before = before
+	}
+	if expiresAt.After(cutoff.expiresAt) {
+		cutoff.expiresAt = expiresAt
+	}
+	r.cutoffs[userID] = cutoff
+	return nil
+}
+
+// UserAccessTokensRevokedBefore 查询用户访问令牌的吊销时间点
+func (r *MemoryTokenRepository) UserAccessTokensRevokedBefore(userID int64) (time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.cutoffs[userID].before, nil
+}
+
+// CreateUserToken 保存一次性令牌
+func (r *MemoryTokenRepository) CreateUserToken(token *models.UserToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	token.ID = r.nextID
+	token.CreatedAt = utils.FromTime(time.Now())
+	stored := *token
+	r.once[token.ID] = &stored
+	return nil
+}
+
+// ConsumeUserToken 使用一次性令牌
+func (r *MemoryTokenRepository) ConsumeUserToken(purpose, tokenHash string) (*models.UserToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, token := range r.once {
+		if token.Purpose != purpose || token.TokenHash != tokenHash {
+			continue
+		}
+		if token.UsedAt.Valid || !now.Before(token.ExpiresAt) {
+			return nil, nil
+		}
+		token.UsedAt = utils.FromTime(now)
+		copied := *token
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+// PurgeExpired 清理已过期的令牌记录
+func (r *MemoryTokenRepository) PurgeExpired(before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var total int64
+	for id, token := range r.refresh {
+		if token.ExpiresAt.Before(before) {
+			delete(r.refresh, id)
+			total++
+		}
+	}
+	for id, token := range r.once {
+		if token.ExpiresAt.Before(before) {
+			delete(r.once, id)
+			total++
+		}
+	}
+	for jti, expiresAt := range r.revoked {
+		if expiresAt.Before(before) {
+			delete(r.revoked, jti)
+			total++
+		}
+	}
+	for userID, cutoff := range r.cutoffs {
+		if cutoff.expiresAt.Before(before) {
+			delete(r.cutoffs, userID)
+			total++
+		}
+	}
+	return total, nil
+}
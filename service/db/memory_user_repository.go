@@ -0,0 +1,112 @@
+package db
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"freight/models"
+	"freight/utils"
+)
+
+// MemoryUserRepository 用户仓储内存实现（用于测试和本地开发）
+type MemoryUserRepository struct {
+	mu     sync.Mutex
+	nextID int64
+	users  map[int64]*models.User
+}
+
+var _ models.UserRepository = (*MemoryUserRepository)(nil)
+
+// NewMemoryUserRepository 创建内存用户仓储实例
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: make(map[int64]*models.User)}
+}
+
+// Create 创建用户（用户名、邮箱唯一，与数据库唯一索引一致）
+func (r *MemoryUserRepository) Create(user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range r.users {
+		if u.Username == user.Username || u.Email == user.Email {
+			return errors.New("用户名或邮箱重复")
+		}
+	}
+
+	now := utils.FromTime(time.Now())
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	if user.Status == 0 {
+		user.Status = models.UserStatusActive
+	}
+	r.nextID++
+	user.ID = r.nextID
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	stored := *user
+	r.users[user.ID] = &stored
+	return nil
+}
+
+// FindByUsername 通过用户名查找用户
+func (r *MemoryUserRepository) FindByUsername(username string) (*models.User, error) {
+	return r.find(func(u *models.User) bool { return u.Username == username })
+}
+
+// FindByEmail 通过邮箱查找用户
+func (r *MemoryUserRepository) FindByEmail(email string) (*models.User, error) {
+	return r.find(func(u *models.User) bool { return u.Email == email })
+}
+
+// FindByID 通过ID查找用户
+func (r *MemoryUserRepository) FindByID(id int64) (*models.User, error) {
+	return r.find(func(u *models.User) bool { return u.ID == id })
+}
+
+// Update 更新用户资料
+func (r *MemoryUserRepository) Update(user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[user.ID]
+	if !ok {
+		return nil
+	}
+	user.UpdatedAt = utils.FromTime(time.Now())
+	stored.Username = user.Username
+	stored.Phone = user.Phone
+	stored.AvatarURL = user.AvatarURL
+	stored.Role = user.Role
+	stored.Six = user.Six
+	stored.Status = user.Status
+	stored.EmailVerifiedAt = user.EmailVerifiedAt
+	stored.UpdatedAt = user.UpdatedAt
+	return nil
+}
+
+// UpdatePassword 更新密码哈希
+func (r *MemoryUserRepository) UpdatePassword(id int64, passwordHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if stored, ok := r.users[id]; ok {
+		stored.Password = passwordHash
+		stored.UpdatedAt = utils.FromTime(time.Now())
+	}
+	return nil
+}
+
+func (r *MemoryUserRepository) find(match func(*models.User) bool) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range r.users {
+		if match(u) {
+			copied := *u
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"freight/models"
+	"freight/utils"
+)
+
+// MemoryVehicleRepository 车辆仓储内存实现（用于测试和本地开发）
+type MemoryVehicleRepository struct {
+	mu       sync.Mutex
+	nextID   uint64
+	vehicles map[uint64]*models.Vehicle
+}
+
+var _ models.VehicleRepository = (*MemoryVehicleRepository)(nil)
+
+// NewMemoryVehicleRepository 创建内存车辆仓储实例
+func NewMemoryVehicleRepository() *MemoryVehicleRepository {
+	return &MemoryVehicleRepository{vehicles: make(map[uint64]*models.Vehicle)}
+}
+
+// Create 登记车辆（车牌号唯一，与数据库唯一索引一致）
+func (r *MemoryVehicleRepository) Create(ctx context.Context, vehicle *models.Vehicle) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, v := range r.vehicles {
+		if v.Plate == vehicle.Plate {
+			return errors.New("车牌号重复")
+		}
+	}
+	r.nextID++
+	now := utils.FromTime(time.Now())
+	vehicle.ID = r.nextID
+	vehicle.CreatedAt = now
+	vehicle.UpdatedAt = now
+	stored := *vehicle
+	r.vehicles[vehicle.ID] = &stored
+	return nil
+}
+
+// GetByID 查找车辆，不存在返回nil
+func (r *MemoryVehicleRepository) GetByID(ctx context.Context, id uint64) (*models.Vehicle, error) {
+	return r.find(func(v *models.Vehicle) bool { return v.ID == id }), nil
+}
+
+// FindByPlate 按车牌号查找车辆，不存在返回nil
+func (r *MemoryVehicleRepository) FindByPlate(ctx context.Context, plate string) (*models.Vehicle, error) {
+	return r.find(func(v *models.Vehicle) bool { return v.Plate == plate }), nil
+}
+
+func (r *MemoryVehicleRepository) find(match func(*models.Vehicle) bool) *models.Vehicle {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, v := range r.vehicles {
+		if match(v) {
+			copied := *v
+			return &copied
+		}
+	}
+	return nil
+}
+
+// ListByOwner 按登记顺序列出承运方的车辆
+func (r *MemoryVehicleRepository) ListByOwner(ctx context.Context, ownerID uint64) ([]*models.Vehicle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	vehicles := []*models.Vehicle{}
+	for _, v := range r.vehicles {
+		if v.OwnerID == ownerID {
+			copied := *v
+			vehicles = append(vehicles, &copied)
+		}
+	}
+	sort.Slice(vehicles, func(i, j int) bool { return vehicles[i].ID < vehicles[j].ID })
+	return vehicles, nil
+}
+
+// Update 更新车辆信息（车主不可变更）
+func (r *MemoryVehicleRepository) Update(ctx context.Context, vehicle *models.Vehicle) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.vehicles[vehicle.ID]
+	if !ok {
+		return nil
+	}
+	vehicle.UpdatedAt = utils.FromTime(time.Now())
+	stored.Plate = vehicle.Plate
+	stored.Type = vehicle.Type
+	stored.CapacityKg = vehicle.CapacityKg
+	stored.CapacityM3 = vehicle.CapacityM3
+	stored.LengthM = vehicle.LengthM
+	stored.UpdatedAt = vehicle.UpdatedAt
+	return nil
+}
+
+// Delete 删除车辆
+func (r *MemoryVehicleRepository) Delete(ctx context.Context, id uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.vehicles, id)
+	return nil
+}
@@ -0,0 +1,211 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"freight/models"
+)
+
+// MemoryWebhookRepository Webhook 仓储内存实现（用于测试和本地开发）
+type MemoryWebhookRepository struct {
+	mu             sync.Mutex
+	nextSubID      uint64
+	nextDeliveryID uint64
+	subs           map[uint64]*models.WebhookSubscription
+	deliveries     map[uint64]*models.WebhookDelivery
+}
+
+var _ models.WebhookRepository = (*MemoryWebhookRepository)(nil)
+
+// NewMemoryWebhookRepository 创建内存 Webhook 仓储实例
+func NewMemoryWebhookRepository() *MemoryWebhookRepository {
+	return &MemoryWebhookRepository{
+		subs:       make(map[uint64]*models.WebhookSubscription),
+		deliveries: make(map[uint64]*models.WebhookDelivery),
+	}
+}
+
+func copySubscription(sub *models.WebhookSubscription) *models.WebhookSubscription {
+	copied := *sub
+	copied.EventTypes = append([]string(nil), sub.EventTypes...)
+	return &copied
+}
+
+func copyDelivery(d *models.WebhookDelivery) *models.WebhookDelivery {
+	copied := *d
+	if d.NextAttemptAt != nil {
+		next := *d.NextAttemptAt
+		copied.NextAttemptAt = &next
+	}
+	if d.DeliveredAt != nil {
+		delivered := *d.DeliveredAt
+		copied.DeliveredAt = &delivered
+	}
+	return &copied
+}
+
+// CreateSubscription 保存订阅
+func (r *MemoryWebhookRepository) CreateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextSubID++
+	now := time.Now()
+	sub.ID, sub.CreatedAt, sub.UpdatedAt = r.nextSubID, now, now
+	r.subs[sub.ID] = copySubscription(sub)
+	return nil
+}
+
+// GetSubscription 按ID查询订阅
+func (r *MemoryWebhookRepository) GetSubscription(ctx context.Context, id uint64) (*models.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub, ok := r.subs[id]
+	if !ok {
+		return nil, nil
+	}
+	return copySubscription(sub), nil
+}
+
+// listSubscriptions 按ID升序返回满足条件的订阅
+func (r *MemoryWebhookRepository) listSubscriptions(match func(*models.WebhookSubscription) bool) []*models.WebhookSubscription {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subs := []*models.WebhookSubscription{}
+	for _, sub := range r.subs {
+		if match(sub) {
+			subs = append(subs, copySubscription(sub))
+		}
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].ID < subs[j].ID })
+	return subs
+}
+
+// ListSubscriptions 查询用户的订阅
+func (r *MemoryWebhookRepository) ListSubscriptions(ctx context.Context, ownerID uint64) ([]*models.WebhookSubscription, error) {
+	return r.listSubscriptions(func(sub *models.WebhookSubscription) bool { return sub.OwnerID == ownerID }), nil
+}
+
+// ListActiveSubscriptions 查询全部启用的订阅
+func (r *MemoryWebhookRepository) ListActiveSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error) {
+	return r.listSubscriptions(func(sub *models.WebhookSubscription) bool { return sub.Active }), nil
+}
+
+// UpdateSubscription 更新订阅（密钥不可修改）
+func (r *MemoryWebhookRepository) UpdateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.subs[sub.ID]
+	if !ok {
+		return nil
+	}
+	sub.UpdatedAt = time.Now()
+	updated := copySubscription(sub)
+	updated.Secret, updated.OwnerID, updated.CreatedAt = stored.Secret, stored.OwnerID, stored.CreatedAt
+	r.subs[sub.ID] = updated
+	return nil
+}
+
+// DeleteSubscription 删除订阅
+func (r *MemoryWebhookRepository) DeleteSubscription(ctx context.Context, id uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.subs, id)
+	return nil
+}
+
+// CreateDeliveries 保存投递记录
+func (r *MemoryWebhookRepository) CreateDeliveries(ctx context.Context, deliveries []*models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, d := range deliveries {
+		r.nextDeliveryID++
+		d.ID = r.nextDeliveryID
+		r.deliveries[d.ID] = copyDelivery(d)
+	}
+	return nil
+}
+
+// ClaimDueDeliveries 领取到期的待投递记录
+func (r *MemoryWebhookRepository) ClaimDueDeliveries(ctx context.Context, now time.Time, limit int, leaseUntil time.Time) ([]*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	due := []*models.WebhookDelivery{}
+	for _, d := range r.deliveries {
+		if d.Status == models.WebhookPending && d.NextAttemptAt != nil && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(*due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(*due[j].NextAttemptAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*models.WebhookDelivery, 0, len(due))
+	for _, d := range due {
+		lease := leaseUntil
+		d.NextAttemptAt = &lease
+		claimed = append(claimed, copyDelivery(d))
+	}
+	return claimed, nil
+}
+
+// UpdateDelivery 保存投递结果
+func (r *MemoryWebhookRepository) UpdateDelivery(ctx context.Context, d *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.deliveries[d.ID]; !ok {
+		return nil
+	}
+	d.UpdatedAt = time.Now()
+	r.deliveries[d.ID] = copyDelivery(d)
+	return nil
+}
+
+// GetDelivery 按ID查询投递记录
+func (r *MemoryWebhookRepository) GetDelivery(ctx context.Context, id uint64) (*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, ok := r.deliveries[id]
+	if !ok {
+		return nil, nil
+	}
+	return copyDelivery(d), nil
+}
+
+// ListDeliveries 按条件倒序查询投递记录
+func (r *MemoryWebhookRepository) ListDeliveries(ctx context.Context, filter models.WebhookDeliveryFilter) ([]*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deliveries := []*models.WebhookDelivery{}
+	for _, d := range r.deliveries {
+		switch {
+		case filter.SubscriptionID > 0 && d.SubscriptionID != filter.SubscriptionID,
+			filter.Status != "" && d.Status != filter.Status,
+			filter.BeforeID > 0 && d.ID >= filter.BeforeID:
+			continue
+		}
+		deliveries = append(deliveries, copyDelivery(d))
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].ID > deliveries[j].ID })
+	if len(deliveries) > filter.Limit {
+		deliveries = deliveries[:filter.Limit]
+	}
+	return deliveries, nil
+}
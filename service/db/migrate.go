@@ -0,0 +1,399 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// migration 数据库迁移项（按 Version 顺序执行，执行后记录到 schema_migrations）
+type migration struct {
+	Version    int
+	Name       string
+	Statements []string
+}
+
+// migrations 迁移列表，新增表结构变更时在末尾追加，不要修改已发布的条目
+var migrations = []migration{
+	{
+		Version: 1,
+		Name:    "create_freight_order_events",
+		Statements: []string{`
+			CREATE TABLE IF NOT EXISTS freight_order_events (
+				id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				order_id BIGINT UNSIGNED NOT NULL,
+				from_status TINYINT UNSIGNED NOT NULL,
+				to_status TINYINT UNSIGNED NOT NULL,
+				actor_id BIGINT UNSIGNED NOT NULL DEFAULT 0,
+				reason VARCHAR(255) NOT NULL DEFAULT '',
+				created_at DATETIME NOT NULL,
+				KEY idx_order_id (order_id, id)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+		},
+	},
+	{
+		// user_id 原先在接单后会被覆盖为司机：待接单（及已删除）的订单视为发货方，其余视为承运方，
+		// 已接单历史订单的发货方无法恢复，保持为0
+		Version: 2,
+		Name:    "split_freight_shipper_carrier",
+		Statements: []string{
+			`ALTER TABLE freight_orders
+				ADD COLUMN shipper_id BIGINT UNSIGNED NOT NULL DEFAULT 0,
+				ADD COLUMN carrier_id BIGINT UNSIGNED NOT NULL DEFAULT 0,
+				ADD KEY idx_shipper_id (shipper_id),
+				ADD KEY idx_carrier_id (carrier_id)`,
+			`UPDATE freight_orders SET shipper_id = user_id WHERE status IN (0, 1)`,
+			`UPDATE freight_orders SET carrier_id = user_id WHERE status NOT IN (0, 1)`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "create_refresh_and_revoked_tokens",
+		Statements: []string{`
+			CREATE TABLE IF NOT EXISTS refresh_tokens (
+				id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				user_id BIGINT NOT NULL,
+				family_id CHAR(32) NOT NULL,
+				token_hash CHAR(64) NOT NULL,
+				expires_at DATETIME NOT NULL,
+				revoked_at DATETIME NULL,
+				created_at DATETIME NOT NULL,
+				UNIQUE KEY uk_token_hash (token_hash),
+				KEY idx_family_id (family_id),
+				KEY idx_expires_at (expires_at)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`, `
+			CREATE TABLE IF NOT EXISTS revoked_tokens (
+				jti CHAR(32) NOT NULL PRIMARY KEY,
+				expires_at DATETIME NOT NULL,
+				created_at DATETIME NOT NULL,
+				KEY idx_expires_at (expires_at)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+		},
+	},
+	{
+		// 已有用户视为邮箱已验证
+		Version: 4,
+		Name:    "create_user_tokens_and_email_verification",
+		Statements: []string{`
+			CREATE TABLE IF NOT EXISTS user_tokens (
+				id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				user_id BIGINT NOT NULL,
+				purpose VARCHAR(32) NOT NULL,
+				token_hash CHAR(64) NOT NULL,
+				expires_at DATETIME NOT NULL,
+				used_at DATETIME NULL,
+				created_at DATETIME NOT NULL,
+				UNIQUE KEY uk_purpose_token (purpose, token_hash),
+				KEY idx_expires_at (expires_at)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+			`ALTER TABLE users ADD COLUMN email_verified_at DATETIME NULL`,
+			`UPDATE users SET email_verified_at = created_at`,
+		},
+	},
+	{
+		Version: 5,
+		Name:    "create_login_guard_and_audit_logs",
+		Statements: []string{`
+			CREATE TABLE IF NOT EXISTS login_failures (
+				id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				scope VARCHAR(16) NOT NULL,
+				subject VARCHAR(255) NOT NULL,
+				created_at DATETIME NOT NULL,
+				KEY idx_scope_subject (scope, subject, created_at),
+				KEY idx_created_at (created_at)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`, `
+			CREATE TABLE IF NOT EXISTS account_locks (
+				subject VARCHAR(255) NOT NULL PRIMARY KEY,
+				locked_until DATETIME NOT NULL,
+				created_at DATETIME NOT NULL
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`, `
+			CREATE TABLE IF NOT EXISTS audit_logs (
+				id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				action VARCHAR(64) NOT NULL,
+				actor_id BIGINT NOT NULL DEFAULT 0,
+				subject VARCHAR(255) NOT NULL DEFAULT '',
+				ip VARCHAR(64) NOT NULL DEFAULT '',
+				detail VARCHAR(512) NOT NULL DEFAULT '',
+				created_at DATETIME NOT NULL,
+				KEY idx_action (action, id)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+		},
+	},
+	{
+		Version: 6,
+		Name:    "add_users_phone",
+		Statements: []string{
+			`ALTER TABLE users ADD COLUMN phone VARCHAR(32) NOT NULL DEFAULT '' AFTER email`,
+		},
+	},
+	{
+		Version: 7,
+		Name:    "add_freight_search_index",
+		Statements: []string{
+			`ALTER TABLE freight_orders ADD FULLTEXT INDEX ft_freight_search
+				(origin_location, destination_location, type, remark) WITH PARSER ngram`,
+		},
+	},
+	{
+		Version: 8,
+		Name:    "add_freight_route_estimate",
+		Statements: []string{
+			`ALTER TABLE freight_orders
+				ADD COLUMN distance_km DECIMAL(10,1) NOT NULL DEFAULT 0,
+				ADD COLUMN estimated_hours DECIMAL(8,1) NOT NULL DEFAULT 0,
+				ADD INDEX idx_distance (distance_km)`,
+		},
+	},
+	{
+		Version: 9,
+		Name:    "create_freight_quotes",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS freight_quotes (
+				id CHAR(32) NOT NULL PRIMARY KEY,
+				shipper_id BIGINT UNSIGNED NOT NULL,
+				origin_code VARCHAR(16) NOT NULL,
+				destination_code VARCHAR(16) NOT NULL,
+				typeid TINYINT UNSIGNED NOT NULL DEFAULT 0,
+				weight_kg DECIMAL(12,2) NOT NULL DEFAULT 0,
+				volume_m3 DECIMAL(12,2) NOT NULL DEFAULT 0,
+				is_urgent TINYINT(1) NOT NULL DEFAULT 0,
+				has_insurance TINYINT(1) NOT NULL DEFAULT 0,
+				cargo_value DECIMAL(14,2) NOT NULL DEFAULT 0,
+				distance_km DECIMAL(10,1) NOT NULL DEFAULT 0,
+				items TEXT NOT NULL,
+				total DECIMAL(12,2) NOT NULL,
+				expires_at DATETIME NOT NULL,
+				order_id BIGINT UNSIGNED NOT NULL DEFAULT 0,
+				used_at DATETIME NULL,
+				created_at DATETIME NOT NULL,
+				KEY idx_shipper (shipper_id, created_at)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+		},
+	},
+	{
+		Version: 10,
+		Name:    "create_freight_bids",
+		Statements: []string{
+			`ALTER TABLE freight_orders
+				ADD COLUMN bidding_enabled TINYINT(1) NOT NULL DEFAULT 0,
+				ADD COLUMN bid_count INT UNSIGNED NOT NULL DEFAULT 0`,
+			`CREATE TABLE IF NOT EXISTS freight_bids (
+				id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				order_id BIGINT UNSIGNED NOT NULL,
+				carrier_id BIGINT UNSIGNED NOT NULL,
+				amount DECIMAL(12,2) NOT NULL,
+				eta DATETIME NOT NULL,
+				note VARCHAR(255) NOT NULL DEFAULT '',
+				status TINYINT UNSIGNED NOT NULL DEFAULT 1,
+				created_at DATETIME NOT NULL,
+				updated_at DATETIME NOT NULL,
+				UNIQUE KEY uk_order_carrier (order_id, carrier_id),
+				KEY idx_carrier (carrier_id, updated_at)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+		},
+	},
+	{
+		Version: 11,
+		Name:    "add_freight_cargo_details",
+		Statements: []string{
+			`ALTER TABLE freight_orders
+				ADD COLUMN weight_kg DECIMAL(10,2) NOT NULL DEFAULT 0,
+				ADD COLUMN volume_m3 DECIMAL(10,3) NOT NULL DEFAULT 0,
+				ADD COLUMN pieces INT UNSIGNED NOT NULL DEFAULT 0,
+				ADD COLUMN length_m DECIMAL(6,2) NOT NULL DEFAULT 0,
+				ADD COLUMN width_m DECIMAL(6,2) NOT NULL DEFAULT 0,
+				ADD COLUMN height_m DECIMAL(6,2) NOT NULL DEFAULT 0,
+				ADD COLUMN hazmat_class TINYINT UNSIGNED NOT NULL DEFAULT 0,
+				ADD COLUMN temp_min_c DECIMAL(4,1) NULL,
+				ADD COLUMN temp_max_c DECIMAL(4,1) NULL,
+				ADD INDEX idx_weight (weight_kg),
+				ADD INDEX idx_volume (volume_m3)`,
+		},
+	},
+	{
+		Version: 12,
+		Name:    "create_vehicles",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS vehicles (
+				id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				owner_id BIGINT UNSIGNED NOT NULL,
+				plate VARCHAR(16) NOT NULL,
+				type VARCHAR(32) NOT NULL,
+				capacity_kg DECIMAL(10,2) NOT NULL,
+				capacity_m3 DECIMAL(10,3) NOT NULL,
+				length_m DECIMAL(6,2) NOT NULL,
+				created_at DATETIME NOT NULL,
+				updated_at DATETIME NOT NULL,
+				UNIQUE KEY uk_plate (plate),
+				KEY idx_owner (owner_id)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+			`ALTER TABLE freight_orders ADD COLUMN vehicle_id BIGINT UNSIGNED NOT NULL DEFAULT 0`,
+		},
+	},
+	{
+		Version: 13,
+		Name:    "add_freight_time_windows",
+		Statements: []string{
+			`ALTER TABLE freight_orders
+				ADD COLUMN pickup_earliest DATETIME NULL,
+				ADD COLUMN pickup_latest DATETIME NULL,
+				ADD COLUMN delivery_earliest DATETIME NULL,
+				ADD COLUMN delivery_latest DATETIME NULL,
+				ADD INDEX idx_pickup (status, pickup_latest)`,
+		},
+	},
+	{
+		Version: 14,
+		Name:    "create_job_leases",
+		Statements: []string{`
+			CREATE TABLE IF NOT EXISTS job_leases (
+				name VARCHAR(64) NOT NULL PRIMARY KEY,
+				holder VARCHAR(128) NOT NULL,
+				expires_at DATETIME(3) NOT NULL
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+		},
+	},
+	{
+		Version: 15,
+		Name:    "create_daily_stats",
+		Statements: []string{`
+			CREATE TABLE IF NOT EXISTS daily_stats (
+				date DATE NOT NULL PRIMARY KEY,
+				orders_created INT NOT NULL DEFAULT 0,
+				orders_accepted INT NOT NULL DEFAULT 0,
+				orders_delivered INT NOT NULL DEFAULT 0,
+				orders_confirmed INT NOT NULL DEFAULT 0,
+				orders_cancelled INT NOT NULL DEFAULT 0,
+				orders_expired INT NOT NULL DEFAULT 0,
+				gmv DECIMAL(14,2) NOT NULL DEFAULT 0,
+				updated_at DATETIME NOT NULL
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+			`ALTER TABLE freight_order_events ADD INDEX idx_created_at (created_at)`,
+		},
+	},
+	{
+		Version: 16,
+		Name:    "create_freight_locations",
+		Statements: []string{`
+			CREATE TABLE IF NOT EXISTS freight_locations (
+				id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				order_id BIGINT UNSIGNED NOT NULL,
+				carrier_id BIGINT UNSIGNED NOT NULL,
+				lat DECIMAL(9,6) NOT NULL,
+				lng DECIMAL(9,6) NOT NULL,
+				speed_kmh DECIMAL(6,2) NOT NULL DEFAULT 0,
+				heading DECIMAL(5,2) NOT NULL DEFAULT 0,
+				recorded_at DATETIME(3) NOT NULL,
+				created_at DATETIME NOT NULL,
+				UNIQUE KEY uk_order_time (order_id, recorded_at)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+		},
+	},
+	{
+		Version: 17,
+		Name:    "create_chat",
+		Statements: []string{`
+			CREATE TABLE IF NOT EXISTS chat_messages (
+				id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				order_id BIGINT UNSIGNED NOT NULL,
+				sender_id BIGINT UNSIGNED NOT NULL,
+				kind VARCHAR(16) NOT NULL,
+				body TEXT NOT NULL,
+				attachment_url VARCHAR(512) NOT NULL DEFAULT '',
+				created_at DATETIME(3) NOT NULL,
+				KEY idx_order_id (order_id, id)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`, `
+			CREATE TABLE IF NOT EXISTS chat_reads (
+				order_id BIGINT UNSIGNED NOT NULL,
+				user_id BIGINT UNSIGNED NOT NULL,
+				last_read_id BIGINT UNSIGNED NOT NULL DEFAULT 0,
+				read_at DATETIME(3) NOT NULL,
+				PRIMARY KEY (order_id, user_id)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+		},
+	},
+	{
+		Version: 18,
+		Name:    "create_webhooks",
+		Statements: []string{`
+			CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+				id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				owner_id BIGINT UNSIGNED NOT NULL,
+				url VARCHAR(512) NOT NULL,
+				event_types VARCHAR(255) NOT NULL,
+				secret VARCHAR(128) NOT NULL,
+				all_orders TINYINT(1) NOT NULL DEFAULT 0,
+				active TINYINT(1) NOT NULL DEFAULT 1,
+				created_at DATETIME NOT NULL,
+				updated_at DATETIME NOT NULL,
+				KEY idx_owner (owner_id)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`, `
+			CREATE TABLE IF NOT EXISTS webhook_deliveries (
+				id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				subscription_id BIGINT UNSIGNED NOT NULL,
+				event_type VARCHAR(32) NOT NULL,
+				order_id BIGINT UNSIGNED NOT NULL,
+				payload MEDIUMTEXT NOT NULL,
+				status VARCHAR(16) NOT NULL,
+				attempts INT UNSIGNED NOT NULL DEFAULT 0,
+				last_status_code INT NOT NULL DEFAULT 0,
+				last_error VARCHAR(255) NOT NULL DEFAULT '',
+				next_attempt_at DATETIME(3) NULL,
+				delivered_at DATETIME(3) NULL,
+				created_at DATETIME(3) NOT NULL,
+				updated_at DATETIME(3) NOT NULL,
+				KEY idx_due (status, next_attempt_at),
+				KEY idx_subscription (subscription_id, id)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+		},
+	},
+	{
+		// 按用户吊销访问令牌：签发时间不晚于 revoked_before 的访问令牌失效，记录保留到这些令牌全部过期
+		Version: 19,
+		Name:    "create_user_token_cutoffs",
+		Statements: []string{`
+			CREATE TABLE IF NOT EXISTS user_token_cutoffs (
+				user_id BIGINT NOT NULL PRIMARY KEY,
+				revoked_before DATETIME(3) NOT NULL,
+				expires_at DATETIME NOT NULL,
+				KEY idx_expires_at (expires_at)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+		},
+	},
+}
+
+// Migrate 执行尚未应用的数据库迁移
+func Migrate(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT NOT NULL PRIMARY KEY,
+			name VARCHAR(128) NOT NULL,
+			applied_at DATETIME NOT NULL
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`)
+	if err != nil {
+		return fmt.Errorf("创建迁移记录表失败: %v", err)
+	}
+
+	for _, m := range migrations {
+		var count int
+		if err := db.QueryRow("SELECT COUNT(*) FROM schema_migrations WHERE version = ?", m.Version).Scan(&count); err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		for _, stmt := range m.Statements {
+			if _, err := db.Exec(stmt); err != nil {
+				return fmt.Errorf("执行迁移 %d_%s 失败: %v", m.Version, m.Name, err)
+			}
+		}
+
+		if _, err := db.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, NOW())", m.Version, m.Name); err != nil {
+			return err
+		}
+		log.Printf("已执行数据库迁移 %d_%s", m.Version, m.Name)
+	}
+
+	return nil
+}
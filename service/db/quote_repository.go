@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"freight/models"
+)
+
+// QuoteRepositoryImpl 报价数据访问实现
+type QuoteRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewQuoteRepository 创建报价数据访问实例
+func NewQuoteRepository(db *sql.DB) models.QuoteRepository {
+	return &QuoteRepositoryImpl{db: db}
+}
+
+// Create 保存报价（明细以JSON保存）
+func (r *QuoteRepositoryImpl) Create(ctx context.Context, quote *models.Quote) error {
+	items, err := json.Marshal(quote.Items)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO freight_quotes (
+			id, shipper_id, origin_code, destination_code, typeid, weight_kg, volume_m3,
+			is_urgent, has_insurance, cargo_value, distance_km, items, total, expires_at, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW())`,
+		quote.ID, quote.ShipperID, quote.OriginCode, quote.DestinationCode, quote.TypeID, quote.WeightKg, quote.VolumeM3,
+		quote.IsUrgent, quote.HasInsurance, quote.CargoValue, quote.DistanceKm, string(items), quote.Total, quote.ExpiresAt)
+	return err
+}
+
+// FindByID 查找报价，不存在返回nil
+func (r *QuoteRepositoryImpl) FindByID(ctx context.Context, id string) (*models.Quote, error) {
+	var (
+		quote models.Quote
+		items string
+	)
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, shipper_id, origin_code, destination_code, typeid, weight_kg, volume_m3,
+		       is_urgent, has_insurance, cargo_value, distance_km, items, total, expires_at, order_id, used_at, created_at
+		FROM freight_quotes WHERE id = ?`, id).Scan(
+		&quote.ID, &quote.ShipperID, &quote.OriginCode, &quote.DestinationCode, &quote.TypeID, &quote.WeightKg, &quote.VolumeM3,
+		&quote.IsUrgent, &quote.HasInsurance, &quote.CargoValue, &quote.DistanceKm, &items, &quote.Total, &quote.ExpiresAt,
+		&quote.OrderID, &quote.UsedAt, &quote.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(items), &quote.Items); err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
+
+// Claim 条件更新占用报价
+func (r *QuoteRepositoryImpl) Claim(ctx context.Context, id string, now time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE freight_quotes SET used_at = ? WHERE id = ? AND used_at IS NULL AND expires_at > ?`, now, id, now)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// Release 释放占用（仅限尚未关联订单的报价）
+func (r *QuoteRepositoryImpl) Release(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE freight_quotes SET used_at = NULL WHERE id = ? AND order_id = 0`, id)
+	return err
+}
+
+// AttachOrder 记录据此报价发布的订单
+func (r *QuoteRepositoryImpl) AttachOrder(ctx context.Context, id string, orderID uint64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE freight_quotes SET order_id = ? WHERE id = ?`, orderID, id)
+	return err
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"freight/models"
+	"freight/utils"
+)
+
+// StatsRepositoryImpl 统计数据访问实现
+type StatsRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewStatsRepository 创建统计仓储实例
+func NewStatsRepository(db *sql.DB) models.StatsRepository {
+	return &StatsRepositoryImpl{db: db}
+}
+
+// RollupDaily 按订单创建时间和状态流转记录汇总，REPLACE 写入 daily_stats
+func (r *StatsRepositoryImpl) RollupDaily(ctx context.Context, date utils.Date, from, to time.Time) (*models.DailyStats, error) {
+	stats := &models.DailyStats{Date: date}
+
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM freight_orders WHERE created_at >= ? AND created_at < ?`, from, to,
+	).Scan(&stats.OrdersCreated)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT to_status, COUNT(*) FROM freight_order_events
+		WHERE created_at >= ? AND created_at < ?
+		GROUP BY to_status`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := make(map[uint8]int)
+	for rows.Next() {
+		var status uint8
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	applyStatusCounts(stats, counts)
+
+	err = r.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(o.price), 0) FROM freight_order_events e
+		JOIN freight_orders o ON o.id = e.order_id
+		WHERE e.to_status = ? AND e.created_at >= ? AND e.created_at < ?`,
+		models.FreightStatusConfirmed, from, to,
+	).Scan(&stats.GMV)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		REPLACE INTO daily_stats (
+			date, orders_created, orders_accepted, orders_delivered, orders_confirmed,
+			orders_cancelled, orders_expired, gmv, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, NOW())`,
+		date, stats.OrdersCreated, stats.OrdersAccepted, stats.OrdersDelivered, stats.OrdersConfirmed,
+		stats.OrdersCancelled, stats.OrdersExpired, stats.GMV,
+	)
+	if err != nil {
+		return nil, err
+	}
+	stats.UpdatedAt = utils.FromTime(time.Now())
+	return stats, nil
+}
+
+// applyStatusCounts 将按目标状态分组的流转次数填入统计
+func applyStatusCounts(stats *models.DailyStats, counts map[uint8]int) {
+	stats.OrdersAccepted = counts[models.FreightStatusAccepted]
+	stats.OrdersDelivered = counts[models.FreightStatusDelivered]
+	stats.OrdersConfirmed = counts[models.FreightStatusConfirmed]
+	stats.OrdersCancelled = counts[models.FreightStatusCancelled]
+	stats.OrdersExpired = counts[models.FreightStatusExpired]
+}
@@ -0,0 +1,180 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"freight/models"
+)
+
+// TokenRepositoryImpl 令牌数据访问实现
+type TokenRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewTokenRepository 创建令牌数据访问实例
+func NewTokenRepository(db *sql.DB) models.TokenRepository {
+	return &TokenRepositoryImpl{db: db}
+}
+
+// CreateRefreshToken 保存刷新令牌
+func (r *TokenRepositoryImpl) CreateRefreshToken(token *models.RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, created_at)
+              VALUES (?, ?, ?, ?, NOW())`
+
+	result, err := r.db.Exec(query, token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	token.ID = id
+	return nil
+}
+
+// FindRefreshToken 通过哈希查找刷新令牌，不存在返回nil
+func (r *TokenRepositoryImpl) FindRefreshToken(tokenHash string) (*models.RefreshToken, error) {
+	query := `SELECT id, user_id, family_id, token_hash, expires_at, revoked_at, created_at
+              FROM refresh_tokens WHERE token_hash = ?`
+
+	var token models.RefreshToken
+	err := r.db.QueryRow(query, tokenHash).Scan(
+		&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash, &token.ExpiresAt, &token.RevokedAt, &token.CreatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// RevokeRefreshToken 条件吊销刷新令牌
+func (r *TokenRepositoryImpl) RevokeRefreshToken(id int64) (bool, error) {
+	result, err := r.db.Exec(`UPDATE refresh_tokens SET revoked_at = NOW() WHERE id = ? AND revoked_at IS NULL`, id)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// RevokeFamily 吊销同一 family 下的全部刷新令牌
+func (r *TokenRepositoryImpl) RevokeFamily(familyID string) error {
+	_, err := r.db.Exec(`UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = ? AND revoked_at IS NULL`, familyID)
+	return err
+}
+
+// RevokeUserRefreshTokens 吊销用户的全部刷新令牌
+func (r *TokenRepositoryImpl) RevokeUserRefreshTokens(userID int64) error {
+	_, err := r.db.Exec(`UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = ? AND revoked_at IS NULL`, userID)
+	return err
+}
+
+// RevokeAccessToken 记录被吊销的访问令牌ID（保留到其自然过期）
+func (r *TokenRepositoryImpl) RevokeAccessToken(jti string, expiresAt time.Time) error {
+	_, err := r.db.Exec(`INSERT IGNORE INTO revoked_tokens (jti, expires_at, created_at) VALUES (?, ?, NOW())`, jti, expiresAt)
+	return err
+}
+
+// IsAccessTokenRevoked 判断访问令牌是否已被吊销
+func (r *TokenRepositoryImpl) IsAccessTokenRevoked(jti string) (bool, error) {
+	var count int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM revoked_tokens WHERE jti = ?`, jti).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// RevokeUserAccessTokens 记录用户访问令牌的吊销时间点（只会后移）
+func (r *TokenRepositoryImpl) RevokeUserAccessTokens(userID int64, before, expiresAt time.Time) error {
+	_, err := r.db.Exec(`INSERT INTO user_token_cutoffs (user_id, revoked_before, expires_at) VALUES (?, ?, ?)
+              ON DUPLICATE KEY UPDATE revoked_before = GREATEST(revoked_before, VALUES(revoked_before)),
+                  expires_at = GREATEST(expires_at, VALUES(expires_at))`, userID, before, expiresAt)
+	return err
+}
+
+// UserAccessTokensRevokedBefore 查询用户访问令牌的吊销时间点
+func (r *TokenRepositoryImpl) UserAccessTokensRevokedBefore(userID int64) (time.Time, error) {
+	var before time.Time
+	err := r.db.QueryRow(`SELECT revoked_before FROM user_token_cutoffs WHERE user_id = ?`, userID).Scan(&before)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	return before, err
+}
+
+// CreateUserToken 保存一次性令牌
+func (r *TokenRepositoryImpl) CreateUserToken(token *models.UserToken) error {
+	query := `INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at, created_at)
+              VALUES (?, ?, ?, ?, NOW())`
+
+	result, err := r.db.Exec(query, token.UserID, token.Purpose, token.TokenHash, token.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	token.ID = id
+	return nil
+}
+
+// ConsumeUserToken 条件标记一次性令牌为已使用，保证同一令牌只能成功使用一次
+func (r *TokenRepositoryImpl) ConsumeUserToken(purpose, tokenHash string) (*models.UserToken, error) {
+	result, err := r.db.Exec(`UPDATE user_tokens SET used_at = NOW()
+              WHERE purpose = ? AND token_hash = ? AND used_at IS NULL AND expires_at > NOW()`, purpose, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, nil
+	}
+
+	var token models.UserToken
+	err = r.db.QueryRow(`SELECT id, user_id, purpose, token_hash, expires_at, used_at, created_at
+              FROM user_tokens WHERE purpose = ? AND token_hash = ?`, purpose, tokenHash).Scan(
+		&token.ID, &token.UserID, &token.Purpose, &token.TokenHash, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// PurgeExpired 清理已过期的刷新令牌、吊销记录和一次性令牌
+func (r *TokenRepositoryImpl) PurgeExpired(before time.Time) (int64, error) {
+	var total int64
+	for _, query := range []string{
+		`DELETE FROM refresh_tokens WHERE expires_at < ?`,
+		`DELETE FROM revoked_tokens WHERE expires_at < ?`,
+		`DELETE FROM user_tokens WHERE expires_at < ?`,
+		`DELETE FROM user_token_cutoffs WHERE expires_at < ?`,
+	} {
+		result, err := r.db.Exec(query, before)
+		if err != nil {
+			return total, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
@@ -2,12 +2,14 @@ package db
 
 import (
 	"database/sql"
-	"errors"
 	"freight/models"
 	"freight/utils"
 	"time"
 )
 
+// userColumns 用户查询字段（与 scanUser 顺序一致）
+const userColumns = `id, username, password, email, phone, avatar_url, role, six, status, email_verified_at, created_at, updated_at`
+
 // UserRepositoryImpl 用户数据访问实现
 type UserRepositoryImpl struct {
 	db *sql.DB
@@ -38,78 +40,96 @@ func (r *UserRepositoryImpl) Create(user *models.User) error {
 
 	query := `
         INSERT INTO users (
-            username, password, email, avatar_url, role, six, status, created_at, updated_at
-        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+            username, password, email, phone, avatar_url, role, six, status, created_at, updated_at
+        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
     `
 
-	_, err := r.db.Exec(query,
+	result, err := r.db.Exec(query,
 		user.Username,
 		user.Password,
-		user.Email,     // 修正顺序
-		user.AvatarURL, // 直接使用值，而非指针
-		user.Role,      // 直接使用值，而非指针
+		user.Email,
+		user.Phone,
+		user.AvatarURL,
+		user.Role,
 		user.Six,
 		user.Status,
 		user.CreatedAt, // 自动生成的时间
 		user.UpdatedAt, // 自动生成的时间
 	)
+	if err != nil {
+		return err
+	}
 
-	return err
-}
-
-// FindByUsername 通过用户名查找用户
-func (r *UserRepositoryImpl) FindByUsername(username string) (*models.User, error) {
-	query := `SELECT id, username, password, email, status, avatar_url, role, six, created_at, updated_at 
-              FROM users WHERE username = ?`
-
-	var user models.User
-	err := r.db.QueryRow(query, username).Scan(
-		&user.ID, &user.Username, &user.Password, &user.Email, &user.AvatarURL, &user.Role, &user.Six, &user.Status, &user.CreatedAt, &user.UpdatedAt)
-
+	id, err := result.LastInsertId()
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, err
+		return err
 	}
+	user.ID = id
+	return nil
+}
 
-	return &user, nil
+// FindByUsername 通过用户名查找用户，不存在返回nil
+func (r *UserRepositoryImpl) FindByUsername(username string) (*models.User, error) {
+	return r.findOne(`SELECT `+userColumns+` FROM users WHERE username = ?`, username)
 }
 
-// FindByEmail 通过邮箱查找用户
+// FindByEmail 通过邮箱查找用户，不存在返回nil
 func (r *UserRepositoryImpl) FindByEmail(email string) (*models.User, error) {
-	query := `SELECT id, username, password, email, avatar_url, role, six, status, created_at, updated_at 
-              FROM users WHERE email = ?`
+	return r.findOne(`SELECT `+userColumns+` FROM users WHERE email = ?`, email)
+}
 
-	var user models.User
-	err := r.db.QueryRow(query, email).Scan(
-		&user.ID, &user.Username, &user.Password, &user.Email, &user.AvatarURL, &user.Role, &user.Six, &user.Status, &user.CreatedAt, &user.UpdatedAt)
+// FindByID 通过ID查找用户，不存在返回nil
+func (r *UserRepositoryImpl) FindByID(id int64) (*models.User, error) {
+	return r.findOne(`SELECT `+userColumns+` FROM users WHERE id = ?`, id)
+}
 
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, err
-	}
+// Update 更新用户资料
+func (r *UserRepositoryImpl) Update(user *models.User) error {
+	user.UpdatedAt = utils.FromTime(time.Now())
 
-	return &user, nil
+	query := `
+        UPDATE users SET
+            username = ?, phone = ?, avatar_url = ?, role = ?, six = ?, status = ?, email_verified_at = ?, updated_at = ?
+        WHERE id = ?
+    `
+
+	_, err := r.db.Exec(query,
+		user.Username,
+		user.Phone,
+		user.AvatarURL,
+		user.Role,
+		user.Six,
+		user.Status,
+		user.EmailVerifiedAt,
+		user.UpdatedAt,
+		user.ID,
+	)
+	return err
 }
 
-// FindByID 通过ID查找用户
-func (r *UserRepositoryImpl) FindByID(id int64) (*models.User, error) {
-	query := `SELECT id, username, password, email, avatar_url, role, six, status, created_at, updated_at 
-              FROM users WHERE id = ?`
+// UpdatePassword 更新密码哈希
+func (r *UserRepositoryImpl) UpdatePassword(id int64, passwordHash string) error {
+	_, err := r.db.Exec(`UPDATE users SET password = ?, updated_at = ? WHERE id = ?`, passwordHash, time.Now(), id)
+	return err
+}
 
+// findOne 查询单个用户
+func (r *UserRepositoryImpl) findOne(query string, args ...interface{}) (*models.User, error) {
 	var user models.User
-	err := r.db.QueryRow(query, id).Scan(
-		&user.ID, &user.Username, &user.Password, &user.Email, &user.AvatarURL, &user.Role, &user.Six, &user.Status, &user.CreatedAt, &user.UpdatedAt)
+	var avatarURL, role, six sql.NullString
+	err := r.db.QueryRow(query, args...).Scan(
+		&user.ID, &user.Username, &user.Password, &user.Email, &user.Phone, &avatarURL, &role, &six,
+		&user.Status, &user.EmailVerifiedAt, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("用户不存在")
+			return nil, nil
 		}
 		return nil, err
 	}
 
+	user.AvatarURL = avatarURL.String
+	user.Role = role.String
+	user.Six = six.String
 	return &user, nil
 }
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"freight/models"
+	"freight/utils"
+)
+
+// VehicleRepositoryImpl 车辆数据访问实现
+type VehicleRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewVehicleRepository 创建车辆数据访问实例
+func NewVehicleRepository(db *sql.DB) models.VehicleRepository {
+	return &VehicleRepositoryImpl{db: db}
+}
+
+const vehicleColumns = `id, owner_id, plate, type, capacity_kg, capacity_m3, length_m, created_at, updated_at`
+
+func scanVehicle(row rowScanner) (*models.Vehicle, error) {
+	var v models.Vehicle
+	if err := row.Scan(&v.ID, &v.OwnerID, &v.Plate, &v.Type, &v.CapacityKg, &v.CapacityM3, &v.LengthM,
+		&v.CreatedAt, &v.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// Create 登记车辆（车牌号唯一）
+func (r *VehicleRepositoryImpl) Create(ctx context.Context, vehicle *models.Vehicle) error {
+	now := time.Now()
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO vehicles (owner_id, plate, type, capacity_kg, capacity_m3, length_m, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		vehicle.OwnerID, vehicle.Plate, vehicle.Type, vehicle.CapacityKg, vehicle.CapacityM3, vehicle.LengthM, now, now)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	vehicle.ID = uint64(id)
+	vehicle.CreatedAt = utils.FromTime(now)
+	vehicle.UpdatedAt = utils.FromTime(now)
+	return nil
+}
+
+// GetByID 查找车辆，不存在返回nil
+func (r *VehicleRepositoryImpl) GetByID(ctx context.Context, id uint64) (*models.Vehicle, error) {
+	return r.findOne(ctx, "SELECT "+vehicleColumns+" FROM vehicles WHERE id = ?", id)
+}
+
+// FindByPlate 按车牌号查找车辆，不存在返回nil
+func (r *VehicleRepositoryImpl) FindByPlate(ctx context.Context, plate string) (*models.Vehicle, error) {
+	return r.findOne(ctx, "SELECT "+vehicleColumns+" FROM vehicles WHERE plate = ?", plate)
+}
+
+func (r *VehicleRepositoryImpl) findOne(ctx context.Context, query string, args ...interface{}) (*models.Vehicle, error) {
+	vehicle, err := scanVehicle(r.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return vehicle, err
+}
+
+// ListByOwner 按登记时间列出承运方的车辆
+func (r *VehicleRepositoryImpl) ListByOwner(ctx context.Context, ownerID uint64) ([]*models.Vehicle, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+vehicleColumns+" FROM vehicles WHERE owner_id = ? ORDER BY id ASC", ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	vehicles := []*models.Vehicle{}
+	for rows.Next() {
+		vehicle, err := scanVehicle(rows)
+		if err != nil {
+			return nil, err
+		}
+		vehicles = append(vehicles, vehicle)
+	}
+	return vehicles, rows.Err()
+}
+
+// Update 更新车辆信息（车主不可变更）
+func (r *VehicleRepositoryImpl) Update(ctx context.Context, vehicle *models.Vehicle) error {
+	now := time.Now()
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE vehicles SET plate = ?, type = ?, capacity_kg = ?, capacity_m3 = ?, length_m = ?, updated_at = ?
+		WHERE id = ?`,
+		vehicle.Plate, vehicle.Type, vehicle.CapacityKg, vehicle.CapacityM3, vehicle.LengthM, now, vehicle.ID)
+	if err == nil {
+		vehicle.UpdatedAt = utils.FromTime(now)
+	}
+	return err
+}
+
+// Delete 删除车辆（已接单订单中记录的车辆ID保留）
+func (r *VehicleRepositoryImpl) Delete(ctx context.Context, id uint64) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM vehicles WHERE id = ?", id)
+	return err
+}
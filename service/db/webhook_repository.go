@@ -0,0 +1,253 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"freight/models"
+)
+
+// WebhookRepositoryImpl Webhook 数据访问实现
+type WebhookRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewWebhookRepository 创建 Webhook 仓储实例
+func NewWebhookRepository(db *sql.DB) models.WebhookRepository {
+	return &WebhookRepositoryImpl{db: db}
+}
+
+const webhookSubscriptionColumns = `id, owner_id, url, event_types, secret, all_orders, active, created_at, updated_at`
+
+func scanWebhookSubscription(row rowScanner) (*models.WebhookSubscription, error) {
+	var sub models.WebhookSubscription
+	var eventTypes string
+	if err := row.Scan(&sub.ID, &sub.OwnerID, &sub.URL, &eventTypes, &sub.Secret, &sub.AllOrders, &sub.Active,
+		&sub.CreatedAt, &sub.UpdatedAt); err != nil {
+		return nil, err
+	}
+	sub.EventTypes = strings.Split(eventTypes, ",")
+	return &sub, nil
+}
+
+func (r *WebhookRepositoryImpl) querySubscriptions(ctx context.Context, query string, args ...interface{}) ([]*models.WebhookSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []*models.WebhookSubscription{}
+	for rows.Next() {
+		sub, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// CreateSubscription 插入订阅，事件类型以逗号分隔保存
+func (r *WebhookRepositoryImpl) CreateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	now := time.Now()
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO webhook_subscriptions (owner_id, url, event_types, secret, all_orders, active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		sub.OwnerID, sub.URL, strings.Join(sub.EventTypes, ","), sub.Secret, sub.AllOrders, sub.Active, now, now)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	sub.ID, sub.CreatedAt, sub.UpdatedAt = uint64(id), now, now
+	return nil
+}
+
+// GetSubscription 按ID查询订阅
+func (r *WebhookRepositoryImpl) GetSubscription(ctx context.Context, id uint64) (*models.WebhookSubscription, error) {
+	sub, err := scanWebhookSubscription(r.db.QueryRowContext(ctx,
+		"SELECT "+webhookSubscriptionColumns+" FROM webhook_subscriptions WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return sub, err
+}
+
+// ListSubscriptions 查询用户的订阅
+func (r *WebhookRepositoryImpl) ListSubscriptions(ctx context.Context, ownerID uint64) ([]*models.WebhookSubscription, error) {
+	return r.querySubscriptions(ctx,
+		"SELECT "+webhookSubscriptionColumns+" FROM webhook_subscriptions WHERE owner_id = ? ORDER BY id", ownerID)
+}
+
+// ListActiveSubscriptions 查询全部启用的订阅
+func (r *WebhookRepositoryImpl) ListActiveSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error) {
+	return r.querySubscriptions(ctx,
+		"SELECT "+webhookSubscriptionColumns+" FROM webhook_subscriptions WHERE active = 1 ORDER BY id")
+}
+
+// UpdateSubscription 更新地址、事件类型、范围和启用状态（密钥不可修改）
+func (r *WebhookRepositoryImpl) UpdateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	now := time.Now()
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_subscriptions SET url = ?, event_types = ?, all_orders = ?, active = ?, updated_at = ?
+		WHERE id = ?`,
+		sub.URL, strings.Join(sub.EventTypes, ","), sub.AllOrders, sub.Active, now, sub.ID)
+	if err == nil {
+		sub.UpdatedAt = now
+	}
+	return err
+}
+
+// DeleteSubscription 删除订阅（投递记录保留，投递时因订阅不存在转为死信）
+func (r *WebhookRepositoryImpl) DeleteSubscription(ctx context.Context, id uint64) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM webhook_subscriptions WHERE id = ?", id)
+	return err
+}
+
+const webhookDeliveryColumns = `id, subscription_id, event_type, order_id, payload, status, attempts, last_status_code, last_error,
+	next_attempt_at, delivered_at, created_at, updated_at`
+
+func scanWebhookDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var next, delivered sql.NullTime
+	if err := row.Scan(&d.ID, &d.SubscriptionID, &d.EventType, &d.OrderID, &d.Payload, &d.Status, &d.Attempts,
+		&d.LastStatusCode, &d.LastError, &next, &delivered, &d.CreatedAt, &d.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if next.Valid {
+		d.NextAttemptAt = &next.Time
+	}
+	if delivered.Valid {
+		d.DeliveredAt = &delivered.Time
+	}
+	return &d, nil
+}
+
+func scanWebhookDeliveries(rows *sql.Rows) ([]*models.WebhookDelivery, error) {
+	defer rows.Close()
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// CreateDeliveries 多行插入投递记录；LastInsertId 为第一行的ID，同一语句内的自增ID连续
+func (r *WebhookRepositoryImpl) CreateDeliveries(ctx context.Context, deliveries []*models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	placeholders := make([]string, 0, len(deliveries))
+	args := make([]interface{}, 0, len(deliveries)*8)
+	for _, d := range deliveries {
+		placeholders = append(placeholders, "(?, ?, ?, ?, ?, ?, ?, ?)")
+		args = append(args, d.SubscriptionID, d.EventType, d.OrderID, d.Payload, d.Status, d.NextAttemptAt, d.CreatedAt, d.UpdatedAt)
+	}
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (subscription_id, event_type, order_id, payload, status, next_attempt_at, created_at, updated_at)
+		VALUES `+strings.Join(placeholders, ", "), args...)
+	if err != nil {
+		return err
+	}
+	first, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for i, d := range deliveries {
+		d.ID = uint64(first) + uint64(i)
+	}
+	return nil
+}
+
+// ClaimDueDeliveries 在事务中锁定到期记录（跳过其他实例已锁定的行）并推迟其下次投递时间
+func (r *WebhookRepositoryImpl) ClaimDueDeliveries(ctx context.Context, now time.Time, limit int, leaseUntil time.Time) ([]*models.WebhookDelivery, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "SELECT "+webhookDeliveryColumns+` FROM webhook_deliveries
+		WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at LIMIT ? FOR UPDATE SKIP LOCKED`,
+		models.WebhookPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := scanWebhookDeliveries(rows)
+	if err != nil || len(deliveries) == 0 {
+		return deliveries, err
+	}
+
+	ids := make([]interface{}, 0, len(deliveries)+1)
+	ids = append(ids, leaseUntil)
+	for _, d := range deliveries {
+		ids = append(ids, d.ID)
+		d.NextAttemptAt = &leaseUntil
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE webhook_deliveries SET next_attempt_at = ?
+		WHERE id IN (?`+strings.Repeat(", ?", len(deliveries)-1)+`)`, ids...); err != nil {
+		return nil, err
+	}
+	return deliveries, tx.Commit()
+}
+
+// UpdateDelivery 保存投递结果
+func (r *WebhookRepositoryImpl) UpdateDelivery(ctx context.Context, d *models.WebhookDelivery) error {
+	d.UpdatedAt = time.Now()
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries SET status = ?, attempts = ?, last_status_code = ?, last_error = ?,
+			next_attempt_at = ?, delivered_at = ?, updated_at = ?
+		WHERE id = ?`,
+		d.Status, d.Attempts, d.LastStatusCode, d.LastError, d.NextAttemptAt, d.DeliveredAt, d.UpdatedAt, d.ID)
+	return err
+}
+
+// GetDelivery 按ID查询投递记录
+func (r *WebhookRepositoryImpl) GetDelivery(ctx context.Context, id uint64) (*models.WebhookDelivery, error) {
+	d, err := scanWebhookDelivery(r.db.QueryRowContext(ctx,
+		"SELECT "+webhookDeliveryColumns+" FROM webhook_deliveries WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return d, err
+}
+
+// ListDeliveries 按条件倒序查询投递记录
+func (r *WebhookRepositoryImpl) ListDeliveries(ctx context.Context, filter models.WebhookDeliveryFilter) ([]*models.WebhookDelivery, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.SubscriptionID > 0 {
+		conditions = append(conditions, "subscription_id = ?")
+		args = append(args, filter.SubscriptionID)
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.BeforeID > 0 {
+		conditions = append(conditions, "id < ?")
+		args = append(args, filter.BeforeID)
+	}
+	query := "SELECT " + webhookDeliveryColumns + " FROM webhook_deliveries"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, filter.Limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanWebhookDeliveries(rows)
+}
@@ -0,0 +1,175 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	TypeOrderCreated   = "order.created"   // 发布订单
+	TypeOrderAccepted  = "order.accepted"  // 接单或定标
+	TypeOrderCompleted = "order.completed" // 确认送达
+	TypeOrderCancelled = "order.cancelled" // 发货方取消
+	TypeOrderStatus    = "order.status"    // 其他状态变更（取货、发车、确认收货、过期）
+	TypeOrderLocation  = "order.location"  // 承运方上报位置
+	TypeChatMessage    = "chat.message"    // 订单沟通新消息
+	TypeChatRead       = "chat.read"       // 订单沟通已读回执
+)
+
+// 默认参数
+const (
+	DefaultBufferSize = 1000 // 保留用于断线重放的最近事件数
+	subscriberBuffer  = 64   // 每个订阅者的待发送事件数，写满说明客户端过慢，断开后由客户端重连重放
+)
+
+// Event 订单事件
+type Event struct {
+	ID      uint64      `json:"id"`
+	Type    string      `json:"type"`
+	OrderID uint64      `json:"order_id"`
+	Time    time.Time   `json:"time"`
+	Data    interface{} `json:"data"` // 订单快照或定位
+
+	// 以下字段用于按用户过滤，不下发给客户端
+	ShipperID uint64 `json:"-"`
+	CarrierID uint64 `json:"-"`
+	Hall      bool   `json:"-"` // 是否属于货源大厅的变化（新订单上架，或待接单订单被接走、取消、过期）
+}
+
+// Publisher 事件发布接口
+type Publisher interface {
+	Publish(e Event) Event
+}
+
+// Publishers 依次发布到多个发布者，后面的发布者收到前面分配过ID和时间的事件
+type Publishers []Publisher
+
+// Publish 依次发布，返回最后一个发布者处理后的事件
+func (ps Publishers) Publish(e Event) Event {
+	for _, p := range ps {
+		e = p.Publish(e)
+	}
+	return e
+}
+
+// OrderEventTypes 可对外订阅（Webhook）的订单事件类型
+var OrderEventTypes = []string{
+	TypeOrderCreated, TypeOrderAccepted, TypeOrderCompleted, TypeOrderCancelled, TypeOrderStatus, TypeOrderLocation,
+}
+
+// Subscription 事件订阅，C 被关闭表示订阅已结束（取消订阅或客户端过慢被断开）
+type Subscription struct {
+	C <-chan Event
+
+	ch    chan Event
+	match func(Event) bool
+}
+
+// Bus 进程内事件总线：为事件分配递增ID并保留最近的事件，订阅时可按 Last-Event-ID 重放
+type Bus struct {
+	mu     sync.Mutex
+	nextID uint64
+	buffer []Event // 环形缓冲区
+	head   int     // 最早事件的位置
+	size   int
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+var _ Publisher = (*Bus)(nil)
+
+// NewBus 创建事件总线，bufferSize 为保留的事件数（为0时使用默认值）
+func NewBus(bufferSize int) *Bus {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+	return &Bus{buffer: make([]Event, bufferSize), subs: make(map[*Subscription]struct{})}
+}
+
+// Publish 发布事件并推送给匹配的订阅者，返回带ID和时间的事件
+func (b *Bus) Publish(e Event) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	e.ID = b.nextID
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	if b.size < len(b.buffer) {
+		b.buffer[(b.head+b.size)%len(b.buffer)] = e
+		b.size++
+	} else {
+		b.buffer[b.head] = e
+		b.head = (b.head + 1) % len(b.buffer)
+	}
+
+	for sub := range b.subs {
+		if !sub.match(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			b.remove(sub)
+		}
+	}
+	return e
+}
+
+// Subscribe 订阅匹配的事件。lastID 不为0时先返回缓冲区中ID大于 lastID 的匹配事件；
+// 若 lastID 之后的事件已不在缓冲区（或 lastID 来自重启前），complete 为 false，客户端应重新拉取全量数据
+func (b *Bus) Subscribe(lastID uint64, match func(Event) bool) (sub *Subscription, replay []Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	complete = true
+	if lastID > 0 {
+		oldest := b.nextID - uint64(b.size) + 1
+		if lastID > b.nextID || lastID+1 < oldest {
+			complete = false
+		}
+		for i := 0; i < b.size; i++ {
+			e := b.buffer[(b.head+i)%len(b.buffer)]
+			if e.ID > lastID && match(e) {
+				replay = append(replay, e)
+			}
+		}
+	}
+
+	ch := make(chan Event, subscriberBuffer)
+	sub = &Subscription{C: ch, ch: ch, match: match}
+	if b.closed {
+		close(ch)
+		return sub, replay, complete
+	}
+	b.subs[sub] = struct{}{}
+	return sub, replay, complete
+}
+
+// Unsubscribe 取消订阅，可重复调用
+func (b *Bus) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(sub)
+}
+
+// Close 结束全部订阅，之后的订阅会立即结束（服务关闭时让长连接尽快退出）
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subs {
+		b.remove(sub)
+	}
+}
+
+// remove 移除订阅者并关闭其通道（调用方需持有锁）
+func (b *Bus) remove(sub *Subscription) {
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
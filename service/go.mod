@@ -5,8 +5,10 @@ go 1.23.5
 require github.com/dgrijalva/jwt-go v3.2.0+incompatible
 
 require (
+	github.com/DATA-DOG/go-sqlmock v1.5.2
 	github.com/go-sql-driver/mysql v1.9.3
 	github.com/gorilla/mux v1.8.1
+	github.com/gorilla/websocket v1.5.3
 	github.com/stretchr/testify v1.10.0
 	golang.org/x/crypto v0.39.0
 	gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"freight/config"
+	"freight/models"
+	"freight/scheduler"
+	"freight/services"
+)
+
+// defaultLocationRetention 订单结束后定位轨迹的默认保留时长
+const defaultLocationRetention = 90 * 24 * time.Hour
+
+// maintenanceJobs 内置的定时维护任务（调度和超时可在配置文件中按任务名覆盖）
+func maintenanceJobs(freights services.FreightService, tokens models.TokenRepository, stats services.StatsService,
+	locations models.LocationRepository, webhooks services.WebhookService, tracking config.TrackingConfig, loc *time.Location) []scheduler.Job {
+	retention := time.Duration(tracking.RetentionDays) * 24 * time.Hour
+	if retention <= 0 {
+		retention = defaultLocationRetention
+	}
+	return []scheduler.Job{
+		{
+			// 将超过取货时间仍未接单的订单标记为过期
+			Name:     "expire_orders",
+			Schedule: scheduler.Every(time.Minute),
+			Timeout:  30 * time.Second,
+			Run: func(ctx context.Context) error {
+				n, err := freights.ExpireOverdueOrders(ctx, time.Now())
+				if n > 0 {
+					log.Printf("已将 %d 个超过取货时间的订单标记为过期", n)
+				}
+				return err
+			},
+		},
+		{
+			// 清理已过期的刷新令牌、吊销记录和一次性令牌
+			Name:     "purge_tokens",
+			Schedule: scheduler.MustParse("30 3 * * *"),
+			Timeout:  5 * time.Minute,
+			Run: func(ctx context.Context) error {
+				n, err := tokens.PurgeExpired(time.Now())
+				if n > 0 {
+					log.Printf("已清理 %d 条过期令牌", n)
+				}
+				return err
+			},
+		},
+		{
+			// 汇总前一天的订单统计（当天凌晨执行，重复执行会覆盖）
+			Name:     "rollup_daily_stats",
+			Schedule: scheduler.MustParse("10 0 * * *"),
+			Timeout:  10 * time.Minute,
+			Run: func(ctx context.Context) error {
+				_, err := stats.RollupDay(ctx, time.Now().In(loc).AddDate(0, 0, -1))
+				return err
+			},
+		},
+		{
+			// 订单结束超过保留期后删除其定位轨迹
+			Name:     "purge_locations",
+			Schedule: scheduler.MustParse("0 4 * * *"),
+			Timeout:  10 * time.Minute,
+			Run: func(ctx context.Context) error {
+				n, err := locations.PurgeFinished(ctx, time.Now().Add(-retention))
+				if n > 0 {
+					log.Printf("已清理 %d 条过期定位", n)
+				}
+				return err
+			},
+		},
+		{
+			// 投递到期的 Webhook（新事件和等待重试的投递）
+			Name:     "deliver_webhooks",
+			Schedule: scheduler.Every(10 * time.Second),
+			Timeout:  5 * time.Minute,
+			Run: func(ctx context.Context) error {
+				_, err := webhooks.DeliverDue(ctx, time.Now())
+				return err
+			},
+		},
+	}
+}
+
+// registerJobs 按配置覆盖调度和超时后注册任务
+func registerJobs(s *scheduler.Scheduler, jobs []scheduler.Job, cfg config.SchedulerConfig) error {
+	for _, job := range jobs {
+		override := cfg.Jobs[job.Name]
+		if override.Disabled {
+			continue
+		}
+		if override.Schedule != "" {
+			schedule, err := scheduler.Parse(override.Schedule)
+			if err != nil {
+				return fmt.Errorf("任务 %s: %w", job.Name, err)
+			}
+			job.Schedule = schedule
+		}
+		if override.Timeout > 0 {
+			job.Timeout = time.Duration(override.Timeout) * time.Second
+		}
+		if err := s.Register(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
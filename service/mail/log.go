@@ -0,0 +1,55 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// LogMailer 将邮件写入日志（本地开发和测试使用），并保留已发送的邮件供测试读取
+type LogMailer struct {
+	mu       sync.Mutex
+	w        io.Writer
+	messages []Message
+}
+
+// NewLogMailer 创建日志邮件实例，w 为nil时写入标准日志
+func NewLogMailer(w io.Writer) *LogMailer {
+	return &LogMailer{w: w}
+}
+
+// NewFileMailer 创建写入文件的日志邮件实例（追加写入）
+func NewFileMailer(path string) (*LogMailer, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("打开邮件日志文件失败: %w", err)
+	}
+	return NewLogMailer(f), nil
+}
+
+// Send 记录邮件
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = append(m.messages, msg)
+	if m.w == nil {
+		log.Printf("[MAIL] to=%s subject=%s\n%s", msg.To, msg.Subject, msg.Body)
+		return nil
+	}
+	_, err := fmt.Fprintf(m.w, "---- %s\nTo: %s\nSubject: %s\n\n%s\n",
+		time.Now().Format(time.RFC3339), msg.To, msg.Subject, msg.Body)
+	return err
+}
+
+// Messages 返回已发送的邮件副本
+func (m *LogMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Message(nil), m.messages...)
+}
@@ -0,0 +1,35 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+
+	"freight/config"
+)
+
+// Message 邮件内容（纯文本）
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer 邮件发送接口
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New 按配置创建邮件发送实例：smtp 发送真实邮件，log（默认）写入日志文件或标准日志
+func New(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		return NewSMTPMailer(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.From), nil
+	case "", "log":
+		if cfg.LogFile == "" {
+			return NewLogMailer(nil), nil
+		}
+		return NewFileMailer(cfg.LogFile)
+	default:
+		return nil, fmt.Errorf("不支持的邮件驱动: %s", cfg.Driver)
+	}
+}
@@ -1,16 +1,25 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"freight/api/handlers"
 	"freight/api/middleware"
 	"freight/api/routes"
 	"freight/config"
 	"freight/db"
+	"freight/events"
+	"freight/mail"
+	"freight/region"
+	"freight/scheduler"
 	"freight/services"
+	"freight/utils"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 func main() {
@@ -18,7 +27,6 @@ func main() {
 	//flag.Parse()
 
 	// 加载配置
-	//cfg, err := models.LoadConfig(*configPath)
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("加载配置失败: %v", err)
@@ -30,39 +38,155 @@ func main() {
 	}
 	defer db.Close()
 
+	// 执行数据库迁移
+	if err := db.Migrate(db.GetDB()); err != nil {
+		log.Fatalf("数据库迁移失败: %v", err)
+	}
+
 	// 创建服务
 	dbInstance := db.GetDB()
 
 	// 创建仓储实例
 	freightRepo := db.NewFreightRepository(dbInstance)
+	userRepo := db.NewUserRepository(dbInstance)
+	configRepo := db.NewConfigRepository(dbInstance)
+	tokenRepo := db.NewTokenRepository(dbInstance)
+	auditRepo := db.NewAuditRepository(dbInstance)
+
+	// 令牌服务（有效期取自配置，单位秒）
+	tokenService := services.NewTokenService(tokenRepo, userRepo, cfg.JWT.Secret,
+		time.Duration(cfg.JWT.Expiry)*time.Second, time.Duration(cfg.JWT.RefreshExpiry)*time.Second)
+
+	// 邮件发送（smtp 或写入日志）
+	mailer, err := mail.New(cfg.Mail)
+	if err != nil {
+		log.Fatalf("初始化邮件发送失败: %v", err)
+	}
+
+	// 登录暴力破解防护（阈值取自配置）
+	loginGuard := services.NewLoginGuard(db.NewLoginAttemptRepository(dbInstance), auditRepo, services.LoginGuardOptions{
+		MaxAccountFailures: cfg.Account.MaxLoginFailures,
+		MaxIPFailures:      cfg.Account.MaxIPLoginFailures,
+		FailureWindow:      time.Duration(cfg.Account.LoginFailureWindow) * time.Second,
+		LockoutDuration:    time.Duration(cfg.Account.LockoutDuration) * time.Second,
+	})
+
+	userService := services.NewUserServiceImpl(userRepo, tokenService, tokenRepo, mailer, loginGuard, services.AccountOptions{
+		RequireEmailVerification: cfg.Account.RequireEmailVerification,
+		VerifyTokenTTL:           time.Duration(cfg.Account.VerifyTokenExpiry) * time.Second,
+		ResetTokenTTL:            time.Duration(cfg.Account.ResetTokenExpiry) * time.Second,
+		AppBaseURL:               cfg.Account.AppBaseURL,
+	})
+	configService := services.NewConfigServiceImpl(configRepo)
+
+	// 行政区划数据（配置了外部文件时优先使用）
+	regions := region.Default()
+	if cfg.Region.DataFile != "" {
+		if regions, err = region.LoadFile(cfg.Region.DataFile); err != nil {
+			log.Fatalf("加载行政区划数据失败: %v", err)
+		}
+	}
 
-	// 注意：这里直接传递数据库连接给服务层
-	userService := services.NewUserServiceImpl(dbInstance, cfg.JWT.Secret)
-	configService := services.NewConfigServiceImpl(dbInstance)
 	//freightService := services.NewFreightService(dbInstance)
-	freightService := services.NewFreightService(freightRepo)
+	routeEstimator := services.NewHaversineEstimator(regions, services.RouteOptions{
+		DetourFactor:    cfg.Route.DetourFactor,
+		AverageSpeedKmh: cfg.Route.AverageSpeedKmh,
+	})
+	vehicleRepo := db.NewVehicleRepository(dbInstance)
+	// 进程内事件总线：订单变化通过 SSE 推送给在线用户
+	eventBus := events.NewBus(cfg.Events.BufferSize)
+	// Webhook 订阅者在事件总线分配ID后收到同一事件
+	webhookService := services.NewWebhookService(db.NewWebhookRepository(dbInstance), services.WebhookOptions{
+		MaxAttempts:          cfg.Webhook.MaxAttempts,
+		Backoff:              time.Duration(cfg.Webhook.BackoffSeconds) * time.Second,
+		MaxBackoff:           time.Duration(cfg.Webhook.MaxBackoffSeconds) * time.Second,
+		Timeout:              time.Duration(cfg.Webhook.TimeoutSeconds) * time.Second,
+		QueueSize:            cfg.Webhook.QueueSize,
+		CacheTTL:             time.Duration(cfg.Webhook.CacheSeconds) * time.Second,
+		AllowPrivateNetworks: cfg.Webhook.AllowPrivateNetworks,
+	})
+	orderEvents := events.Publishers{eventBus, webhookService}
+	freightService := services.NewFreightService(freightRepo, db.NewFreightSearchIndex(dbInstance), regions, routeEstimator, vehicleRepo, orderEvents)
+	quoteService := services.NewQuoteService(db.NewQuoteRepository(dbInstance), configService, regions, routeEstimator, freightService)
+	bidService := services.NewBidService(db.NewBidRepository(dbInstance), freightRepo, orderEvents)
+	vehicleService := services.NewVehicleService(vehicleRepo)
+	locationRepo := db.NewLocationRepository(dbInstance)
+	trackingService := services.NewTrackingService(locationRepo, freightRepo, routeEstimator, orderEvents)
+	chatService := services.NewChatService(db.NewChatRepository(dbInstance), freightRepo)
 
 	// 创建中间件
-	authMiddleware := middleware.NewAuthMiddleware(cfg.JWT.Secret)
+	authMiddleware := middleware.NewAuthMiddleware(cfg.JWT.Secret, tokenService)
 
-	// 设置路由（传递三个参数）
-	router := routes.SetupRoutes(userService, configService, freightService, authMiddleware)
+	// 定时任务：多实例部署时通过数据库租约避免重复执行
+	loc, err := time.LoadLocation(cfg.DB.Loc)
+	if err != nil {
+		log.Fatalf("加载时区 %s 失败: %v", cfg.DB.Loc, err)
+	}
+	schedulerOpts := scheduler.Options{Location: loc}
+	if cfg.Scheduler.Lease {
+		schedulerOpts.Locker = db.NewJobLease(dbInstance)
+	}
+	jobScheduler := scheduler.New(schedulerOpts)
+	statsService := services.NewStatsService(db.NewStatsRepository(dbInstance))
+	jobs := maintenanceJobs(freightService, tokenRepo, statsService, locationRepo, webhookService, cfg.Tracking, loc)
+	if err := registerJobs(jobScheduler, jobs, cfg.Scheduler); err != nil {
+		log.Fatalf("注册定时任务失败: %v", err)
+	}
+	if !cfg.Scheduler.Disabled {
+		jobScheduler.Start()
+	}
 
-	// 启动服务器
-	log.Printf("服务器启动在端口 %s", cfg.Server.Port)
-	log.Fatal(http.ListenAndServe(":"+cfg.Server.Port, router))
+	// 设置路由
+	trustedProxies, err := utils.ParseTrustedProxies(cfg.Server.TrustedProxies)
+	if err != nil {
+		log.Fatalf("解析受信任代理失败: %v", err)
+	}
+	router := routes.SetupRoutes(routes.Deps{
+		UserService:     userService,
+		ConfigService:   configService,
+		FreightService:  freightService,
+		TokenService:    tokenService,
+		QuoteService:    quoteService,
+		BidService:      bidService,
+		VehicleService:  vehicleService,
+		TrackingService: trackingService,
+		ChatService:     chatService,
+		WebhookService:  webhookService,
+		Jobs:            jobScheduler,
+		EventHandler:    handlers.NewEventHandler(eventBus, time.Duration(cfg.Events.HeartbeatSeconds)*time.Second),
+		Regions:         regions,
+		AuthMiddleware:  authMiddleware,
+		TrustedProxies:  trustedProxies,
+	})
 
-	log.Printf("服务器启动在端口 %s", config.Get().Server.Port)
+	// 启动服务器
+	server := &http.Server{Addr: ":" + cfg.Server.Port, Handler: router}
+	// 关闭时结束事件推送长连接，否则 Shutdown 会一直等待
+	server.RegisterOnShutdown(eventBus.Close)
+	go func() {
+		log.Printf("服务器启动在端口 %s", cfg.Server.Port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("服务器异常退出: %v", err)
+		}
+	}()
 
-	// 优雅关闭
+	// 优雅关闭：停止接收请求和调度新任务，等待进行中的请求和任务结束
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	log.Println("服务器正在关闭...")
-
-	// 关闭数据库连接
-	db.GetDB().Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("关闭HTTP服务失败: %v", err)
+	}
+	if err := jobScheduler.Stop(ctx); err != nil {
+		log.Printf("等待定时任务结束超时: %v", err)
+	}
+	if err := webhookService.Flush(ctx); err != nil {
+		log.Printf("等待 Webhook 投递记录保存超时: %v", err)
+	}
 
 	log.Println("服务器已关闭")
 }
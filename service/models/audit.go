@@ -0,0 +1,30 @@
+package models
+
+import (
+	"freight/utils"
+)
+
+// 审计事件类型
+const (
+	AuditAccountLocked   = "account_locked"   // 登录失败次数过多，账号被临时锁定
+	AuditAccountUnlocked = "account_unlocked" // 管理员解除账号锁定
+	AuditIPBlocked       = "ip_blocked"       // 同一IP登录失败次数过多
+)
+
+// AuditLog 审计记录
+type AuditLog struct {
+	ID        int64                `json:"id"`
+	Action    string               `json:"action"`
+	ActorID   int64                `json:"actor_id"` // 操作人，系统触发为0
+	Subject   string               `json:"subject"`  // 作用对象，如 user:12、login:alice
+	IP        string               `json:"ip"`
+	Detail    string               `json:"detail"`
+	CreatedAt utils.CustomNullTime `json:"created_at"`
+}
+
+// AuditRepository 审计记录数据访问接口
+type AuditRepository interface {
+	Create(log *AuditLog) error
+	// List 按时间倒序列出审计记录，action 为空时不过滤
+	List(action string, limit int) ([]*AuditLog, error)
+}
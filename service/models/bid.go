@@ -0,0 +1,24 @@
+package models
+
+import "freight/utils"
+
+// BidStatus 竞价状态
+const (
+	BidStatusPending  = 1 // 待定
+	BidStatusAwarded  = 2 // 中标
+	BidStatusRejected = 3 // 未中标（订单已由其他报价中标）
+	BidStatusClosed   = 4 // 已关闭（订单取消或过期，未定标）
+)
+
+// Bid 承运方对竞价订单的出价，每个承运方对同一订单只保留一条出价，重复出价覆盖原出价
+type Bid struct {
+	ID        uint64               `json:"id" db:"id"`
+	OrderID   uint64               `json:"order_id" db:"order_id"`
+	CarrierID uint64               `json:"carrier_id" db:"carrier_id"`
+	Amount    float64              `json:"amount" db:"amount"` // 出价金额，中标后作为订单价格
+	ETA       utils.CustomNullTime `json:"eta" db:"eta"`       // 承运方承诺的预计送达时间
+	Note      string               `json:"note" db:"note"`
+	Status    uint8                `json:"status" db:"status"`
+	CreatedAt utils.CustomNullTime `json:"created_at" db:"created_at"`
+	UpdatedAt utils.CustomNullTime `json:"updated_at" db:"updated_at"`
+}
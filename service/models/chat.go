@@ -0,0 +1,50 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// 消息类型
+const (
+	ChatKindText  = "text"  // 文字消息
+	ChatKindImage = "image" // 图片消息（附件为图片地址，正文可作为说明）
+)
+
+// ChatMessage 订单双方沟通的一条消息
+type ChatMessage struct {
+	ID            uint64    `json:"id" db:"id"`
+	OrderID       uint64    `json:"order_id" db:"order_id"`
+	SenderID      uint64    `json:"sender_id" db:"sender_id"`
+	Kind          string    `json:"kind" db:"kind"` // 取值见 ChatKindText、ChatKindImage
+	Body          string    `json:"body" db:"body"`
+	AttachmentURL string    `json:"attachment_url,omitempty" db:"attachment_url"` // 已上传图片的地址
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+
+	// ClientID 客户端生成的消息标识，不保存，仅在推送时原样带回便于发送方对应本地消息
+	ClientID string `json:"client_id,omitempty" db:"-"`
+}
+
+// ChatReadReceipt 已读回执：用户在订单沟通中已读到的最后一条消息
+type ChatReadReceipt struct {
+	OrderID    uint64    `json:"order_id" db:"order_id"`
+	UserID     uint64    `json:"user_id" db:"user_id"`
+	LastReadID uint64    `json:"last_read_id" db:"last_read_id"`
+	ReadAt     time.Time `json:"read_at" db:"read_at"`
+}
+
+// ChatRepository 订单沟通数据访问接口
+type ChatRepository interface {
+	// AddMessage 保存消息，回填ID
+	AddMessage(ctx context.Context, msg *ChatMessage) error
+	// ListMessages 按ID升序返回消息：afterID 不为0时返回其后最早的 limit 条，
+	// 否则返回 beforeID（为0表示不限）之前最近的 limit 条
+	ListMessages(ctx context.Context, orderID, beforeID, afterID uint64, limit int) ([]*ChatMessage, error)
+	// MarkRead 将已读位置推进到 upToID 及之前的最新一条消息（不会后退），
+	// 返回当前回执及已读位置是否有推进
+	MarkRead(ctx context.Context, orderID, userID, upToID uint64, at time.Time) (*ChatReadReceipt, bool, error)
+	// ReadReceipts 订单沟通中各用户的已读回执
+	ReadReceipts(ctx context.Context, orderID uint64) ([]*ChatReadReceipt, error)
+	// UnreadCounts 用户作为发货方或承运方的订单中，他人发送且未读的消息数（按订单）
+	UnreadCounts(ctx context.Context, userID uint64) (map[uint64]int, error)
+}
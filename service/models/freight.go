@@ -1,18 +1,35 @@
 package models
 
-import "freight/utils"
+import (
+	"time"
 
-// FreightStatus 货运状态常量
+	"freight/utils"
+)
+
+// FreightStatus 货运状态常量（1~3 沿用历史取值，新增状态依次往后编号）
 const (
-	FreightStatusPending   = 1 // 待取货
+	FreightStatusPending   = 1 // 待接单
 	FreightStatusShipping  = 2 // 运输中
 	FreightStatusDelivered = 3 // 已送达
+	FreightStatusAccepted  = 4 // 已接单
+	FreightStatusPickedUp  = 5 // 已取货
+	FreightStatusConfirmed = 6 // 已确认收货
+	FreightStatusCancelled = 7 // 已取消
+	FreightStatusExpired   = 8 // 已过期
+	FreightStatusDisputed  = 9 // 争议中
+)
+
+// 订单参与方角色
+const (
+	FreightRoleShipper = "shipper" // 发货方
+	FreightRoleCarrier = "carrier" // 承运方
 )
 
 // FreightOrder 运输订单模型
 type FreightOrder struct {
 	ID                  uint64               `json:"id" db:"id"`
-	UserID              uint64               `json:"user_id" db:"user_id"`
+	ShipperID           uint64               `json:"shipper_id" db:"shipper_id"` // 发货方（发布订单的用户）
+	CarrierID           uint64               `json:"carrier_id" db:"carrier_id"` // 承运方（接单的司机），未接单为0
 	OriginLocation      string               `json:"origin_location" db:"origin_location"`
 	OriginCode          string               `json:"origin_code" db:"origin_code"`
 	DestinationLocation string               `json:"destination_location" db:"destination_location"`
@@ -28,6 +45,28 @@ type FreightOrder struct {
 	CreatedAt           utils.CustomNullTime `json:"created_at" db:"created_at"`
 	UpdatedAt           utils.CustomNullTime `json:"updated_at" db:"updated_at"`
 	Email               string               `json:"email" db:"email"`
+	DistanceKm          float64              `json:"distance_km" db:"distance_km"`         // 预估公路里程（公里），创建时计算
+	EstimatedHours      float64              `json:"estimated_hours" db:"estimated_hours"` // 预估行驶时长（小时）
+	BiddingEnabled      bool                 `json:"bidding_enabled" db:"bidding_enabled"` // 竞价模式：承运方出价，由发货方选定中标者，不能直接接单
+	BidCount            int                  `json:"bid_count" db:"bid_count"`             // 出价数量，由竞价仓储维护
+	VehicleID           uint64               `json:"vehicle_id" db:"vehicle_id"`           // 承运车辆，接单时选择，未选择为0
+
+	// 货物信息（零值表示未填写）
+	WeightKg    float64  `json:"weight_kg" db:"weight_kg"`       // 总重量（千克）
+	VolumeM3    float64  `json:"volume_m3" db:"volume_m3"`       // 总体积（立方米）
+	Pieces      int      `json:"pieces" db:"pieces"`             // 件数
+	LengthM     float64  `json:"length_m" db:"length_m"`         // 最大单件长度（米）
+	WidthM      float64  `json:"width_m" db:"width_m"`           // 最大单件宽度（米）
+	HeightM     float64  `json:"height_m" db:"height_m"`         // 最大单件高度（米）
+	HazmatClass uint8    `json:"hazmat_class" db:"hazmat_class"` // 危险品类别（GB 6944 第1~9类），0 表示普通货物
+	TempMinC    *float64 `json:"temp_min_c" db:"temp_min_c"`     // 冷链温控下限（℃），为空表示无要求
+	TempMaxC    *float64 `json:"temp_max_c" db:"temp_max_c"`     // 冷链温控上限（℃），为空表示无要求
+
+	// 取货和送达时间窗口（RFC 3339 格式，须带时区偏移；为空表示不限）
+	PickupEarliest   *time.Time `json:"pickup_earliest" db:"pickup_earliest"`
+	PickupLatest     *time.Time `json:"pickup_latest" db:"pickup_latest"` // 超过该时间仍未接单的订单由定时任务标记为过期
+	DeliveryEarliest *time.Time `json:"delivery_earliest" db:"delivery_earliest"`
+	DeliveryLatest   *time.Time `json:"delivery_latest" db:"delivery_latest"`
 }
 
 // FreightFilter 订单过滤条件
@@ -35,7 +74,7 @@ type FreightFilter struct {
 	OriginLocation      string  `json:"origin_location,omitempty" db:"origin_location"`
 	OriginCode          string  `json:"origin_code,omitempty" db:"origin_code"`
 	DestinationLocation string  `json:"destination_location,omitempty" db:"destination_location"`
-	DestinationCode     string  `json:"origin_code,omitempty" db:"origin_code"`
+	DestinationCode     string  `json:"destination_code,omitempty" db:"destination_code"`
 	TypeID              uint8   `json:"type_id,omitempty" db:"typeid"`
 	Status              *uint8  `json:"status,omitempty" db:"status"`
 	MinPrice            float64 `json:"min_price,omitempty" db:"price >= ?"`
@@ -43,8 +82,63 @@ type FreightFilter struct {
 	IsUrgent            *bool   `json:"is_urgent,omitempty" db:"is_urgent = ?"`
 	HasInsurance        *bool   `json:"has_insurance,omitempty" db:"has_insurance = ?"`
 	OrderDate           string  `json:"order_date,omitempty" db:"order_date = ?"`
+	OrderDateFrom       string  `json:"order_date_from,omitempty"`     // 下单日期范围起（含），YYYY-MM-DD
+	OrderDateTo         string  `json:"order_date_to,omitempty"`       // 下单日期范围止（含），YYYY-MM-DD
+	MinDistance         float64 `json:"min_distance,omitempty"`        // 预估里程下限（公里）
+	MaxDistance         float64 `json:"max_distance,omitempty"`        // 预估里程上限（公里）
+	MinWeight           float64 `json:"min_weight,omitempty"`          // 货物重量下限（千克）
+	MaxWeight           float64 `json:"max_weight,omitempty"`          // 货物重量上限（千克），用于按车辆载重筛选
+	MaxVolume           float64 `json:"max_volume,omitempty"`          // 货物体积上限（立方米），用于按车厢容积筛选
+	MaxLength           float64 `json:"max_length,omitempty"`          // 单件长度上限（米），用于按车厢尺寸筛选
+	MaxWidth            float64 `json:"max_width,omitempty"`           // 单件宽度上限（米）
+	MaxHeight           float64 `json:"max_height,omitempty"`          // 单件高度上限（米）
+	Hazmat              *bool   `json:"hazmat,omitempty"`              // 是否危险品
+	HazmatClass         uint8   `json:"hazmat_class,omitempty"`        // 危险品类别
+	ColdChain           *bool   `json:"cold_chain,omitempty"`          // 是否有冷链温控要求
+	PickupWithinHours   int     `json:"pickup_within_hours,omitempty"` // 取货时间窗口与未来N小时有交集
+	Role                string  `json:"role,omitempty"`                // 按用户查询时的角色：shipper 或 carrier，为空表示两者
 	Page                int     `json:"page,omitempty"`
 	PageSize            int     `json:"page_size,omitempty"`
-	SortField           string  // 新增：排序字段（如 "created_at", "price", "order_date"）
-	SortOrder           string  // 新增：排序方向（"asc" 升序 或 "desc" 降序）
+	SortField           string  `json:"sort_field,omitempty"` // 排序字段，取值见 FreightSortFields
+	SortOrder           string  `json:"sort_order,omitempty"` // 排序方向（"asc" 升序 或 "desc" 降序）
+
+	// 区划前缀：按省或市过滤时匹配其下全部区划编码（由服务层根据 OriginCode/DestinationCode 展开）
+	OriginCodePrefix      string `json:"-"`
+	DestinationCodePrefix string `json:"-"`
+
+	// 按车辆筛选可承运的订单（仅大厅列表）：由服务层展开为载重、容积、长度和冷链过滤，车辆须属于 VehicleOwnerID
+	VehicleID      uint64 `json:"vehicle_id,omitempty"`
+	VehicleOwnerID uint64 `json:"-"`
+
+	// 取货时间范围：由服务层根据 PickupWithinHours 和当前时间计算，匹配取货窗口与之有交集的订单
+	PickupFrom *time.Time `json:"-"`
+	PickupTo   *time.Time `json:"-"`
+
+	// 游标模式：按 (updated_at, id) 倒序，忽略 Page 和排序参数；After 为空表示第一页
+	CursorMode bool           `json:"-"`
+	After      *FreightCursor `json:"-"`
+}
+
+// FreightSortFields 订单列表允许排序的字段（与数据库列名一致）
+var FreightSortFields = []string{"created_at", "updated_at", "price", "order_date", "distance_km"}
+
+// ValidFreightSortField 判断排序字段是否在白名单中
+func ValidFreightSortField(field string) bool {
+	for _, f := range FreightSortFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// FreightOrderEvent 订单状态流转记录
+type FreightOrderEvent struct {
+	ID         uint64               `json:"id" db:"id"`
+	OrderID    uint64               `json:"order_id" db:"order_id"`
+	FromStatus uint8                `json:"from_status" db:"from_status"`
+	ToStatus   uint8                `json:"to_status" db:"to_status"`
+	ActorID    uint64               `json:"actor_id" db:"actor_id"`
+	Reason     string               `json:"reason" db:"reason"`
+	CreatedAt  utils.CustomNullTime `json:"created_at" db:"created_at"`
 }
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// LocationPing 承运方上报的一次GPS定位
+type LocationPing struct {
+	ID         uint64    `json:"id" db:"id"`
+	OrderID    uint64    `json:"order_id" db:"order_id"`
+	CarrierID  uint64    `json:"carrier_id" db:"carrier_id"`
+	Lat        float64   `json:"lat" db:"lat"`
+	Lng        float64   `json:"lng" db:"lng"`
+	SpeedKmh   float64   `json:"speed" db:"speed_kmh"`       // 速度（公里/小时）
+	Heading    float64   `json:"heading" db:"heading"`       // 行驶方向（度，正北为0，顺时针）
+	RecordedAt time.Time `json:"timestamp" db:"recorded_at"` // 设备定位时间（离线补传时早于上报时间）
+}
+
+// LocationRepository 定位数据访问接口（按订单和定位时间存储的时序数据）
+type LocationRepository interface {
+	// AddPings 批量保存定位，同一订单相同定位时间的重复上报被忽略；返回实际保存的条数
+	AddPings(ctx context.Context, pings []*LocationPing) (int, error)
+	// Latest 返回订单定位时间最新的一条，没有定位时返回 nil, nil
+	Latest(ctx context.Context, orderID uint64) (*LocationPing, error)
+	// Trail 按定位时间升序返回订单最近的 limit 条定位
+	Trail(ctx context.Context, orderID uint64, limit int) ([]*LocationPing, error)
+	// PurgeFinished 删除已结束（确认收货、取消、过期或删除）且最后更新早于 before 的订单的定位，返回删除条数
+	PurgeFinished(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// 登录失败计数的统计维度
+const (
+	LoginScopeAccount = "account" // 按账号
+	LoginScopeIP      = "ip"      // 按客户端IP
+)
+
+// LoginAttemptRepository 登录失败记录与账号锁定的数据访问接口
+type LoginAttemptRepository interface {
+	RecordFailure(scope, subject string, at time.Time) error
+	// CountFailures 统计 since 之后的失败次数（滑动窗口）
+	CountFailures(scope, subject string, since time.Time) (int, error)
+	ClearFailures(scope, subject string) error
+	Lock(subject string, until time.Time) error
+	// LockedUntil 返回账号锁定截止时间，未锁定时 ok 为false
+	LockedUntil(subject string) (until time.Time, ok bool, err error)
+	// Unlock 解除锁定，返回是否存在锁定记录
+	Unlock(subject string) (bool, error)
+	// PurgeFailures 清理 before 之前的失败记录和已过期的锁定，返回删除条数
+	PurgeFailures(before time.Time) (int64, error)
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 分页大小
+const (
+	DefaultPageSize = 20  // 未指定 page_size 时的默认值
+	MaxPageSize     = 100 // 服务端允许的最大 page_size，超出时按最大值返回
+)
+
+// ErrInvalidCursor 游标无法解析
+var ErrInvalidCursor = errors.New("无效的游标")
+
+// FreightPage 订单分页结果
+type FreightPage struct {
+	Items      []*FreightOrder `json:"items"`
+	Total      int64           `json:"total"`                 // 满足过滤条件的订单总数
+	Page       int             `json:"page,omitempty"`        // 页码模式下的当前页
+	PageSize   int             `json:"page_size"`             // 实际使用的每页大小
+	HasMore    bool            `json:"has_more"`              // 是否还有下一页
+	NextCursor string          `json:"next_cursor,omitempty"` // 游标模式下获取下一页使用的游标
+}
+
+// FreightCursor 游标位置：按 (updated_at, id) 倒序翻页，新发布的订单不会导致翻页时重复或遗漏
+type FreightCursor struct {
+	UpdatedAt time.Time
+	ID        uint64
+}
+
+// Encode 编码为不透明的游标字符串
+func (c FreightCursor) Encode() string {
+	raw := strconv.FormatInt(c.UpdatedAt.UnixNano(), 10) + ":" + strconv.FormatUint(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeFreightCursor 解析游标字符串
+func DecodeFreightCursor(s string) (*FreightCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	nanos, idStr, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil || id == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &FreightCursor{UpdatedAt: time.Unix(0, n), ID: id}, nil
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"freight/utils"
+)
+
+// 报价明细项编码
+const (
+	QuoteItemBase       = "base"        // 基础运费：里程 × 每公里运价（含计费重量部分）
+	QuoteItemType       = "type"        // 货物类型调整
+	QuoteItemUrgent     = "urgent"      // 加急附加费
+	QuoteItemInsurance  = "insurance"   // 保价费
+	QuoteItemMinimumFee = "minimum_fee" // 不足起步价时补足的差额
+)
+
+// QuoteItem 报价明细
+type QuoteItem struct {
+	Code   string  `json:"code"`
+	Name   string  `json:"name"`
+	Amount float64 `json:"amount"`
+}
+
+// Quote 运费报价（有效期内可据此发布订单，每个报价只能使用一次）
+type Quote struct {
+	ID              string               `json:"id"`
+	ShipperID       uint64               `json:"shipper_id"`
+	OriginCode      string               `json:"origin_code"`
+	DestinationCode string               `json:"destination_code"`
+	TypeID          uint8                `json:"type_id"`
+	WeightKg        float64              `json:"weight_kg"`
+	VolumeM3        float64              `json:"volume_m3"`
+	IsUrgent        bool                 `json:"is_urgent"`
+	HasInsurance    bool                 `json:"has_insurance"`
+	CargoValue      float64              `json:"cargo_value"` // 货物申报价值，用于计算保价费
+	DistanceKm      float64              `json:"distance_km"`
+	Items           []QuoteItem          `json:"items"`
+	Total           float64              `json:"total"`
+	ExpiresAt       time.Time            `json:"expires_at"`
+	OrderID         uint64               `json:"order_id,omitempty"` // 据此报价发布的订单
+	UsedAt          utils.CustomNullTime `json:"used_at"`
+	CreatedAt       utils.CustomNullTime `json:"created_at"`
+}
+
+// QuoteRepository 报价数据访问接口
+type QuoteRepository interface {
+	Create(ctx context.Context, quote *Quote) error
+	// FindByID 不存在时返回 nil, nil
+	FindByID(ctx context.Context, id string) (*Quote, error)
+	// Claim 占用未使用且未过期的报价，返回是否占用成功（并发时只有一次成功）
+	Claim(ctx context.Context, id string, now time.Time) (bool, error)
+	// Release 订单创建失败时释放占用
+	Release(ctx context.Context, id string) error
+	// AttachOrder 记录据此报价发布的订单
+	AttachOrder(ctx context.Context, id string, orderID uint64) error
+}
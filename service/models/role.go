@@ -0,0 +1,52 @@
+package models
+
+// 用户角色
+const (
+	RoleAdmin      = "admin"      // 管理员
+	RoleShipper    = "shipper"    // 发货方
+	RoleCarrier    = "carrier"    // 承运方（司机）
+	RoleDispatcher = "dispatcher" // 调度员
+	RoleUser       = "user"       // 普通用户（历史默认角色，可发货也可接单）
+)
+
+// 权限标识
+const (
+	PermFreightPublish = "freight:publish"  // 发布货运订单
+	PermFreightAccept  = "freight:accept"   // 接单
+	PermFreightViewAll = "freight:view_all" // 查看任意用户的订单
+	PermConfigWrite    = "config:write"     // 修改系统配置
+	PermUserManage     = "user:manage"      // 管理用户（分配角色等）
+	PermSystemMonitor  = "system:monitor"   // 查看系统运行状态（定时任务等）
+	PermChatModerate   = "chat:moderate"    // 查看和参与任意订单的沟通
+	PermWebhookAdmin   = "webhook:admin"    // 查看全部 Webhook 投递记录并手动重新投递
+)
+
+// rolePermissions 角色与权限映射（管理员拥有全部权限，单独处理）
+var rolePermissions = map[string][]string{
+	RoleShipper:    {PermFreightPublish},
+	RoleCarrier:    {PermFreightAccept},
+	RoleDispatcher: {PermFreightPublish, PermFreightAccept, PermFreightViewAll},
+	RoleUser:       {PermFreightPublish, PermFreightAccept},
+}
+
+// ValidRole 判断角色是否已定义
+func ValidRole(role string) bool {
+	if role == RoleAdmin {
+		return true
+	}
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission 判断角色是否拥有指定权限
+func HasPermission(role, perm string) bool {
+	if role == RoleAdmin {
+		return true
+	}
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
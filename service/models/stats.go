@@ -0,0 +1,27 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"freight/utils"
+)
+
+// DailyStats 每日订单统计（由定时任务汇总，重复汇总同一天时覆盖）
+type DailyStats struct {
+	Date            utils.Date           `json:"date" db:"date"`
+	OrdersCreated   int                  `json:"orders_created" db:"orders_created"`     // 当天发布的订单数
+	OrdersAccepted  int                  `json:"orders_accepted" db:"orders_accepted"`   // 当天接单（含定标）的订单数
+	OrdersDelivered int                  `json:"orders_delivered" db:"orders_delivered"` // 当天送达的订单数
+	OrdersConfirmed int                  `json:"orders_confirmed" db:"orders_confirmed"` // 当天确认收货的订单数
+	OrdersCancelled int                  `json:"orders_cancelled" db:"orders_cancelled"`
+	OrdersExpired   int                  `json:"orders_expired" db:"orders_expired"`
+	GMV             float64              `json:"gmv" db:"gmv"` // 当天确认收货订单的运费合计
+	UpdatedAt       utils.CustomNullTime `json:"updated_at" db:"updated_at"`
+}
+
+// StatsRepository 统计数据访问接口
+type StatsRepository interface {
+	// RollupDaily 汇总 [from, to) 内的订单数据并保存为 date 当天的统计
+	RollupDaily(ctx context.Context, date utils.Date, from, to time.Time) (*DailyStats, error)
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"freight/utils"
+)
+
+// RefreshToken 刷新令牌（仅保存哈希值，同一次登录派生的令牌属于同一 family）
+type RefreshToken struct {
+	ID        int64                `json:"id"`
+	UserID    int64                `json:"user_id"`
+	FamilyID  string               `json:"family_id"`
+	TokenHash string               `json:"-"`
+	ExpiresAt time.Time            `json:"expires_at"`
+	RevokedAt utils.CustomNullTime `json:"revoked_at"`
+	CreatedAt utils.CustomNullTime `json:"created_at"`
+}
+
+// 一次性令牌用途
+const (
+	TokenPurposeVerifyEmail   = "verify_email"   // 邮箱验证
+	TokenPurposeResetPassword = "reset_password" // 重置密码
+)
+
+// UserToken 一次性令牌（邮箱验证、重置密码），仅保存哈希值
+type UserToken struct {
+	ID        int64                `json:"id"`
+	UserID    int64                `json:"user_id"`
+	Purpose   string               `json:"purpose"`
+	TokenHash string               `json:"-"`
+	ExpiresAt time.Time            `json:"expires_at"`
+	UsedAt    utils.CustomNullTime `json:"used_at"`
+	CreatedAt utils.CustomNullTime `json:"created_at"`
+}
+
+// TokenPair 登录/刷新返回的令牌对
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"` // 访问令牌有效期（秒）
+}
+
+// TokenRepository 令牌数据访问接口
+type TokenRepository interface {
+	CreateRefreshToken(token *RefreshToken) error
+	FindRefreshToken(tokenHash string) (*RefreshToken, error)
+	// RevokeRefreshToken 仅当令牌未被吊销时吊销，返回是否吊销成功（用于轮换时防止并发重复使用）
+	RevokeRefreshToken(id int64) (bool, error)
+	RevokeFamily(familyID string) error
+	// RevokeUserRefreshTokens 吊销用户的全部刷新令牌（修改密码、注销账号时使用）
+	RevokeUserRefreshTokens(userID int64) error
+	RevokeAccessToken(jti string, expiresAt time.Time) error
+	IsAccessTokenRevoked(jti string) (bool, error)
+	// RevokeUserAccessTokens 使用户在 before 及之前签发的访问令牌全部失效，记录保留到 expiresAt
+	RevokeUserAccessTokens(userID int64, before, expiresAt time.Time) error
+	// UserAccessTokensRevokedBefore 返回用户访问令牌的吊销时间点，未吊销时返回零值
+	UserAccessTokensRevokedBefore(userID int64) (time.Time, error)
+	CreateUserToken(token *UserToken) error
+	// ConsumeUserToken 使用一次性令牌：未使用且未过期时标记为已使用并返回，否则返回nil
+	ConsumeUserToken(purpose, tokenHash string) (*UserToken, error)
+	// PurgeExpired 清理在 before 之前已过期的令牌记录，返回删除条数
+	PurgeExpired(before time.Time) (int64, error)
+}
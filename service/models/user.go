@@ -4,18 +4,27 @@ import (
 	"freight/utils"
 )
 
+// 用户状态
+const (
+	UserStatusActive      = 1 // 正常
+	UserStatusUnverified  = 2 // 邮箱未验证（配置要求验证时不能登录）
+	UserStatusDeactivated = 3 // 已注销（不能登录）
+)
+
 // User 用户模型
 type User struct {
-	ID        int64                `json:"id"`
-	Username  string               `json:"username"`
-	Password  string               `json:"-"` // 不返回密码
-	Email     string               `json:"email"`
-	AvatarURL string               `json:"avatar_url"`
-	Role      string               `json:"role" db:"role"`
-	Six       string               `json:"six" db:"six"`
-	Status    int                  `json:"status"`
-	CreatedAt utils.CustomNullTime `json:"created_at"`
-	UpdatedAt utils.CustomNullTime `json:"updated_at"`
+	ID              int64                `json:"id"`
+	Username        string               `json:"username"`
+	Password        string               `json:"-"` // 不返回密码
+	Email           string               `json:"email"`
+	Phone           string               `json:"phone"`
+	AvatarURL       string               `json:"avatar_url"`
+	Role            string               `json:"role" db:"role"`
+	Six             string               `json:"six" db:"six"`
+	Status          int                  `json:"status"`
+	EmailVerifiedAt utils.CustomNullTime `json:"email_verified_at"` // 邮箱验证时间，未验证为null
+	CreatedAt       utils.CustomNullTime `json:"created_at"`
+	UpdatedAt       utils.CustomNullTime `json:"updated_at"`
 }
 
 // UserRepository 用户数据访问接口
@@ -24,11 +33,7 @@ type UserRepository interface {
 	FindByUsername(username string) (*User, error)
 	FindByEmail(email string) (*User, error)
 	FindByID(id int64) (*User, error)
-}
-
-// UserService 用户服务接口
-type UserService interface {
-	Register(username, password, email string) (*User, error)
-	Login(username, password string) (*User, string, error)
-	GetUserByID(id int64) (*User, error)
+	// Update 更新用户资料（用户名、手机号、头像、性别、角色、状态、邮箱验证时间）
+	Update(user *User) error
+	UpdatePassword(id int64, passwordHash string) error
 }
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"context"
+
+	"freight/utils"
+)
+
+// 车辆类型
+const (
+	VehicleTypeVan          = "van"          // 厢式货车
+	VehicleTypeFlatbed      = "flatbed"      // 平板车
+	VehicleTypeRefrigerated = "refrigerated" // 冷藏车
+)
+
+// VehicleTypes 允许登记的车辆类型
+var VehicleTypes = []string{VehicleTypeVan, VehicleTypeFlatbed, VehicleTypeRefrigerated}
+
+// Vehicle 承运方登记的车辆
+type Vehicle struct {
+	ID         uint64               `json:"id" db:"id"`
+	OwnerID    uint64               `json:"owner_id" db:"owner_id"` // 登记车辆的承运方用户
+	Plate      string               `json:"plate" db:"plate"`       // 车牌号（大写，不含空格和分隔符）
+	Type       string               `json:"type" db:"type"`         // 车辆类型，取值见 VehicleTypes
+	CapacityKg float64              `json:"capacity_kg" db:"capacity_kg"`
+	CapacityM3 float64              `json:"capacity_m3" db:"capacity_m3"`
+	LengthM    float64              `json:"length_m" db:"length_m"` // 货厢长度（米）
+	CreatedAt  utils.CustomNullTime `json:"created_at" db:"created_at"`
+	UpdatedAt  utils.CustomNullTime `json:"updated_at" db:"updated_at"`
+}
+
+// Refrigerated 是否能满足冷链温控要求
+func (v *Vehicle) Refrigerated() bool {
+	return v.Type == VehicleTypeRefrigerated
+}
+
+// VehicleRepository 车辆数据访问接口
+type VehicleRepository interface {
+	Create(ctx context.Context, vehicle *Vehicle) error
+	// GetByID 不存在时返回 nil, nil
+	GetByID(ctx context.Context, id uint64) (*Vehicle, error)
+	// FindByPlate 不存在时返回 nil, nil
+	FindByPlate(ctx context.Context, plate string) (*Vehicle, error)
+	ListByOwner(ctx context.Context, ownerID uint64) ([]*Vehicle, error)
+	Update(ctx context.Context, vehicle *Vehicle) error
+	Delete(ctx context.Context, id uint64) error
+}
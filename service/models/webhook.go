@@ -0,0 +1,76 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// Webhook 投递状态
+const (
+	WebhookPending   = "pending"   // 等待投递或重试
+	WebhookDelivered = "delivered" // 接收方返回 2xx
+	WebhookDead      = "dead"      // 重试次数用尽（死信），可由管理员手动重新投递
+)
+
+// WebhookSubscription 外部系统（如 ERP）订阅的订单事件推送
+type WebhookSubscription struct {
+	ID         uint64    `json:"id" db:"id"`
+	OwnerID    uint64    `json:"owner_id" db:"owner_id"`
+	URL        string    `json:"url" db:"url"`
+	EventTypes []string  `json:"event_types" db:"event_types"` // 订阅的事件类型，取值见 events.OrderEventTypes
+	Secret     string    `json:"secret,omitempty" db:"secret"` // 签名密钥，仅在创建时返回
+	AllOrders  bool      `json:"all_orders" db:"all_orders"`   // 接收全部订单的事件（仅限可查看全部订单的角色），否则只接收本人发布或承运的订单
+	Active     bool      `json:"active" db:"active"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// WebhookDelivery 一次事件投递（含全部重试）
+type WebhookDelivery struct {
+	ID             uint64     `json:"id" db:"id"`
+	SubscriptionID uint64     `json:"subscription_id" db:"subscription_id"`
+	EventType      string     `json:"event_type" db:"event_type"`
+	OrderID        uint64     `json:"order_id" db:"order_id"`
+	Payload        string     `json:"payload" db:"payload"` // 请求体，重试时原样发送
+	Status         string     `json:"status" db:"status"`
+	Attempts       int        `json:"attempts" db:"attempts"`
+	LastStatusCode int        `json:"last_status_code" db:"last_status_code"` // 最近一次的HTTP状态码，连接失败时为0
+	LastError      string     `json:"last_error" db:"last_error"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at" db:"next_attempt_at"`
+	DeliveredAt    *time.Time `json:"delivered_at" db:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// WebhookDeliveryFilter 投递记录查询条件（零值表示不限）
+type WebhookDeliveryFilter struct {
+	SubscriptionID uint64
+	Status         string
+	BeforeID       uint64 // 按ID倒序翻页
+	Limit          int
+}
+
+// WebhookRepository Webhook 订阅和投递记录数据访问接口
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, sub *WebhookSubscription) error
+	// GetSubscription 不存在时返回 nil, nil
+	GetSubscription(ctx context.Context, id uint64) (*WebhookSubscription, error)
+	// ListSubscriptions 按ID升序返回用户的订阅
+	ListSubscriptions(ctx context.Context, ownerID uint64) ([]*WebhookSubscription, error)
+	// ListActiveSubscriptions 返回全部启用的订阅
+	ListActiveSubscriptions(ctx context.Context) ([]*WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, sub *WebhookSubscription) error
+	DeleteSubscription(ctx context.Context, id uint64) error
+
+	// CreateDeliveries 批量保存待投递记录，回填ID
+	CreateDeliveries(ctx context.Context, deliveries []*WebhookDelivery) error
+	// ClaimDueDeliveries 领取最多 limit 条到期的待投递记录，并将其下次投递时间推迟到 leaseUntil，
+	// 避免投递过程中被重复领取
+	ClaimDueDeliveries(ctx context.Context, now time.Time, limit int, leaseUntil time.Time) ([]*WebhookDelivery, error)
+	// UpdateDelivery 保存投递结果（状态、次数、错误和下次投递时间）
+	UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	// GetDelivery 不存在时返回 nil, nil
+	GetDelivery(ctx context.Context, id uint64) (*WebhookDelivery, error)
+	// ListDeliveries 按ID倒序查询投递记录
+	ListDeliveries(ctx context.Context, filter WebhookDeliveryFilter) ([]*WebhookDelivery, error)
+}
@@ -0,0 +1,206 @@
+// Package region 行政区划数据：省/市/区县三级编码（GB/T 2260）及名称，用于校验订单地址编码和按区域过滤。
+//
+// 内置数据（regions.json）收录全部省级和地级行政区，区县级目前只收录直辖市和杭州；
+// 未收录的区县编码只要格式正确且所属地级区划存在即视为有效（见 Resolve），
+// 需要校验区县编码是否真实存在时通过配置 region.data_file 加载同格式的完整数据文件。
+// 省级和地级节点带有中心点坐标（省会或政府驻地），未设置坐标的节点沿用上级坐标。
+package region
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+)
+
+//go:embed regions.json
+var embedded []byte
+
+// Level 行政区划级别
+type Level int
+
+const (
+	LevelProvince Level = 1 // 省、自治区、直辖市
+	LevelCity     Level = 2 // 地级市、自治州、盟
+	LevelDistrict Level = 3 // 区、县、县级市
+)
+
+// Region 行政区划节点
+type Region struct {
+	Code       string    `json:"code"`
+	Name       string    `json:"name"`
+	Level      Level     `json:"level"`
+	ParentCode string    `json:"parent_code,omitempty"`
+	Lat        float64   `json:"lat,omitempty"` // 中心点纬度
+	Lng        float64   `json:"lng,omitempty"` // 中心点经度
+	Children   []*Region `json:"children,omitempty"`
+}
+
+// Tree 行政区划树
+type Tree struct {
+	roots  []*Region
+	byCode map[string]*Region
+}
+
+// Parse 解析区划数据（省级节点数组，子节点放在 children 中），校验编码为6位数字且不重复
+func Parse(data []byte) (*Tree, error) {
+	var roots []*Region
+	if err := json.Unmarshal(data, &roots); err != nil {
+		return nil, fmt.Errorf("解析行政区划数据失败: %v", err)
+	}
+	t := &Tree{roots: roots, byCode: make(map[string]*Region)}
+	if err := t.index(roots, nil, LevelProvince); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// LoadFile 从文件加载区划数据
+func LoadFile(path string) (*Tree, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取行政区划数据失败: %v", err)
+	}
+	return Parse(data)
+}
+
+var (
+	defaultOnce sync.Once
+	defaultTree *Tree
+)
+
+// Default 返回内置的区划数据
+func Default() *Tree {
+	defaultOnce.Do(func() {
+		tree, err := Parse(embedded)
+		if err != nil {
+			panic(err)
+		}
+		defaultTree = tree
+	})
+	return defaultTree
+}
+
+func (t *Tree) index(nodes []*Region, parent *Region, level Level) error {
+	if level > LevelDistrict && len(nodes) > 0 {
+		return fmt.Errorf("行政区划 %s 层级超过三级", parent.Code)
+	}
+	for _, node := range nodes {
+		if !validCode(node.Code) {
+			return fmt.Errorf("行政区划编码 %q 无效", node.Code)
+		}
+		if _, ok := t.byCode[node.Code]; ok {
+			return fmt.Errorf("行政区划编码 %s 重复", node.Code)
+		}
+		if parent != nil && !strings.HasPrefix(node.Code, prefix(parent.Code, parent.Level)) {
+			return fmt.Errorf("行政区划编码 %s 不属于上级 %s", node.Code, parent.Code)
+		}
+		node.Level = level
+		if parent != nil {
+			node.ParentCode = parent.Code
+		}
+		t.byCode[node.Code] = node
+		if err := t.index(node.Children, node, level+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validCode(code string) bool {
+	if len(code) != 6 {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// prefix 区划编码的有效前缀：省级取前2位，地级取前4位，区县取全部6位
+func prefix(code string, level Level) string {
+	switch level {
+	case LevelProvince:
+		return code[:2]
+	case LevelCity:
+		return code[:4]
+	default:
+		return code
+	}
+}
+
+// Roots 返回全部省级节点
+func (t *Tree) Roots() []*Region {
+	return t.roots
+}
+
+// Lookup 按编码查找区划，不存在返回nil
+func (t *Tree) Lookup(code string) *Region {
+	return t.byCode[code]
+}
+
+// Resolve 按编码查找区划；数据中未收录的区县编码（后两位不为00）只要所属地级区划存在，
+// 返回以该地级区划为上级的区县节点（名称为空，不加入数据树）
+func (t *Tree) Resolve(code string) *Region {
+	if r := t.byCode[code]; r != nil {
+		return r
+	}
+	if !validCode(code) || code[4:] == "00" {
+		return nil
+	}
+	city := t.byCode[code[:4]+"00"]
+	if city == nil || city.Level != LevelCity {
+		return nil
+	}
+	return &Region{Code: code, Level: LevelDistrict, ParentCode: city.Code}
+}
+
+// Prefix 返回编码的下级编码公共前缀，用于按省或市过滤其下全部区划；编码不存在时返回空字符串
+func (t *Tree) Prefix(code string) string {
+	r := t.byCode[code]
+	if r == nil {
+		return ""
+	}
+	return prefix(r.Code, r.Level)
+}
+
+// FullName 返回逐级拼接的完整名称，如“浙江省杭州市西湖区”；直辖市的市级与省级同名时只保留一次，
+// 未收录名称的区县只拼接到地级
+func (t *Tree) FullName(code string) string {
+	var names []string
+	for r := t.Resolve(code); r != nil; r = t.byCode[r.ParentCode] {
+		if r.Name == "" || len(names) > 0 && names[0] == r.Name {
+			continue
+		}
+		names = append([]string{r.Name}, names...)
+	}
+	return strings.Join(names, "")
+}
+
+// Centroid 返回区划中心点坐标，本级未设置时沿用最近一级上级的坐标
+func (t *Tree) Centroid(code string) (lat, lng float64, ok bool) {
+	for r := t.Resolve(code); r != nil; r = t.byCode[r.ParentCode] {
+		if r.Lat != 0 || r.Lng != 0 {
+			return r.Lat, r.Lng, true
+		}
+	}
+	return 0, 0, false
+}
+
+// earthRadiusKm 地球平均半径（公里）
+const earthRadiusKm = 6371.0
+
+// Haversine 计算两点间的球面距离（公里）
+func Haversine(lat1, lng1, lat2, lng2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLng := (lng2 - lng1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
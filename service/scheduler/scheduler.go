@@ -0,0 +1,272 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// 默认参数
+const (
+	defaultTimeout = time.Minute
+	leaseSlack     = time.Second // 租约比下一次执行时间提前释放，避免实例间的时钟误差导致跳过一次
+)
+
+var (
+	// ErrDuplicateJob 任务名称重复
+	ErrDuplicateJob = errors.New("任务名称重复")
+	// ErrStarted 调度器已启动，不能再注册任务
+	ErrStarted = errors.New("调度器已启动")
+)
+
+// Job 定时任务
+type Job struct {
+	Name     string
+	Schedule Schedule
+	Timeout  time.Duration // 单次执行超时，超时后取消 ctx；为0时使用默认1分钟
+	Run      func(ctx context.Context) error
+}
+
+// Locker 任务租约：多实例部署时保证同一时间只有一个实例执行某个任务
+type Locker interface {
+	// TryAcquire 当租约空闲、已过期或已由 holder 持有时，将租约交给 holder 直到 until，返回是否获得租约
+	TryAcquire(ctx context.Context, name, holder string, until time.Time) (bool, error)
+}
+
+// Options 调度器配置
+type Options struct {
+	Locker   Locker         // 为空时不使用租约（单实例部署）
+	Holder   string         // 租约持有者标识，默认为 主机名:进程号
+	Location *time.Location // 计算 cron 时间使用的时区，默认为本地时区
+	Logger   *log.Logger    // 默认为标准日志
+}
+
+// JobStatus 任务运行状态
+type JobStatus struct {
+	Name         string     `json:"name"`
+	Schedule     string     `json:"schedule"`
+	Running      bool       `json:"running"`
+	NextRun      *time.Time `json:"next_run"`
+	LastStart    *time.Time `json:"last_start"`
+	LastEnd      *time.Time `json:"last_end"`
+	LastDuration string     `json:"last_duration"`
+	LastError    string     `json:"last_error"`
+	LastSkipped  string     `json:"last_skipped"` // 最近一次跳过执行的原因（如租约被其他实例持有）
+	Runs         int        `json:"runs"`
+	Failures     int        `json:"failures"`
+}
+
+type jobEntry struct {
+	job    Job
+	status JobStatus
+}
+
+// Scheduler 进程内定时任务调度器：每个任务一个协程顺序执行，同一任务不会重叠运行；
+// 执行期间错过的调度时间直接跳过
+type Scheduler struct {
+	opts Options
+
+	mu      sync.Mutex
+	jobs    []*jobEntry
+	started bool
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New 创建调度器
+func New(opts Options) *Scheduler {
+	if opts.Holder == "" {
+		host, _ := os.Hostname()
+		opts.Holder = fmt.Sprintf("%s:%d", host, os.Getpid())
+	}
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+	if opts.Logger == nil {
+		opts.Logger = log.Default()
+	}
+	return &Scheduler{opts: opts}
+}
+
+// Register 注册任务，须在 Start 之前调用
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" || job.Schedule == nil || job.Run == nil {
+		return fmt.Errorf("任务 %q 缺少名称、调度或执行函数", job.Name)
+	}
+	if job.Timeout <= 0 {
+		job.Timeout = defaultTimeout
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return ErrStarted
+	}
+	for _, e := range s.jobs {
+		if e.job.Name == job.Name {
+			return fmt.Errorf("%w: %s", ErrDuplicateJob, job.Name)
+		}
+	}
+	s.jobs = append(s.jobs, &jobEntry{job: job, status: JobStatus{Name: job.Name, Schedule: describe(job.Schedule)}})
+	return nil
+}
+
+// describe 调度的文字描述
+func describe(schedule Schedule) string {
+	if s, ok := schedule.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", schedule)
+}
+
+// Start 启动全部任务；重复调用无效
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	for _, e := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, e)
+	}
+}
+
+// Stop 停止调度并等待正在执行的任务结束（任务的 ctx 会被取消）；ctx 到期时不再等待并返回其错误
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Status 返回全部任务的运行状态（按注册顺序）
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, e := range s.jobs {
+		statuses = append(statuses, e.status)
+	}
+	return statuses
+}
+
+// loop 按调度时间循环执行单个任务，直到调度器停止
+func (s *Scheduler) loop(ctx context.Context, e *jobEntry) {
+	defer s.wg.Done()
+
+	for {
+		next := e.job.Schedule.Next(time.Now().In(s.opts.Location))
+		if next.IsZero() {
+			s.opts.Logger.Printf("任务 %s 没有下一次执行时间，停止调度", e.job.Name)
+			return
+		}
+		s.update(e, func(st *JobStatus) { st.NextRun = &next })
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.runOnce(ctx, e, next)
+	}
+}
+
+// runOnce 获取租约（如启用）后执行一次任务，记录结果
+func (s *Scheduler) runOnce(ctx context.Context, e *jobEntry, scheduled time.Time) {
+	if s.opts.Locker != nil {
+		// 租约持有到下一次调度时间，同一调度时刻其他实例不会重复执行
+		until := e.job.Schedule.Next(scheduled).Add(-leaseSlack)
+		if deadline := time.Now().Add(e.job.Timeout); until.Before(deadline) {
+			until = deadline
+		}
+		ok, err := s.opts.Locker.TryAcquire(ctx, e.job.Name, s.opts.Holder, until)
+		if err != nil || !ok {
+			reason := "租约由其他实例持有"
+			if err != nil {
+				reason = "获取租约失败：" + err.Error()
+			}
+			s.update(e, func(st *JobStatus) { st.LastSkipped = reason })
+			return
+		}
+	}
+
+	start := time.Now()
+	s.update(e, func(st *JobStatus) {
+		st.Running = true
+		st.LastStart = &start
+	})
+
+	err := s.execute(ctx, e.job)
+
+	end := time.Now()
+	s.update(e, func(st *JobStatus) {
+		st.Running = false
+		st.LastEnd = &end
+		st.LastDuration = end.Sub(start).String()
+		st.Runs++
+		st.LastError = ""
+		if err != nil {
+			st.Failures++
+			st.LastError = err.Error()
+		}
+	})
+	if err != nil {
+		s.opts.Logger.Printf("任务 %s 执行失败: %v", e.job.Name, err)
+	}
+}
+
+// execute 在超时控制下执行任务，任务 panic 时转为错误
+func (s *Scheduler) execute(ctx context.Context, job Job) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, job.Timeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			s.opts.Logger.Printf("任务 %s panic: %v\n%s", job.Name, r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	if err := job.Run(ctx); err != nil {
+		return err
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("执行超过 %s", job.Timeout)
+	}
+	return nil
+}
+
+// update 在锁内修改任务状态
+func (s *Scheduler) update(e *jobEntry, fn func(*JobStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&e.status)
+}
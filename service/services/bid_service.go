@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"freight/db"
+	"freight/events"
+	"freight/models"
+	"freight/utils"
+)
+
+// maxBidNoteLength 出价备注最大长度（字符）
+const maxBidNoteLength = 255
+
+var (
+	// ErrBidNotFound 出价不存在或不属于该订单
+	ErrBidNotFound = errors.New("出价不存在")
+	// ErrBiddingDisabled 订单未开启竞价
+	ErrBiddingDisabled = errors.New("订单未开启竞价")
+	// ErrBiddingClosed 订单已不是待接单状态，不能再出价或定标
+	ErrBiddingClosed = errors.New("订单竞价已结束")
+	// ErrBiddingOnly 竞价订单只能由发货方定标，不能直接接单
+	ErrBiddingOnly = errors.New("竞价订单不能直接接单，请出价")
+)
+
+// BidRequest 出价请求
+type BidRequest struct {
+	Amount float64              `json:"amount"`
+	ETA    utils.CustomNullTime `json:"eta"`
+	Note   string               `json:"note"`
+}
+
+// BidService 竞价服务：承运方对待接单的竞价订单出价，发货方选定中标出价
+type BidService interface {
+	PlaceBid(ctx context.Context, orderID, carrierID uint64, req BidRequest) (*models.Bid, error)
+	// ListBids 发货方查看订单的全部出价，其他用户只能看到自己的出价
+	ListBids(ctx context.Context, orderID, userID uint64) ([]*models.Bid, error)
+	ListMyBids(ctx context.Context, carrierID uint64) ([]*models.Bid, error)
+	// AwardBid 定标：订单改为已接单，承运方和价格取自中标出价，其余出价改为未中标
+	AwardBid(ctx context.Context, orderID, bidID, shipperID uint64) (*models.FreightOrder, error)
+}
+
+type bidServiceImpl struct {
+	repo     db.BidRepository
+	freights db.FreightRepository
+	events   events.Publisher
+}
+
+// NewBidService 创建竞价服务实例，定标时通过 publisher 发布接单事件（可为空）
+func NewBidService(repo db.BidRepository, freights db.FreightRepository, publisher events.Publisher) BidService {
+	return &bidServiceImpl{repo: repo, freights: freights, events: publisher}
+}
+
+// biddingOrder 查询开启竞价的订单
+func (s *bidServiceImpl) biddingOrder(ctx context.Context, orderID uint64) (*models.FreightOrder, error) {
+	order, err := s.freights.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, ErrFreightNotFound
+	}
+	if !order.BiddingEnabled {
+		return nil, ErrBiddingDisabled
+	}
+	return order, nil
+}
+
+// PlaceBid 出价，同一承运方重复出价时覆盖原出价
+func (s *bidServiceImpl) PlaceBid(ctx context.Context, orderID, carrierID uint64, req BidRequest) (*models.Bid, error) {
+	order, err := s.biddingOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.ShipperID == carrierID {
+		return nil, ErrPermissionDenied
+	}
+	if order.Status != models.FreightStatusPending {
+		return nil, ErrBiddingClosed
+	}
+
+	req.Note = strings.TrimSpace(req.Note)
+	switch {
+	case req.Amount <= 0:
+		return nil, &FreightFieldError{Field: "amount", Reason: "必须大于0"}
+	case !req.ETA.Valid:
+		return nil, &FreightFieldError{Field: "eta", Reason: "不能为空"}
+	case !req.ETA.Time.After(time.Now()):
+		return nil, &FreightFieldError{Field: "eta", Reason: "必须晚于当前时间"}
+	case utf8.RuneCountInString(req.Note) > maxBidNoteLength:
+		return nil, &FreightFieldError{Field: "note", Reason: "不能超过255个字符"}
+	}
+
+	bid := &models.Bid{
+		OrderID:   orderID,
+		CarrierID: carrierID,
+		Amount:    round2(req.Amount),
+		ETA:       req.ETA,
+		Note:      req.Note,
+	}
+	placed, err := s.repo.Place(ctx, bid)
+	if err != nil {
+		return nil, err
+	}
+	if !placed {
+		return nil, ErrBiddingClosed
+	}
+	return bid, nil
+}
+
+// ListBids 查看订单的出价
+func (s *bidServiceImpl) ListBids(ctx context.Context, orderID, userID uint64) ([]*models.Bid, error) {
+	order, err := s.biddingOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	bids, err := s.repo.ListByOrder(ctx, orderID)
+	if err != nil || order.ShipperID == userID {
+		return bids, err
+	}
+
+	own := []*models.Bid{}
+	for _, bid := range bids {
+		if bid.CarrierID == userID {
+			own = append(own, bid)
+		}
+	}
+	return own, nil
+}
+
+// ListMyBids 查看本人的全部出价
+func (s *bidServiceImpl) ListMyBids(ctx context.Context, carrierID uint64) ([]*models.Bid, error) {
+	return s.repo.ListByCarrier(ctx, carrierID)
+}
+
+// AwardBid 发货方定标（须在取货时间窗口结束前）
+func (s *bidServiceImpl) AwardBid(ctx context.Context, orderID, bidID, shipperID uint64) (*models.FreightOrder, error) {
+	order, err := s.biddingOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.ShipperID != shipperID {
+		return nil, ErrPermissionDenied
+	}
+	if order.Status != models.FreightStatusPending {
+		return nil, ErrBiddingClosed
+	}
+	// 与直接接单一致，取货时间窗口结束后不能再定标（订单将由定时任务标记为过期）
+	if order.PickupLatest != nil && order.PickupLatest.Before(time.Now()) {
+		return nil, ErrPickupWindowClosed
+	}
+
+	bid, err := s.repo.GetByID(ctx, bidID)
+	if err != nil {
+		return nil, err
+	}
+	if bid == nil || bid.OrderID != orderID {
+		return nil, ErrBidNotFound
+	}
+
+	awarded, err := s.repo.Award(ctx, bidID, shipperID)
+	if err != nil {
+		return nil, err
+	}
+	if !awarded {
+		return nil, ErrBiddingClosed
+	}
+	order, err = s.freights.GetByID(ctx, orderID)
+	if err != nil || order == nil {
+		return order, err
+	}
+	publish(s.events, orderEvent(events.TypeOrderAccepted, order, true))
+	return order, nil
+}
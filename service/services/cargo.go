@@ -0,0 +1,72 @@
+package services
+
+import (
+	"fmt"
+
+	"freight/models"
+)
+
+// 货物信息校验参数
+const (
+	maxHazmatClass  = 9     // 危险品类别：GB 6944 第1~9类
+	minColdChainC   = -60.0 // 冷链温控范围下限（℃），覆盖超低温冷冻
+	maxColdChainC   = 30.0  // 冷链温控范围上限（℃）
+	maxCargoDensity = 20000 // 货物密度上限（千克/立方米），超过说明重量或体积填写有误
+)
+
+// validateCargo 校验订单的货物信息：数值不能为负，且相互之间不能矛盾
+func validateCargo(freight *models.FreightOrder) error {
+	for _, f := range []struct {
+		field string
+		value float64
+	}{
+		{"weight_kg", freight.WeightKg},
+		{"volume_m3", freight.VolumeM3},
+		{"pieces", float64(freight.Pieces)},
+		{"length_m", freight.LengthM},
+		{"width_m", freight.WidthM},
+		{"height_m", freight.HeightM},
+	} {
+		if f.value < 0 {
+			return &FreightFieldError{Field: f.field, Reason: "不能为负数"}
+		}
+	}
+
+	if freight.HazmatClass > maxHazmatClass {
+		return &FreightFieldError{Field: "hazmat_class", Reason: "必须是0~9（0表示普通货物）"}
+	}
+
+	// 尺寸描述最大的一件货物，须同时填写长宽高
+	dims := 0
+	for _, v := range []float64{freight.LengthM, freight.WidthM, freight.HeightM} {
+		if v > 0 {
+			dims++
+		}
+	}
+	if dims > 0 && dims < 3 {
+		return &FreightFieldError{Field: "length_m", Reason: "长、宽、高须同时填写"}
+	}
+	if dims == 3 && freight.VolumeM3 > 0 && round2(freight.LengthM*freight.WidthM*freight.HeightM) > freight.VolumeM3 {
+		return &FreightFieldError{Field: "volume_m3", Reason: "小于最大单件的体积（长×宽×高）"}
+	}
+
+	if freight.WeightKg > 0 && freight.VolumeM3 > 0 && freight.WeightKg/freight.VolumeM3 > maxCargoDensity {
+		return &FreightFieldError{Field: "weight_kg", Reason: "与体积不符（密度过大）"}
+	}
+
+	for _, f := range []struct {
+		field string
+		value *float64
+	}{
+		{"temp_min_c", freight.TempMinC},
+		{"temp_max_c", freight.TempMaxC},
+	} {
+		if f.value != nil && (*f.value < minColdChainC || *f.value > maxColdChainC) {
+			return &FreightFieldError{Field: f.field, Reason: fmt.Sprintf("须在 %.0f~%.0f℃ 之间", minColdChainC, maxColdChainC)}
+		}
+	}
+	if freight.TempMinC != nil && freight.TempMaxC != nil && *freight.TempMinC > *freight.TempMaxC {
+		return &FreightFieldError{Field: "temp_min_c", Reason: "不能高于 temp_max_c"}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"freight/db"
+	"freight/events"
+	"freight/models"
+)
+
+// 订单沟通参数
+const (
+	maxChatBodyRunes     = 2000 // 消息正文最多字数
+	maxChatAttachmentLen = 512  // 附件地址最大长度
+	defaultChatPageSize  = 50
+	maxChatPageSize      = 200
+	chatBufferSize       = 256 // 实时推送总线保留的事件数（断线后通过历史接口补齐）
+)
+
+// ErrChatUnavailable 订单尚未被接单，没有可沟通的承运方
+var ErrChatUnavailable = errors.New("订单尚未被接单，不能发起沟通")
+
+// ChatAccess 订单沟通的访问者
+type ChatAccess struct {
+	UserID    uint64
+	Moderator bool // 管理员：可查看和参与任意订单的沟通
+}
+
+// ChatHistory 一页消息及订单双方的已读回执
+type ChatHistory struct {
+	Messages []*models.ChatMessage     `json:"messages"`
+	Receipts []*models.ChatReadReceipt `json:"read_receipts"`
+}
+
+// ChatService 订单接单后发货方与承运方的沟通
+type ChatService interface {
+	// Send 发送消息并实时推送给在线的参与者
+	Send(ctx context.Context, orderID uint64, access ChatAccess, msg *models.ChatMessage) error
+	// History 按ID分页查询消息（afterID 用于重连后补齐，beforeID 用于向前翻页）
+	History(ctx context.Context, orderID uint64, access ChatAccess, beforeID, afterID uint64, limit int) (*ChatHistory, error)
+	// MarkRead 将本人已读位置推进到 upToID（为0表示全部已读），位置推进时推送已读回执；仅订单双方可标记
+	MarkRead(ctx context.Context, orderID uint64, access ChatAccess, upToID uint64) (*models.ChatReadReceipt, error)
+	// UnreadCounts 本人参与订单的未读消息数（按订单）
+	UnreadCounts(ctx context.Context, userID uint64) (map[uint64]int, error)
+	// Subscribe 校验权限后订阅订单的新消息和已读回执
+	Subscribe(ctx context.Context, orderID uint64, access ChatAccess) (*events.Subscription, error)
+	// Unsubscribe 取消订阅
+	Unsubscribe(sub *events.Subscription)
+}
+
+type chatServiceImpl struct {
+	repo     models.ChatRepository
+	freights db.FreightRepository
+	bus      *events.Bus
+}
+
+// NewChatService 创建订单沟通服务实例（实时推送只在本实例内有效）
+func NewChatService(repo models.ChatRepository, freights db.FreightRepository) ChatService {
+	return &chatServiceImpl{repo: repo, freights: freights, bus: events.NewBus(chatBufferSize)}
+}
+
+// chatOrder 查询订单并校验沟通权限：订单已接单，访问者为订单双方或管理员
+func (s *chatServiceImpl) chatOrder(ctx context.Context, orderID uint64, access ChatAccess) (*models.FreightOrder, error) {
+	order, err := s.freights.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, ErrFreightNotFound
+	}
+	party := order.ShipperID == access.UserID || (order.CarrierID != 0 && order.CarrierID == access.UserID)
+	if !party && !access.Moderator {
+		return nil, ErrPermissionDenied
+	}
+	if order.CarrierID == 0 {
+		return nil, ErrChatUnavailable
+	}
+	return order, nil
+}
+
+// validateChatMessage 校验消息类型、正文和附件地址
+func validateChatMessage(msg *models.ChatMessage) *FreightFieldError {
+	msg.Body = strings.TrimSpace(msg.Body)
+	msg.AttachmentURL = strings.TrimSpace(msg.AttachmentURL)
+	if msg.Kind == "" {
+		msg.Kind = models.ChatKindText
+	}
+	switch msg.Kind {
+	case models.ChatKindText:
+		if msg.Body == "" {
+			return &FreightFieldError{Field: "body", Reason: "不能为空"}
+		}
+		if msg.AttachmentURL != "" {
+			return &FreightFieldError{Field: "attachment_url", Reason: "文字消息不能带附件"}
+		}
+	case models.ChatKindImage:
+		if msg.AttachmentURL == "" {
+			return &FreightFieldError{Field: "attachment_url", Reason: "不能为空"}
+		}
+		if len(msg.AttachmentURL) > maxChatAttachmentLen {
+			return &FreightFieldError{Field: "attachment_url", Reason: fmt.Sprintf("不能超过 %d 个字符", maxChatAttachmentLen)}
+		}
+		u, err := url.Parse(msg.AttachmentURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return &FreightFieldError{Field: "attachment_url", Reason: "必须为 http(s) 地址"}
+		}
+	default:
+		return &FreightFieldError{Field: "kind", Reason: "只能为 text 或 image"}
+	}
+	if utf8.RuneCountInString(msg.Body) > maxChatBodyRunes {
+		return &FreightFieldError{Field: "body", Reason: fmt.Sprintf("不能超过 %d 个字", maxChatBodyRunes)}
+	}
+	return nil
+}
+
+// Send 保存消息后推送
+func (s *chatServiceImpl) Send(ctx context.Context, orderID uint64, access ChatAccess, msg *models.ChatMessage) error {
+	if _, err := s.chatOrder(ctx, orderID, access); err != nil {
+		return err
+	}
+	if err := validateChatMessage(msg); err != nil {
+		return err
+	}
+	msg.ID, msg.OrderID, msg.SenderID = 0, orderID, access.UserID
+	msg.CreatedAt = time.Now().Truncate(time.Millisecond)
+	if err := s.repo.AddMessage(ctx, msg); err != nil {
+		return err
+	}
+	copied := *msg
+	s.bus.Publish(events.Event{Type: events.TypeChatMessage, OrderID: orderID, Data: &copied})
+	return nil
+}
+
+// History 查询一页消息和已读回执
+func (s *chatServiceImpl) History(ctx context.Context, orderID uint64, access ChatAccess, beforeID, afterID uint64, limit int) (*ChatHistory, error) {
+	if _, err := s.chatOrder(ctx, orderID, access); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultChatPageSize
+	}
+	if limit > maxChatPageSize {
+		limit = maxChatPageSize
+	}
+	messages, err := s.repo.ListMessages(ctx, orderID, beforeID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	receipts, err := s.repo.ReadReceipts(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return &ChatHistory{Messages: messages, Receipts: receipts}, nil
+}
+
+// MarkRead 推进已读位置；管理员旁观时不产生已读回执
+func (s *chatServiceImpl) MarkRead(ctx context.Context, orderID uint64, access ChatAccess, upToID uint64) (*models.ChatReadReceipt, error) {
+	order, err := s.chatOrder(ctx, orderID, access)
+	if err != nil {
+		return nil, err
+	}
+	if order.ShipperID != access.UserID && order.CarrierID != access.UserID {
+		return nil, ErrPermissionDenied
+	}
+	if upToID == 0 {
+		upToID = math.MaxInt64
+	}
+	receipt, advanced, err := s.repo.MarkRead(ctx, orderID, access.UserID, upToID, time.Now().Truncate(time.Millisecond))
+	if err != nil {
+		return nil, err
+	}
+	if advanced {
+		copied := *receipt
+		s.bus.Publish(events.Event{Type: events.TypeChatRead, OrderID: orderID, Data: &copied})
+	}
+	return receipt, nil
+}
+
+// UnreadCounts 查询未读数
+func (s *chatServiceImpl) UnreadCounts(ctx context.Context, userID uint64) (map[uint64]int, error) {
+	return s.repo.UnreadCounts(ctx, userID)
+}
+
+// Subscribe 订阅单个订单的沟通事件
+func (s *chatServiceImpl) Subscribe(ctx context.Context, orderID uint64, access ChatAccess) (*events.Subscription, error) {
+	if _, err := s.chatOrder(ctx, orderID, access); err != nil {
+		return nil, err
+	}
+	sub, _, _ := s.bus.Subscribe(0, func(e events.Event) bool { return e.OrderID == orderID })
+	return sub, nil
+}
+
+// Unsubscribe 取消订阅
+func (s *chatServiceImpl) Unsubscribe(sub *events.Subscription) {
+	s.bus.Unsubscribe(sub)
+}
@@ -2,10 +2,15 @@
 package services
 
 import (
-	"database/sql"
 	"errors"
 	"freight/models"
-	"time"
+)
+
+var (
+	// ErrConfigNotFound 配置不存在
+	ErrConfigNotFound = errors.New("配置不存在")
+	// ErrConfigExists 配置已存在
+	ErrConfigExists = errors.New("配置已存在")
 )
 
 type ConfigService interface {
@@ -18,121 +23,73 @@ type ConfigService interface {
 }
 
 type configServiceImpl struct {
-	db *sql.DB
+	repo models.ConfigRepository
 }
 
-func NewConfigServiceImpl(db *sql.DB) ConfigService {
-	return &configServiceImpl{db: db}
+func NewConfigServiceImpl(repo models.ConfigRepository) ConfigService {
+	return &configServiceImpl{repo: repo}
 }
 
+// CreateConfig 创建配置，键已存在时返回 ErrConfigExists
 func (s *configServiceImpl) CreateConfig(key, value, description string) error {
-	// 检查配置是否已存在
-	var count int
-	query := "SELECT COUNT(*) FROM configs WHERE `key` = ?"
-	err := s.db.QueryRow(query, key).Scan(&count)
+	existing, err := s.repo.GetByKey(key)
 	if err != nil {
 		return err
 	}
-
-	if count > 0 {
-		return errors.New("配置已存在")
+	if existing != nil {
+		return ErrConfigExists
 	}
 
-	// 创建新配置
-	query = "INSERT INTO configs (`key`, `value`, description, created_at, updated_at) VALUES (?, ?, ?, ?, ?)"
-	_, err = s.db.Exec(query, key, value, description, time.Now(), time.Now())
-	return err
+	return s.repo.Create(&models.Config{Key: key, Value: value, Description: description})
 }
 
+// SetConfig 写入配置：不存在时创建，已存在时覆盖值（description 为空时保留原描述）
 func (s *configServiceImpl) SetConfig(key, value, description string) error {
-	// 检查配置是否已存在
-	var count int
-	query := "SELECT COUNT(*) FROM configs WHERE `key` = ?"
-	err := s.db.QueryRow(query, key).Scan(&count)
+	existing, err := s.repo.GetByKey(key)
 	if err != nil {
 		return err
 	}
-
-	if count > 0 {
-		return errors.New("配置已存在")
+	if existing == nil {
+		return s.repo.Create(&models.Config{Key: key, Value: value, Description: description})
 	}
 
-	// 创建新配置
-	query = "INSERT INTO configs (`key`, `value`, description, created_at, updated_at) VALUES (?, ?, ?, ?, ?)"
-	_, err = s.db.Exec(query, key, value, description, time.Now(), time.Now())
-	return err
+	existing.Value = value
+	if description != "" {
+		existing.Description = description
+	}
+	return s.repo.Update(existing)
 }
 
+// GetConfig 获取配置，不存在时返回 ErrConfigNotFound
 func (s *configServiceImpl) GetConfig(key string) (*models.Config, error) {
-	var config models.Config
-	query := "SELECT id, `key`, `value`, description, created_at, updated_at FROM configs WHERE `key` = ?"
-	err := s.db.QueryRow(query, key).Scan(
-		&config.ID,
-		&config.Key,
-		&config.Value,
-		&config.Description,
-		&config.CreatedAt,
-		&config.UpdatedAt,
-	)
-
+	config, err := s.repo.GetByKey(key)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("配置不存在")
-		}
 		return nil, err
 	}
-
-	return &config, nil
+	if config == nil {
+		return nil, ErrConfigNotFound
+	}
+	return config, nil
 }
 
+// UpdateConfig 更新配置的值和描述
 func (s *configServiceImpl) UpdateConfig(config *models.Config) error {
-	//config.UpdatedAt = time.Now()
-
-	query := "UPDATE configs SET `value` = ?, description = ?, updated_at = ? WHERE `key` = ?"
-	_, err := s.db.Exec(query, config.Value, config.Description, time.Now(), config.Key)
-	return err
+	return s.repo.Update(config)
 }
 
+// DeleteConfig 删除配置，不存在时返回 ErrConfigNotFound
 func (s *configServiceImpl) DeleteConfig(key string) error {
-	// 检查配置是否存在
-	_, err := s.GetConfig(key)
+	deleted, err := s.repo.Delete(key)
 	if err != nil {
 		return err
 	}
-
-	// 删除配置
-	query := "DELETE FROM configs WHERE `key` = ?"
-	_, err = s.db.Exec(query, key)
-	return err
+	if !deleted {
+		return ErrConfigNotFound
+	}
+	return nil
 }
 
+// ListConfigs 获取配置列表
 func (s *configServiceImpl) ListConfigs() ([]*models.Config, error) {
-	query := "SELECT id, `key`, `value`, description, created_at, updated_at FROM configs ORDER BY created_at DESC"
-	rows, err := s.db.Query(query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var configs []*models.Config
-	for rows.Next() {
-		var config models.Config
-		if err := rows.Scan(
-			&config.ID,
-			&config.Key,
-			&config.Value,
-			&config.Description,
-			&config.CreatedAt,
-			&config.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-		configs = append(configs, &config)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return configs, nil
+	return s.repo.List()
 }
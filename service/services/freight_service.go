@@ -5,8 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"freight/db"
+	"freight/events"
 	"freight/models"
-	"freight/utils"
+	"freight/region"
+	"log"
+	"strings"
 	"time"
 )
 
@@ -14,43 +17,129 @@ import (
 type FreightService interface {
 	CreateFreight(ctx context.Context, freight *models.FreightOrder) error
 	GetFreightByID(ctx context.Context, id uint64) (*models.FreightOrder, error)
-	ListFreights(ctx context.Context, filter models.FreightFilter) ([]*models.FreightOrder, error)
-	UpdateFreight(ctx context.Context, freight *models.FreightOrder) error
-	DeleteFreight(ctx context.Context, id uint64) error // 新增删除方法
-	AcceptOrder(ctx context.Context, orderID, userID uint64) error
-	ListByUserID(ctx context.Context, userID uint64, filter models.FreightFilter) ([]*models.FreightOrder, error) // 新增
+	ListFreights(ctx context.Context, filter models.FreightFilter) (*models.FreightPage, error)
+	SearchFreights(ctx context.Context, query string, filter models.FreightFilter) (*models.FreightPage, error)
+	UpdateFreight(ctx context.Context, freight *models.FreightOrder, userID uint64) error
+	DeleteFreight(ctx context.Context, id, userID uint64) error // 新增删除方法
+	// AcceptOrder 接单，vehicleID 为承运车辆（0 表示不指定），指定时校验车辆能否承运货物
+	AcceptOrder(ctx context.Context, orderID, userID, vehicleID uint64) error
+	ListByUserID(ctx context.Context, userID uint64, filter models.FreightFilter) (*models.FreightPage, error) // 新增
 	CompleteOrder(ctx context.Context, orderID uint64, userID uint64) error
+	TransitionOrder(ctx context.Context, orderID, actorID uint64, to uint8, reason string) error
+	// GetOrderHistory 订单状态时间线，仅订单双方可查看；viewAll 表示可查看任意订单
+	GetOrderHistory(ctx context.Context, orderID, userID uint64, viewAll bool) ([]*models.FreightOrderEvent, error)
+	// ExpireOverdueOrders 将取货时间已过仍未接单的订单标记为已过期，返回过期的订单数
+	ExpireOverdueOrders(ctx context.Context, now time.Time) (int, error)
 }
 
+var (
+	// ErrEmptySearchQuery 搜索关键词为空
+	ErrEmptySearchQuery = errors.New("搜索关键词不能为空")
+	// ErrFreightNotFound 订单不存在或已删除
+	ErrFreightNotFound = errors.New("货运订单不存在")
+	// ErrOrderTaken 订单已被其他用户接单
+	ErrOrderTaken = errors.New("订单已被其他用户接单")
+	// ErrOrderNotEditable 订单已被接单，不能再修改
+	ErrOrderNotEditable = errors.New("订单已被接单，不能修改")
+	// ErrOrderNotDeletable 订单已被接单或已结束，不能删除（已接单的订单须走取消流程）
+	ErrOrderNotDeletable = errors.New("订单已被接单或已结束，不能删除，请通过取消流程处理")
+	// ErrPickupWindowClosed 取货时间窗口已结束，订单不能再接单
+	ErrPickupWindowClosed = errors.New("已超过取货时间，不能接单")
+)
+
 // FreightServiceImpl 货运订单服务实现
 type FreightServiceImpl struct {
 	//db   *sql.DB
-	repo db.FreightRepository // 替换原来的 *sql.DB，用仓储接口
+	repo     db.FreightRepository // 替换原来的 *sql.DB，用仓储接口
+	search   db.FreightSearchIndex
+	regions  *region.Tree
+	routes   RouteEstimator
+	vehicles models.VehicleRepository
+	events   events.Publisher // 订单变化通知，可为空
 }
 
-// NewFreightService 创建货运订单服务实例
-func NewFreightService(repo db.FreightRepository) FreightService {
-	return &FreightServiceImpl{repo: repo}
+// NewFreightService 创建货运订单服务实例，publisher 为空时不发布订单事件
+func NewFreightService(repo db.FreightRepository, search db.FreightSearchIndex, regions *region.Tree, routes RouteEstimator,
+	vehicles models.VehicleRepository, publisher events.Publisher) FreightService {
+	return &FreightServiceImpl{repo: repo, search: search, regions: regions, routes: routes, vehicles: vehicles, events: publisher}
 }
 
-// CreateFreight 创建货运订单
+// FreightFieldError 订单字段校验失败
+type FreightFieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *FreightFieldError) Error() string {
+	return fmt.Sprintf("%s 无效：%s", e.Field, e.Reason)
+}
+
+// CreateFreight 创建货运订单：出发地、目的地编码须为市级或区县级区划，地点名称按区划填写；
+// 时间窗口依赖预计行驶时长，在路线估算之后校验
 func (s *FreightServiceImpl) CreateFreight(ctx context.Context, freight *models.FreightOrder) error {
-	if freight.OriginLocation == "" {
-		return errors.New("出发地不能为空")
+	origin, err := resolveRegion(s.regions, "origin_code", freight.OriginCode)
+	if err != nil {
+		return err
 	}
-	if freight.OriginCode == "" {
-		return errors.New("出发地不能为空")
+	destination, err := resolveRegion(s.regions, "destination_code", freight.DestinationCode)
+	if err != nil {
+		return err
 	}
-	if freight.DestinationLocation == "" {
-		return errors.New("目的地不能为空")
+	if freight.Price <= 0 {
+		return &FreightFieldError{Field: "price", Reason: "价格必须大于0"}
 	}
-	if freight.DestinationCode == "" {
-		return errors.New("目的地不能为空")
+	if err := validateCargo(freight); err != nil {
+		return err
 	}
-	if freight.Price <= 0 {
-		return errors.New("价格必须大于0")
+	s.applyRoute(ctx, freight, origin, destination)
+	if err := validateTimeWindows(freight, time.Now()); err != nil {
+		return err
 	}
-	return s.repo.Create(ctx, freight)
+	if err := s.repo.Create(ctx, freight); err != nil {
+		return err
+	}
+	publish(s.events, orderEvent(events.TypeOrderCreated, freight, true))
+	return nil
+}
+
+// applyRoute 按区划编码填写地点名称，并估算里程和时长；估算失败不影响发布，里程和时长保持为0
+func (s *FreightServiceImpl) applyRoute(ctx context.Context, freight *models.FreightOrder, origin, destination *region.Region) {
+	freight.OriginLocation = s.regions.FullName(origin.Code)
+	freight.DestinationLocation = s.regions.FullName(destination.Code)
+
+	freight.DistanceKm, freight.EstimatedHours = 0, 0
+	if estimate, err := s.routes.Estimate(ctx, origin.Code, destination.Code); err != nil {
+		log.Printf("估算路线失败 %s -> %s: %v", origin.Code, destination.Code, err)
+	} else {
+		freight.DistanceKm, freight.EstimatedHours = estimate.DistanceKm, estimate.EstimatedHours
+	}
+}
+
+// resolveRegion 校验订单地址编码：须为市级或区县级区划（未收录的区县按所属地级区划校验），
+// 不设地级区划的省级单位（香港、澳门、台湾）可直接使用省级编码
+func resolveRegion(regions *region.Tree, field, code string) (*region.Region, error) {
+	if code == "" {
+		return nil, &FreightFieldError{Field: field, Reason: "不能为空"}
+	}
+	r := regions.Resolve(code)
+	if r == nil {
+		return nil, &FreightFieldError{Field: field, Reason: "不是有效的行政区划编码"}
+	}
+	if r.Level == region.LevelProvince && len(r.Children) > 0 {
+		return nil, &FreightFieldError{Field: field, Reason: "须精确到市或区县"}
+	}
+	return r, nil
+}
+
+// expandRegions 省级或市级编码展开为前缀过滤，匹配其下全部区划；区县或未知编码仍按精确匹配
+func (s *FreightServiceImpl) expandRegions(filter models.FreightFilter) models.FreightFilter {
+	if r := s.regions.Lookup(filter.OriginCode); r != nil && r.Level != region.LevelDistrict {
+		filter.OriginCodePrefix, filter.OriginCode = s.regions.Prefix(r.Code), ""
+	}
+	if r := s.regions.Lookup(filter.DestinationCode); r != nil && r.Level != region.LevelDistrict {
+		filter.DestinationCodePrefix, filter.DestinationCode = s.regions.Prefix(r.Code), ""
+	}
+	return filter
 }
 
 // GetFreightByID 获取单个货运订单
@@ -58,72 +147,257 @@ func (s *FreightServiceImpl) GetFreightByID(ctx context.Context, id uint64) (*mo
 	return s.repo.GetByID(ctx, id)
 }
 
-// ListFreights 列出货运订单
-func (s *FreightServiceImpl) ListFreights(ctx context.Context, filter models.FreightFilter) ([]*models.FreightOrder, error) {
-	return s.repo.List(ctx, filter)
+// ListFreights 分页列出货运订单，指定车辆时只列出该车辆能承运的订单
+func (s *FreightServiceImpl) ListFreights(ctx context.Context, filter models.FreightFilter) (*models.FreightPage, error) {
+	fits := true
+	if filter.VehicleID != 0 {
+		vehicle, err := ownedVehicle(ctx, s.vehicles, filter.VehicleID, filter.VehicleOwnerID)
+		if err != nil {
+			return nil, err
+		}
+		filter, fits = vehicleFilter(vehicle, filter)
+	}
+	filter = pickupWithin(filter, time.Now())
+	return listPage(s.expandRegions(filter), func(f models.FreightFilter) ([]*models.FreightOrder, int64, error) {
+		if !fits {
+			return nil, 0, nil
+		}
+		return s.repo.List(ctx, f)
+	})
 }
 
-// UpdateFreight 更新货运订单
-func (s *FreightServiceImpl) UpdateFreight(ctx context.Context, freight *models.FreightOrder) error {
+// SearchFreights 按关键词全文检索订单，默认按相关度排序（不支持游标模式）
+func (s *FreightServiceImpl) SearchFreights(ctx context.Context, query string, filter models.FreightFilter) (*models.FreightPage, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, ErrEmptySearchQuery
+	}
+	filter.CursorMode, filter.After = false, nil
+	filter = pickupWithin(filter, time.Now())
+	return listPage(s.expandRegions(filter), func(f models.FreightFilter) ([]*models.FreightOrder, int64, error) {
+		return s.search.Search(ctx, query, f)
+	})
+}
+
+// UpdateFreight 更新货运订单（仅发货方可修改待接单的订单）：地点通过区划编码修改，
+// 名称、里程和时长按新路线重新生成，并重新校验能否按时送达
+func (s *FreightServiceImpl) UpdateFreight(ctx context.Context, freight *models.FreightOrder, userID uint64) error {
 	// 检查订单是否存在（可选）
 	existing, err := s.repo.GetByID(ctx, freight.ID)
 	if err != nil {
 		return err
 	}
 	if existing == nil {
-		return errors.New("货运订单不存在")
+		return ErrFreightNotFound
+	}
+	if existing.ShipperID != userID {
+		return ErrPermissionDenied
+	}
+	if existing.Status != models.FreightStatusPending {
+		return ErrOrderNotEditable
+	}
+	// 状态只能通过状态机流转，避免绕过流转校验和记录
+	if freight.Status != 0 && freight.Status != existing.Status {
+		return ErrStatusNotEditable
+	}
+	if err := s.updateRoute(ctx, freight, existing); err != nil {
+		return err
+	}
+	freight.Status = 0
+	ok, err := s.repo.Update(ctx, freight)
+	if err != nil {
+		return err
 	}
-	return s.repo.Update(ctx, freight)
+	// 读取后订单可能已被并发接单或删除
+	if !ok {
+		return ErrOrderNotEditable
+	}
+	return nil
 }
 
-// DeleteFreight 删除货运订单（实现缺失的方法）
-func (s *FreightServiceImpl) DeleteFreight(ctx context.Context, id uint64) error {
-	// 检查订单是否存在（可选）
+// updateRoute 处理修改中的地点：未修改编码时保留原路线，地点名称不能单独修改；
+// 修改任一编码时按新编码校验区划、生成名称并重新估算路线
+func (s *FreightServiceImpl) updateRoute(ctx context.Context, freight, existing *models.FreightOrder) error {
+	if freight.OriginCode == "" && freight.DestinationCode == "" {
+		if freight.OriginLocation != "" && freight.OriginLocation != existing.OriginLocation {
+			return &FreightFieldError{Field: "origin_location", Reason: "由 origin_code 决定，不能单独修改"}
+		}
+		if freight.DestinationLocation != "" && freight.DestinationLocation != existing.DestinationLocation {
+			return &FreightFieldError{Field: "destination_location", Reason: "由 destination_code 决定，不能单独修改"}
+		}
+		freight.OriginLocation, freight.DestinationLocation = "", ""
+		return nil
+	}
+
+	if freight.OriginCode == "" {
+		freight.OriginCode = existing.OriginCode
+	}
+	if freight.DestinationCode == "" {
+		freight.DestinationCode = existing.DestinationCode
+	}
+	origin, err := resolveRegion(s.regions, "origin_code", freight.OriginCode)
+	if err != nil {
+		return err
+	}
+	destination, err := resolveRegion(s.regions, "destination_code", freight.DestinationCode)
+	if err != nil {
+		return err
+	}
+	originName, destinationName := freight.OriginLocation, freight.DestinationLocation
+	s.applyRoute(ctx, freight, origin, destination)
+	if originName != "" && originName != freight.OriginLocation {
+		return &FreightFieldError{Field: "origin_location", Reason: "由 origin_code 决定，不能单独修改"}
+	}
+	if destinationName != "" && destinationName != freight.DestinationLocation {
+		return &FreightFieldError{Field: "destination_location", Reason: "由 destination_code 决定，不能单独修改"}
+	}
+
+	// 时间窗口不随修改变化，按原窗口校验新路线能否按时送达
+	merged := *existing
+	merged.DistanceKm, merged.EstimatedHours = freight.DistanceKm, freight.EstimatedHours
+	return validateTransit(&merged, time.Now())
+}
+
+// DeleteFreight 删除货运订单（仅发货方可删除待接单的订单，接单后只能按状态机取消）
+func (s *FreightServiceImpl) DeleteFreight(ctx context.Context, id, userID uint64) error {
+	// 检查订单是否存在
 	freight, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return err
 	}
 	if freight == nil {
-		return errors.New("货运订单不存在")
+		return ErrFreightNotFound
+	}
+	if freight.ShipperID != userID {
+		return ErrPermissionDenied
 	}
-	return s.repo.Delete(ctx, id)
+	if freight.Status != models.FreightStatusPending {
+		return ErrOrderNotDeletable
+	}
+	ok, err := s.repo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrStatusConflict
+	}
+	return nil
 }
 
-// AcceptOrder 接单逻辑：复用Update方法更新指定字段
-func (s *FreightServiceImpl) AcceptOrder(ctx context.Context, orderID, userID uint64) error {
-	// 1. 查询订单是否存在且状态为“待接单”
+// AcceptOrder 接单逻辑：待接单 → 已接单，并记录接单用户和承运车辆（并发接单时仅一人成功）
+func (s *FreightServiceImpl) AcceptOrder(ctx context.Context, orderID, userID, vehicleID uint64) error {
+	// 1. 查询订单是否存在
 	order, err := s.repo.GetByID(ctx, orderID)
 	if err != nil {
 		return err
 	}
 	if order == nil {
-		return errors.New("订单不存在")
+		return ErrFreightNotFound
 	}
 
-	// 2. 验证订单状态（假设1为待接单，2为运输中）
-	if order.Status != 1 {
-		return errors.New("订单状态不允许接单（仅待接单状态可接单）")
+	// 2. 发货方不能接自己的订单；校验状态机（已被接走的订单直接返回冲突）
+	if order.ShipperID == userID {
+		return ErrPermissionDenied
+	}
+	if order.BiddingEnabled {
+		return ErrBiddingOnly
+	}
+	if order.Status == models.FreightStatusAccepted {
+		return ErrOrderTaken
+	}
+	if order.Status == models.FreightStatusPending && order.PickupLatest != nil && order.PickupLatest.Before(time.Now()) {
+		return ErrPickupWindowClosed
+	}
+	if err := ValidateTransition(order.Status, models.FreightStatusAccepted); err != nil {
+		return err
 	}
-	fmt.Println("OrderDate", order.OrderDate)
-	// 3. 构建仅包含需要更新的字段的订单对象
-	updateOrder := &models.FreightOrder{
-		ID:        orderID,                    // 必须指定ID，用于Update方法定位订单
-		UserID:    userID,                     // 更新接单用户ID
-		Status:    2,                          // 状态改为“运输中”
-		UpdatedAt: utils.FromTime(time.Now()), // 更新时间
-		OrderDate: order.OrderDate,            // 关键：保留原订单的 order_date
+
+	// 3. 指定车辆时须为本人车辆，且能承运订单货物
+	if vehicleID != 0 {
+		vehicle, err := ownedVehicle(ctx, s.vehicles, vehicleID, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkVehicleFits(vehicle, order); err != nil {
+			return err
+		}
 	}
 
-	// 4. 调用Update方法更新订单
-	return s.repo.Update(ctx, updateOrder)
+	// 4. 条件更新抢单，失败说明已被其他用户抢先
+	ok, err := s.repo.AcceptPending(ctx, orderID, userID, vehicleID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrOrderTaken
+	}
+	order.Status, order.CarrierID, order.VehicleID = models.FreightStatusAccepted, userID, vehicleID
+	publish(s.events, orderEvent(events.TypeOrderAccepted, order, true))
+	return nil
 }
 
 // 实现 ListByUserID 方法
-func (s *FreightServiceImpl) ListByUserID(ctx context.Context, userID uint64, filter models.FreightFilter) ([]*models.FreightOrder, error) {
-	return s.repo.ListByUserID(ctx, userID, filter)
+func (s *FreightServiceImpl) ListByUserID(ctx context.Context, userID uint64, filter models.FreightFilter) (*models.FreightPage, error) {
+	return listPage(s.expandRegions(filter), func(f models.FreightFilter) ([]*models.FreightOrder, int64, error) {
+		return s.repo.ListByUserID(ctx, userID, f)
+	})
+}
+
+// listPage 规范分页参数（默认第1页、每页 DefaultPageSize 条，最多 MaxPageSize 条）并组装分页结果。
+// 游标模式多取一条用于判断是否还有下一页
+func listPage(filter models.FreightFilter, fetch func(models.FreightFilter) ([]*models.FreightOrder, int64, error)) (*models.FreightPage, error) {
+	pageSize := filter.PageSize
+	if pageSize <= 0 {
+		pageSize = models.DefaultPageSize
+	}
+	if pageSize > models.MaxPageSize {
+		pageSize = models.MaxPageSize
+	}
+
+	if filter.CursorMode {
+		filter.Page = 0
+		filter.PageSize = pageSize + 1
+		items, total, err := fetch(filter)
+		if err != nil {
+			return nil, err
+		}
+		page := &models.FreightPage{Total: total, PageSize: pageSize}
+		if len(items) > pageSize {
+			items = items[:pageSize]
+			page.HasMore = true
+			last := items[len(items)-1]
+			page.NextCursor = models.FreightCursor{UpdatedAt: last.UpdatedAt.Time, ID: last.ID}.Encode()
+		}
+		page.Items = nonNilFreights(items)
+		return page, nil
+	}
+
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	filter.PageSize = pageSize
+	items, total, err := fetch(filter)
+	if err != nil {
+		return nil, err
+	}
+	return &models.FreightPage{
+		Items:    nonNilFreights(items),
+		Total:    total,
+		Page:     filter.Page,
+		PageSize: pageSize,
+		HasMore:  int64((filter.Page-1)*pageSize+len(items)) < total,
+	}, nil
 }
 
-// CompleteOrder 完成订单（仅接单用户可操作，状态从“运输中”转为“已完成”）
+// nonNilFreights 空结果返回空数组而不是 null
+func nonNilFreights(items []*models.FreightOrder) []*models.FreightOrder {
+	if items == nil {
+		return []*models.FreightOrder{}
+	}
+	return items
+}
+
+// CompleteOrder 完成订单（仅承运方可操作，状态从“运输中”转为“已送达”）
 func (s *FreightServiceImpl) CompleteOrder(ctx context.Context, orderID uint64, userID uint64) error {
 	// 新增：参数合法性校验（防止非整数userID）
 	if userID == 0 {
@@ -139,40 +413,79 @@ func (s *FreightServiceImpl) CompleteOrder(ctx context.Context, orderID uint64,
 		return fmt.Errorf("查询订单失败：%w", err) // 使用%w包装原始错误
 	}
 	if order == nil {
-		return errors.New("订单不存在或已被删除")
-	}
-
-	// 2. 验证订单状态（仅“运输中”可完成，状态码 2→3）
-	if order.Status != 2 {
-		return errors.New("订单状态不允许完成（仅运输中状态可操作）")
-	}
-
-	// 3. 验证操作权限（仅接单用户可完成订单）
-	if order.UserID != userID {
-		return errors.New("无权操作：仅接单用户可完成订单")
-	}
-
-	// 4. 构建更新对象（仅修改必要字段）
-	updateOrder := &models.FreightOrder{
-		UpdatedAt: utils.FromTime(time.Now()), // 更新时间
-		// 复用原始订单的其他字段（避免Update方法覆盖非必要字段）
-		OriginLocation:      order.OriginLocation,
-		OriginCode:          order.OriginCode,
-		DestinationLocation: order.DestinationLocation,
-		DestinationCode:     order.DestinationCode,
-		Type:                order.Type,
-		TypeID:              order.TypeID,
-		Remark:              order.Remark,
-		OrderDate:           order.OrderDate,
-		Price:               order.Price,
-		Status:              3, // 状态改为“已完成”
-		IsUrgent:            order.IsUrgent,
-		HasInsurance:        order.HasInsurance,
-		ID:                  orderID,
-		Email:               order.Email,
-		UserID:              order.UserID, // 保持接单用户ID不变
-	}
-
-	// 5. 调用仓储层更新
-	return s.repo.Update(ctx, updateOrder)
+		return ErrFreightNotFound
+	}
+
+	// 2. 验证操作权限（仅承运方可完成订单）
+	if err := checkTransitionActor(order, models.FreightStatusDelivered, userID); err != nil {
+		return err
+	}
+
+	// 3. 按状态机流转（仅运输中可变为已送达）
+	return s.transition(ctx, order, models.FreightStatusDelivered, userID, "确认送达")
+}
+
+// TransitionOrder 按状态机变更订单状态
+func (s *FreightServiceImpl) TransitionOrder(ctx context.Context, orderID, actorID uint64, to uint8, reason string) error {
+	// 接单需要同时记录接单用户，统一走接单流程
+	if to == models.FreightStatusAccepted {
+		return s.AcceptOrder(ctx, orderID, actorID, 0)
+	}
+
+	order, err := s.repo.GetByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if order == nil {
+		return ErrFreightNotFound
+	}
+	if err := checkTransitionActor(order, to, actorID); err != nil {
+		return err
+	}
+
+	return s.transition(ctx, order, to, actorID, reason)
+}
+
+// GetOrderHistory 获取订单状态流转时间线（含各次操作人），仅发货方、承运方或可查看全部订单的角色可见
+func (s *FreightServiceImpl) GetOrderHistory(ctx context.Context, orderID, userID uint64, viewAll bool) ([]*models.FreightOrderEvent, error) {
+	order, err := s.repo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, ErrFreightNotFound
+	}
+	party := order.ShipperID == userID || (order.CarrierID != 0 && order.CarrierID == userID)
+	if !party && !viewAll {
+		return nil, ErrPermissionDenied
+	}
+	return s.repo.ListEvents(ctx, orderID)
+}
+
+// transition 校验并执行状态流转，同时写入流转记录
+func (s *FreightServiceImpl) transition(ctx context.Context, order *models.FreightOrder, to uint8, actorID uint64, reason string) error {
+	if err := ValidateTransition(order.Status, to); err != nil {
+		return err
+	}
+
+	event := &models.FreightOrderEvent{
+		OrderID:    order.ID,
+		FromStatus: order.Status,
+		ToStatus:   to,
+		ActorID:    actorID,
+		Reason:     reason,
+	}
+	ok, err := s.repo.UpdateStatus(ctx, event)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrStatusConflict
+	}
+
+	// 待接单订单的状态变化（取消、过期）同时意味着从货源大厅下架
+	hall := order.Status == models.FreightStatusPending
+	order.Status = to
+	publish(s.events, orderEvent(statusEventType(to), order, hall))
+	return nil
 }
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"freight/models"
+)
+
+// freightTransitions 订单状态机：key 为当前状态，value 为允许流转到的状态
+//
+//	待接单 → 已接单 → 已取货 → 运输中 → 已送达 → 已确认收货
+//	待接单可取消或过期；接单后至确认收货前可发起争议，争议可确认收货或取消
+var freightTransitions = map[uint8][]uint8{
+	models.FreightStatusPending:   {models.FreightStatusAccepted, models.FreightStatusCancelled, models.FreightStatusExpired},
+	models.FreightStatusAccepted:  {models.FreightStatusPickedUp, models.FreightStatusCancelled, models.FreightStatusDisputed},
+	models.FreightStatusPickedUp:  {models.FreightStatusShipping, models.FreightStatusDisputed},
+	models.FreightStatusShipping:  {models.FreightStatusDelivered, models.FreightStatusDisputed},
+	models.FreightStatusDelivered: {models.FreightStatusConfirmed, models.FreightStatusDisputed},
+	models.FreightStatusDisputed:  {models.FreightStatusConfirmed, models.FreightStatusCancelled},
+	models.FreightStatusConfirmed: {},
+	models.FreightStatusCancelled: {},
+	models.FreightStatusExpired:   {},
+}
+
+// SystemActorID 系统操作（定时任务等）使用的操作人ID
+const SystemActorID uint64 = 0
+
+// 状态流转的操作方
+const (
+	actorShipper = 1 << iota // 发货方
+	actorCarrier             // 承运方
+	actorSystem              // 系统
+)
+
+// transitionActors 流转到各状态时允许的操作方（接单由 AcceptOrder 单独处理）
+var transitionActors = map[uint8]int{
+	models.FreightStatusPickedUp:  actorCarrier,
+	models.FreightStatusShipping:  actorCarrier,
+	models.FreightStatusDelivered: actorCarrier,
+	models.FreightStatusConfirmed: actorShipper,
+	models.FreightStatusCancelled: actorShipper | actorSystem,
+	models.FreightStatusExpired:   actorSystem,
+	models.FreightStatusDisputed:  actorShipper | actorCarrier,
+}
+
+// freightStatusNames 状态名称（用于错误信息和时间线展示）
+var freightStatusNames = map[uint8]string{
+	models.FreightStatusPending:   "待接单",
+	models.FreightStatusAccepted:  "已接单",
+	models.FreightStatusPickedUp:  "已取货",
+	models.FreightStatusShipping:  "运输中",
+	models.FreightStatusDelivered: "已送达",
+	models.FreightStatusConfirmed: "已确认收货",
+	models.FreightStatusCancelled: "已取消",
+	models.FreightStatusExpired:   "已过期",
+	models.FreightStatusDisputed:  "争议中",
+}
+
+var (
+	// ErrStatusConflict 订单状态已被其他请求修改
+	ErrStatusConflict = errors.New("订单状态已变更，请刷新后重试")
+	// ErrStatusNotEditable 不允许通过更新接口直接修改状态
+	ErrStatusNotEditable = errors.New("订单状态只能通过状态流转接口修改")
+	// ErrPermissionDenied 操作人不是订单的相应参与方
+	ErrPermissionDenied = errors.New("无权操作该订单")
+)
+
+// UnknownStatusError 未定义的订单状态
+type UnknownStatusError struct {
+	Status uint8
+}
+
+func (e *UnknownStatusError) Error() string {
+	return fmt.Sprintf("未知的订单状态：%d", e.Status)
+}
+
+// IllegalTransitionError 不允许的状态流转
+type IllegalTransitionError struct {
+	From uint8
+	To   uint8
+}
+
+func (e *IllegalTransitionError) Error() string {
+	return fmt.Sprintf("订单状态不允许从「%s」变更为「%s」", FreightStatusName(e.From), FreightStatusName(e.To))
+}
+
+// FreightStatusName 返回状态名称，未知状态返回数字
+func FreightStatusName(status uint8) string {
+	if name, ok := freightStatusNames[status]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", status)
+}
+
+// CanTransition 判断状态是否允许从 from 流转到 to
+func CanTransition(from, to uint8) bool {
+	return ValidateTransition(from, to) == nil
+}
+
+// ValidateTransition 校验状态流转，非法时返回 *UnknownStatusError 或 *IllegalTransitionError
+func ValidateTransition(from, to uint8) error {
+	if _, ok := freightTransitions[to]; !ok {
+		return &UnknownStatusError{Status: to}
+	}
+	next, ok := freightTransitions[from]
+	if !ok {
+		return &UnknownStatusError{Status: from}
+	}
+	for _, s := range next {
+		if s == to {
+			return nil
+		}
+	}
+	return &IllegalTransitionError{From: from, To: to}
+}
+
+// checkTransitionActor 校验操作人是否为允许执行该流转的参与方
+func checkTransitionActor(order *models.FreightOrder, to uint8, actorID uint64) error {
+	var actor int
+	switch {
+	case actorID == SystemActorID:
+		actor = actorSystem
+	case actorID == order.ShipperID:
+		actor = actorShipper
+	case actorID == order.CarrierID:
+		actor = actorCarrier
+	}
+	if transitionActors[to]&actor == 0 {
+		return ErrPermissionDenied
+	}
+	return nil
+}
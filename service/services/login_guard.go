@@ -0,0 +1,181 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"freight/models"
+)
+
+// 登录防护默认阈值（配置未设置时使用）
+const (
+	defaultMaxAccountFailures = 5
+	defaultMaxIPFailures      = 20
+	defaultFailureWindow      = 15 * time.Minute
+	defaultLockoutDuration    = 15 * time.Minute
+)
+
+var (
+	// ErrInvalidCredentials 登录失败的统一提示，不区分账号不存在和密码错误
+	ErrInvalidCredentials = errors.New("用户名或密码错误")
+	// ErrTooManyAttempts 账号已锁定或该IP失败次数过多
+	ErrTooManyAttempts = errors.New("登录失败次数过多，请稍后再试")
+)
+
+// LoginGuardOptions 登录防护阈值
+type LoginGuardOptions struct {
+	MaxAccountFailures int           // 窗口内单个账号允许的失败次数，达到后锁定账号
+	MaxIPFailures      int           // 窗口内单个IP允许的失败次数，达到后拒绝该IP登录
+	FailureWindow      time.Duration // 失败计数的滑动窗口
+	LockoutDuration    time.Duration // 账号锁定时长
+}
+
+// LoginGuard 登录暴力破解防护：按账号和IP统计失败次数，超过阈值后临时锁定
+type LoginGuard struct {
+	repo  models.LoginAttemptRepository
+	audit models.AuditRepository
+	opts  LoginGuardOptions
+}
+
+// NewLoginGuard 创建登录防护实例，阈值为0时使用默认值
+func NewLoginGuard(repo models.LoginAttemptRepository, audit models.AuditRepository, opts LoginGuardOptions) *LoginGuard {
+	if opts.MaxAccountFailures <= 0 {
+		opts.MaxAccountFailures = defaultMaxAccountFailures
+	}
+	if opts.MaxIPFailures <= 0 {
+		opts.MaxIPFailures = defaultMaxIPFailures
+	}
+	if opts.FailureWindow <= 0 {
+		opts.FailureWindow = defaultFailureWindow
+	}
+	if opts.LockoutDuration <= 0 {
+		opts.LockoutDuration = defaultLockoutDuration
+	}
+	return &LoginGuard{repo: repo, audit: audit, opts: opts}
+}
+
+// AccountSubject 已存在账号的锁定键
+func AccountSubject(userID int64) string {
+	return "user:" + strconv.FormatInt(userID, 10)
+}
+
+// LoginSubject 不存在账号的锁定键（按登录名计数，使不存在的账号与真实账号表现一致）
+func LoginSubject(loginID string) string {
+	return "login:" + strings.ToLower(strings.TrimSpace(loginID))
+}
+
+// CheckIP 检查IP在窗口内的失败次数是否超过阈值
+func (g *LoginGuard) CheckIP(ip string) error {
+	if ip == "" {
+		return nil
+	}
+	count, err := g.repo.CountFailures(models.LoginScopeIP, ip, time.Now().Add(-g.opts.FailureWindow))
+	if err != nil {
+		return err
+	}
+	if count >= g.opts.MaxIPFailures {
+		return ErrTooManyAttempts
+	}
+	return nil
+}
+
+// CheckAccount 检查账号是否处于锁定期
+func (g *LoginGuard) CheckAccount(subject string) error {
+	until, ok, err := g.repo.LockedUntil(subject)
+	if err != nil {
+		return err
+	}
+	if ok && time.Now().Before(until) {
+		return ErrTooManyAttempts
+	}
+	return nil
+}
+
+// Fail 记录一次登录失败，达到阈值时锁定账号并写入审计记录
+func (g *LoginGuard) Fail(subject, ip string) error {
+	now := time.Now()
+	since := now.Add(-g.opts.FailureWindow)
+
+	if err := g.repo.RecordFailure(models.LoginScopeAccount, subject, now); err != nil {
+		return err
+	}
+	count, err := g.repo.CountFailures(models.LoginScopeAccount, subject, since)
+	if err != nil {
+		return err
+	}
+	if count >= g.opts.MaxAccountFailures {
+		if err := g.repo.Lock(subject, now.Add(g.opts.LockoutDuration)); err != nil {
+			return err
+		}
+		// 锁定后重新计数，解锁或到期后重新获得完整的尝试次数
+		if err := g.repo.ClearFailures(models.LoginScopeAccount, subject); err != nil {
+			return err
+		}
+		g.record(&models.AuditLog{
+			Action:  models.AuditAccountLocked,
+			Subject: subject,
+			IP:      ip,
+			Detail:  fmt.Sprintf("%d 次登录失败，锁定至 %s", count, now.Add(g.opts.LockoutDuration).Format(time.RFC3339)),
+		})
+	}
+
+	if ip == "" {
+		return nil
+	}
+	if err := g.repo.RecordFailure(models.LoginScopeIP, ip, now); err != nil {
+		return err
+	}
+	ipCount, err := g.repo.CountFailures(models.LoginScopeIP, ip, since)
+	if err != nil {
+		return err
+	}
+	// 只在刚达到阈值时记录一次，避免持续攻击刷满审计表
+	if ipCount == g.opts.MaxIPFailures {
+		g.record(&models.AuditLog{
+			Action:  models.AuditIPBlocked,
+			Subject: ip,
+			IP:      ip,
+			Detail:  fmt.Sprintf("%d 次登录失败", ipCount),
+		})
+	}
+	return nil
+}
+
+// Succeed 登录成功后清除账号的失败计数
+func (g *LoginGuard) Succeed(subject string) error {
+	return g.repo.ClearFailures(models.LoginScopeAccount, subject)
+}
+
+// Unlock 管理员解除账号锁定，返回账号此前是否处于锁定状态
+func (g *LoginGuard) Unlock(userID, adminID int64) (bool, error) {
+	subject := AccountSubject(userID)
+	unlocked, err := g.repo.Unlock(subject)
+	if err != nil {
+		return false, err
+	}
+	if err := g.repo.ClearFailures(models.LoginScopeAccount, subject); err != nil {
+		return false, err
+	}
+	if unlocked {
+		g.record(&models.AuditLog{
+			Action:  models.AuditAccountUnlocked,
+			ActorID: adminID,
+			Subject: subject,
+		})
+	}
+	return unlocked, nil
+}
+
+// record 写入审计记录，失败只记日志，不影响登录流程
+func (g *LoginGuard) record(entry *models.AuditLog) {
+	if g.audit == nil {
+		return
+	}
+	if err := g.audit.Create(entry); err != nil {
+		log.Printf("写入审计记录失败 action=%s subject=%s: %v", entry.Action, entry.Subject, err)
+	}
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"freight/events"
+	"freight/models"
+)
+
+// publish 发布事件，未配置事件总线时忽略
+func publish(publisher events.Publisher, e events.Event) {
+	if publisher != nil {
+		publisher.Publish(e)
+	}
+}
+
+// orderEvent 以订单快照构造事件
+func orderEvent(eventType string, order *models.FreightOrder, hall bool) events.Event {
+	snapshot := *order
+	return events.Event{
+		Type:      eventType,
+		OrderID:   order.ID,
+		Data:      &snapshot,
+		ShipperID: order.ShipperID,
+		CarrierID: order.CarrierID,
+		Hall:      hall,
+	}
+}
+
+// statusEventType 状态变更对应的事件类型
+func statusEventType(to uint8) string {
+	switch to {
+	case models.FreightStatusAccepted:
+		return events.TypeOrderAccepted
+	case models.FreightStatusDelivered:
+		return events.TypeOrderCompleted
+	case models.FreightStatusCancelled:
+		return events.TypeOrderCancelled
+	default:
+		return events.TypeOrderStatus
+	}
+}
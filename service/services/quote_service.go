@@ -0,0 +1,278 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"freight/models"
+	"freight/region"
+	"freight/utils"
+)
+
+// QuoteRateConfigKey 报价规则在配置服务中的键，值为 RateTable 的JSON，未配置的字段使用默认值
+const QuoteRateConfigKey = "pricing.rate_table"
+
+var (
+	// ErrQuoteNotFound 报价不存在或不属于当前用户
+	ErrQuoteNotFound = errors.New("报价不存在")
+	// ErrQuoteExpired 报价已过期
+	ErrQuoteExpired = errors.New("报价已过期，请重新报价")
+	// ErrQuoteUsed 报价已用于发布订单
+	ErrQuoteUsed = errors.New("报价已使用")
+	// ErrInvalidRateTable 配置中的报价规则无法解析或取值无效
+	ErrInvalidRateTable = errors.New("报价规则配置无效")
+)
+
+// RateTable 报价规则
+type RateTable struct {
+	PerKm           float64           `json:"per_km"`           // 每公里基础运价（元）
+	PerTonKm        float64           `json:"per_ton_km"`       // 每吨公里运价（元），按计费重量计算
+	VolumeTonRatio  float64           `json:"volume_ton_ratio"` // 每立方米折算吨数，计费重量取实重与体积重的较大值
+	TypeMultipliers map[uint8]float64 `json:"type_multipliers"` // 货物类型系数，键为 type_id，未配置的类型按1计算
+	UrgentRate      float64           `json:"urgent_rate"`      // 加急附加费比例（基础运费与类型调整之和）
+	InsuranceRate   float64           `json:"insurance_rate"`   // 保价费率（货物申报价值）
+	InsuranceMin    float64           `json:"insurance_min"`    // 最低保价费
+	MinimumFee      float64           `json:"minimum_fee"`      // 起步价
+	ValidMinutes    int               `json:"valid_minutes"`    // 报价有效期（分钟）
+}
+
+// DefaultRateTable 未配置报价规则时使用的默认值
+func DefaultRateTable() RateTable {
+	return RateTable{
+		PerKm:           2.0,
+		PerTonKm:        0.5,
+		VolumeTonRatio:  0.333,
+		TypeMultipliers: map[uint8]float64{},
+		UrgentRate:      0.2,
+		InsuranceRate:   0.003,
+		InsuranceMin:    20,
+		MinimumFee:      200,
+		ValidMinutes:    30,
+	}
+}
+
+// QuoteRequest 报价请求
+type QuoteRequest struct {
+	OriginCode      string  `json:"origin_code"`
+	DestinationCode string  `json:"destination_code"`
+	TypeID          uint8   `json:"type_id"`
+	WeightKg        float64 `json:"weight_kg"`
+	VolumeM3        float64 `json:"volume_m3"`
+	IsUrgent        bool    `json:"is_urgent"`
+	HasInsurance    bool    `json:"has_insurance"`
+	CargoValue      float64 `json:"cargo_value"`
+}
+
+// QuoteService 运费报价服务
+type QuoteService interface {
+	CreateQuote(ctx context.Context, shipperID uint64, req QuoteRequest) (*models.Quote, error)
+	GetQuote(ctx context.Context, id string, shipperID uint64) (*models.Quote, error)
+	// CreateOrderFromQuote 按报价发布订单：地址、货物类型、加急、保价和价格取自报价，其余字段取自 order
+	CreateOrderFromQuote(ctx context.Context, id string, shipperID uint64, order *models.FreightOrder) error
+}
+
+type quoteServiceImpl struct {
+	repo     models.QuoteRepository
+	configs  ConfigService
+	regions  *region.Tree
+	routes   RouteEstimator
+	freights FreightService
+}
+
+// NewQuoteService 创建报价服务实例
+func NewQuoteService(repo models.QuoteRepository, configs ConfigService, regions *region.Tree, routes RouteEstimator, freights FreightService) QuoteService {
+	return &quoteServiceImpl{repo: repo, configs: configs, regions: regions, routes: routes, freights: freights}
+}
+
+// rateTable 读取配置中的报价规则，未配置时使用默认值
+func (s *quoteServiceImpl) rateTable() (RateTable, error) {
+	rates := DefaultRateTable()
+	config, err := s.configs.GetConfig(QuoteRateConfigKey)
+	if errors.Is(err, ErrConfigNotFound) {
+		return rates, nil
+	}
+	if err != nil {
+		return rates, err
+	}
+	if err := json.Unmarshal([]byte(config.Value), &rates); err != nil {
+		return rates, fmt.Errorf("%w: %v", ErrInvalidRateTable, err)
+	}
+	if err := rates.validate(); err != nil {
+		return rates, fmt.Errorf("%w: %v", ErrInvalidRateTable, err)
+	}
+	return rates, nil
+}
+
+// validate 校验报价规则：费率和金额不能为负数，货物类型系数和报价有效期必须大于0
+func (r RateTable) validate() error {
+	for _, f := range []struct {
+		field string
+		value float64
+	}{
+		{"per_km", r.PerKm},
+		{"per_ton_km", r.PerTonKm},
+		{"volume_ton_ratio", r.VolumeTonRatio},
+		{"urgent_rate", r.UrgentRate},
+		{"insurance_rate", r.InsuranceRate},
+		{"insurance_min", r.InsuranceMin},
+		{"minimum_fee", r.MinimumFee},
+	} {
+		if f.value < 0 {
+			return fmt.Errorf("%s 不能为负数", f.field)
+		}
+	}
+	for typeID, m := range r.TypeMultipliers {
+		if m <= 0 {
+			return fmt.Errorf("type_multipliers[%d] 必须大于0", typeID)
+		}
+	}
+	if r.ValidMinutes <= 0 {
+		return errors.New("valid_minutes 必须大于0")
+	}
+	return nil
+}
+
+// CreateQuote 计算分项报价并保存
+func (s *quoteServiceImpl) CreateQuote(ctx context.Context, shipperID uint64, req QuoteRequest) (*models.Quote, error) {
+	origin, err := resolveRegion(s.regions, "origin_code", req.OriginCode)
+	if err != nil {
+		return nil, err
+	}
+	destination, err := resolveRegion(s.regions, "destination_code", req.DestinationCode)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case req.WeightKg < 0:
+		return nil, &FreightFieldError{Field: "weight_kg", Reason: "不能为负数"}
+	case req.VolumeM3 < 0:
+		return nil, &FreightFieldError{Field: "volume_m3", Reason: "不能为负数"}
+	case req.HasInsurance && req.CargoValue <= 0:
+		return nil, &FreightFieldError{Field: "cargo_value", Reason: "保价时必须填写货物价值"}
+	}
+
+	rates, err := s.rateTable()
+	if err != nil {
+		return nil, err
+	}
+	route, err := s.routes.Estimate(ctx, origin.Code, destination.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	quote := &models.Quote{
+		ID:              id,
+		ShipperID:       shipperID,
+		OriginCode:      origin.Code,
+		DestinationCode: destination.Code,
+		TypeID:          req.TypeID,
+		WeightKg:        req.WeightKg,
+		VolumeM3:        req.VolumeM3,
+		IsUrgent:        req.IsUrgent,
+		HasInsurance:    req.HasInsurance,
+		CargoValue:      req.CargoValue,
+		DistanceKm:      route.DistanceKm,
+		ExpiresAt:       time.Now().Add(time.Duration(rates.ValidMinutes) * time.Minute),
+	}
+	quote.Items, quote.Total = priceQuote(rates, quote)
+
+	if err := s.repo.Create(ctx, quote); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+// priceQuote 按报价规则计算明细和合计（金额保留两位小数）
+func priceQuote(rates RateTable, q *models.Quote) ([]models.QuoteItem, float64) {
+	tons := math.Max(q.WeightKg/1000, q.VolumeM3*rates.VolumeTonRatio)
+	base := round2(q.DistanceKm * (rates.PerKm + rates.PerTonKm*tons))
+	items := []models.QuoteItem{{Code: models.QuoteItemBase, Name: "基础运费", Amount: base}}
+	total := base
+
+	if multiplier, ok := rates.TypeMultipliers[q.TypeID]; ok && multiplier != 1 {
+		amount := round2(base * (multiplier - 1))
+		items = append(items, models.QuoteItem{Code: models.QuoteItemType, Name: "货物类型调整", Amount: amount})
+		total += amount
+	}
+	if q.IsUrgent && rates.UrgentRate > 0 {
+		amount := round2(total * rates.UrgentRate)
+		items = append(items, models.QuoteItem{Code: models.QuoteItemUrgent, Name: "加急附加费", Amount: amount})
+		total += amount
+	}
+	if q.HasInsurance {
+		amount := round2(math.Max(q.CargoValue*rates.InsuranceRate, rates.InsuranceMin))
+		items = append(items, models.QuoteItem{Code: models.QuoteItemInsurance, Name: "保价费", Amount: amount})
+		total += amount
+	}
+	if total < rates.MinimumFee {
+		amount := round2(rates.MinimumFee - total)
+		items = append(items, models.QuoteItem{Code: models.QuoteItemMinimumFee, Name: "起步价补足", Amount: amount})
+		total += amount
+	}
+	return items, round2(total)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// GetQuote 查询本人的报价
+func (s *quoteServiceImpl) GetQuote(ctx context.Context, id string, shipperID uint64) (*models.Quote, error) {
+	quote, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if quote == nil || quote.ShipperID != shipperID {
+		return nil, ErrQuoteNotFound
+	}
+	return quote, nil
+}
+
+// CreateOrderFromQuote 占用报价后发布订单，发布失败时释放报价
+func (s *quoteServiceImpl) CreateOrderFromQuote(ctx context.Context, id string, shipperID uint64, order *models.FreightOrder) error {
+	quote, err := s.GetQuote(ctx, id, shipperID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	switch {
+	case quote.UsedAt.Valid:
+		return ErrQuoteUsed
+	case !quote.ExpiresAt.After(now):
+		return ErrQuoteExpired
+	}
+
+	claimed, err := s.repo.Claim(ctx, id, now)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrQuoteUsed
+	}
+
+	order.ShipperID = shipperID
+	order.CarrierID = 0
+	order.OriginCode = quote.OriginCode
+	order.DestinationCode = quote.DestinationCode
+	order.TypeID = quote.TypeID
+	order.IsUrgent = quote.IsUrgent
+	order.HasInsurance = quote.HasInsurance
+	order.WeightKg = quote.WeightKg
+	order.VolumeM3 = quote.VolumeM3
+	order.Price = quote.Total
+	if err := s.freights.CreateFreight(ctx, order); err != nil {
+		if releaseErr := s.repo.Release(ctx, id); releaseErr != nil {
+			return fmt.Errorf("%w（释放报价失败: %v）", err, releaseErr)
+		}
+		return err
+	}
+	return s.repo.AttachOrder(ctx, id, order.ID)
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"freight/region"
+)
+
+// 路线估算默认参数（配置未设置时使用）
+const (
+	defaultDetourFactor    = 1.3  // 公路里程约为直线距离的1.3倍
+	defaultAverageSpeedKmh = 60.0 // 货车平均行驶速度（公里/小时）
+)
+
+// ErrRouteUnavailable 区划缺少坐标，无法估算路线
+var ErrRouteUnavailable = errors.New("无法估算路线")
+
+// RouteEstimate 路线估算结果
+type RouteEstimate struct {
+	DistanceKm     float64 // 公路里程（公里）
+	EstimatedHours float64 // 预计行驶时长（小时）
+}
+
+// RouteEstimator 估算两个区划之间的公路里程和行驶时长，可替换为真实的路径规划服务
+type RouteEstimator interface {
+	Estimate(ctx context.Context, originCode, destinationCode string) (*RouteEstimate, error)
+	// EstimateFrom 估算从当前坐标到目的地区划的剩余里程和时长（用于运输途中重新计算预计到达时间）
+	EstimateFrom(ctx context.Context, lat, lng float64, destinationCode string) (*RouteEstimate, error)
+}
+
+// RouteOptions 直线距离估算参数
+type RouteOptions struct {
+	DetourFactor    float64 // 绕行系数：公路里程 = 直线距离 × 绕行系数
+	AverageSpeedKmh float64 // 平均行驶速度（公里/小时）
+}
+
+// HaversineEstimator 按区划中心点的球面距离乘以绕行系数估算公路里程
+type HaversineEstimator struct {
+	regions *region.Tree
+	opts    RouteOptions
+}
+
+var _ RouteEstimator = (*HaversineEstimator)(nil)
+
+// NewHaversineEstimator 创建直线距离估算器，参数为0时使用默认值
+func NewHaversineEstimator(regions *region.Tree, opts RouteOptions) *HaversineEstimator {
+	if opts.DetourFactor <= 0 {
+		opts.DetourFactor = defaultDetourFactor
+	}
+	if opts.AverageSpeedKmh <= 0 {
+		opts.AverageSpeedKmh = defaultAverageSpeedKmh
+	}
+	return &HaversineEstimator{regions: regions, opts: opts}
+}
+
+// Estimate 估算路线，结果保留一位小数
+func (e *HaversineEstimator) Estimate(ctx context.Context, originCode, destinationCode string) (*RouteEstimate, error) {
+	lat1, lng1, ok := e.regions.Centroid(originCode)
+	if !ok {
+		return nil, ErrRouteUnavailable
+	}
+	return e.EstimateFrom(ctx, lat1, lng1, destinationCode)
+}
+
+// EstimateFrom 按当前坐标到目的地区划中心点的球面距离估算
+func (e *HaversineEstimator) EstimateFrom(ctx context.Context, lat, lng float64, destinationCode string) (*RouteEstimate, error) {
+	lat2, lng2, ok := e.regions.Centroid(destinationCode)
+	if !ok {
+		return nil, ErrRouteUnavailable
+	}
+
+	distance := region.Haversine(lat, lng, lat2, lng2) * e.opts.DetourFactor
+	return &RouteEstimate{
+		DistanceKm:     round1(distance),
+		EstimatedHours: round1(distance / e.opts.AverageSpeedKmh),
+	}, nil
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"freight/models"
+	"freight/utils"
+)
+
+// StatsService 运营统计
+type StatsService interface {
+	// RollupDay 汇总 day 所在自然日（按 day 的时区）的订单统计，可重复执行
+	RollupDay(ctx context.Context, day time.Time) (*models.DailyStats, error)
+}
+
+type statsServiceImpl struct {
+	repo models.StatsRepository
+}
+
+// NewStatsService 创建统计服务实例
+func NewStatsService(repo models.StatsRepository) StatsService {
+	return &statsServiceImpl{repo: repo}
+}
+
+// RollupDay 汇总当天 00:00 至次日 00:00 的数据
+func (s *statsServiceImpl) RollupDay(ctx context.Context, day time.Time) (*models.DailyStats, error) {
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	to := from.AddDate(0, 0, 1)
+	return s.repo.RollupDaily(ctx, utils.NewDate(from.Year(), int(from.Month()), from.Day()), from, to)
+}
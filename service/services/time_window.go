@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"freight/models"
+)
+
+// 时间窗口校验参数
+const (
+	timeWindowClockSkew = 5 * time.Minute // 允许客户端与服务器之间的时钟误差
+	expireBatchSize     = 100             // 过期任务每批处理的订单数
+)
+
+// validateTimeWindows 校验取货和送达时间窗口：起止须成对填写且不在过去，
+// 送达不早于取货，并且按预计行驶时长能够按时送达；须在路线估算之后调用
+func validateTimeWindows(freight *models.FreightOrder, now time.Time) error {
+	if err := validateWindow("pickup", freight.PickupEarliest, freight.PickupLatest, now); err != nil {
+		return err
+	}
+	if err := validateWindow("delivery", freight.DeliveryEarliest, freight.DeliveryLatest, now); err != nil {
+		return err
+	}
+	if freight.DeliveryLatest == nil {
+		return nil
+	}
+	if freight.PickupEarliest != nil && freight.DeliveryEarliest.Before(*freight.PickupEarliest) {
+		return &FreightFieldError{Field: "delivery_earliest", Reason: "不能早于 pickup_earliest"}
+	}
+	return validateTransit(freight, now)
+}
+
+// validateTransit 校验按预计行驶时长能否在 delivery_latest 之前送达；修改路线后单独调用，
+// 已开始的取货窗口不再按“不能早于当前时间”拒绝
+func validateTransit(freight *models.FreightOrder, now time.Time) error {
+	if freight.DeliveryLatest == nil {
+		return nil
+	}
+	departure := now
+	if freight.PickupEarliest != nil && freight.PickupEarliest.After(now) {
+		departure = *freight.PickupEarliest
+	}
+	transit := time.Duration(freight.EstimatedHours * float64(time.Hour))
+	if arrival := departure.Add(transit); freight.DeliveryLatest.Before(arrival) {
+		return &FreightFieldError{
+			Field:  "delivery_latest",
+			Reason: fmt.Sprintf("预计行驶 %.1f 小时，最早 %s 才能送达", freight.EstimatedHours, arrival.In(freight.DeliveryLatest.Location()).Format(time.RFC3339)),
+		}
+	}
+	return nil
+}
+
+// validateWindow 校验单个时间窗口，prefix 为字段名前缀（pickup、delivery）
+func validateWindow(prefix string, earliest, latest *time.Time, now time.Time) error {
+	switch {
+	case earliest == nil && latest == nil:
+		return nil
+	case earliest == nil:
+		return &FreightFieldError{Field: prefix + "_earliest", Reason: "须与 " + prefix + "_latest 同时填写"}
+	case latest == nil:
+		return &FreightFieldError{Field: prefix + "_latest", Reason: "须与 " + prefix + "_earliest 同时填写"}
+	case earliest.Before(now.Add(-timeWindowClockSkew)):
+		return &FreightFieldError{Field: prefix + "_earliest", Reason: "不能早于当前时间"}
+	case latest.Before(*earliest):
+		return &FreightFieldError{Field: prefix + "_latest", Reason: "不能早于 " + prefix + "_earliest"}
+	}
+	return nil
+}
+
+// pickupWithin 将“未来N小时内可取货”展开为取货时间范围
+func pickupWithin(filter models.FreightFilter, now time.Time) models.FreightFilter {
+	if filter.PickupWithinHours <= 0 {
+		return filter
+	}
+	to := now.Add(time.Duration(filter.PickupWithinHours) * time.Hour)
+	filter.PickupFrom, filter.PickupTo = &now, &to
+	return filter
+}
+
+// ExpireOverdueOrders 将取货时间窗口已结束仍未接单的订单标记为已过期，返回处理的订单数；
+// 与接单并发时以条件更新为准，已被接走的订单跳过
+func (s *FreightServiceImpl) ExpireOverdueOrders(ctx context.Context, now time.Time) (int, error) {
+	expired := 0
+	for {
+		orders, err := s.repo.ListPickupOverdue(ctx, now, expireBatchSize)
+		if err != nil {
+			return expired, err
+		}
+		progressed := false
+		for _, order := range orders {
+			err := s.transition(ctx, order, models.FreightStatusExpired, SystemActorID, "超过取货时间未接单")
+			switch {
+			case err == nil:
+				expired++
+				progressed = true
+			case errors.Is(err, ErrStatusConflict):
+				log.Printf("订单 %d 状态已变化，跳过过期处理", order.ID)
+			default:
+				return expired, err
+			}
+		}
+		if len(orders) < expireBatchSize || !progressed {
+			return expired, nil
+		}
+	}
+}
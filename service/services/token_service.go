@@ -0,0 +1,196 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"freight/models"
+	"freight/utils"
+)
+
+// 令牌默认有效期（配置未设置时使用）
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+var (
+	// ErrInvalidRefreshToken 刷新令牌无效或已过期
+	ErrInvalidRefreshToken = errors.New("刷新令牌无效或已过期")
+	// ErrRefreshTokenReused 已轮换的刷新令牌被再次使用，整个令牌族已被吊销
+	ErrRefreshTokenReused = errors.New("刷新令牌已被使用，请重新登录")
+)
+
+// TokenService 令牌服务接口：签发访问/刷新令牌、轮换刷新令牌和吊销
+type TokenService interface {
+	Issue(user *models.User) (*models.TokenPair, error)
+	Refresh(refreshToken string) (*models.TokenPair, error)
+	Logout(userID int64, jti string, accessExpiresAt time.Time, refreshToken string) error
+	RevokeUser(userID int64) error
+	RevokeAllSessions(userID int64) error
+	IsRevoked(jti string, userID int64, issuedAt time.Time) (bool, error)
+}
+
+type tokenServiceImpl struct {
+	repo       models.TokenRepository
+	users      models.UserRepository
+	secret     string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// NewTokenService 创建令牌服务实例，ttl 为0时使用默认有效期
+func NewTokenService(repo models.TokenRepository, users models.UserRepository, secret string, accessTTL, refreshTTL time.Duration) TokenService {
+	if accessTTL <= 0 {
+		accessTTL = defaultAccessTokenTTL
+	}
+	if refreshTTL <= 0 {
+		refreshTTL = defaultRefreshTokenTTL
+	}
+	return &tokenServiceImpl{
+		repo:       repo,
+		users:      users,
+		secret:     secret,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+	}
+}
+
+// Issue 登录时签发令牌对，并开启新的刷新令牌族
+func (s *tokenServiceImpl) Issue(user *models.User) (*models.TokenPair, error) {
+	familyID, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(user, familyID)
+}
+
+// Refresh 使用刷新令牌换取新的令牌对（旧刷新令牌立即失效；重复使用将吊销整个令牌族）
+func (s *tokenServiceImpl) Refresh(refreshToken string) (*models.TokenPair, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	token, err := s.repo.FindRefreshToken(utils.HashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if token.RevokedAt.Valid {
+		if err := s.repo.RevokeFamily(token.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// 条件吊销失败说明并发请求已抢先轮换，同样按重复使用处理
+	ok, err := s.repo.RevokeRefreshToken(token.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := s.repo.RevokeFamily(token.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	user, err := s.users.FindByID(token.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.Status == models.UserStatusDeactivated {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return s.issue(user, token.FamilyID)
+}
+
+// Logout 吊销当前访问令牌，并吊销刷新令牌所在的令牌族；不属于当前用户的刷新令牌忽略
+func (s *tokenServiceImpl) Logout(userID int64, jti string, accessExpiresAt time.Time, refreshToken string) error {
+	if jti != "" {
+		if err := s.repo.RevokeAccessToken(jti, accessExpiresAt); err != nil {
+			return err
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+	token, err := s.repo.FindRefreshToken(utils.HashToken(refreshToken))
+	if err != nil {
+		return err
+	}
+	if token == nil || token.UserID != userID {
+		return nil
+	}
+	return s.repo.RevokeFamily(token.FamilyID)
+}
+
+// RevokeUser 吊销用户的全部刷新令牌，使其他设备上的登录在访问令牌过期后失效
+func (s *tokenServiceImpl) RevokeUser(userID int64) error {
+	return s.repo.RevokeUserRefreshTokens(userID)
+}
+
+// RevokeAllSessions 吊销用户的全部刷新令牌，并使此前签发的访问令牌立即失效（角色变更、注销账号时使用）
+func (s *tokenServiceImpl) RevokeAllSessions(userID int64) error {
+	if err := s.repo.RevokeUserRefreshTokens(userID); err != nil {
+		return err
+	}
+	now := time.Now()
+	return s.repo.RevokeUserAccessTokens(userID, now, now.Add(s.accessTTL))
+}
+
+// IsRevoked 判断访问令牌是否已被吊销：单个令牌已登出，或签发时间不晚于用户的吊销时间点
+func (s *tokenServiceImpl) IsRevoked(jti string, userID int64, issuedAt time.Time) (bool, error) {
+	if jti != "" {
+		revoked, err := s.repo.IsAccessTokenRevoked(jti)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+	before, err := s.repo.UserAccessTokensRevokedBefore(userID)
+	if err != nil {
+		return false, err
+	}
+	return !before.IsZero() && !issuedAt.After(before), nil
+}
+
+// issue 签发访问令牌并在指定令牌族中生成新的刷新令牌
+func (s *tokenServiceImpl) issue(user *models.User, familyID string) (*models.TokenPair, error) {
+	role := user.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+
+	accessToken, _, err := utils.GenerateJWT(user.ID, user.Username, role, s.secret, s.accessTTL)
+	if err != nil {
+		return nil, errors.New("生成认证令牌失败")
+	}
+
+	refreshToken, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateRefreshToken(&models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: time.Now().Add(s.refreshTTL),
+	}); err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.accessTTL / time.Second),
+	}, nil
+}
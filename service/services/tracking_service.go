@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"freight/db"
+	"freight/events"
+	"freight/models"
+)
+
+// 定位上报参数
+const (
+	maxPingBatch      = 500                // 单次最多上报的定位条数（离线补传时分批上传）
+	maxPingAge        = 7 * 24 * time.Hour // 补传定位的最长时间
+	maxPingClockSkew  = 5 * time.Minute    // 允许定位时间略晚于服务器时间
+	maxTrailPoints    = 5000               // 轨迹最多返回的定位条数
+	maxPlausibleSpeed = 200.0              // 速度上限（公里/小时），超过视为定位异常
+)
+
+var (
+	// ErrTrackingInactive 订单不在运输途中，不能上报定位
+	ErrTrackingInactive = errors.New("订单不在运输途中，不能上报位置")
+	// ErrNoLocation 订单还没有定位数据
+	ErrNoLocation = errors.New("暂无位置信息")
+)
+
+// TrackingPosition 订单当前位置及按剩余里程重新计算的预计到达时间
+type TrackingPosition struct {
+	Location       *models.LocationPing `json:"location"`
+	RemainingKm    *float64             `json:"remaining_km"`    // 无法估算时为空
+	RemainingHours *float64             `json:"remaining_hours"` // 无法估算时为空
+	ETA            *time.Time           `json:"eta"`             // 预计到达时间 = 最新定位时间 + 剩余时长
+}
+
+// TrackingService 运输途中的位置跟踪
+type TrackingService interface {
+	// RecordPings 承运方上报本人承运订单的定位（已取货或运输中），返回实际保存的条数
+	RecordPings(ctx context.Context, orderID, carrierID uint64, pings []*models.LocationPing) (int, error)
+	// LatestPosition 查询订单最新位置，仅订单双方或可查看全部订单的角色可见
+	LatestPosition(ctx context.Context, orderID, userID uint64, viewAll bool) (*TrackingPosition, error)
+	// Trail 按时间升序返回订单的轨迹
+	Trail(ctx context.Context, orderID, userID uint64, viewAll bool) ([]*models.LocationPing, error)
+}
+
+type trackingServiceImpl struct {
+	repo     models.LocationRepository
+	freights db.FreightRepository
+	routes   RouteEstimator
+	events   events.Publisher
+}
+
+// NewTrackingService 创建位置跟踪服务实例，上报位置时通过 publisher 通知订单双方（可为空）
+func NewTrackingService(repo models.LocationRepository, freights db.FreightRepository, routes RouteEstimator, publisher events.Publisher) TrackingService {
+	return &trackingServiceImpl{repo: repo, freights: freights, routes: routes, events: publisher}
+}
+
+// RecordPings 校验后批量保存定位
+func (s *trackingServiceImpl) RecordPings(ctx context.Context, orderID, carrierID uint64, pings []*models.LocationPing) (int, error) {
+	order, err := s.freights.GetByID(ctx, orderID)
+	if err != nil {
+		return 0, err
+	}
+	if order == nil {
+		return 0, ErrFreightNotFound
+	}
+	if order.CarrierID != carrierID {
+		return 0, ErrPermissionDenied
+	}
+	if order.Status != models.FreightStatusPickedUp && order.Status != models.FreightStatusShipping {
+		return 0, ErrTrackingInactive
+	}
+
+	switch {
+	case len(pings) == 0:
+		return 0, &FreightFieldError{Field: "pings", Reason: "不能为空"}
+	case len(pings) > maxPingBatch:
+		return 0, &FreightFieldError{Field: "pings", Reason: fmt.Sprintf("单次最多上报 %d 条", maxPingBatch)}
+	}
+	now := time.Now()
+	for i, p := range pings {
+		if err := validatePing(p, now); err != nil {
+			err.Field = fmt.Sprintf("pings[%d].%s", i, err.Field)
+			return 0, err
+		}
+		p.ID, p.OrderID, p.CarrierID = 0, orderID, carrierID
+	}
+	saved, err := s.repo.AddPings(ctx, pings)
+	if err != nil {
+		return 0, err
+	}
+
+	// 每批只通知一次，内容为本批中定位时间最新的一条
+	if saved > 0 {
+		latest := pings[0]
+		for _, p := range pings[1:] {
+			if p.RecordedAt.After(latest.RecordedAt) {
+				latest = p
+			}
+		}
+		copied := *latest
+		publish(s.events, events.Event{
+			Type: events.TypeOrderLocation, OrderID: orderID, Data: &copied,
+			ShipperID: order.ShipperID, CarrierID: order.CarrierID,
+		})
+	}
+	return saved, nil
+}
+
+// validatePing 校验单条定位的坐标、速度、方向和定位时间
+func validatePing(p *models.LocationPing, now time.Time) *FreightFieldError {
+	switch {
+	case p.Lat < -90 || p.Lat > 90:
+		return &FreightFieldError{Field: "lat", Reason: "必须在-90~90之间"}
+	case p.Lng < -180 || p.Lng > 180:
+		return &FreightFieldError{Field: "lng", Reason: "必须在-180~180之间"}
+	case p.Lat == 0 && p.Lng == 0:
+		return &FreightFieldError{Field: "lat", Reason: "定位无效"}
+	case p.SpeedKmh < 0 || p.SpeedKmh > maxPlausibleSpeed:
+		return &FreightFieldError{Field: "speed", Reason: fmt.Sprintf("必须在0~%.0f之间", maxPlausibleSpeed)}
+	case p.Heading < 0 || p.Heading >= 360:
+		return &FreightFieldError{Field: "heading", Reason: "必须在0~360之间"}
+	case p.RecordedAt.IsZero():
+		return &FreightFieldError{Field: "timestamp", Reason: "不能为空"}
+	case p.RecordedAt.After(now.Add(maxPingClockSkew)):
+		return &FreightFieldError{Field: "timestamp", Reason: "不能晚于当前时间"}
+	case p.RecordedAt.Before(now.Add(-maxPingAge)):
+		return &FreightFieldError{Field: "timestamp", Reason: "超过补传期限"}
+	}
+	return nil
+}
+
+// visibleOrder 查询订单并校验查看权限：发货方、承运方或可查看全部订单的角色
+func (s *trackingServiceImpl) visibleOrder(ctx context.Context, orderID, userID uint64, viewAll bool) (*models.FreightOrder, error) {
+	order, err := s.freights.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, ErrFreightNotFound
+	}
+	if !viewAll && order.ShipperID != userID && order.CarrierID != userID {
+		return nil, ErrPermissionDenied
+	}
+	return order, nil
+}
+
+// LatestPosition 最新位置；运输途中按剩余里程估算到达时间，已送达的订单不再估算
+func (s *trackingServiceImpl) LatestPosition(ctx context.Context, orderID, userID uint64, viewAll bool) (*TrackingPosition, error) {
+	order, err := s.visibleOrder(ctx, orderID, userID, viewAll)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := s.repo.Latest(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if latest == nil {
+		return nil, ErrNoLocation
+	}
+
+	position := &TrackingPosition{Location: latest}
+	if order.Status != models.FreightStatusPickedUp && order.Status != models.FreightStatusShipping {
+		return position, nil
+	}
+	estimate, err := s.routes.EstimateFrom(ctx, latest.Lat, latest.Lng, order.DestinationCode)
+	if err != nil {
+		log.Printf("估算订单 %d 剩余路线失败: %v", orderID, err)
+		return position, nil
+	}
+	eta := latest.RecordedAt.Add(time.Duration(estimate.EstimatedHours * float64(time.Hour)))
+	position.RemainingKm, position.RemainingHours, position.ETA = &estimate.DistanceKm, &estimate.EstimatedHours, &eta
+	return position, nil
+}
+
+// Trail 订单轨迹（最近 maxTrailPoints 条）
+func (s *trackingServiceImpl) Trail(ctx context.Context, orderID, userID uint64, viewAll bool) ([]*models.LocationPing, error) {
+	if _, err := s.visibleOrder(ctx, orderID, userID, viewAll); err != nil {
+		return nil, err
+	}
+	return s.repo.Trail(ctx, orderID, maxTrailPoints)
+}
@@ -2,53 +2,129 @@
 package services
 
 import (
-	"database/sql"
+	"context"
 	"errors"
 	"fmt"
+	"freight/mail"
 	"freight/models"
 	"freight/utils"
+	"log"
+	"net/url"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 )
 
+// 一次性令牌默认有效期（配置未设置时使用）
+const (
+	defaultVerifyTokenTTL = 24 * time.Hour
+	defaultResetTokenTTL  = 30 * time.Minute
+)
+
+var (
+	// ErrUserNotFound 用户不存在
+	ErrUserNotFound = errors.New("用户不存在")
+	// ErrEmailNotVerified 邮箱未验证，禁止登录
+	ErrEmailNotVerified = errors.New("邮箱未验证，请先完成邮箱验证")
+	// ErrInvalidUserToken 验证/重置令牌无效、已使用或已过期
+	ErrInvalidUserToken = errors.New("令牌无效或已过期")
+	// ErrPasswordTooShort 新密码长度不足
+	ErrPasswordTooShort = errors.New("密码长度不能少于6位")
+	// ErrWrongPassword 修改密码、注销账号时原密码校验失败
+	ErrWrongPassword = errors.New("原密码错误")
+	// ErrUsernameTaken 用户名已被占用
+	ErrUsernameTaken = errors.New("用户名已存在")
+	// ErrAccountDeactivated 账号已注销
+	ErrAccountDeactivated = errors.New("账号已注销")
+)
+
+// 资料字段格式
+var (
+	usernamePattern = regexp.MustCompile(`^[\p{L}\p{N}_.-]{3,32}$`)
+	phonePattern    = regexp.MustCompile(`^\+?[0-9]{6,20}$`)
+)
+
+// ProfileFieldError 资料字段校验失败
+type ProfileFieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ProfileFieldError) Error() string {
+	return fmt.Sprintf("%s 无效：%s", e.Field, e.Reason)
+}
+
+// ProfileUpdate 资料修改（nil 表示不修改该字段）
+type ProfileUpdate struct {
+	Username  *string `json:"username"`
+	Phone     *string `json:"phone"`
+	AvatarURL *string `json:"avatar_url"`
+}
+
 type UserService interface {
 	Register(username, password, email string) (*models.User, error)
-	Login(username, password string) (*models.User, string, error)
+	Login(username, password, ip string) (*models.User, *models.TokenPair, error)
 	GetUserByID(id int64) (*models.User, error)
 	UpdateGender(userID int64, newGender string) (*models.User, error) // 新增：更新性别接口
+	UpdateRole(userID int64, role string) (*models.User, error)
+	VerifyEmail(token string) error
+	ResendVerification(email string) error
+	ForgotPassword(email string) error
+	ResetPassword(token, newPassword string) error
+	UnlockAccount(userID, adminID int64) (bool, error)
+	UpdateProfile(userID int64, update ProfileUpdate) (*models.User, error)
+	ChangePassword(userID int64, oldPassword, newPassword string) error
+	Deactivate(userID int64, password string) error
+}
+
+// AccountOptions 账号安全相关选项
+type AccountOptions struct {
+	RequireEmailVerification bool          // 未验证邮箱时禁止登录
+	VerifyTokenTTL           time.Duration // 邮箱验证令牌有效期，0 使用默认值
+	ResetTokenTTL            time.Duration // 密码重置令牌有效期，0 使用默认值
+	AppBaseURL               string        // 邮件中链接的地址前缀
 }
 
 type userServiceImpl struct {
-	db        *sql.DB
-	jwtSecret string
+	users     models.UserRepository
+	tokens    TokenService
+	tokenRepo models.TokenRepository
+	mailer    mail.Mailer
+	guard     *LoginGuard
+	opts      AccountOptions
 }
 
-func NewUserServiceImpl(db *sql.DB, jwtSecret string) UserService {
-	return &userServiceImpl{db: db, jwtSecret: jwtSecret}
+func NewUserServiceImpl(users models.UserRepository, tokens TokenService, tokenRepo models.TokenRepository, mailer mail.Mailer, guard *LoginGuard, opts AccountOptions) UserService {
+	if opts.VerifyTokenTTL <= 0 {
+		opts.VerifyTokenTTL = defaultVerifyTokenTTL
+	}
+	if opts.ResetTokenTTL <= 0 {
+		opts.ResetTokenTTL = defaultResetTokenTTL
+	}
+	opts.AppBaseURL = strings.TrimRight(opts.AppBaseURL, "/")
+	return &userServiceImpl{users: users, tokens: tokens, tokenRepo: tokenRepo, mailer: mailer, guard: guard, opts: opts}
 }
 
 func (s *userServiceImpl) Register(username, password, email string) (*models.User, error) {
-	// 检查用户名是否已存在
-	var count int
 	username = strings.TrimSpace(username) // 去除可能的空格
-	query := "SELECT COUNT(*) FROM users WHERE username = ?"
-	err := s.db.QueryRow(query, username).Scan(&count)
+	email = strings.TrimSpace(email)
+
+	// 检查用户名是否已存在
+	existing, err := s.users.FindByUsername(username)
 	if err != nil {
 		return nil, err
 	}
-
-	if count > 0 {
-		return nil, errors.New("用户名已存在")
+	if existing != nil {
+		return nil, ErrUsernameTaken
 	}
 
 	// 检查邮箱是否已存在
-	query = "SELECT COUNT(*) FROM users WHERE email = ?"
-	err = s.db.QueryRow(query, email).Scan(&count)
+	existing, err = s.users.FindByEmail(email)
 	if err != nil {
 		return nil, err
 	}
-
-	if count > 0 {
+	if existing != nil {
 		return nil, errors.New("邮箱已被注册")
 	}
 
@@ -58,123 +134,128 @@ func (s *userServiceImpl) Register(username, password, email string) (*models.Us
 		return nil, errors.New("密码加密失败")
 	}
 
-	// 创建用户
-	query = "INSERT INTO users (username, password, email, status, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)"
-	result, err := s.db.Exec(query, username, hashedPassword, email, 1, time.Now(), time.Now())
-	if err != nil {
+	// 创建用户（要求验证邮箱时，验证前为未验证状态）
+	status := models.UserStatusActive
+	if s.opts.RequireEmailVerification {
+		status = models.UserStatusUnverified
+	}
+	user := &models.User{
+		Username: username,
+		Password: hashedPassword,
+		Email:    email,
+		Role:     models.RoleUser,
+		Status:   status,
+	}
+	if err := s.users.Create(user); err != nil {
 		return nil, err
 	}
 
-	// 获取新创建的用户ID
-	userID, err := result.LastInsertId()
-	if err != nil {
-		return nil, err
+	// 发送验证邮件失败不影响注册，用户可重新发送
+	if err := s.sendVerification(user); err != nil {
+		log.Printf("发送验证邮件失败 user=%d: %v", user.ID, err)
 	}
 
 	// 返回用户信息
-	return &models.User{
-		ID:        userID,
-		Username:  username,
-		Password:  hashedPassword,
-		Email:     email,
-		Status:    1,
-		CreatedAt: utils.FromTime(time.Now()),
-		UpdatedAt: utils.FromTime(time.Now()),
-	}, nil
-}
-
-func (s *userServiceImpl) Login(loginID, password string) (*models.User, string, error) {
-	var user models.User
+	return user, nil
+}
+
+func (s *userServiceImpl) Login(loginID, password, ip string) (*models.User, *models.TokenPair, error) {
 	loginID = strings.TrimSpace(loginID) // 去除可能的空格
-	fmt.Printf("Login attempt with: %s\n", loginID)
-
-	query := `
-        SELECT id, username, password, email, role, six, status, created_at, updated_at 
-        FROM users 
-        WHERE 
-            (LOCATE('@', ?) > 0 AND email = ?) 
-            OR 
-            (LOCATE('@', ?) = 0 AND username = ?)
-    `
-
-	// 准备查询，不立即执行
-	stmt, err := s.db.Prepare(query)
-	if err != nil {
-		fmt.Printf("Prepare error: %v\n", err)
-		return nil, "", err
-	}
-	defer stmt.Close()
-
-	// 执行查询并捕获错误
-	err = stmt.QueryRow(loginID, loginID, loginID, loginID).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Password,
-		&user.Email,
-		&user.Role,
-		&user.Six,
-		&user.Status,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			fmt.Printf("User not found for: %s\n", loginID)
-			return nil, "", errors.New("用户不存在")
-		}
-		fmt.Printf("Query error: %v\n", err)
-		return nil, "", err
+
+	// 同一IP失败次数过多时直接拒绝
+	if err := s.guard.CheckIP(ip); err != nil {
+		return nil, nil, err
 	}
 
-	// 打印查询到的用户信息（调试用）
-	fmt.Printf("User found: %+v\n", user)
+	// 包含@按邮箱查找，否则按用户名查找
+	var user *models.User
+	var err error
+	if strings.Contains(loginID, "@") {
+		user, err = s.users.FindByEmail(loginID)
+	} else {
+		user, err = s.users.FindByUsername(loginID)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	found := user != nil
+	if !found {
+		user = &models.User{}
+	}
 
-	// 验证密码
-	if !utils.CheckPasswordHash(password, user.Password) {
-		return nil, "", errors.New("密码错误")
+	// 不存在的账号按登录名计数和锁定，与真实账号表现一致，避免被枚举
+	subject := LoginSubject(loginID)
+	if found {
+		subject = AccountSubject(user.ID)
+	}
+	if err := s.guard.CheckAccount(subject); err != nil {
+		return nil, nil, err
 	}
 
-	// 生成JWT token
-	token, err := utils.GenerateJWT(user.ID, user.Username, s.jwtSecret)
+	// 验证密码（账号不存在时也做一次哈希比较，避免通过响应时间区分）
+	hash := user.Password
+	if !found {
+		hash = dummyPasswordHash()
+	}
+	if !utils.CheckPasswordHash(password, hash) || !found {
+		if err := s.guard.Fail(subject, ip); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrInvalidCredentials
+	}
+	if err := s.guard.Succeed(subject); err != nil {
+		return nil, nil, err
+	}
+
+	if user.Status == models.UserStatusDeactivated {
+		return nil, nil, ErrAccountDeactivated
+	}
+	if s.opts.RequireEmailVerification && user.Status == models.UserStatusUnverified {
+		return nil, nil, ErrEmailNotVerified
+	}
+
+	// 签发访问令牌和刷新令牌
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	tokens, err := s.tokens.Issue(user)
 	if err != nil {
-		return nil, "", errors.New("生成认证令牌失败")
+		return nil, nil, err
 	}
 
-	return &user, token, nil
+	return user, tokens, nil
 }
 
-// 辅助函数：模拟三元运算符
-func ifElse(condition bool, trueVal, falseVal string) string {
-	if condition {
-		return trueVal
+// UnlockAccount 管理员解除账号的登录锁定
+func (s *userServiceImpl) UnlockAccount(userID, adminID int64) (bool, error) {
+	if _, err := s.GetUserByID(userID); err != nil {
+		return false, err
 	}
-	return falseVal
+	return s.guard.Unlock(userID, adminID)
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// dummyPasswordHash 返回一个固定的bcrypt哈希，用于账号不存在时的等时比较
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = utils.HashPassword("freight-dummy-password")
+	})
+	return dummyHash
 }
 
 func (s *userServiceImpl) GetUserByID(id int64) (*models.User, error) {
-	var user models.User
-	query := "SELECT id, username, password, email, role, six,  status, created_at, updated_at FROM users WHERE id = ?"
-	err := s.db.QueryRow(query, id).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Password,
-		&user.Email,
-		&user.Role,
-		&user.Six,
-		&user.Status,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("用户不存在")
-		}
+	user, err := s.users.FindByID(id)
+	if err != nil {
 		return nil, err
 	}
-
-	return &user, nil
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
 }
 
 // UpdateGender 更新用户性别（仅允许更新为 'man' 或 'women'）
@@ -191,28 +272,256 @@ func (s *userServiceImpl) UpdateGender(userID int64, newGender string) (*models.
 		return nil, err // 直接返回 "用户不存在" 等错误
 	}
 
-	// 3. 执行更新操作（只更新 six 字段和 updated_at 时间）
-	query := `
-		UPDATE users 
-		SET six = ?, updated_at = ? 
-		WHERE id = ?
-	`
-	result, err := s.db.Exec(query, newGender, time.Now(), userID)
-	if err != nil {
+	// 3. 更新性别并返回最新信息
+	user.Six = newGender
+	if err := s.users.Update(user); err != nil {
 		return nil, fmt.Errorf("更新性别失败: %w", err)
 	}
+	return user, nil
+}
+
+// UpdateRole 更新用户角色（仅允许已定义的角色），并吊销其全部令牌，使新角色在重新登录后生效
+func (s *userServiceImpl) UpdateRole(userID int64, role string) (*models.User, error) {
+	role = strings.TrimSpace(role)
+	if !models.ValidRole(role) {
+		return nil, errors.New("无效的角色")
+	}
 
-	// 4. 检查更新是否生效
-	rowsAffected, err := result.RowsAffected()
+	user, err := s.GetUserByID(userID)
 	if err != nil {
-		return nil, fmt.Errorf("获取更新结果失败: %w", err)
+		return nil, err
 	}
-	if rowsAffected == 0 {
-		return nil, errors.New("更新失败，未找到可更新的用户")
+
+	user.Role = role
+	if err := s.users.Update(user); err != nil {
+		return nil, fmt.Errorf("更新角色失败: %w", err)
+	}
+	if err := s.tokens.RevokeAllSessions(userID); err != nil {
+		return nil, err
 	}
+	return user, nil
+}
 
-	// 5. 更新内存中的用户性别，并返回最新信息
-	user.Six = newGender
-	user.UpdatedAt = utils.FromTime(time.Now())
+// VerifyEmail 使用邮箱验证令牌完成验证（令牌只能使用一次）
+func (s *userServiceImpl) VerifyEmail(token string) error {
+	userToken, err := s.consumeToken(models.TokenPurposeVerifyEmail, token)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.GetUserByID(userToken.UserID)
+	if err != nil {
+		return err
+	}
+
+	// 未开启强制验证时用户已是正常状态，只记录验证时间
+	if user.Status == models.UserStatusUnverified {
+		user.Status = models.UserStatusActive
+	}
+	if !user.EmailVerifiedAt.Valid {
+		user.EmailVerifiedAt = utils.FromTime(time.Now())
+	}
+	if err := s.users.Update(user); err != nil {
+		return fmt.Errorf("更新验证状态失败: %w", err)
+	}
+	return nil
+}
+
+// ResendVerification 重新发送验证邮件（邮箱不存在或已验证时静默成功，避免泄露注册信息）
+func (s *userServiceImpl) ResendVerification(email string) error {
+	user, err := s.users.FindByEmail(strings.TrimSpace(email))
+	if err != nil || user == nil || user.EmailVerifiedAt.Valid {
+		return err
+	}
+	return s.sendVerification(user)
+}
+
+// ForgotPassword 发送密码重置邮件（邮箱不存在时静默成功，避免泄露注册信息）
+func (s *userServiceImpl) ForgotPassword(email string) error {
+	user, err := s.users.FindByEmail(strings.TrimSpace(email))
+	if err != nil || user == nil || user.Status == models.UserStatusDeactivated {
+		return err
+	}
+
+	token, err := s.createToken(user.ID, models.TokenPurposeResetPassword, s.opts.ResetTokenTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(context.Background(), mail.Message{
+		To:      user.Email,
+		Subject: "重置密码",
+		Body: fmt.Sprintf("您好 %s：\n\n请在 %d 分钟内打开以下链接重置密码，如非本人操作请忽略本邮件。\n%s/reset-password?token=%s\n",
+			user.Username, int(s.opts.ResetTokenTTL/time.Minute), s.opts.AppBaseURL, token),
+	})
+}
+
+// ResetPassword 使用重置令牌设置新密码（令牌只能使用一次）
+func (s *userServiceImpl) ResetPassword(token, newPassword string) error {
+	if len(newPassword) < 6 {
+		return ErrPasswordTooShort
+	}
+
+	userToken, err := s.consumeToken(models.TokenPurposeResetPassword, token)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return errors.New("密码加密失败")
+	}
+	if err := s.users.UpdatePassword(userToken.UserID, hashedPassword); err != nil {
+		return fmt.Errorf("更新密码失败: %w", err)
+	}
+	// 重置密码后其他设备需要重新登录
+	return s.tokens.RevokeUser(userToken.UserID)
+}
+
+// UpdateProfile 修改当前用户资料（用户名、手机号、头像）
+func (s *userServiceImpl) UpdateProfile(userID int64, update ProfileUpdate) (*models.User, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Username != nil {
+		username := strings.TrimSpace(*update.Username)
+		if !usernamePattern.MatchString(username) {
+			return nil, &ProfileFieldError{Field: "username", Reason: "长度3-32，只能包含字母、数字、下划线、点和短横线"}
+		}
+		if username != user.Username {
+			existing, err := s.users.FindByUsername(username)
+			if err != nil {
+				return nil, err
+			}
+			if existing != nil {
+				return nil, ErrUsernameTaken
+			}
+			user.Username = username
+		}
+	}
+	if update.Phone != nil {
+		phone := strings.TrimSpace(*update.Phone)
+		if phone != "" && !phonePattern.MatchString(phone) {
+			return nil, &ProfileFieldError{Field: "phone", Reason: "只能包含数字，可带国际区号前缀+"}
+		}
+		user.Phone = phone
+	}
+	if update.AvatarURL != nil {
+		avatarURL := strings.TrimSpace(*update.AvatarURL)
+		if avatarURL != "" {
+			u, err := url.Parse(avatarURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, &ProfileFieldError{Field: "avatar_url", Reason: "必须是 http 或 https 地址"}
+			}
+		}
+		user.AvatarURL = avatarURL
+	}
+
+	if err := s.users.Update(user); err != nil {
+		return nil, fmt.Errorf("更新资料失败: %w", err)
+	}
 	return user, nil
 }
+
+// ChangePassword 校验原密码后修改密码，并使其他设备上的登录失效
+func (s *userServiceImpl) ChangePassword(userID int64, oldPassword, newPassword string) error {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkPassword(user, oldPassword); err != nil {
+		return err
+	}
+	if len(newPassword) < 6 {
+		return ErrPasswordTooShort
+	}
+
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return errors.New("密码加密失败")
+	}
+	if err := s.users.UpdatePassword(userID, hashedPassword); err != nil {
+		return fmt.Errorf("更新密码失败: %w", err)
+	}
+	return s.tokens.RevokeUser(userID)
+}
+
+// Deactivate 校验密码后注销账号（保留数据，禁止再登录）
+func (s *userServiceImpl) Deactivate(userID int64, password string) error {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkPassword(user, password); err != nil {
+		return err
+	}
+
+	user.Status = models.UserStatusDeactivated
+	if err := s.users.Update(user); err != nil {
+		return fmt.Errorf("注销账号失败: %w", err)
+	}
+	return s.tokens.RevokeAllSessions(userID)
+}
+
+// checkPassword 校验当前密码：与登录共用账号的失败计数和锁定，避免持有访问令牌的人无限次猜测密码
+func (s *userServiceImpl) checkPassword(user *models.User, password string) error {
+	subject := AccountSubject(user.ID)
+	if err := s.guard.CheckAccount(subject); err != nil {
+		return err
+	}
+	if !utils.CheckPasswordHash(password, user.Password) {
+		if err := s.guard.Fail(subject, ""); err != nil {
+			return err
+		}
+		return ErrWrongPassword
+	}
+	return s.guard.Succeed(subject)
+}
+
+// sendVerification 生成邮箱验证令牌并发送验证邮件
+func (s *userServiceImpl) sendVerification(user *models.User) error {
+	token, err := s.createToken(user.ID, models.TokenPurposeVerifyEmail, s.opts.VerifyTokenTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(context.Background(), mail.Message{
+		To:      user.Email,
+		Subject: "验证您的邮箱",
+		Body: fmt.Sprintf("您好 %s：\n\n请打开以下链接完成邮箱验证：\n%s/api/users/email/verify?token=%s\n",
+			user.Username, s.opts.AppBaseURL, token),
+	})
+}
+
+// createToken 生成一次性令牌，仅保存其哈希值，返回明文令牌
+func (s *userServiceImpl) createToken(userID int64, purpose string, ttl time.Duration) (string, error) {
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	if err := s.tokenRepo.CreateUserToken(&models.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeToken 使用一次性令牌，无效时返回 ErrInvalidUserToken
+func (s *userServiceImpl) consumeToken(purpose, token string) (*models.UserToken, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, ErrInvalidUserToken
+	}
+	userToken, err := s.tokenRepo.ConsumeUserToken(purpose, utils.HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if userToken == nil {
+		return nil, ErrInvalidUserToken
+	}
+	return userToken, nil
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"freight/models"
+)
+
+var (
+	// ErrVehicleNotFound 车辆不存在或不属于当前用户
+	ErrVehicleNotFound = errors.New("车辆不存在")
+	// ErrPlateTaken 车牌号已被登记
+	ErrPlateTaken = errors.New("车牌号已登记")
+)
+
+// VehicleMismatchError 车辆无法承运订单货物
+type VehicleMismatchError struct {
+	Reason string
+}
+
+func (e *VehicleMismatchError) Error() string {
+	return "车辆不满足承运要求：" + e.Reason
+}
+
+// platePattern 车牌号：省份简称 + 发牌机关字母 + 5位（普通）或6位（新能源）字母数字
+var platePattern = regexp.MustCompile(`^[京津沪渝冀豫云辽黑湘皖鲁新苏浙赣鄂桂甘晋蒙陕吉闽贵粤青藏川宁琼][A-Z][A-Z0-9]{5,6}$`)
+
+// VehicleService 承运方车辆管理
+type VehicleService interface {
+	CreateVehicle(ctx context.Context, ownerID uint64, vehicle *models.Vehicle) error
+	GetVehicle(ctx context.Context, id, ownerID uint64) (*models.Vehicle, error)
+	ListVehicles(ctx context.Context, ownerID uint64) ([]*models.Vehicle, error)
+	UpdateVehicle(ctx context.Context, ownerID uint64, vehicle *models.Vehicle) error
+	DeleteVehicle(ctx context.Context, id, ownerID uint64) error
+}
+
+type vehicleServiceImpl struct {
+	repo models.VehicleRepository
+}
+
+// NewVehicleService 创建车辆服务实例
+func NewVehicleService(repo models.VehicleRepository) VehicleService {
+	return &vehicleServiceImpl{repo: repo}
+}
+
+// normalizePlate 去除空格、分隔符并转为大写
+func normalizePlate(plate string) string {
+	plate = strings.NewReplacer(" ", "", "-", "", "·", "", ".", "").Replace(strings.TrimSpace(plate))
+	return strings.ToUpper(plate)
+}
+
+// validateVehicle 校验车辆信息
+func validateVehicle(vehicle *models.Vehicle) error {
+	vehicle.Plate = normalizePlate(vehicle.Plate)
+	if !platePattern.MatchString(vehicle.Plate) {
+		return &FreightFieldError{Field: "plate", Reason: "不是有效的车牌号"}
+	}
+	valid := false
+	for _, t := range models.VehicleTypes {
+		if vehicle.Type == t {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return &FreightFieldError{Field: "type", Reason: "只能是 " + strings.Join(models.VehicleTypes, "、")}
+	}
+	switch {
+	case vehicle.CapacityKg <= 0:
+		return &FreightFieldError{Field: "capacity_kg", Reason: "必须大于0"}
+	case vehicle.CapacityM3 <= 0:
+		return &FreightFieldError{Field: "capacity_m3", Reason: "必须大于0"}
+	case vehicle.LengthM <= 0:
+		return &FreightFieldError{Field: "length_m", Reason: "必须大于0"}
+	}
+	return nil
+}
+
+// checkPlate 车牌号不能与其他车辆重复
+func (s *vehicleServiceImpl) checkPlate(ctx context.Context, vehicle *models.Vehicle) error {
+	existing, err := s.repo.FindByPlate(ctx, vehicle.Plate)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != vehicle.ID {
+		return ErrPlateTaken
+	}
+	return nil
+}
+
+// CreateVehicle 登记车辆
+func (s *vehicleServiceImpl) CreateVehicle(ctx context.Context, ownerID uint64, vehicle *models.Vehicle) error {
+	vehicle.ID = 0
+	vehicle.OwnerID = ownerID
+	if err := validateVehicle(vehicle); err != nil {
+		return err
+	}
+	if err := s.checkPlate(ctx, vehicle); err != nil {
+		return err
+	}
+	return s.repo.Create(ctx, vehicle)
+}
+
+// GetVehicle 查询本人的车辆
+func (s *vehicleServiceImpl) GetVehicle(ctx context.Context, id, ownerID uint64) (*models.Vehicle, error) {
+	return ownedVehicle(ctx, s.repo, id, ownerID)
+}
+
+// ownedVehicle 查询车辆并校验归属，不属于该用户时按不存在处理
+func ownedVehicle(ctx context.Context, repo models.VehicleRepository, id, ownerID uint64) (*models.Vehicle, error) {
+	vehicle, err := repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if vehicle == nil || vehicle.OwnerID != ownerID {
+		return nil, ErrVehicleNotFound
+	}
+	return vehicle, nil
+}
+
+// ListVehicles 列出本人的车辆
+func (s *vehicleServiceImpl) ListVehicles(ctx context.Context, ownerID uint64) ([]*models.Vehicle, error) {
+	return s.repo.ListByOwner(ctx, ownerID)
+}
+
+// UpdateVehicle 修改本人的车辆
+func (s *vehicleServiceImpl) UpdateVehicle(ctx context.Context, ownerID uint64, vehicle *models.Vehicle) error {
+	existing, err := ownedVehicle(ctx, s.repo, vehicle.ID, ownerID)
+	if err != nil {
+		return err
+	}
+	vehicle.OwnerID = ownerID
+	vehicle.CreatedAt = existing.CreatedAt
+	if err := validateVehicle(vehicle); err != nil {
+		return err
+	}
+	if err := s.checkPlate(ctx, vehicle); err != nil {
+		return err
+	}
+	return s.repo.Update(ctx, vehicle)
+}
+
+// DeleteVehicle 删除本人的车辆
+func (s *vehicleServiceImpl) DeleteVehicle(ctx context.Context, id, ownerID uint64) error {
+	if _, err := ownedVehicle(ctx, s.repo, id, ownerID); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+// checkVehicleFits 校验车辆能否承运订单货物：重量、体积、单件长度不超过车辆参数，冷链货物须使用冷藏车
+func checkVehicleFits(vehicle *models.Vehicle, order *models.FreightOrder) error {
+	switch {
+	case order.WeightKg > vehicle.CapacityKg:
+		return &VehicleMismatchError{Reason: fmt.Sprintf("货物重量 %.0fkg 超过核定载重 %.0fkg", order.WeightKg, vehicle.CapacityKg)}
+	case order.VolumeM3 > vehicle.CapacityM3:
+		return &VehicleMismatchError{Reason: fmt.Sprintf("货物体积 %.1fm³ 超过货厢容积 %.1fm³", order.VolumeM3, vehicle.CapacityM3)}
+	case order.LengthM > vehicle.LengthM:
+		return &VehicleMismatchError{Reason: fmt.Sprintf("单件长度 %.1fm 超过货厢长度 %.1fm", order.LengthM, vehicle.LengthM)}
+	case (order.TempMinC != nil || order.TempMaxC != nil) && !vehicle.Refrigerated():
+		return &VehicleMismatchError{Reason: "冷链货物须使用冷藏车"}
+	}
+	return nil
+}
+
+// vehicleFilter 按车辆参数收紧过滤条件，只保留该车辆能承运的订单；
+// 过滤条件与车辆矛盾（如非冷藏车查询冷链订单）时返回 false
+func vehicleFilter(vehicle *models.Vehicle, filter models.FreightFilter) (models.FreightFilter, bool) {
+	tighten := func(current, limit float64) float64 {
+		if current > 0 {
+			return math.Min(current, limit)
+		}
+		return limit
+	}
+	filter.MaxWeight = tighten(filter.MaxWeight, vehicle.CapacityKg)
+	filter.MaxVolume = tighten(filter.MaxVolume, vehicle.CapacityM3)
+	filter.MaxLength = tighten(filter.MaxLength, vehicle.LengthM)
+	if !vehicle.Refrigerated() {
+		if filter.ColdChain != nil && *filter.ColdChain {
+			return filter, false
+		}
+		noColdChain := false
+		filter.ColdChain = &noColdChain
+	}
+	return filter, true
+}
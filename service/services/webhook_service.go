@@ -0,0 +1,647 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"freight/events"
+	"freight/models"
+	"freight/utils"
+)
+
+// Webhook 请求头：接收方用订阅密钥按 SignWebhook 计算签名并与 X-Freight-Signature 比对，
+// 同一投递的重试使用相同的 X-Freight-Delivery，可用于去重
+const (
+	WebhookSignatureHeader = "X-Freight-Signature"
+	WebhookTimestampHeader = "X-Freight-Timestamp"
+	WebhookEventHeader     = "X-Freight-Event"
+	WebhookDeliveryHeader  = "X-Freight-Delivery"
+)
+
+// Webhook 默认参数
+const (
+	defaultWebhookMaxAttempts = 8                // 最多投递次数，用尽后转为死信
+	defaultWebhookBackoff     = 30 * time.Second // 首次重试间隔，之后每次翻倍
+	defaultWebhookMaxBackoff  = time.Hour        // 重试间隔上限
+	defaultWebhookTimeout     = 10 * time.Second // 单次请求超时
+	webhookClaimBatch         = 50               // 每次领取的投递数
+	webhookConcurrency        = 8                // 同时进行的投递请求数
+	webhookEnqueueTimeout     = 5 * time.Second  // 后台保存投递记录的超时
+	defaultWebhookQueueSize   = 1024             // 等待保存投递记录的事件数，写满时丢弃并记录日志
+	defaultWebhookCacheTTL    = 30 * time.Second // 启用订阅的缓存时长，其他实例修改的订阅最迟在此之后生效
+	maxWebhookURLLen          = 512
+	minWebhookSecretLen       = 16
+	maxWebhookSecretLen       = 128
+	maxWebhookErrorLen        = 255
+	defaultWebhookPageSize    = 50
+	maxWebhookPageSize        = 200
+)
+
+var (
+	// ErrWebhookNotFound 订阅不存在
+	ErrWebhookNotFound = errors.New("Webhook 订阅不存在")
+	// ErrDeliveryNotFound 投递记录不存在
+	ErrDeliveryNotFound = errors.New("投递记录不存在")
+	// ErrWebhookTargetBlocked 投递地址解析为本机或内网地址（未开启 AllowPrivateNetworks），不重试
+	ErrWebhookTargetBlocked = errors.New("投递地址为本机或内网地址，已拒绝")
+)
+
+// WebhookOptions 投递参数，零值使用默认值
+type WebhookOptions struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	Timeout     time.Duration
+	QueueSize   int
+	CacheTTL    time.Duration
+	// AllowPrivateNetworks 允许投递到回环、内网、链路本地地址（仅用于测试或受信任的内网部署）
+	AllowPrivateNetworks bool
+	// Client 为空时使用 newWebhookClient：连接前校验解析后的地址，不跟随重定向
+	Client *http.Client
+}
+
+// WebhookActor 管理订阅的用户
+type WebhookActor struct {
+	UserID  uint64
+	ViewAll bool // 可查看全部订单：允许订阅全部订单的事件
+	Admin   bool // 可管理任意用户的订阅
+}
+
+// WebhookSubscriptionRequest 创建或修改订阅的参数（修改时 Secret 被忽略）
+type WebhookSubscriptionRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret"` // 为空时自动生成
+	AllOrders  bool     `json:"all_orders"`
+	Active     *bool    `json:"active"` // 为空时启用
+}
+
+// WebhookService 订单事件的 Webhook 订阅与投递。
+// 作为 events.Publisher 接收订单事件，由后台协程保存待投递记录，定时任务调用 DeliverDue 实际投递。
+type WebhookService interface {
+	events.Publisher
+	// Flush 等待已发布事件的投递记录保存完成（关闭服务前调用）
+	Flush(ctx context.Context) error
+
+	CreateSubscription(ctx context.Context, actor WebhookActor, req WebhookSubscriptionRequest) (*models.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context, actor WebhookActor) ([]*models.WebhookSubscription, error)
+	GetSubscription(ctx context.Context, actor WebhookActor, id uint64) (*models.WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, actor WebhookActor, id uint64, req WebhookSubscriptionRequest) (*models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, actor WebhookActor, id uint64) error
+
+	// ListDeliveries 投递记录（管理员），Status 为 dead 时即死信列表
+	ListDeliveries(ctx context.Context, filter models.WebhookDeliveryFilter) ([]*models.WebhookDelivery, error)
+	// Redeliver 立即重新投递（重置重试次数），返回本次投递后的记录
+	Redeliver(ctx context.Context, id uint64) (*models.WebhookDelivery, error)
+	// DeliverDue 投递全部到期的记录，返回投递次数
+	DeliverDue(ctx context.Context, now time.Time) (int, error)
+}
+
+type webhookServiceImpl struct {
+	repo  models.WebhookRepository
+	opts  WebhookOptions
+	queue chan webhookJob
+
+	mu       sync.RWMutex
+	subs     []*models.WebhookSubscription // 启用订阅的缓存
+	loadedAt time.Time                     // 为零表示缓存失效
+}
+
+// webhookJob 待保存的事件：matched 为 true 时 subs 为发布时按缓存匹配到的订阅，否则由后台协程重新匹配；
+// 投递记录按发布时间 at 到期；done 不为空时表示 Flush 标记
+type webhookJob struct {
+	event   events.Event
+	at      time.Time
+	matched bool
+	subs    []*models.WebhookSubscription
+	done    chan struct{}
+}
+
+// NewWebhookService 创建 Webhook 服务实例
+func NewWebhookService(repo models.WebhookRepository, opts WebhookOptions) WebhookService {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultWebhookMaxAttempts
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = defaultWebhookBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaultWebhookMaxBackoff
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultWebhookTimeout
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultWebhookQueueSize
+	}
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = defaultWebhookCacheTTL
+	}
+	if opts.Client == nil {
+		opts.Client = newWebhookClient(opts.AllowPrivateNetworks)
+	}
+	s := &webhookServiceImpl{repo: repo, opts: opts, queue: make(chan webhookJob, opts.QueueSize)}
+	go s.run()
+	return s
+}
+
+// newWebhookClient 创建投递用的 HTTP 客户端：不使用环境变量中的代理，在建立连接时校验 DNS 解析后的目标地址
+// （避免订阅地址指向内网或解析结果被替换），重定向响应直接作为投递结果返回
+func newWebhookClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || blockedWebhookIP(ip) {
+				return ErrWebhookTargetBlocked
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// blockedWebhookIP 回环、内网、链路本地（含云服务元数据地址）、未指定和组播地址不允许作为投递目标
+func blockedWebhookIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// SignWebhook 计算签名：HMAC-SHA256(secret, "时间戳.请求体")，格式为 "sha256=十六进制"
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// applySubscriptionRequest 校验参数并写入订阅；地址为IP时提前拒绝内网地址（域名在投递时校验解析结果）
+func (s *webhookServiceImpl) applySubscriptionRequest(actor WebhookActor, req WebhookSubscriptionRequest, sub *models.WebhookSubscription) error {
+	target := strings.TrimSpace(req.URL)
+	u, err := url.Parse(target)
+	switch {
+	case target == "":
+		return &FreightFieldError{Field: "url", Reason: "不能为空"}
+	case len(target) > maxWebhookURLLen:
+		return &FreightFieldError{Field: "url", Reason: fmt.Sprintf("不能超过 %d 个字符", maxWebhookURLLen)}
+	case err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "":
+		return &FreightFieldError{Field: "url", Reason: "必须为 http(s) 地址"}
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && blockedWebhookIP(ip) && !s.opts.AllowPrivateNetworks {
+		return &FreightFieldError{Field: "url", Reason: "不能为本机或内网地址"}
+	}
+
+	if len(req.EventTypes) == 0 {
+		return &FreightFieldError{Field: "event_types", Reason: "不能为空"}
+	}
+	eventTypes := make([]string, 0, len(req.EventTypes))
+	for _, t := range req.EventTypes {
+		if !containsString(events.OrderEventTypes, t) {
+			return &FreightFieldError{Field: "event_types", Reason: "不支持的事件类型 " + t}
+		}
+		if !containsString(eventTypes, t) {
+			eventTypes = append(eventTypes, t)
+		}
+	}
+	if req.AllOrders && !actor.ViewAll {
+		return ErrPermissionDenied
+	}
+
+	sub.URL, sub.EventTypes, sub.AllOrders = target, eventTypes, req.AllOrders
+	sub.Active = req.Active == nil || *req.Active
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateSubscription 创建订阅，未指定密钥时生成随机密钥；返回的订阅包含密钥
+func (s *webhookServiceImpl) CreateSubscription(ctx context.Context, actor WebhookActor, req WebhookSubscriptionRequest) (*models.WebhookSubscription, error) {
+	sub := &models.WebhookSubscription{OwnerID: actor.UserID}
+	if err := s.applySubscriptionRequest(actor, req, sub); err != nil {
+		return nil, err
+	}
+	switch n := utf8.RuneCountInString(req.Secret); {
+	case n == 0:
+		secret, err := utils.RandomToken(32)
+		if err != nil {
+			return nil, err
+		}
+		sub.Secret = secret
+	case n < minWebhookSecretLen || n > maxWebhookSecretLen:
+		return nil, &FreightFieldError{Field: "secret", Reason: fmt.Sprintf("长度必须在 %d~%d 之间", minWebhookSecretLen, maxWebhookSecretLen)}
+	default:
+		sub.Secret = req.Secret
+	}
+	if err := s.repo.CreateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	s.reloadSubscriptions(ctx)
+	return sub, nil
+}
+
+// ListSubscriptions 本人的订阅（不含密钥）
+func (s *webhookServiceImpl) ListSubscriptions(ctx context.Context, actor WebhookActor) ([]*models.WebhookSubscription, error) {
+	subs, err := s.repo.ListSubscriptions(ctx, actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range subs {
+		sub.Secret = ""
+	}
+	return subs, nil
+}
+
+// ownedSubscription 查询订阅并校验归属（管理员可访问任意订阅）
+func (s *webhookServiceImpl) ownedSubscription(ctx context.Context, actor WebhookActor, id uint64) (*models.WebhookSubscription, error) {
+	sub, err := s.repo.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil {
+		return nil, ErrWebhookNotFound
+	}
+	if sub.OwnerID != actor.UserID && !actor.Admin {
+		return nil, ErrPermissionDenied
+	}
+	return sub, nil
+}
+
+// GetSubscription 查询订阅（不含密钥）
+func (s *webhookServiceImpl) GetSubscription(ctx context.Context, actor WebhookActor, id uint64) (*models.WebhookSubscription, error) {
+	sub, err := s.ownedSubscription(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	sub.Secret = ""
+	return sub, nil
+}
+
+// UpdateSubscription 修改地址、事件类型、范围和启用状态
+func (s *webhookServiceImpl) UpdateSubscription(ctx context.Context, actor WebhookActor, id uint64, req WebhookSubscriptionRequest) (*models.WebhookSubscription, error) {
+	sub, err := s.ownedSubscription(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applySubscriptionRequest(actor, req, sub); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	s.reloadSubscriptions(ctx)
+	sub.Secret = ""
+	return sub, nil
+}
+
+// DeleteSubscription 删除订阅，尚未完成的投递在下次投递时转为死信
+func (s *webhookServiceImpl) DeleteSubscription(ctx context.Context, actor WebhookActor, id uint64) error {
+	if _, err := s.ownedSubscription(ctx, actor, id); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteSubscription(ctx, id); err != nil {
+		return err
+	}
+	s.reloadSubscriptions(ctx)
+	return nil
+}
+
+// Publish 交给后台协程保存待投递记录，不阻塞订单操作：订阅缓存有效时先在内存中匹配，
+// 没有订阅该事件的订阅时直接返回（如无人订阅的定位上报）；队列写满时丢弃并记录日志
+func (s *webhookServiceImpl) Publish(e events.Event) events.Event {
+	job := webhookJob{event: e, at: time.Now()}
+	if subs, ok := s.cachedSubscriptions(job.at); ok {
+		job.matched, job.subs = true, matchSubscriptions(subs, e)
+		if len(job.subs) == 0 {
+			return e
+		}
+	}
+	select {
+	case s.queue <- job:
+	default:
+		log.Printf("Webhook 队列已满，丢弃事件（%s 订单 %d）", e.Type, e.OrderID)
+	}
+	return e
+}
+
+// Flush 等待此前发布的事件处理完成
+func (s *webhookServiceImpl) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case s.queue <- webhookJob{done: done}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run 后台协程：按发布顺序保存投递记录，保存失败只记录日志
+func (s *webhookServiceImpl) run() {
+	for job := range s.queue {
+		if job.done != nil {
+			close(job.done)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), webhookEnqueueTimeout)
+		if err := s.enqueue(ctx, job); err != nil {
+			log.Printf("保存 Webhook 投递记录失败（%s 订单 %d）: %v", job.event.Type, job.event.OrderID, err)
+		}
+		cancel()
+	}
+}
+
+// enqueue 为匹配的订阅保存待投递记录；发布时缓存已失效的事件在此重新加载订阅后匹配
+func (s *webhookServiceImpl) enqueue(ctx context.Context, job webhookJob) error {
+	e, subs := job.event, job.subs
+	if !job.matched {
+		all, err := s.activeSubscriptions(ctx)
+		if err != nil {
+			return err
+		}
+		subs = matchSubscriptions(all, e)
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	deliveries := make([]*models.WebhookDelivery, 0, len(subs))
+	for _, sub := range subs {
+		next := job.at
+		deliveries = append(deliveries, &models.WebhookDelivery{
+			SubscriptionID: sub.ID, EventType: e.Type, OrderID: e.OrderID, Payload: string(payload),
+			Status: models.WebhookPending, NextAttemptAt: &next, CreatedAt: job.at, UpdatedAt: job.at,
+		})
+	}
+	return s.repo.CreateDeliveries(ctx, deliveries)
+}
+
+// matchSubscriptions 按事件类型和订单归属匹配订阅
+func matchSubscriptions(subs []*models.WebhookSubscription, e events.Event) []*models.WebhookSubscription {
+	var matched []*models.WebhookSubscription
+	for _, sub := range subs {
+		if !containsString(sub.EventTypes, e.Type) {
+			continue
+		}
+		if !sub.AllOrders && sub.OwnerID != e.ShipperID && sub.OwnerID != e.CarrierID {
+			continue
+		}
+		matched = append(matched, sub)
+	}
+	return matched
+}
+
+// cachedSubscriptions 返回未过期的订阅缓存
+func (s *webhookServiceImpl) cachedSubscriptions(now time.Time) ([]*models.WebhookSubscription, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.loadedAt.IsZero() || now.Sub(s.loadedAt) > s.opts.CacheTTL {
+		return nil, false
+	}
+	return s.subs, true
+}
+
+// activeSubscriptions 返回启用的订阅，缓存过期时从仓储重新加载
+func (s *webhookServiceImpl) activeSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error) {
+	if subs, ok := s.cachedSubscriptions(time.Now()); ok {
+		return subs, nil
+	}
+	return s.loadSubscriptions(ctx)
+}
+
+// loadSubscriptions 从仓储加载启用的订阅并更新缓存；并发加载时只保留开始较晚的结果
+func (s *webhookServiceImpl) loadSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error) {
+	loadedAt := time.Now()
+	subs, err := s.repo.ListActiveSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if loadedAt.After(s.loadedAt) {
+		s.subs, s.loadedAt = subs, loadedAt
+	}
+	return subs, nil
+}
+
+// reloadSubscriptions 本实例修改订阅后立即刷新缓存；加载失败时使缓存失效，由后台协程重新加载
+func (s *webhookServiceImpl) reloadSubscriptions(ctx context.Context) {
+	if _, err := s.loadSubscriptions(ctx); err != nil {
+		log.Printf("刷新 Webhook 订阅缓存失败: %v", err)
+		s.mu.Lock()
+		s.loadedAt = time.Time{}
+		s.mu.Unlock()
+	}
+}
+
+// ListDeliveries 查询投递记录
+func (s *webhookServiceImpl) ListDeliveries(ctx context.Context, filter models.WebhookDeliveryFilter) ([]*models.WebhookDelivery, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultWebhookPageSize
+	}
+	if filter.Limit > maxWebhookPageSize {
+		filter.Limit = maxWebhookPageSize
+	}
+	return s.repo.ListDeliveries(ctx, filter)
+}
+
+// Redeliver 重置为待投递后立即投递一次，失败时按正常退避继续重试
+func (s *webhookServiceImpl) Redeliver(ctx context.Context, id uint64) (*models.WebhookDelivery, error) {
+	d, err := s.repo.GetDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, ErrDeliveryNotFound
+	}
+	now := time.Now()
+	lease := now.Add(s.lease())
+	d.Status, d.Attempts, d.NextAttemptAt, d.DeliveredAt = models.WebhookPending, 0, &lease, nil
+	if err := s.repo.UpdateDelivery(ctx, d); err != nil {
+		return nil, err
+	}
+	sub, err := s.repo.GetSubscription(ctx, d.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.deliver(ctx, d, sub, now); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// lease 领取投递后的占用时长：超过请求超时，避免投递中被再次领取
+func (s *webhookServiceImpl) lease() time.Duration {
+	return s.opts.Timeout + time.Minute
+}
+
+// DeliverDue 分批领取到期记录并发投递，直到没有到期记录
+func (s *webhookServiceImpl) DeliverDue(ctx context.Context, now time.Time) (int, error) {
+	// 先保存已发布事件的投递记录，使其在本轮投递
+	if err := s.Flush(ctx); err != nil {
+		return 0, err
+	}
+	total := 0
+	for ctx.Err() == nil {
+		batch, err := s.repo.ClaimDueDeliveries(ctx, now, webhookClaimBatch, now.Add(s.lease()))
+		if err != nil {
+			return total, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		subs := make(map[uint64]*models.WebhookSubscription)
+		for _, d := range batch {
+			if _, ok := subs[d.SubscriptionID]; ok {
+				continue
+			}
+			if subs[d.SubscriptionID], err = s.repo.GetSubscription(ctx, d.SubscriptionID); err != nil {
+				return total, err
+			}
+		}
+
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, webhookConcurrency)
+		errs := make(chan error, len(batch))
+		for _, d := range batch {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(d *models.WebhookDelivery) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				if err := s.deliver(ctx, d, subs[d.SubscriptionID], now); err != nil {
+					errs <- err
+				}
+			}(d)
+		}
+		wg.Wait()
+		close(errs)
+		total += len(batch)
+		if err := <-errs; err != nil {
+			return total, err
+		}
+	}
+	return total, ctx.Err()
+}
+
+// deliver 发送一次并保存结果：2xx 为成功；失败时按指数退避安排重试，次数用尽转为死信
+func (s *webhookServiceImpl) deliver(ctx context.Context, d *models.WebhookDelivery, sub *models.WebhookSubscription, now time.Time) error {
+	var statusCode int
+	var err error
+	switch {
+	case sub == nil:
+		err = errors.New("订阅已删除")
+	case !sub.Active:
+		err = errors.New("订阅已停用")
+	default:
+		statusCode, err = s.send(ctx, d, sub)
+	}
+
+	d.Attempts++
+	d.LastStatusCode = statusCode
+	switch {
+	case err == nil:
+		delivered := time.Now()
+		d.Status, d.LastError, d.NextAttemptAt, d.DeliveredAt = models.WebhookDelivered, "", nil, &delivered
+	case sub == nil || !sub.Active || errors.Is(err, ErrWebhookTargetBlocked) || d.Attempts >= s.opts.MaxAttempts:
+		d.Status, d.NextAttemptAt = models.WebhookDead, nil
+	default:
+		next := now.Add(s.backoff(d.Attempts))
+		d.NextAttemptAt = &next
+	}
+	if err != nil {
+		d.LastError = truncateRunes(err.Error(), maxWebhookErrorLen)
+	}
+	return s.repo.UpdateDelivery(ctx, d)
+}
+
+// backoff 第 n 次失败后的重试间隔：Backoff × 2^(n-1)，不超过 MaxBackoff
+func (s *webhookServiceImpl) backoff(n int) time.Duration {
+	delay := s.opts.Backoff
+	for i := 1; i < n && delay < s.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > s.opts.MaxBackoff {
+		delay = s.opts.MaxBackoff
+	}
+	return delay
+}
+
+// send 签名并发送请求，返回HTTP状态码
+func (s *webhookServiceImpl) send(ctx context.Context, d *models.WebhookDelivery, sub *models.WebhookSubscription) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.opts.Timeout)
+	defer cancel()
+
+	body := []byte(d.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, strings.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "freight-webhook/1.0")
+	req.Header.Set(WebhookEventHeader, d.EventType)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatUint(d.ID, 10))
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(sub.Secret, timestamp, body))
+
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("接收方返回 HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// truncateRunes 截断为最多 n 个字符
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,72 @@
+package handlers_freight_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/db"
+	"freight/mail"
+	"freight/models"
+	"freight/utils"
+)
+
+// 测试一次性令牌只能使用一次，且过期后不可用
+func TestUserTokenSingleUse(t *testing.T) {
+	repo := db.NewMemoryTokenRepository()
+
+	require.NoError(t, repo.CreateUserToken(&models.UserToken{
+		UserID:    7,
+		Purpose:   models.TokenPurposeResetPassword,
+		TokenHash: utils.HashToken("reset-token"),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}))
+	require.NoError(t, repo.CreateUserToken(&models.UserToken{
+		UserID:    7,
+		Purpose:   models.TokenPurposeVerifyEmail,
+		TokenHash: utils.HashToken("expired-token"),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}))
+
+	// 用途不匹配
+	token, err := repo.ConsumeUserToken(models.TokenPurposeVerifyEmail, utils.HashToken("reset-token"))
+	require.NoError(t, err)
+	assert.Nil(t, token)
+
+	token, err = repo.ConsumeUserToken(models.TokenPurposeResetPassword, utils.HashToken("reset-token"))
+	require.NoError(t, err)
+	require.NotNil(t, token)
+	assert.Equal(t, int64(7), token.UserID)
+
+	// 重复使用
+	token, err = repo.ConsumeUserToken(models.TokenPurposeResetPassword, utils.HashToken("reset-token"))
+	require.NoError(t, err)
+	assert.Nil(t, token)
+
+	// 已过期
+	token, err = repo.ConsumeUserToken(models.TokenPurposeVerifyEmail, utils.HashToken("expired-token"))
+	require.NoError(t, err)
+	assert.Nil(t, token)
+}
+
+// 测试日志邮件实现写入内容并保留已发送邮件
+func TestLogMailer(t *testing.T) {
+	var buf bytes.Buffer
+	mailer := mail.NewLogMailer(&buf)
+
+	require.NoError(t, mailer.Send(context.Background(), mail.Message{
+		To:      "a@example.com",
+		Subject: "验证您的邮箱",
+		Body:    "token=abc",
+	}))
+
+	messages := mailer.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "a@example.com", messages[0].To)
+	assert.Contains(t, buf.String(), "Subject: 验证您的邮箱")
+	assert.Contains(t, buf.String(), "token=abc")
+}
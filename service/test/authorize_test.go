@@ -0,0 +1,89 @@
+package handlers_freight_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/api/middleware"
+	"freight/api/routes"
+	"freight/models"
+	"freight/utils"
+)
+
+const testJWTSecret = "test-secret"
+
+// 测试用的配置服务实现
+type testConfigService struct{}
+
+func (t *testConfigService) CreateConfig(key, value, description string) error { return nil }
+func (t *testConfigService) SetConfig(key, value, description string) error    { return nil }
+func (t *testConfigService) GetConfig(key string) (*models.Config, error) {
+	return &models.Config{Key: key, Value: "v"}, nil
+}
+func (t *testConfigService) UpdateConfig(config *models.Config) error { return nil }
+func (t *testConfigService) DeleteConfig(key string) error            { return nil }
+func (t *testConfigService) ListConfigs() ([]*models.Config, error) {
+	return []*models.Config{}, nil
+}
+
+// 生成指定角色的测试token
+func tokenFor(t *testing.T, userID int64, role string) string {
+	token, _, err := utils.GenerateJWT(userID, "tester", role, testJWTSecret, time.Minute)
+	require.NoError(t, err)
+	return token
+}
+
+// 测试按角色/权限声明的路由授权
+func TestRouteAuthorization(t *testing.T) {
+	router := routes.SetupRoutes(routes.Deps{
+		UserService:    &testUserService{},
+		ConfigService:  &testConfigService{},
+		FreightService: &testFreightService{},
+		AuthMiddleware: middleware.NewAuthMiddleware(testJWTSecret, nil),
+	})
+
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		role         string
+		expectedCode int
+	}{
+		{"普通用户读取配置", "GET", "/api/configs?key=a", "", models.RoleUser, http.StatusOK},
+		{"普通用户创建配置", "POST", "/api/configs", `{"key":"a","value":"b"}`, models.RoleUser, http.StatusForbidden},
+		{"调度员删除配置", "DELETE", "/api/configs?key=a", "", models.RoleDispatcher, http.StatusForbidden},
+		{"管理员创建配置", "POST", "/api/configs", `{"key":"a","value":"b"}`, models.RoleAdmin, http.StatusCreated},
+		{"承运方发布订单", "POST", "/api/freights", `{}`, models.RoleCarrier, http.StatusForbidden},
+		{"发货方接单", "POST", "/api/freights/1/accept", "", models.RoleShipper, http.StatusForbidden},
+		{"承运方接单", "POST", "/api/freights/1/accept", "", models.RoleCarrier, http.StatusOK},
+		{"普通用户分配角色", "PATCH", "/api/users/2/role", `{"role":"admin"}`, models.RoleUser, http.StatusForbidden},
+		{"管理员分配角色", "PATCH", "/api/users/2/role", `{"role":"carrier"}`, models.RoleAdmin, http.StatusOK},
+		{"调度员解除锁定", "POST", "/api/users/2/unlock", "", models.RoleDispatcher, http.StatusForbidden},
+		{"管理员解除锁定", "POST", "/api/users/2/unlock", "", models.RoleAdmin, http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Authorization", "Bearer "+tokenFor(t, 1, tc.role))
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.expectedCode, recorder.Code)
+
+			if tc.expectedCode == http.StatusForbidden {
+				var body map[string]string
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+				assert.Equal(t, "权限不足", body["error"])
+				assert.NotEmpty(t, body["detail"])
+			}
+		})
+	}
+}
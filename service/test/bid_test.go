@@ -0,0 +1,202 @@
+package handlers_freight_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/api/middleware"
+	"freight/api/routes"
+	"freight/db"
+	"freight/models"
+	"freight/services"
+	"freight/utils"
+)
+
+// 创建一个开启竞价的待接单订单
+func newBiddingOrder(t *testing.T, service services.FreightService, shipperID uint64) *models.FreightOrder {
+	order := &models.FreightOrder{
+		OriginCode: "310100", DestinationCode: "330100", Price: 1000, ShipperID: shipperID,
+		OrderDate: utils.NewDate(2025, 3, 1), BiddingEnabled: true,
+	}
+	require.NoError(t, service.CreateFreight(context.Background(), order))
+	return order
+}
+
+func bidRequest(amount float64) services.BidRequest {
+	return services.BidRequest{Amount: amount, ETA: utils.FromTime(time.Now().Add(24 * time.Hour))}
+}
+
+// 测试出价、查看出价和定标
+func TestBidAndAward(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	freights := newMemoryFreightService(repo)
+	bids := services.NewBidService(db.NewMemoryBidRepository(repo), repo, nil)
+	ctx := context.Background()
+
+	order := newBiddingOrder(t, freights, 1)
+	assert.ErrorIs(t, freights.AcceptOrder(ctx, order.ID, 2, 0), services.ErrBiddingOnly)
+
+	_, err := bids.PlaceBid(ctx, order.ID, 1, bidRequest(900))
+	assert.ErrorIs(t, err, services.ErrPermissionDenied)
+	var fieldErr *services.FreightFieldError
+	_, err = bids.PlaceBid(ctx, order.ID, 2, services.BidRequest{Amount: 900, ETA: utils.FromTime(time.Now().Add(-time.Hour))})
+	require.ErrorAs(t, err, &fieldErr)
+	assert.Equal(t, "eta", fieldErr.Field)
+
+	// 重复出价覆盖原出价，不增加出价数量
+	_, err = bids.PlaceBid(ctx, order.ID, 2, bidRequest(950))
+	require.NoError(t, err)
+	first, err := bids.PlaceBid(ctx, order.ID, 2, bidRequest(920))
+	require.NoError(t, err)
+	winner, err := bids.PlaceBid(ctx, order.ID, 3, bidRequest(880))
+	require.NoError(t, err)
+
+	stored, err := freights.GetFreightByID(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored.BidCount)
+
+	all, err := bids.ListBids(ctx, order.ID, 1)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, winner.ID, all[0].ID)
+	own, err := bids.ListBids(ctx, order.ID, 2)
+	require.NoError(t, err)
+	require.Len(t, own, 1)
+	assert.Equal(t, 920.0, own[0].Amount)
+
+	_, err = bids.AwardBid(ctx, order.ID, winner.ID, 2)
+	assert.ErrorIs(t, err, services.ErrPermissionDenied)
+	awarded, err := bids.AwardBid(ctx, order.ID, winner.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(models.FreightStatusAccepted), awarded.Status)
+	assert.Equal(t, uint64(3), awarded.CarrierID)
+	assert.Equal(t, 880.0, awarded.Price)
+
+	mine, err := bids.ListMyBids(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, mine, 1)
+	assert.Equal(t, first.ID, mine[0].ID)
+	assert.Equal(t, uint8(models.BidStatusRejected), mine[0].Status)
+
+	_, err = bids.AwardBid(ctx, order.ID, first.ID, 1)
+	assert.ErrorIs(t, err, services.ErrBiddingClosed)
+	_, err = bids.PlaceBid(ctx, order.ID, 4, bidRequest(800))
+	assert.ErrorIs(t, err, services.ErrBiddingClosed)
+
+	history, err := freights.GetOrderHistory(ctx, order.ID, order.ShipperID, false)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, uint64(1), history[0].ActorID)
+
+	// 未开启竞价的订单不能出价
+	plain := &models.FreightOrder{OriginCode: "310100", DestinationCode: "330100", Price: 1000, ShipperID: 1, OrderDate: utils.NewDate(2025, 3, 1)}
+	require.NoError(t, freights.CreateFreight(ctx, plain))
+	_, err = bids.PlaceBid(ctx, plain.ID, 2, bidRequest(900))
+	assert.ErrorIs(t, err, services.ErrBiddingDisabled)
+}
+
+// 测试订单取消或过期时关闭待定出价，取货时间已过不能定标
+func TestBidsClosedWithOrder(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	freights := newMemoryFreightService(repo)
+	bids := services.NewBidService(db.NewMemoryBidRepository(repo), repo, nil)
+	ctx := context.Background()
+
+	cancelled := newBiddingOrder(t, freights, 1)
+	_, err := bids.PlaceBid(ctx, cancelled.ID, 2, bidRequest(900))
+	require.NoError(t, err)
+	require.NoError(t, freights.TransitionOrder(ctx, cancelled.ID, 1, models.FreightStatusCancelled, "不发了"))
+	mine, err := bids.ListMyBids(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, mine, 1)
+	assert.Equal(t, uint8(models.BidStatusClosed), mine[0].Status)
+	_, err = bids.PlaceBid(ctx, cancelled.ID, 2, bidRequest(850))
+	assert.ErrorIs(t, err, services.ErrBiddingClosed)
+
+	// 直接写入仓储，模拟取货时间已过仍未定标的竞价订单
+	pickupLatest := time.Now().Add(-time.Hour)
+	overdue := &models.FreightOrder{
+		OriginCode: "310100", DestinationCode: "330100", Price: 1000, ShipperID: 1,
+		OrderDate: utils.NewDate(2025, 3, 1), BiddingEnabled: true, PickupLatest: &pickupLatest,
+	}
+	require.NoError(t, repo.Create(ctx, overdue))
+	late, err := bids.PlaceBid(ctx, overdue.ID, 3, bidRequest(900))
+	require.NoError(t, err)
+	_, err = bids.AwardBid(ctx, overdue.ID, late.ID, 1)
+	assert.ErrorIs(t, err, services.ErrPickupWindowClosed)
+
+	expired, err := freights.ExpireOverdueOrders(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	mine, err = bids.ListMyBids(ctx, 3)
+	require.NoError(t, err)
+	require.Len(t, mine, 1)
+	assert.Equal(t, uint8(models.BidStatusClosed), mine[0].Status)
+}
+
+// 测试竞价接口
+func TestBidRoutes(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	freights := newMemoryFreightService(repo)
+	bids := services.NewBidService(db.NewMemoryBidRepository(repo), repo, nil)
+	router := routes.SetupRoutes(routes.Deps{
+		FreightService: freights,
+		BidService:     bids,
+		AuthMiddleware: middleware.NewAuthMiddleware(testJWTSecret, nil),
+	})
+	order := newBiddingOrder(t, freights, 1)
+
+	do := func(method, path, body string, userID int64, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+tokenFor(t, userID, role))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+	bidsPath := fmt.Sprintf("/api/freights/%d/bids", order.ID)
+	eta := time.Now().Add(48 * time.Hour).Format(utils.LayoutDateTime)
+
+	recorder := do("POST", bidsPath, `{"amount":900,"eta":"`+eta+`"}`, 1, models.RoleShipper)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	recorder = do("POST", bidsPath, `{"amount":0,"eta":"`+eta+`"}`, 2, models.RoleCarrier)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	recorder = do("POST", fmt.Sprintf("/api/freights/%d/accept", order.ID), "", 2, models.RoleCarrier)
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+
+	recorder = do("POST", bidsPath, `{"amount":900,"eta":"`+eta+`","note":"当天装车"}`, 2, models.RoleCarrier)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	var placed struct {
+		Data models.Bid `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &placed))
+
+	recorder = do("GET", bidsPath, "", 1, models.RoleShipper)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var listed struct {
+		Data []models.Bid `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &listed))
+	require.Len(t, listed.Data, 1)
+	assert.Equal(t, "当天装车", listed.Data[0].Note)
+
+	recorder = do("POST", fmt.Sprintf("%s/%d/award", bidsPath, placed.Data.ID), "", 1, models.RoleShipper)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var awarded struct {
+		Data models.FreightOrder `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &awarded))
+	assert.Equal(t, 900.0, awarded.Data.Price)
+	assert.Equal(t, 1, awarded.Data.BidCount)
+
+	recorder = do("POST", fmt.Sprintf("%s/%d/award", bidsPath, placed.Data.ID), "", 1, models.RoleShipper)
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+}
@@ -0,0 +1,100 @@
+package handlers_freight_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/db"
+	"freight/models"
+	"freight/services"
+	"freight/utils"
+)
+
+func celsius(v float64) *float64 { return &v }
+
+// 测试创建订单时校验货物信息
+func TestCreateFreightCargoValidation(t *testing.T) {
+	service := newMemoryFreightService(db.NewMemoryFreightRepository())
+	ctx := context.Background()
+
+	newOrder := func(cargo func(o *models.FreightOrder)) *models.FreightOrder {
+		order := &models.FreightOrder{OriginCode: "310100", DestinationCode: "330100", Price: 800, ShipperID: 1, OrderDate: utils.NewDate(2025, 3, 1)}
+		cargo(order)
+		return order
+	}
+
+	testCases := []struct {
+		name  string
+		cargo func(o *models.FreightOrder)
+		field string
+	}{
+		{"重量为负", func(o *models.FreightOrder) { o.WeightKg = -1 }, "weight_kg"},
+		{"件数为负", func(o *models.FreightOrder) { o.Pieces = -2 }, "pieces"},
+		{"危险品类别超出范围", func(o *models.FreightOrder) { o.HazmatClass = 10 }, "hazmat_class"},
+		{"尺寸不完整", func(o *models.FreightOrder) { o.LengthM, o.WidthM = 2, 1 }, "length_m"},
+		{"单件体积超过总体积", func(o *models.FreightOrder) { o.LengthM, o.WidthM, o.HeightM, o.VolumeM3 = 2, 2, 2, 5 }, "volume_m3"},
+		{"密度过大", func(o *models.FreightOrder) { o.WeightKg, o.VolumeM3 = 30000, 1 }, "weight_kg"},
+		{"温度超出范围", func(o *models.FreightOrder) { o.TempMaxC = celsius(45) }, "temp_max_c"},
+		{"温度上下限颠倒", func(o *models.FreightOrder) { o.TempMinC, o.TempMaxC = celsius(8), celsius(2) }, "temp_min_c"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var fieldErr *services.FreightFieldError
+			require.ErrorAs(t, service.CreateFreight(ctx, newOrder(tc.cargo)), &fieldErr)
+			assert.Equal(t, tc.field, fieldErr.Field)
+		})
+	}
+
+	order := newOrder(func(o *models.FreightOrder) {
+		o.WeightKg, o.VolumeM3, o.Pieces = 1200, 8, 10
+		o.LengthM, o.WidthM, o.HeightM = 2, 1, 1
+		o.TempMinC, o.TempMaxC = celsius(2), celsius(8)
+	})
+	require.NoError(t, service.CreateFreight(ctx, order))
+	stored, err := service.GetFreightByID(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 10, stored.Pieces)
+	require.NotNil(t, stored.TempMaxC)
+	assert.Equal(t, 8.0, *stored.TempMaxC)
+}
+
+// 测试按货物重量、尺寸、危险品和冷链过滤
+func TestFreightCargoFilters(t *testing.T) {
+	service := newMemoryFreightService(db.NewMemoryFreightRepository())
+	ctx := context.Background()
+
+	orders := []*models.FreightOrder{
+		{Remark: "普货", WeightKg: 2000, VolumeM3: 10, LengthM: 2, WidthM: 1, HeightM: 1},
+		{Remark: "重货", WeightKg: 25000, VolumeM3: 30, LengthM: 12, WidthM: 2.4, HeightM: 1},
+		{Remark: "冷链", WeightKg: 5000, VolumeM3: 20, TempMinC: celsius(-18), TempMaxC: celsius(-12)},
+		{Remark: "危险品", WeightKg: 3000, VolumeM3: 6, HazmatClass: 3},
+	}
+	for _, o := range orders {
+		o.OriginCode, o.DestinationCode, o.Price, o.ShipperID, o.OrderDate = "310100", "330100", 1000, 1, utils.NewDate(2025, 3, 1)
+		require.NoError(t, service.CreateFreight(ctx, o))
+	}
+
+	remarks := func(filter models.FreightFilter) []string {
+		filter.SortField, filter.SortOrder = "created_at", "asc"
+		page, err := service.ListFreights(ctx, filter)
+		require.NoError(t, err)
+		var result []string
+		for _, o := range page.Items {
+			result = append(result, o.Remark)
+		}
+		return result
+	}
+	yes, no := true, false
+
+	assert.Equal(t, []string{"普货", "冷链", "危险品"}, remarks(models.FreightFilter{MaxWeight: 10000}))
+	assert.Equal(t, []string{"重货", "冷链"}, remarks(models.FreightFilter{MinWeight: 5000}))
+	assert.Equal(t, []string{"普货", "冷链", "危险品"}, remarks(models.FreightFilter{MaxLength: 9.6}))
+	assert.Equal(t, []string{"普货", "危险品"}, remarks(models.FreightFilter{MaxVolume: 15}))
+	assert.Equal(t, []string{"危险品"}, remarks(models.FreightFilter{Hazmat: &yes}))
+	assert.Equal(t, []string{"危险品"}, remarks(models.FreightFilter{HazmatClass: 3}))
+	assert.Equal(t, []string{"冷链"}, remarks(models.FreightFilter{ColdChain: &yes}))
+	assert.Equal(t, []string{"普货", "重货"}, remarks(models.FreightFilter{ColdChain: &no, Hazmat: &no}))
+}
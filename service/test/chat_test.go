@@ -0,0 +1,222 @@
+package handlers_freight_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/api/middleware"
+	"freight/api/routes"
+	"freight/db"
+	"freight/models"
+	"freight/services"
+	"freight/utils"
+)
+
+// 测试订单沟通的权限、消息校验、分页、已读回执和未读数
+func TestChatService(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	freights := newMemoryFreightService(repo)
+	chat := services.NewChatService(db.NewMemoryChatRepository(repo), repo)
+	ctx := context.Background()
+	shipper, carrier := services.ChatAccess{UserID: 1}, services.ChatAccess{UserID: 2}
+	admin := services.ChatAccess{UserID: 9, Moderator: true}
+
+	order := &models.FreightOrder{OriginCode: "310100", DestinationCode: "330100", Price: 1000, ShipperID: 1, OrderDate: utils.NewDate(2025, 3, 1)}
+	require.NoError(t, freights.CreateFreight(ctx, order))
+	err := chat.Send(ctx, order.ID, shipper, &models.ChatMessage{Body: "在吗"})
+	assert.ErrorIs(t, err, services.ErrChatUnavailable)
+	require.NoError(t, freights.AcceptOrder(ctx, order.ID, 2, 0))
+
+	_, err = chat.History(ctx, order.ID, services.ChatAccess{UserID: 3}, 0, 0, 0)
+	assert.ErrorIs(t, err, services.ErrPermissionDenied)
+	_, err = chat.History(ctx, order.ID+100, shipper, 0, 0, 0)
+	assert.ErrorIs(t, err, services.ErrFreightNotFound)
+
+	var fieldErr *services.FreightFieldError
+	for _, tc := range []struct {
+		msg   models.ChatMessage
+		field string
+	}{
+		{models.ChatMessage{Body: "  "}, "body"},
+		{models.ChatMessage{Kind: "voice", Body: "x"}, "kind"},
+		{models.ChatMessage{Body: strings.Repeat("货", 2001)}, "body"},
+		{models.ChatMessage{Kind: models.ChatKindImage}, "attachment_url"},
+		{models.ChatMessage{Kind: models.ChatKindImage, AttachmentURL: "javascript:alert(1)"}, "attachment_url"},
+	} {
+		msg := tc.msg
+		require.ErrorAs(t, chat.Send(ctx, order.ID, shipper, &msg), &fieldErr)
+		assert.Equal(t, tc.field, fieldErr.Field)
+	}
+
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, chat.Send(ctx, order.ID, carrier, &models.ChatMessage{Body: fmt.Sprintf("消息%d", i)}))
+	}
+	photo := &models.ChatMessage{Kind: models.ChatKindImage, AttachmentURL: "https://cdn.example.com/pod/1.jpg", Body: "回单"}
+	require.NoError(t, chat.Send(ctx, order.ID, carrier, photo))
+	require.NoError(t, chat.Send(ctx, order.ID, admin, &models.ChatMessage{Body: "平台客服介入"}))
+
+	history, err := chat.History(ctx, order.ID, shipper, 0, 0, 3)
+	require.NoError(t, err)
+	require.Len(t, history.Messages, 3)
+	assert.Equal(t, photo.ID, history.Messages[1].ID)
+	assert.EqualValues(t, 9, history.Messages[2].SenderID)
+	older, err := chat.History(ctx, order.ID, shipper, history.Messages[0].ID, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, older.Messages, 4)
+	assert.Equal(t, "消息1", older.Messages[0].Body)
+	newer, err := chat.History(ctx, order.ID, shipper, 0, older.Messages[1].ID, 2)
+	require.NoError(t, err)
+	require.Len(t, newer.Messages, 2)
+	assert.Equal(t, "消息3", newer.Messages[0].Body)
+
+	counts, err := chat.UnreadCounts(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, map[uint64]int{order.ID: 7}, counts)
+	counts, err = chat.UnreadCounts(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, map[uint64]int{order.ID: 1}, counts)
+
+	// 已读位置只前进不后退；管理员旁观不产生回执
+	receipt, err := chat.MarkRead(ctx, order.ID, shipper, older.Messages[2].ID)
+	require.NoError(t, err)
+	assert.Equal(t, older.Messages[2].ID, receipt.LastReadID)
+	receipt, err = chat.MarkRead(ctx, order.ID, shipper, older.Messages[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, older.Messages[2].ID, receipt.LastReadID)
+	counts, err = chat.UnreadCounts(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 4, counts[order.ID])
+	_, err = chat.MarkRead(ctx, order.ID, admin, 0)
+	assert.ErrorIs(t, err, services.ErrPermissionDenied)
+
+	_, err = chat.MarkRead(ctx, order.ID, shipper, 0)
+	require.NoError(t, err)
+	counts, err = chat.UnreadCounts(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+	history, err = chat.History(ctx, order.ID, admin, 0, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, history.Receipts, 1)
+	assert.EqualValues(t, 1, history.Receipts[0].UserID)
+	assert.Equal(t, history.Messages[len(history.Messages)-1].ID, history.Receipts[0].LastReadID)
+}
+
+type chatFrame struct {
+	Type     string          `json:"type"`
+	Data     json.RawMessage `json:"data"`
+	Error    string          `json:"error"`
+	Field    string          `json:"field"`
+	ClientID string          `json:"client_id"`
+}
+
+func readChatFrame(t *testing.T, conn *websocket.Conn) chatFrame {
+	t.Helper()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	var frame chatFrame
+	require.NoError(t, conn.ReadJSON(&frame))
+	return frame
+}
+
+// 测试 WebSocket 收发消息、已读回执以及 REST 历史和未读接口
+func TestChatRoutes(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	freights := newMemoryFreightService(repo)
+	chat := services.NewChatService(db.NewMemoryChatRepository(repo), repo)
+	router := routes.SetupRoutes(routes.Deps{
+		FreightService: freights,
+		ChatService:    chat,
+		AuthMiddleware: middleware.NewAuthMiddleware(testJWTSecret, nil),
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	order := &models.FreightOrder{OriginCode: "310100", DestinationCode: "330100", Price: 1000, ShipperID: 1, OrderDate: utils.NewDate(2025, 3, 1)}
+	require.NoError(t, freights.CreateFreight(context.Background(), order))
+	require.NoError(t, freights.AcceptOrder(context.Background(), order.ID, 2, 0))
+	wsURL := fmt.Sprintf("ws%s/api/freights/%d/chat/ws", strings.TrimPrefix(server.URL, "http"), order.ID)
+
+	// 非订单双方在升级前被拒绝
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL+"?access_token="+tokenFor(t, 3, models.RoleCarrier), nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	_, resp, err = websocket.DefaultDialer.Dial(wsURL, nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// 浏览器通过查询参数携带令牌，其他客户端使用请求头
+	shipperConn, _, err := websocket.DefaultDialer.Dial(wsURL+"?access_token="+tokenFor(t, 1, models.RoleShipper), nil)
+	require.NoError(t, err)
+	defer shipperConn.Close()
+	carrierConn, _, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Authorization": {"Bearer " + tokenFor(t, 2, models.RoleCarrier)}})
+	require.NoError(t, err)
+	defer carrierConn.Close()
+
+	require.NoError(t, carrierConn.WriteJSON(map[string]string{"type": "message", "body": "已到达装货点", "client_id": "c-1"}))
+	for _, conn := range []*websocket.Conn{carrierConn, shipperConn} {
+		frame := readChatFrame(t, conn)
+		require.Equal(t, "message", frame.Type)
+		assert.Equal(t, "c-1", frame.ClientID)
+		var msg models.ChatMessage
+		require.NoError(t, json.Unmarshal(frame.Data, &msg))
+		assert.Equal(t, "已到达装货点", msg.Body)
+		assert.EqualValues(t, 2, msg.SenderID)
+	}
+
+	require.NoError(t, carrierConn.WriteJSON(map[string]string{"type": "message", "kind": "image", "client_id": "c-2"}))
+	frame := readChatFrame(t, carrierConn)
+	assert.Equal(t, "error", frame.Type)
+	assert.Equal(t, "attachment_url", frame.Field)
+	assert.Equal(t, "c-2", frame.ClientID)
+
+	do := func(method, path, body string, userID int64, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+tokenFor(t, userID, role))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+	recorder := do("GET", "/api/chat/unread", "", 1, models.RoleShipper)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"total":1`)
+
+	// 发货方标记已读，承运方收到回执
+	require.NoError(t, shipperConn.WriteJSON(map[string]interface{}{"type": "read"}))
+	frame = readChatFrame(t, carrierConn)
+	require.Equal(t, "read", frame.Type)
+	var receipt models.ChatReadReceipt
+	require.NoError(t, json.Unmarshal(frame.Data, &receipt))
+	assert.EqualValues(t, 1, receipt.UserID)
+	assert.Equal(t, "read", readChatFrame(t, shipperConn).Type)
+	recorder = do("GET", "/api/chat/unread", "", 1, models.RoleShipper)
+	assert.Contains(t, recorder.Body.String(), `"total":0`)
+
+	// REST 发送的消息同样实时推送
+	path := fmt.Sprintf("/api/freights/%d/chat/messages", order.ID)
+	recorder = do("POST", path, `{"kind":"image","attachment_url":"https://cdn.example.com/a.jpg"}`, 1, models.RoleShipper)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	frame = readChatFrame(t, carrierConn)
+	assert.Equal(t, "message", frame.Type)
+	assert.Contains(t, string(frame.Data), "a.jpg")
+
+	recorder = do("GET", path+"?limit=1", "", 9, models.RoleAdmin)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var body struct {
+		Data services.ChatHistory `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	require.Len(t, body.Data.Messages, 1)
+	assert.Equal(t, models.ChatKindImage, body.Data.Messages[0].Kind)
+	require.Len(t, body.Data.Receipts, 1)
+	assert.Equal(t, http.StatusForbidden, do("GET", path, "", 3, models.RoleDispatcher).Code)
+	assert.Equal(t, http.StatusBadRequest, do("GET", path+"?before_id=x", "", 1, models.RoleShipper).Code)
+}
@@ -0,0 +1,43 @@
+package handlers_freight_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/db"
+	"freight/services"
+)
+
+// 测试配置服务的增删改查
+func TestConfigService(t *testing.T) {
+	service := services.NewConfigServiceImpl(db.NewMemoryConfigRepository())
+
+	require.NoError(t, service.CreateConfig("rate", "1.5", "单价"))
+	assert.ErrorIs(t, service.CreateConfig("rate", "2", ""), services.ErrConfigExists)
+
+	// SetConfig 已存在时覆盖值，描述为空时保留原描述
+	require.NoError(t, service.SetConfig("rate", "2", ""))
+	config, err := service.GetConfig("rate")
+	require.NoError(t, err)
+	assert.Equal(t, "2", config.Value)
+	assert.Equal(t, "单价", config.Description)
+
+	// SetConfig 不存在时创建
+	require.NoError(t, service.SetConfig("currency", "CNY", ""))
+	configs, err := service.ListConfigs()
+	require.NoError(t, err)
+	assert.Len(t, configs, 2)
+
+	config.Value = "3"
+	require.NoError(t, service.UpdateConfig(config))
+	config, err = service.GetConfig("rate")
+	require.NoError(t, err)
+	assert.Equal(t, "3", config.Value)
+
+	require.NoError(t, service.DeleteConfig("rate"))
+	assert.ErrorIs(t, service.DeleteConfig("rate"), services.ErrConfigNotFound)
+	_, err = service.GetConfig("rate")
+	assert.ErrorIs(t, err, services.ErrConfigNotFound)
+}
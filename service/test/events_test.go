@@ -0,0 +1,229 @@
+package handlers_freight_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/api/handlers"
+	"freight/api/middleware"
+	"freight/api/routes"
+	"freight/db"
+	"freight/events"
+	"freight/models"
+	"freight/region"
+	"freight/services"
+	"freight/utils"
+)
+
+// 测试事件总线的缓冲区重放和缺口检测
+func TestEventBusReplay(t *testing.T) {
+	bus := events.NewBus(3)
+	all := func(events.Event) bool { return true }
+	for i := 1; i <= 5; i++ {
+		bus.Publish(events.Event{Type: events.TypeOrderCreated, OrderID: uint64(i)})
+	}
+
+	// 缓冲区保留事件 3~5
+	_, replay, complete := bus.Subscribe(3, all)
+	assert.True(t, complete)
+	require.Len(t, replay, 2)
+	assert.EqualValues(t, 4, replay[0].ID)
+	_, replay, complete = bus.Subscribe(2, all)
+	assert.True(t, complete)
+	assert.Len(t, replay, 3)
+	_, _, complete = bus.Subscribe(1, all)
+	assert.False(t, complete)
+	_, replay, complete = bus.Subscribe(99, all) // 重启前的事件ID
+	assert.False(t, complete)
+	assert.Empty(t, replay)
+
+	// 只推送匹配的事件；过慢的订阅者被断开
+	sub, _, _ := bus.Subscribe(0, func(e events.Event) bool { return e.OrderID == 7 })
+	slow, _, _ := bus.Subscribe(0, all)
+	bus.Publish(events.Event{OrderID: 6})
+	bus.Publish(events.Event{OrderID: 7})
+	e := <-sub.C
+	assert.EqualValues(t, 7, e.OrderID)
+	for i := 0; i < 100; i++ {
+		bus.Publish(events.Event{OrderID: 8})
+	}
+	drained := 0
+	for range slow.C {
+		drained++
+	}
+	assert.Less(t, drained, 100)
+
+	bus.Close()
+	_, open := <-sub.C
+	assert.False(t, open)
+	bus.Unsubscribe(sub)
+}
+
+type sseEvent struct {
+	id, event, data string
+}
+
+// openStream 建立 SSE 连接并逐条读取事件（心跳记为 event=ping）
+func openStream(t *testing.T, url, token, lastEventID string) <-chan sseEvent {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	ch := make(chan sseEvent, 64)
+	go func() {
+		defer resp.Body.Close()
+		defer close(ch)
+		var current sseEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == ": ping":
+				ch <- sseEvent{event: "ping"}
+			case strings.HasPrefix(line, "id: "):
+				current.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				current.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				current.data = strings.TrimPrefix(line, "data: ")
+			case line == "" && current.event != "":
+				ch <- current
+				current = sseEvent{}
+			}
+		}
+	}()
+	return ch
+}
+
+// nextEvent 读取下一条非心跳事件
+func nextEvent(t *testing.T, ch <-chan sseEvent) sseEvent {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case e, ok := <-ch:
+			require.True(t, ok, "事件流已关闭")
+			if e.event != "ping" {
+				return e
+			}
+		case <-timeout:
+			t.Fatal("等待事件超时")
+		}
+	}
+}
+
+// 测试 SSE 推送：大厅与本人订单的过滤、心跳和 Last-Event-ID 重放
+func TestEventStream(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	regions := region.Default()
+	bus := events.NewBus(0)
+	freights := services.NewFreightService(repo, repo, regions, services.NewHaversineEstimator(regions, services.RouteOptions{}),
+		db.NewMemoryVehicleRepository(), bus)
+	router := routes.SetupRoutes(routes.Deps{
+		FreightService: freights,
+		EventHandler:   handlers.NewEventHandler(bus, 20*time.Millisecond),
+		Regions:        regions,
+		AuthMiddleware: middleware.NewAuthMiddleware(testJWTSecret, nil),
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+	defer bus.Close()
+	ctx := context.Background()
+
+	req := httptest.NewRequest("GET", "/api/events", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	req = httptest.NewRequest("GET", "/api/events?topics=all", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenFor(t, 1, models.RoleShipper))
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	hallFeed := openStream(t, server.URL+"/api/events?topics=hall", tokenFor(t, 3, models.RoleCarrier), "")
+	shipperFeed := openStream(t, server.URL+"/api/events?topics=orders", tokenFor(t, 1, models.RoleShipper), "")
+
+	order := &models.FreightOrder{OriginCode: "310100", DestinationCode: "330100", Price: 1000, ShipperID: 1, OrderDate: utils.NewDate(2025, 3, 1)}
+	require.NoError(t, freights.CreateFreight(ctx, order))
+	require.NoError(t, freights.AcceptOrder(ctx, order.ID, 2, 0))
+	require.NoError(t, freights.TransitionOrder(ctx, order.ID, 2, models.FreightStatusPickedUp, ""))
+	other := &models.FreightOrder{OriginCode: "310100", DestinationCode: "330100", Price: 800, ShipperID: 5, OrderDate: utils.NewDate(2025, 3, 1)}
+	require.NoError(t, freights.CreateFreight(ctx, other))
+
+	// 大厅：上架和被接走，不包含取货等后续变化
+	e := nextEvent(t, hallFeed)
+	assert.Equal(t, events.TypeOrderCreated, e.event)
+	assert.Equal(t, "1", e.id)
+	var payload struct {
+		ID      uint64 `json:"id"`
+		OrderID uint64 `json:"order_id"`
+		Data    struct {
+			Price float64 `json:"price"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(e.data), &payload))
+	assert.Equal(t, order.ID, payload.OrderID)
+	assert.Equal(t, 1000.0, payload.Data.Price)
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal([]byte(e.data), &fields))
+	assert.ElementsMatch(t, []string{"id", "type", "order_id", "time", "data"}, keys(fields)) // 过滤字段不下发
+	assert.Equal(t, events.TypeOrderAccepted, nextEvent(t, hallFeed).event)
+	e = nextEvent(t, hallFeed)
+	assert.Equal(t, events.TypeOrderCreated, e.event)
+	assert.Equal(t, "4", e.id)
+
+	// 发货方：本人订单的全部变化，不包含他人订单
+	for _, want := range []string{events.TypeOrderCreated, events.TypeOrderAccepted, events.TypeOrderStatus} {
+		assert.Equal(t, want, nextEvent(t, shipperFeed).event)
+	}
+	pinged := false
+	for !pinged {
+		select {
+		case e := <-shipperFeed:
+			require.Equal(t, "ping", e.event)
+			pinged = true
+		case <-time.After(2 * time.Second):
+			t.Fatal("未收到心跳")
+		}
+	}
+
+	// 承运方断线重连：重放本人承运订单的事件
+	replayed := openStream(t, server.URL+"/api/events?topics=orders", tokenFor(t, 2, models.RoleCarrier), "2")
+	e = nextEvent(t, replayed)
+	assert.Equal(t, events.TypeOrderStatus, e.event)
+	assert.Equal(t, "3", e.id)
+
+	// 调度可查看全部订单；ID 不在缓冲区时先收到 reset
+	dispatcher := openStream(t, server.URL+"/api/events?topics=orders", tokenFor(t, 9, models.RoleDispatcher), "100")
+	assert.Equal(t, "reset", nextEvent(t, dispatcher).event)
+	require.NoError(t, freights.TransitionOrder(ctx, other.ID, 5, models.FreightStatusCancelled, ""))
+	e = nextEvent(t, dispatcher)
+	assert.Equal(t, events.TypeOrderCancelled, e.event)
+	assert.Equal(t, events.TypeOrderCancelled, nextEvent(t, hallFeed).event)
+}
+
+func keys(m map[string]json.RawMessage) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
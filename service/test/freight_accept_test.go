@@ -0,0 +1,260 @@
+package handlers_freight_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/api/handlers"
+	"freight/api/middleware"
+	"freight/api/reqctx"
+	"freight/api/routes"
+	"freight/db"
+	"freight/models"
+	"freight/region"
+	"freight/services"
+)
+
+// 创建基于内存仓储的订单服务（内存仓储同时提供全文检索）
+func newMemoryFreightService(repo *db.MemoryFreightRepository) services.FreightService {
+	return newMemoryFreightServiceWithVehicles(repo, db.NewMemoryVehicleRepository())
+}
+
+// 创建基于内存仓储的订单服务，使用指定的车辆仓储
+func newMemoryFreightServiceWithVehicles(repo *db.MemoryFreightRepository, vehicles models.VehicleRepository) services.FreightService {
+	regions := region.Default()
+	return services.NewFreightService(repo, repo, regions, services.NewHaversineEstimator(regions, services.RouteOptions{}), vehicles, nil)
+}
+
+// 创建一条待接单的测试订单
+func createPendingOrder(t *testing.T, repo db.FreightRepository) *models.FreightOrder {
+	order := &models.FreightOrder{
+		OriginLocation:      "上海",
+		OriginCode:          "310100",
+		DestinationLocation: "杭州",
+		DestinationCode:     "330100",
+		Price:               1200,
+		ShipperID:           1,
+	}
+	require.NoError(t, repo.Create(context.Background(), order))
+	return order
+}
+
+// 多个司机同时抢同一订单，只能有一人成功
+func TestAcceptOrderConcurrent(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	service := newMemoryFreightService(repo)
+	order := createPendingOrder(t, repo)
+
+	const drivers = 50
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		winners []uint64
+		taken   int
+	)
+	for i := 0; i < drivers; i++ {
+		wg.Add(1)
+		go func(userID uint64) {
+			defer wg.Done()
+			err := service.AcceptOrder(context.Background(), order.ID, userID, 0)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				winners = append(winners, userID)
+			case errors.Is(err, services.ErrOrderTaken):
+				taken++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}(uint64(100 + i))
+	}
+	wg.Wait()
+
+	require.Len(t, winners, 1)
+	assert.Equal(t, drivers-1, taken)
+
+	accepted, err := repo.GetByID(context.Background(), order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(models.FreightStatusAccepted), accepted.Status)
+	assert.Equal(t, winners[0], accepted.CarrierID)
+	assert.Equal(t, uint64(1), accepted.ShipperID)
+
+	events, err := service.GetOrderHistory(context.Background(), order.ID, order.ShipperID, false)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, winners[0], events[0].ActorID)
+}
+
+// 抢单失败的一方收到409
+func TestAcceptFreightConflict(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	handler := handlers.NewFreightHandler(newMemoryFreightService(repo))
+	order := createPendingOrder(t, repo)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/freights/{id:[0-9]+}/accept", handler.AcceptFreight).Methods("POST")
+
+	accept := func(userID int64) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/freights/1/accept", strings.NewReader(`{"user_id":99}`))
+		req = req.WithContext(reqctx.WithUser(req.Context(), userID, "driver"))
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, uint64(1), order.ID)
+	assert.Equal(t, http.StatusOK, accept(2))
+	assert.Equal(t, http.StatusConflict, accept(3))
+
+	// 请求体中的user_id被忽略，接单人为认证用户
+	accepted, err := repo.GetByID(context.Background(), order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), accepted.CarrierID)
+
+	// 发货方不能接自己的订单
+	other := createPendingOrder(t, repo)
+	req := httptest.NewRequest(http.MethodPost, "/api/freights/2/accept", nil)
+	req = req.WithContext(reqctx.WithUser(req.Context(), int64(other.ShipperID), "shipper"))
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+}
+
+// 只有承运方可以取货送达，只有发货方可以取消
+func TestFreightOwnershipRules(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	service := newMemoryFreightService(repo)
+	ctx := context.Background()
+
+	order := createPendingOrder(t, repo)
+	const shipper, carrier, stranger = 1, 2, 3
+
+	assert.ErrorIs(t, service.TransitionOrder(ctx, order.ID, carrier, models.FreightStatusCancelled, ""), services.ErrPermissionDenied)
+	require.NoError(t, service.AcceptOrder(ctx, order.ID, carrier, 0))
+
+	assert.ErrorIs(t, service.TransitionOrder(ctx, order.ID, shipper, models.FreightStatusPickedUp, ""), services.ErrPermissionDenied)
+	assert.ErrorIs(t, service.TransitionOrder(ctx, order.ID, stranger, models.FreightStatusPickedUp, ""), services.ErrPermissionDenied)
+	require.NoError(t, service.TransitionOrder(ctx, order.ID, carrier, models.FreightStatusPickedUp, "已装车"))
+	require.NoError(t, service.TransitionOrder(ctx, order.ID, carrier, models.FreightStatusShipping, "发车"))
+	assert.ErrorIs(t, service.CompleteOrder(ctx, order.ID, shipper), services.ErrPermissionDenied)
+	require.NoError(t, service.CompleteOrder(ctx, order.ID, carrier))
+	require.NoError(t, service.TransitionOrder(ctx, order.ID, shipper, models.FreightStatusConfirmed, "已收货"))
+
+	events, err := service.GetOrderHistory(ctx, order.ID, carrier, false)
+	require.NoError(t, err)
+	assert.Len(t, events, 5)
+	_, err = service.GetOrderHistory(ctx, order.ID, stranger, false)
+	assert.ErrorIs(t, err, services.ErrPermissionDenied)
+	events, err = service.GetOrderHistory(ctx, order.ID, stranger, true)
+	require.NoError(t, err)
+	assert.Len(t, events, 5)
+
+	mine, err := service.ListByUserID(ctx, carrier, models.FreightFilter{Role: models.FreightRoleCarrier})
+	require.NoError(t, err)
+	assert.Len(t, mine.Items, 1)
+	assert.EqualValues(t, 1, mine.Total)
+	mine, err = service.ListByUserID(ctx, carrier, models.FreightFilter{Role: models.FreightRoleShipper})
+	require.NoError(t, err)
+	assert.Empty(t, mine.Items)
+}
+
+// 订单时间线仅订单双方和可查看全部订单的角色可见，其他用户403
+func TestFreightHistoryAccess(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	service := newMemoryFreightService(repo)
+	order := createPendingOrder(t, repo)
+	require.NoError(t, service.AcceptOrder(context.Background(), order.ID, 2, 0))
+	router := routes.SetupRoutes(routes.Deps{
+		FreightService: service,
+		AuthMiddleware: middleware.NewAuthMiddleware(testJWTSecret, nil),
+	})
+
+	history := func(userID int64, role string) int {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/freights/%d/history", order.ID), nil)
+		req.Header.Set("Authorization", "Bearer "+tokenFor(t, userID, role))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusOK, history(1, models.RoleShipper))
+	assert.Equal(t, http.StatusOK, history(2, models.RoleCarrier))
+	assert.Equal(t, http.StatusForbidden, history(3, models.RoleCarrier))
+	assert.Equal(t, http.StatusOK, history(9, models.RoleDispatcher))
+}
+
+// 发货方只能删除待接单的订单，接单后须走取消流程
+func TestDeleteFreightOnlyPending(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	service := newMemoryFreightService(repo)
+	ctx := context.Background()
+	const shipper, carrier = 1, 2
+
+	accepted := createPendingOrder(t, repo)
+	require.NoError(t, service.AcceptOrder(ctx, accepted.ID, carrier, 0))
+	require.NoError(t, service.TransitionOrder(ctx, accepted.ID, carrier, models.FreightStatusPickedUp, "已装车"))
+	assert.ErrorIs(t, service.DeleteFreight(ctx, accepted.ID, shipper), services.ErrOrderNotDeletable)
+	order, err := service.GetFreightByID(ctx, accepted.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, models.FreightStatusPickedUp, order.Status)
+
+	cancelled := createPendingOrder(t, repo)
+	require.NoError(t, service.TransitionOrder(ctx, cancelled.ID, shipper, models.FreightStatusCancelled, "不发了"))
+	assert.ErrorIs(t, service.DeleteFreight(ctx, cancelled.ID, shipper), services.ErrOrderNotDeletable)
+
+	pending := createPendingOrder(t, repo)
+	assert.ErrorIs(t, service.DeleteFreight(ctx, pending.ID, carrier), services.ErrPermissionDenied)
+	require.NoError(t, service.DeleteFreight(ctx, pending.ID, shipper))
+	assert.ErrorIs(t, service.DeleteFreight(ctx, pending.ID, shipper), services.ErrFreightNotFound)
+}
+
+// 读取订单后被并发接单的仓储：模拟 UpdateFreight 校验通过到写入之间订单被抢走
+type acceptAfterReadRepo struct {
+	*db.MemoryFreightRepository
+	carrierID uint64
+}
+
+func (r *acceptAfterReadRepo) GetByID(ctx context.Context, id uint64) (*models.FreightOrder, error) {
+	order, err := r.MemoryFreightRepository.GetByID(ctx, id)
+	if err != nil || order == nil {
+		return order, err
+	}
+	if _, err := r.AcceptPending(ctx, id, r.carrierID, 0); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// 仅待接单的订单可修改；读取后被并发接单时不能覆盖已接单的订单
+func TestUpdateFreightOnlyPending(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	service := newMemoryFreightService(repo)
+	ctx := context.Background()
+	const shipper, carrier = 1, 2
+
+	accepted := createPendingOrder(t, repo)
+	require.NoError(t, service.AcceptOrder(ctx, accepted.ID, carrier, 0))
+	err := service.UpdateFreight(ctx, &models.FreightOrder{ID: accepted.ID, OriginCode: "320100"}, shipper)
+	assert.ErrorIs(t, err, services.ErrOrderNotEditable)
+
+	racing := createPendingOrder(t, repo)
+	raced := services.NewFreightService(&acceptAfterReadRepo{MemoryFreightRepository: repo, carrierID: carrier}, repo,
+		region.Default(), services.NewHaversineEstimator(region.Default(), services.RouteOptions{}), db.NewMemoryVehicleRepository(), nil)
+	err = raced.UpdateFreight(ctx, &models.FreightOrder{ID: racing.ID, OriginCode: "320100"}, shipper)
+	assert.ErrorIs(t, err, services.ErrOrderNotEditable)
+	order, err := service.GetFreightByID(ctx, racing.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, models.FreightStatusAccepted, order.Status)
+	assert.Equal(t, "上海", order.OriginLocation)
+}
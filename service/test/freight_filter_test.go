@@ -0,0 +1,113 @@
+package handlers_freight_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/api/middleware"
+	"freight/api/routes"
+	"freight/db"
+	"freight/models"
+	"freight/utils"
+)
+
+// 预置几条不同价格、日期、加急标记的待接单订单
+func seedFilterOrders(t *testing.T, repo db.FreightRepository) {
+	orders := []models.FreightOrder{
+		{OriginCode: "310100", DestinationCode: "330100", TypeID: 1, Price: 800, IsUrgent: true, OrderDate: utils.NewDate(2025, 3, 1)},
+		{OriginCode: "310100", DestinationCode: "320100", TypeID: 2, Price: 1500, OrderDate: utils.NewDate(2025, 3, 5)},
+		{OriginCode: "110100", DestinationCode: "330100", TypeID: 1, Price: 2600, HasInsurance: true, OrderDate: utils.NewDate(2025, 3, 10)},
+	}
+	for i := range orders {
+		orders[i].ShipperID = 1
+		require.NoError(t, repo.Create(context.Background(), &orders[i]))
+	}
+}
+
+// 测试订单列表应用全部过滤条件和白名单排序
+func TestFreightListFilters(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	seedFilterOrders(t, repo)
+	ctx := context.Background()
+	urgent := true
+
+	testCases := []struct {
+		name   string
+		filter models.FreightFilter
+		prices []float64
+	}{
+		{"价格区间", models.FreightFilter{MinPrice: 1000, MaxPrice: 2000}, []float64{1500}},
+		{"起点编码", models.FreightFilter{OriginCode: "310100", SortField: "price", SortOrder: "asc"}, []float64{800, 1500}},
+		{"类型和目的地", models.FreightFilter{TypeID: 1, DestinationCode: "330100", SortField: "price", SortOrder: "desc"}, []float64{2600, 800}},
+		{"加急", models.FreightFilter{IsUrgent: &urgent}, []float64{800}},
+		{"下单日期范围", models.FreightFilter{OrderDateFrom: "2025-03-02", OrderDateTo: "2025-03-10", SortField: "order_date", SortOrder: "asc"}, []float64{1500, 2600}},
+		{"下单日期", models.FreightFilter{OrderDate: "2025-03-01"}, []float64{800}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			freights, total, err := repo.List(ctx, tc.filter)
+			require.NoError(t, err)
+			assert.EqualValues(t, len(tc.prices), total)
+			var prices []float64
+			for _, f := range freights {
+				prices = append(prices, f.Price)
+			}
+			assert.Equal(t, tc.prices, prices)
+		})
+	}
+}
+
+// 测试列表参数校验：400 响应中给出无效的参数名
+func TestFreightListInvalidParams(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	router := routes.SetupRoutes(routes.Deps{
+		FreightService: newMemoryFreightService(repo),
+		AuthMiddleware: middleware.NewAuthMiddleware(testJWTSecret, nil),
+	})
+
+	testCases := []struct {
+		query string
+		param string
+	}{
+		{"min_price=abc", "min_price"},
+		{"min_price=500&max_price=100", "min_price"},
+		{"is_urgent=maybe", "is_urgent"},
+		{"order_date_from=2025/01/01", "order_date_from"},
+		{"order_date_from=2025-02-01&order_date_to=2025-01-01", "order_date_from"},
+		{"sort=email", "sort"},
+		{"order=up", "order"},
+		{"min_distance=900&max_distance=100", "min_distance"},
+		{"min_weight=5000&max_weight=100", "min_weight"},
+		{"hazmat_class=10", "hazmat_class"},
+		{"cold_chain=yes", "cold_chain"},
+		{"pickup_within_hours=0", "pickup_within_hours"},
+		{"pickup_within_hours=1000", "pickup_within_hours"},
+		{"status=42", "status"},
+		{"page_size=-1", "page_size"},
+		{"cursor=bm90LWEtY3Vyc29y", "cursor"},
+		{"cursor=&sort=price", "cursor"},
+		{"cursor=&page=2", "cursor"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/freights?"+tc.query, nil)
+			req.Header.Set("Authorization", "Bearer "+tokenFor(t, 1, models.RoleUser))
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusBadRequest, recorder.Code)
+			var body map[string]string
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+			assert.Equal(t, tc.param, body["param"])
+			assert.Contains(t, body["error"], tc.param)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package handlers_freight_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/db"
+	"freight/models"
+	"freight/region"
+	"freight/services"
+)
+
+// 抢单的条件更新语句：仅当订单仍为待接单时更新
+const acceptPendingSQL = `UPDATE freight_orders\s+SET carrier_id = \?, vehicle_id = \?, status = \?, updated_at = NOW\(\)\s+WHERE id = \? AND status = \?`
+
+// 一条待接单订单的查询结果（列与 GetByID 查询一致）
+func pendingOrderRows(id, shipperID uint64) *sqlmock.Rows {
+	now := time.Now()
+	return sqlmock.NewRows([]string{
+		"id", "origin_location", "destination_location", "origin_code", "destination_code", "type", "typeid", "remark",
+		"order_date", "price", "status", "is_urgent", "has_insurance",
+		"created_at", "updated_at", "email", "shipper_id", "carrier_id", "distance_km", "estimated_hours",
+		"bidding_enabled", "bid_count", "weight_kg", "volume_m3", "pieces", "length_m", "width_m", "height_m",
+		"hazmat_class", "temp_min_c", "temp_max_c", "vehicle_id",
+		"pickup_earliest", "pickup_latest", "delivery_earliest", "delivery_latest",
+	}).AddRow(
+		id, "上海市", "浙江省杭州市", "310100", "330100", "", 0, "",
+		now, 1200, models.FreightStatusPending, false, false,
+		now, now, "", shipperID, 0, 170, 2.5,
+		false, 0, 0, 0, 0, 0, 0, 0,
+		0, nil, nil, 0,
+		nil, nil, nil, nil,
+	)
+}
+
+// 测试 MySQL 抢单使用带状态条件的更新，并在同一事务中写入流转记录
+func TestMySQLAcceptPending(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer conn.Close()
+	repo := db.NewFreightRepository(conn)
+	ctx := context.Background()
+	const orderID, carrier, vehicle = 10, 2, 3
+
+	mock.ExpectBegin()
+	mock.ExpectExec(acceptPendingSQL).
+		WithArgs(carrier, vehicle, models.FreightStatusAccepted, orderID, models.FreightStatusPending).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO freight_order_events`).
+		WithArgs(orderID, models.FreightStatusPending, models.FreightStatusAccepted, carrier, "接单", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	ok, err := repo.AcceptPending(ctx, orderID, carrier, vehicle)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// 条件更新未命中：不写流转记录，回滚事务
+	mock.ExpectBegin()
+	mock.ExpectExec(acceptPendingSQL).
+		WithArgs(carrier, vehicle, models.FreightStatusAccepted, orderID, models.FreightStatusPending).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	ok, err = repo.AcceptPending(ctx, orderID, carrier, vehicle)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 测试读取订单后被他人抢先接单时，条件更新未命中返回 ErrOrderTaken
+func TestAcceptOrderTakenMySQL(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer conn.Close()
+	repo := db.NewFreightRepository(conn)
+	regions := region.Default()
+	service := services.NewFreightService(repo, db.NewFreightSearchIndex(conn), regions,
+		services.NewHaversineEstimator(regions, services.RouteOptions{}), db.NewMemoryVehicleRepository(), nil)
+	const orderID, shipper, carrier = 10, 1, 2
+
+	mock.ExpectQuery(`SELECT .+ FROM freight_orders WHERE id = \? AND status != 0`).
+		WithArgs(orderID).
+		WillReturnRows(pendingOrderRows(orderID, shipper))
+	mock.ExpectBegin()
+	mock.ExpectExec(acceptPendingSQL).
+		WithArgs(carrier, 0, models.FreightStatusAccepted, orderID, models.FreightStatusPending).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err = service.AcceptOrder(context.Background(), orderID, carrier, 0)
+	assert.ErrorIs(t, err, services.ErrOrderTaken)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,107 @@
+package handlers_freight_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/api/middleware"
+	"freight/api/routes"
+	"freight/db"
+	"freight/models"
+	"freight/utils"
+)
+
+// 发布 n 条待接单订单
+func seedPendingOrders(t *testing.T, repo db.FreightRepository, n int) {
+	for i := 0; i < n; i++ {
+		require.NoError(t, repo.Create(context.Background(), &models.FreightOrder{
+			OriginCode: "310100", DestinationCode: "330100", Price: float64(100 + i),
+			OrderDate: utils.NewDate(2025, 3, 1), ShipperID: 1,
+		}))
+	}
+}
+
+// 测试列表返回分页信封，并限制最大每页条数
+func TestFreightListPageEnvelope(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	seedPendingOrders(t, repo, 5)
+	router := routes.SetupRoutes(routes.Deps{
+		FreightService: newMemoryFreightService(repo),
+		AuthMiddleware: middleware.NewAuthMiddleware(testJWTSecret, nil),
+	})
+
+	get := func(query string) models.FreightPage {
+		req := httptest.NewRequest("GET", "/api/freights?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+tokenFor(t, 1, models.RoleUser))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var body struct {
+			Data models.FreightPage `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		return body.Data
+	}
+
+	page := get("page=2&page_size=2")
+	assert.Len(t, page.Items, 2)
+	assert.EqualValues(t, 5, page.Total)
+	assert.Equal(t, 2, page.Page)
+	assert.Equal(t, 2, page.PageSize)
+	assert.True(t, page.HasMore)
+
+	page = get("page=3&page_size=2")
+	assert.Len(t, page.Items, 1)
+	assert.False(t, page.HasMore)
+
+	page = get("page_size=1000")
+	assert.Equal(t, models.MaxPageSize, page.PageSize)
+	assert.Equal(t, 1, page.Page)
+	assert.Len(t, page.Items, 5)
+}
+
+// 测试游标翻页期间有新订单发布时，结果不重复也不遗漏
+func TestFreightListCursorStable(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	seedPendingOrders(t, repo, 5)
+	service := newMemoryFreightService(repo)
+	ctx := context.Background()
+
+	first, err := service.ListFreights(ctx, models.FreightFilter{CursorMode: true, PageSize: 2})
+	require.NoError(t, err)
+	require.Len(t, first.Items, 2)
+	require.True(t, first.HasMore)
+	require.NotEmpty(t, first.NextCursor)
+
+	// 翻页过程中有新订单发布，页码模式下会导致第二页出现重复订单
+	seedPendingOrders(t, repo, 3)
+
+	seen := map[uint64]bool{}
+	for _, o := range first.Items {
+		seen[o.ID] = true
+	}
+	cursor := first.NextCursor
+	for cursor != "" {
+		after, err := models.DecodeFreightCursor(cursor)
+		require.NoError(t, err)
+		page, err := service.ListFreights(ctx, models.FreightFilter{CursorMode: true, After: after, PageSize: 2})
+		require.NoError(t, err)
+		for _, o := range page.Items {
+			assert.False(t, seen[o.ID], "订单 %d 重复出现", o.ID)
+			assert.LessOrEqual(t, o.ID, uint64(5), "翻页过程中不应出现新发布的订单")
+			seen[o.ID] = true
+		}
+		cursor = page.NextCursor
+	}
+	assert.Len(t, seen, 5)
+
+	_, err = models.DecodeFreightCursor("bm90LWEtY3Vyc29y")
+	assert.ErrorIs(t, err, models.ErrInvalidCursor)
+}
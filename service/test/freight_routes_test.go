@@ -3,9 +3,11 @@ package handlers_freight_test
 import (
 	"context"
 	"freight/api/handlers"
+	"freight/api/reqctx"
 	"freight/models"
 	"github.com/gorilla/mux"
 	"net/http"
+	"time"
 )
 
 // 测试用的货运服务实现
@@ -17,8 +19,12 @@ func (t *testFreightService) CreateFreight(ctx context.Context, freight *models.
 	return nil
 }
 
-func (t *testFreightService) ListFreights(ctx context.Context, filter models.FreightFilter) ([]*models.FreightOrder, error) {
-	return []*models.FreightOrder{{ID: 1}}, nil
+func (t *testFreightService) ListFreights(ctx context.Context, filter models.FreightFilter) (*models.FreightPage, error) {
+	return &models.FreightPage{Items: []*models.FreightOrder{{ID: 1}}, Total: 1, Page: 1, PageSize: models.DefaultPageSize}, nil
+}
+
+func (t *testFreightService) SearchFreights(ctx context.Context, query string, filter models.FreightFilter) (*models.FreightPage, error) {
+	return &models.FreightPage{Items: []*models.FreightOrder{}, PageSize: models.DefaultPageSize}, nil
 }
 
 // 确保其他方法也与接口一致
@@ -27,13 +33,13 @@ func (t *testFreightService) GetFreightByID(ctx context.Context, id uint64) (*mo
 }
 
 // 4. 更新货运单
-func (t *testFreightService) UpdateFreight(ctx context.Context, freight *models.FreightOrder) error {
+func (t *testFreightService) UpdateFreight(ctx context.Context, freight *models.FreightOrder, userID uint64) error {
 	// 模拟更新成功
 	return nil
 }
 
 // 5. 删除货运单
-func (t *testFreightService) DeleteFreight(ctx context.Context, id uint64) error {
+func (t *testFreightService) DeleteFreight(ctx context.Context, id, userID uint64) error {
 	// 模拟删除成功
 	return nil
 }
@@ -47,24 +53,35 @@ func (t *testFreightService) CompleteOrder(ctx context.Context, orderID uint64,
 	return nil
 }
 
-func (t *testFreightService) ListByUserID(ctx context.Context, userID uint64, filter models.FreightFilter) ([]*models.FreightOrder, error) {
+func (t *testFreightService) ListByUserID(ctx context.Context, userID uint64, filter models.FreightFilter) (*models.FreightPage, error) {
 	//TODO implement me
 	panic("implement me")
 }
 
 // 补全缺失的 AcceptOrder 方法（关键）
-func (t *testFreightService) AcceptOrder(ctx context.Context, orderID uint64, userID uint64) error {
+func (t *testFreightService) AcceptOrder(ctx context.Context, orderID uint64, userID uint64, vehicleID uint64) error {
 	// 模拟接单成功
 	return nil
 }
 
+func (t *testFreightService) TransitionOrder(ctx context.Context, orderID, actorID uint64, to uint8, reason string) error {
+	return nil
+}
+
+func (t *testFreightService) GetOrderHistory(ctx context.Context, orderID, userID uint64, viewAll bool) ([]*models.FreightOrderEvent, error) {
+	return []*models.FreightOrderEvent{}, nil
+}
+
+func (t *testFreightService) ExpireOverdueOrders(ctx context.Context, now time.Time) (int, error) {
+	return 0, nil
+}
+
 // 测试用认证中间件
 type testAuthMiddlewares struct{}
 
 func (t *testAuthMiddlewares) Handler(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.WithValue(r.Context(), "userID", uint64(1))
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r.WithContext(reqctx.WithUser(r.Context(), 1, "test")))
 	}
 }
 
@@ -0,0 +1,127 @@
+package handlers_freight_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/api/middleware"
+	"freight/api/routes"
+	"freight/db"
+	"freight/models"
+	"freight/services"
+	"freight/utils"
+)
+
+// 预置几条出发地、货物类型和备注不同的待接单订单，返回订单ID
+func seedSearchOrders(t *testing.T, repo db.FreightRepository) []uint64 {
+	orders := []models.FreightOrder{
+		{OriginLocation: "上海市浦东新区", DestinationLocation: "杭州市", Type: "冷链", Remark: "冷链生鲜，需全程冷藏", Price: 1200},
+		{OriginLocation: "上海市嘉定区", DestinationLocation: "南京市", Type: "普货", Remark: "家具", Price: 900},
+		{OriginLocation: "北京市", DestinationLocation: "天津市", Type: "冷链", Remark: "冷冻肉类", Price: 700},
+		{OriginLocation: "广州市", DestinationLocation: "深圳市", Type: "普货", Remark: "电子产品", Price: 500, IsUrgent: true},
+	}
+	var ids []uint64
+	for i := range orders {
+		orders[i].OriginCode, orders[i].DestinationCode = "000000", "000000"
+		orders[i].OrderDate = utils.NewDate(2025, 3, 1)
+		orders[i].ShipperID = 1
+		require.NoError(t, repo.Create(context.Background(), &orders[i]))
+		ids = append(ids, orders[i].ID)
+	}
+	return ids
+}
+
+// 测试关键词检索按相关度排序，并可叠加列表过滤条件
+func TestSearchFreights(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	ids := seedSearchOrders(t, repo)
+	service := newMemoryFreightService(repo)
+	ctx := context.Background()
+
+	resultIDs := func(page *models.FreightPage) []uint64 {
+		var got []uint64
+		for _, o := range page.Items {
+			got = append(got, o.ID)
+		}
+		return got
+	}
+
+	// 同时匹配“上海”和“冷链”的订单排在最前，只匹配其一的排在后面
+	page, err := service.SearchFreights(ctx, "上海 冷链", models.FreightFilter{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, page.Total)
+	require.Len(t, page.Items, 3)
+	assert.Equal(t, ids[0], page.Items[0].ID)
+	assert.ElementsMatch(t, []uint64{ids[1], ids[2]}, resultIDs(page)[1:])
+
+	page, err = service.SearchFreights(ctx, "冷链", models.FreightFilter{MaxPrice: 1000})
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{ids[2]}, resultIDs(page))
+
+	page, err = service.SearchFreights(ctx, "电子", models.FreightFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{ids[3]}, resultIDs(page))
+
+	// 删除后的订单不再出现在结果中
+	deleted, err := repo.Delete(ctx, ids[0])
+	require.NoError(t, err)
+	require.True(t, deleted)
+	page, err = service.SearchFreights(ctx, "上海 冷链", models.FreightFilter{})
+	require.NoError(t, err)
+	assert.NotContains(t, resultIDs(page), ids[0])
+
+	page, err = service.SearchFreights(ctx, "成都", models.FreightFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, page.Items)
+
+	_, err = service.SearchFreights(ctx, "  ", models.FreightFilter{})
+	assert.ErrorIs(t, err, services.ErrEmptySearchQuery)
+}
+
+// 测试搜索接口的参数校验和分页信封
+func TestSearchFreightsRoute(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	seedSearchOrders(t, repo)
+	router := routes.SetupRoutes(routes.Deps{
+		FreightService: newMemoryFreightService(repo),
+		AuthMiddleware: middleware.NewAuthMiddleware(testJWTSecret, nil),
+	})
+
+	search := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/freights/search?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+tokenFor(t, 1, models.RoleUser))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := search("q=" + url.QueryEscape("上海") + "&page_size=1")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var body struct {
+		Data models.FreightPage `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.EqualValues(t, 2, body.Data.Total)
+	assert.Len(t, body.Data.Items, 1)
+	assert.True(t, body.Data.HasMore)
+
+	for query, param := range map[string]string{
+		"":                     "q",
+		"q=%20":                "q",
+		"q=abc&cursor=":        "cursor",
+		"q=abc&min_price=oops": "min_price",
+	} {
+		recorder := search(query)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, query)
+		var errBody map[string]string
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &errBody))
+		assert.Equal(t, param, errBody["param"], query)
+	}
+}
@@ -0,0 +1,50 @@
+package handlers_freight_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"freight/models"
+	"freight/services"
+)
+
+// 测试订单状态机的合法与非法流转
+func TestFreightStateMachine(t *testing.T) {
+	testCases := []struct {
+		name    string
+		from    uint8
+		to      uint8
+		allowed bool
+	}{
+		{"待接单-接单", models.FreightStatusPending, models.FreightStatusAccepted, true},
+		{"已接单-取货", models.FreightStatusAccepted, models.FreightStatusPickedUp, true},
+		{"已取货-运输中", models.FreightStatusPickedUp, models.FreightStatusShipping, true},
+		{"运输中-已送达", models.FreightStatusShipping, models.FreightStatusDelivered, true},
+		{"已送达-确认收货", models.FreightStatusDelivered, models.FreightStatusConfirmed, true},
+		{"待接单-过期", models.FreightStatusPending, models.FreightStatusExpired, true},
+		{"运输中-争议", models.FreightStatusShipping, models.FreightStatusDisputed, true},
+		{"待接单-直接送达", models.FreightStatusPending, models.FreightStatusDelivered, false},
+		{"运输中-取消", models.FreightStatusShipping, models.FreightStatusCancelled, false},
+		{"已确认-再次接单", models.FreightStatusConfirmed, models.FreightStatusAccepted, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.allowed, services.CanTransition(tc.from, tc.to))
+		})
+	}
+}
+
+// 测试非法流转返回的错误类型
+func TestFreightStateMachineErrors(t *testing.T) {
+	var illegal *services.IllegalTransitionError
+	err := services.ValidateTransition(models.FreightStatusPending, models.FreightStatusConfirmed)
+	assert.True(t, errors.As(err, &illegal))
+	assert.Equal(t, uint8(models.FreightStatusPending), illegal.From)
+
+	var unknown *services.UnknownStatusError
+	err = services.ValidateTransition(models.FreightStatusPending, 42)
+	assert.True(t, errors.As(err, &unknown))
+}
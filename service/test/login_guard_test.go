@@ -0,0 +1,157 @@
+package handlers_freight_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/api/handlers"
+	"freight/db"
+	"freight/models"
+	"freight/services"
+	"freight/utils"
+)
+
+func newTestLoginGuard(audit models.AuditRepository) *services.LoginGuard {
+	return services.NewLoginGuard(db.NewMemoryLoginAttemptRepository(), audit, services.LoginGuardOptions{
+		MaxAccountFailures: 3,
+		MaxIPFailures:      5,
+		FailureWindow:      time.Minute,
+		LockoutDuration:    time.Minute,
+	})
+}
+
+// 测试账号在窗口内失败达到阈值后被锁定，并写入审计记录
+func TestLoginGuardLocksAccount(t *testing.T) {
+	audit := db.NewMemoryAuditRepository()
+	guard := newTestLoginGuard(audit)
+	subject := services.AccountSubject(7)
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, guard.Fail(subject, "10.0.0.1"))
+		require.NoError(t, guard.CheckAccount(subject))
+	}
+	require.NoError(t, guard.Fail(subject, "10.0.0.1"))
+	assert.ErrorIs(t, guard.CheckAccount(subject), services.ErrTooManyAttempts)
+
+	// 其他账号不受影响
+	assert.NoError(t, guard.CheckAccount(services.AccountSubject(8)))
+
+	logs, err := audit.List(models.AuditAccountLocked, 10)
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, subject, logs[0].Subject)
+	assert.Equal(t, "10.0.0.1", logs[0].IP)
+}
+
+// 测试登录成功清除失败计数
+func TestLoginGuardSuccessResetsCount(t *testing.T) {
+	guard := newTestLoginGuard(db.NewMemoryAuditRepository())
+	subject := services.LoginSubject("Alice")
+
+	require.NoError(t, guard.Fail(subject, ""))
+	require.NoError(t, guard.Fail(subject, ""))
+	require.NoError(t, guard.Succeed(subject))
+	require.NoError(t, guard.Fail(subject, ""))
+	assert.NoError(t, guard.CheckAccount(subject))
+}
+
+// 测试同一IP跨多个账号失败达到阈值后被拒绝
+func TestLoginGuardBlocksIP(t *testing.T) {
+	audit := db.NewMemoryAuditRepository()
+	guard := newTestLoginGuard(audit)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, guard.CheckIP("10.0.0.2"))
+		require.NoError(t, guard.Fail(services.LoginSubject(string(rune('a'+i))), "10.0.0.2"))
+	}
+	assert.ErrorIs(t, guard.CheckIP("10.0.0.2"), services.ErrTooManyAttempts)
+	assert.NoError(t, guard.CheckIP("10.0.0.3"))
+
+	logs, err := audit.List(models.AuditIPBlocked, 10)
+	require.NoError(t, err)
+	assert.Len(t, logs, 1)
+}
+
+// 测试管理员解除锁定
+func TestLoginGuardUnlock(t *testing.T) {
+	audit := db.NewMemoryAuditRepository()
+	guard := newTestLoginGuard(audit)
+	subject := services.AccountSubject(7)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, guard.Fail(subject, ""))
+	}
+	require.ErrorIs(t, guard.CheckAccount(subject), services.ErrTooManyAttempts)
+
+	unlocked, err := guard.Unlock(7, 1)
+	require.NoError(t, err)
+	assert.True(t, unlocked)
+	assert.NoError(t, guard.CheckAccount(subject))
+
+	logs, err := audit.List(models.AuditAccountUnlocked, 10)
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, int64(1), logs[0].ActorID)
+
+	// 未锁定时返回false
+	unlocked, err = guard.Unlock(7, 1)
+	require.NoError(t, err)
+	assert.False(t, unlocked)
+}
+
+// 记录登录请求客户端IP的用户服务
+type ipRecordingUserService struct {
+	testUserService
+	ips []string
+}
+
+func (s *ipRecordingUserService) Login(loginID, password, ip string) (*models.User, *models.TokenPair, error) {
+	s.ips = append(s.ips, ip)
+	return nil, nil, services.ErrInvalidCredentials
+}
+
+// 测试只有受信任代理设置的 X-Forwarded-For/X-Real-IP 才被采用，直连客户端伪造的请求头被忽略
+func TestClientIPTrustedProxies(t *testing.T) {
+	proxies, err := utils.ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	require.NoError(t, err)
+	_, err = utils.ParseTrustedProxies([]string{"not-an-ip"})
+	assert.Error(t, err)
+
+	service := &ipRecordingUserService{}
+	handler := handlers.NewUserHandler(service, nil)
+	handler.TrustedProxies = proxies
+	login := func(remoteAddr string, header map[string]string) {
+		req := httptest.NewRequest("POST", "/api/users/login", strings.NewReader(`{"login_id":"a","password":"b"}`))
+		req.RemoteAddr = remoteAddr
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		recorder := httptest.NewRecorder()
+		handler.Login(recorder, req)
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	}
+
+	// 直连客户端每次更换请求头，仍按连接地址统计
+	for i := 0; i < 3; i++ {
+		login("203.0.113.7:5000", map[string]string{"X-Real-IP": "198.51.100." + string(rune('1'+i)), "X-Forwarded-For": "198.51.100.9"})
+	}
+	login("192.168.1.2:5000", map[string]string{"X-Real-IP": "198.51.100.1"})
+	// 受信任代理转发：取 X-Forwarded-For 中最右侧的非代理地址，客户端自带的前段不可信
+	login("10.1.2.3:443", map[string]string{"X-Forwarded-For": "1.1.1.1, 203.0.113.8, 10.0.0.5"})
+	login("192.168.1.1:443", map[string]string{"X-Real-IP": "203.0.113.9"})
+	login("[::1]:443", map[string]string{"X-Real-IP": "203.0.113.10"})
+
+	assert.Equal(t, []string{"203.0.113.7", "203.0.113.7", "203.0.113.7", "192.168.1.2", "203.0.113.8", "203.0.113.9", "::1"}, service.ips)
+
+	// 未配置受信任代理时一律使用连接地址
+	service.ips = nil
+	handler.TrustedProxies = nil
+	login("10.1.2.3:443", map[string]string{"X-Forwarded-For": "203.0.113.8", "X-Real-IP": "203.0.113.8"})
+	assert.Equal(t, []string{"10.1.2.3"}, service.ips)
+}
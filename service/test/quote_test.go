@@ -0,0 +1,179 @@
+package handlers_freight_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/api/middleware"
+	"freight/api/routes"
+	"freight/db"
+	"freight/models"
+	"freight/region"
+	"freight/services"
+)
+
+// 固定里程的路线估算，便于核对报价金额
+type fixedRouteEstimator struct {
+	distance float64
+}
+
+func (e fixedRouteEstimator) Estimate(ctx context.Context, originCode, destinationCode string) (*services.RouteEstimate, error) {
+	return &services.RouteEstimate{DistanceKm: e.distance, EstimatedHours: e.distance / 60}, nil
+}
+
+func (e fixedRouteEstimator) EstimateFrom(ctx context.Context, lat, lng float64, destinationCode string) (*services.RouteEstimate, error) {
+	return e.Estimate(ctx, "", destinationCode)
+}
+
+// 创建基于内存仓储的报价服务
+func newTestQuoteService(distance float64) (services.QuoteService, services.ConfigService, *db.MemoryQuoteRepository, *db.MemoryFreightRepository) {
+	configs := services.NewConfigServiceImpl(db.NewMemoryConfigRepository())
+	quotes := db.NewMemoryQuoteRepository()
+	freights := db.NewMemoryFreightRepository()
+	regions := region.Default()
+	routeEstimator := fixedRouteEstimator{distance: distance}
+	freightService := services.NewFreightService(freights, freights, regions, routeEstimator, db.NewMemoryVehicleRepository(), nil)
+	return services.NewQuoteService(quotes, configs, regions, routeEstimator, freightService), configs, quotes, freights
+}
+
+func quoteAmounts(quote *models.Quote) map[string]float64 {
+	amounts := map[string]float64{}
+	for _, item := range quote.Items {
+		amounts[item.Code] = item.Amount
+	}
+	return amounts
+}
+
+// 测试按配置中的报价规则计算分项报价
+func TestCreateQuoteItemized(t *testing.T) {
+	service, configs, _, _ := newTestQuoteService(200)
+	ctx := context.Background()
+
+	require.NoError(t, configs.SetConfig(services.QuoteRateConfigKey,
+		`{"per_km":2,"per_ton_km":0.5,"volume_ton_ratio":0.5,"type_multipliers":{"3":1.5},"urgent_rate":0.1,"insurance_rate":0.01,"insurance_min":50,"minimum_fee":300}`, "报价规则"))
+
+	// 体积重 20×0.5=10吨 大于实重 4吨：基础运费 200×(2+0.5×10)=1400
+	quote, err := service.CreateQuote(ctx, 1, services.QuoteRequest{
+		OriginCode: "310100", DestinationCode: "330100", TypeID: 3,
+		WeightKg: 4000, VolumeM3: 20, IsUrgent: true, HasInsurance: true, CargoValue: 100000,
+	})
+	require.NoError(t, err)
+	assert.Len(t, quote.ID, 32)
+	assert.Equal(t, map[string]float64{
+		models.QuoteItemBase:      1400,
+		models.QuoteItemType:      700,
+		models.QuoteItemUrgent:    210,
+		models.QuoteItemInsurance: 1000,
+	}, quoteAmounts(quote))
+	assert.Equal(t, 3310.0, quote.Total)
+	assert.WithinDuration(t, time.Now().Add(30*time.Minute), quote.ExpiresAt, time.Minute)
+
+	var fieldErr *services.FreightFieldError
+	_, err = service.CreateQuote(ctx, 1, services.QuoteRequest{OriginCode: "310100", DestinationCode: "330100", HasInsurance: true})
+	require.ErrorAs(t, err, &fieldErr)
+	assert.Equal(t, "cargo_value", fieldErr.Field)
+
+	// 无法解析或取值无效的报价规则
+	for _, value := range []string{
+		`{"per_km":`,
+		`{"valid_minutes":0}`,
+		`{"valid_minutes":-5}`,
+		`{"per_km":-1}`,
+		`{"per_ton_km":-0.5}`,
+		`{"urgent_rate":-0.1}`,
+		`{"type_multipliers":{"3":0}}`,
+		`{"type_multipliers":{"3":-1.5}}`,
+	} {
+		require.NoError(t, configs.SetConfig(services.QuoteRateConfigKey, value, ""))
+		_, err = service.CreateQuote(ctx, 1, services.QuoteRequest{OriginCode: "310100", DestinationCode: "330100"})
+		assert.ErrorIs(t, err, services.ErrInvalidRateTable, value)
+	}
+
+	// 短途轻货不足起步价时补足差额：基础运费 10×2=20，默认起步价200
+	short, _, _, _ := newTestQuoteService(10)
+	quote, err = short.CreateQuote(ctx, 1, services.QuoteRequest{OriginCode: "310100", DestinationCode: "310115"})
+	require.NoError(t, err)
+	assert.Equal(t, 200.0, quote.Total)
+	assert.Equal(t, 180.0, quoteAmounts(quote)[models.QuoteItemMinimumFee])
+}
+
+// 测试按报价发布订单：价格取自报价，报价只能使用一次
+func TestCreateOrderFromQuote(t *testing.T) {
+	service, _, quotes, freights := newTestQuoteService(200)
+	ctx := context.Background()
+
+	quote, err := service.CreateQuote(ctx, 1, services.QuoteRequest{OriginCode: "310100", DestinationCode: "330100", IsUrgent: true})
+	require.NoError(t, err)
+
+	_, err = service.GetQuote(ctx, quote.ID, 2)
+	assert.ErrorIs(t, err, services.ErrQuoteNotFound)
+	assert.ErrorIs(t, service.CreateOrderFromQuote(ctx, quote.ID, 2, &models.FreightOrder{}), services.ErrQuoteNotFound)
+
+	order := &models.FreightOrder{Remark: "整车", Price: 1}
+	require.NoError(t, service.CreateOrderFromQuote(ctx, quote.ID, 1, order))
+	assert.Equal(t, quote.Total, order.Price)
+	assert.True(t, order.IsUrgent)
+	assert.Equal(t, "浙江省杭州市", order.DestinationLocation)
+
+	stored, err := freights.GetByID(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "整车", stored.Remark)
+
+	used, err := service.GetQuote(ctx, quote.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, order.ID, used.OrderID)
+	assert.ErrorIs(t, service.CreateOrderFromQuote(ctx, quote.ID, 1, &models.FreightOrder{}), services.ErrQuoteUsed)
+
+	// 过期报价不能再使用
+	expired := &models.Quote{ID: "expired", ShipperID: 1, OriginCode: "310100", DestinationCode: "330100", Total: 500, ExpiresAt: time.Now().Add(-time.Minute)}
+	require.NoError(t, quotes.Create(ctx, expired))
+	assert.ErrorIs(t, service.CreateOrderFromQuote(ctx, expired.ID, 1, &models.FreightOrder{}), services.ErrQuoteExpired)
+}
+
+// 测试报价接口
+func TestQuoteRoutes(t *testing.T) {
+	service, _, _, _ := newTestQuoteService(200)
+	router := routes.SetupRoutes(routes.Deps{
+		QuoteService:   service,
+		AuthMiddleware: middleware.NewAuthMiddleware(testJWTSecret, nil),
+	})
+
+	post := func(path string, body interface{}, role string) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", path, bytes.NewReader(payload))
+		req.Header.Set("Authorization", "Bearer "+tokenFor(t, 1, role))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := post("/api/quotes", services.QuoteRequest{OriginCode: "310100", DestinationCode: "330100"}, models.RoleCarrier)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+
+	recorder = post("/api/quotes", services.QuoteRequest{OriginCode: "330000", DestinationCode: "330100"}, models.RoleShipper)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	var errBody map[string]string
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &errBody))
+	assert.Equal(t, "origin_code", errBody["field"])
+
+	recorder = post("/api/quotes", services.QuoteRequest{OriginCode: "310100", DestinationCode: "330100"}, models.RoleShipper)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	var body struct {
+		Data models.Quote `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Equal(t, 400.0, body.Data.Total)
+
+	recorder = post("/api/quotes/"+body.Data.ID+"/order", map[string]string{"remark": "整车"}, models.RoleShipper)
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	recorder = post("/api/quotes/"+body.Data.ID+"/order", map[string]string{}, models.RoleShipper)
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+}
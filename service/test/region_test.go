@@ -0,0 +1,183 @@
+package handlers_freight_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/api/middleware"
+	"freight/api/routes"
+	"freight/db"
+	"freight/models"
+	"freight/region"
+	"freight/services"
+	"freight/utils"
+)
+
+// 测试内置区划数据的查找、前缀和完整名称
+func TestRegionTree(t *testing.T) {
+	tree := region.Default()
+
+	hz := tree.Lookup("330100")
+	require.NotNil(t, hz)
+	assert.Equal(t, "杭州市", hz.Name)
+	assert.Equal(t, region.LevelCity, hz.Level)
+	assert.Equal(t, "330000", hz.ParentCode)
+	assert.Equal(t, "3301", tree.Prefix("330100"))
+	assert.Equal(t, "33", tree.Prefix("330000"))
+	assert.Equal(t, "330106", tree.Prefix("330106"))
+	assert.Empty(t, tree.Prefix("999999"))
+
+	assert.Equal(t, "浙江省杭州市西湖区", tree.FullName("330106"))
+	assert.Equal(t, "上海市浦东新区", tree.FullName("310115"))
+	assert.Len(t, tree.Roots(), 34)
+
+	nanshan := tree.Resolve("440305")
+	require.NotNil(t, nanshan)
+	assert.Equal(t, region.LevelDistrict, nanshan.Level)
+	assert.Equal(t, "440300", nanshan.ParentCode)
+	assert.Nil(t, tree.Lookup("440305"))
+	assert.Nil(t, tree.Resolve("449901"))
+	assert.Nil(t, tree.Resolve("440300x"))
+
+	_, err := region.Parse([]byte(`[{"code":"330000","name":"浙江省","children":[{"code":"320100","name":"南京市"}]}]`))
+	assert.Error(t, err)
+}
+
+// 测试创建订单校验区划编码并填写地点名称
+func TestCreateFreightRegions(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	service := newMemoryFreightService(repo)
+	ctx := context.Background()
+
+	order := &models.FreightOrder{OriginCode: "310115", DestinationCode: "330100", Price: 800, ShipperID: 1, OrderDate: utils.NewDate(2025, 3, 1)}
+	require.NoError(t, service.CreateFreight(ctx, order))
+	assert.Equal(t, "上海市浦东新区", order.OriginLocation)
+	assert.Equal(t, "浙江省杭州市", order.DestinationLocation)
+
+	var fieldErr *services.FreightFieldError
+	for code, field := range map[string]string{"": "origin_code", "123456": "origin_code", "330000": "origin_code", "449901": "origin_code"} {
+		err := service.CreateFreight(ctx, &models.FreightOrder{OriginCode: code, DestinationCode: "330100", Price: 800})
+		require.ErrorAs(t, err, &fieldErr, code)
+		assert.Equal(t, field, fieldErr.Field)
+	}
+
+	// 内置数据未收录的区县按所属地级区划校验，港澳台使用省级编码
+	order = &models.FreightOrder{OriginCode: "440305", DestinationCode: "810000", Price: 800, ShipperID: 1, OrderDate: utils.NewDate(2025, 3, 1)}
+	require.NoError(t, service.CreateFreight(ctx, order))
+	assert.Equal(t, "广东省深圳市", order.OriginLocation)
+	assert.Equal(t, "香港特别行政区", order.DestinationLocation)
+	assert.Greater(t, order.DistanceKm, 0.0)
+	require.NoError(t, service.CreateFreight(ctx, &models.FreightOrder{OriginCode: "820000", DestinationCode: "710000", Price: 800, ShipperID: 1, OrderDate: utils.NewDate(2025, 3, 1)}))
+
+	page, err := service.ListFreights(ctx, models.FreightFilter{OriginCode: "440300"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, page.Total)
+}
+
+// 测试修改订单地点时按编码重新校验区划、生成名称和估算路线，名称不能单独修改
+func TestUpdateFreightRegions(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	service := newMemoryFreightService(repo)
+	ctx := context.Background()
+	const shipper = 1
+
+	order := &models.FreightOrder{OriginCode: "310100", DestinationCode: "330100", Price: 800, ShipperID: shipper, OrderDate: utils.NewDate(2025, 3, 1)}
+	require.NoError(t, service.CreateFreight(ctx, order))
+	before := order.DistanceKm
+
+	var fieldErr *services.FreightFieldError
+	for _, tc := range []struct {
+		name   string
+		update models.FreightOrder
+		field  string
+	}{
+		{"单独修改出发地名称", models.FreightOrder{OriginLocation: "火星"}, "origin_location"},
+		{"名称与编码不符", models.FreightOrder{DestinationCode: "320100", DestinationLocation: "浙江省杭州市"}, "destination_location"},
+		{"无效编码", models.FreightOrder{OriginCode: "123456"}, "origin_code"},
+		{"省级编码", models.FreightOrder{DestinationCode: "330000"}, "destination_code"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			update := tc.update
+			update.ID = order.ID
+			require.ErrorAs(t, service.UpdateFreight(ctx, &update, shipper), &fieldErr)
+			assert.Equal(t, tc.field, fieldErr.Field)
+		})
+	}
+
+	// 原样提交名称不视为修改
+	require.NoError(t, service.UpdateFreight(ctx, &models.FreightOrder{ID: order.ID, OriginLocation: "上海市", Remark: "x"}, shipper))
+
+	require.NoError(t, service.UpdateFreight(ctx, &models.FreightOrder{ID: order.ID, DestinationCode: "440305"}, shipper))
+	updated, err := service.GetFreightByID(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "310100", updated.OriginCode)
+	assert.Equal(t, "上海市", updated.OriginLocation)
+	assert.Equal(t, "440305", updated.DestinationCode)
+	assert.Equal(t, "广东省深圳市", updated.DestinationLocation)
+	assert.Greater(t, updated.DistanceKm, before)
+	assert.Greater(t, updated.EstimatedHours, 0.0)
+
+	page, err := service.ListFreights(ctx, models.FreightFilter{DestinationCode: "440000"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, page.Total)
+}
+
+// 测试按省级编码过滤时匹配其下全部城市
+func TestFreightListProvinceFilter(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	service := newMemoryFreightService(repo)
+	ctx := context.Background()
+
+	for _, code := range []string{"330100", "330200", "330106", "320100"} {
+		require.NoError(t, service.CreateFreight(ctx, &models.FreightOrder{
+			OriginCode: code, DestinationCode: "310100", Price: 500, ShipperID: 1, OrderDate: utils.NewDate(2025, 3, 1),
+		}))
+	}
+
+	count := func(originCode string) int64 {
+		page, err := service.ListFreights(ctx, models.FreightFilter{OriginCode: originCode})
+		require.NoError(t, err)
+		return page.Total
+	}
+	assert.EqualValues(t, 3, count("330000"))
+	assert.EqualValues(t, 2, count("330100"))
+	assert.EqualValues(t, 1, count("330106"))
+	assert.EqualValues(t, 1, count("320000"))
+	assert.EqualValues(t, 0, count("440000"))
+}
+
+// 测试区划接口返回整棵树或指定区划的下级
+func TestListRegionsRoute(t *testing.T) {
+	router := routes.SetupRoutes(routes.Deps{
+		AuthMiddleware: middleware.NewAuthMiddleware(testJWTSecret, nil),
+	})
+
+	get := func(query string) (int, []*region.Region) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/regions"+query, nil))
+		var body struct {
+			Data []*region.Region `json:"data"`
+		}
+		_ = json.Unmarshal(recorder.Body.Bytes(), &body)
+		return recorder.Code, body.Data
+	}
+
+	code, roots := get("")
+	require.Equal(t, http.StatusOK, code)
+	assert.Len(t, roots, 34)
+
+	code, cities := get("?parent=330000")
+	require.Equal(t, http.StatusOK, code)
+	require.NotEmpty(t, cities)
+	assert.Equal(t, "330100", cities[0].Code)
+	assert.Equal(t, region.LevelCity, cities[0].Level)
+
+	code, _ = get("?parent=999999")
+	assert.Equal(t, http.StatusNotFound, code)
+}
@@ -0,0 +1,64 @@
+package handlers_freight_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/db"
+	"freight/models"
+	"freight/region"
+	"freight/services"
+	"freight/utils"
+)
+
+// 测试按区划中心点估算里程和时长
+func TestHaversineEstimator(t *testing.T) {
+	ctx := context.Background()
+	estimator := services.NewHaversineEstimator(region.Default(), services.RouteOptions{DetourFactor: 1.2, AverageSpeedKmh: 60})
+
+	// 上海—杭州直线距离约165公里
+	estimate, err := estimator.Estimate(ctx, "310100", "330100")
+	require.NoError(t, err)
+	assert.InDelta(t, 165*1.2, estimate.DistanceKm, 10)
+	assert.InDelta(t, estimate.DistanceKm/60, estimate.EstimatedHours, 0.1)
+
+	// 区县未设置坐标时沿用所属城市的中心点
+	estimate, err = estimator.Estimate(ctx, "330106", "330100")
+	require.NoError(t, err)
+	assert.Zero(t, estimate.DistanceKm)
+
+	_, err = estimator.Estimate(ctx, "999999", "330100")
+	assert.ErrorIs(t, err, services.ErrRouteUnavailable)
+}
+
+// 测试创建订单时写入预估里程，并可按里程过滤和排序
+func TestFreightDistanceFilterAndSort(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	service := newMemoryFreightService(repo)
+	ctx := context.Background()
+
+	// 上海出发：杭州（约200公里）、南京（约350公里）、广州（约1500公里）
+	for _, destination := range []string{"440100", "330100", "320100"} {
+		order := &models.FreightOrder{OriginCode: "310100", DestinationCode: destination, Price: 1000, ShipperID: 1, OrderDate: utils.NewDate(2025, 3, 1)}
+		require.NoError(t, service.CreateFreight(ctx, order))
+		assert.Greater(t, order.DistanceKm, 0.0)
+		assert.Greater(t, order.EstimatedHours, 0.0)
+	}
+
+	destinations := func(filter models.FreightFilter) []string {
+		page, err := service.ListFreights(ctx, filter)
+		require.NoError(t, err)
+		var codes []string
+		for _, o := range page.Items {
+			codes = append(codes, o.DestinationCode)
+		}
+		return codes
+	}
+
+	assert.Equal(t, []string{"330100", "320100", "440100"}, destinations(models.FreightFilter{SortField: "distance_km", SortOrder: "asc"}))
+	assert.Equal(t, []string{"320100", "330100"}, destinations(models.FreightFilter{MaxDistance: 800, SortField: "distance_km", SortOrder: "desc"}))
+	assert.Equal(t, []string{"440100"}, destinations(models.FreightFilter{MinDistance: 800}))
+}
@@ -10,7 +10,9 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"freight/api/handlers"
+	"freight/api/reqctx"
 	"freight/models"
+	"freight/services"
 )
 
 // 手动创建测试路由（模拟实际项目中的路由定义）
@@ -20,7 +22,7 @@ func createTestRouter() *mux.Router {
 	authMiddleware := &testAuthMiddleware{}
 
 	// 2. 创建处理器
-	userHandler := handlers.NewUserHandler(userService)
+	userHandler := handlers.NewUserHandler(userService, nil)
 
 	// 3. 定义路由（完全复制实际项目中的路由配置）
 	r := mux.NewRouter()
@@ -46,8 +48,8 @@ func (t *testUserService) Register(username, password, email string) (*models.Us
 	}, nil
 }
 
-func (t *testUserService) Login(loginID, password string) (*models.User, string, error) {
-	return &models.User{ID: 1, Username: loginID}, "test-token", nil
+func (t *testUserService) Login(loginID, password, ip string) (*models.User, *models.TokenPair, error) {
+	return &models.User{ID: 1, Username: loginID}, &models.TokenPair{AccessToken: "test-token"}, nil
 }
 
 func (t *testUserService) GetUserByID(id int64) (*models.User, error) {
@@ -58,13 +60,32 @@ func (t *testUserService) UpdateGender(userID int64, six string) (*models.User,
 	return &models.User{ID: userID, Six: six}, nil
 }
 
+func (t *testUserService) UpdateRole(userID int64, role string) (*models.User, error) {
+	return &models.User{ID: userID, Role: role}, nil
+}
+
+func (t *testUserService) VerifyEmail(token string) error                { return nil }
+func (t *testUserService) ResendVerification(email string) error         { return nil }
+func (t *testUserService) ForgotPassword(email string) error             { return nil }
+func (t *testUserService) ResetPassword(token, newPassword string) error { return nil }
+func (t *testUserService) UnlockAccount(userID, adminID int64) (bool, error) {
+	return true, nil
+}
+func (t *testUserService) UpdateProfile(userID int64, update services.ProfileUpdate) (*models.User, error) {
+	return &models.User{ID: userID}, nil
+}
+func (t *testUserService) ChangePassword(userID int64, oldPassword, newPassword string) error {
+	return nil
+}
+func (t *testUserService) Deactivate(userID int64, password string) error { return nil }
+
 // 测试用的认证中间件（直接通过认证）
 type testAuthMiddleware struct{}
 
 func (t *testAuthMiddleware) Handler(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// 简化认证：直接调用下一个处理器
-		next.ServeHTTP(w, r)
+		// 简化认证：固定为用户1
+		next.ServeHTTP(w, r.WithContext(reqctx.WithUser(r.Context(), 1, "test")))
 	}
 }
 
@@ -101,7 +122,7 @@ func TestUserRoutes(t *testing.T) {
 			name:         "更新性别接口",
 			path:         "/api/users/gender",
 			method:       "PATCH",
-			body:         `{"user_id":1,"gender":"women"}`,
+			body:         `{"gender":"women"}`,
 			expectedCode: http.StatusOK,
 			expectedMsg:  "性别更新成功",
 		},
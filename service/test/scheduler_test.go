@@ -0,0 +1,230 @@
+package handlers_freight_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/api/middleware"
+	"freight/api/routes"
+	"freight/db"
+	"freight/models"
+	"freight/scheduler"
+	"freight/services"
+	"freight/utils"
+)
+
+// 测试 cron 表达式解析和下一次执行时间
+func TestCronSchedule(t *testing.T) {
+	base := time.Date(2025, 3, 1, 10, 17, 30, 0, time.UTC) // 星期六
+	for _, tc := range []struct {
+		spec string
+		next time.Time
+	}{
+		{"*/15 * * * *", time.Date(2025, 3, 1, 10, 30, 0, 0, time.UTC)},
+		{"30 3 * * *", time.Date(2025, 3, 2, 3, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * 1-5", time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 12 15 * 0", time.Date(2025, 3, 2, 12, 0, 0, 0, time.UTC)}, // 日和星期满足其一即可
+		{"@monthly", time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", base.Add(90 * time.Second)},
+	} {
+		schedule, err := scheduler.Parse(tc.spec)
+		require.NoError(t, err, tc.spec)
+		assert.Equal(t, tc.next, schedule.Next(base), tc.spec)
+	}
+
+	for _, spec := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "@every -1m", "a * * * *"} {
+		_, err := scheduler.Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+// waitFor 轮询直到条件满足或超时
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("等待条件超时")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// 测试任务执行、panic 恢复、超时、不重叠以及停止时等待任务结束
+func TestSchedulerRunsJobs(t *testing.T) {
+	s := scheduler.New(scheduler.Options{})
+	var ok, panics, running, maxRunning, slowDone int32
+
+	require.NoError(t, s.Register(scheduler.Job{Name: "ok", Schedule: scheduler.Every(10 * time.Millisecond),
+		Run: func(ctx context.Context) error { atomic.AddInt32(&ok, 1); return nil }}))
+	require.NoError(t, s.Register(scheduler.Job{Name: "panic", Schedule: scheduler.Every(10 * time.Millisecond),
+		Run: func(ctx context.Context) error { atomic.AddInt32(&panics, 1); panic("boom") }}))
+	require.NoError(t, s.Register(scheduler.Job{Name: "timeout", Schedule: scheduler.Every(10 * time.Millisecond), Timeout: 20 * time.Millisecond,
+		Run: func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() }}))
+	require.NoError(t, s.Register(scheduler.Job{Name: "slow", Schedule: scheduler.Every(time.Millisecond),
+		Run: func(ctx context.Context) error {
+			if n := atomic.AddInt32(&running, 1); n > atomic.LoadInt32(&maxRunning) {
+				atomic.StoreInt32(&maxRunning, n)
+			}
+			time.Sleep(30 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			atomic.AddInt32(&slowDone, 1)
+			return nil
+		}}))
+	err := s.Register(scheduler.Job{Name: "ok", Schedule: scheduler.Every(time.Second), Run: func(ctx context.Context) error { return nil }})
+	assert.ErrorIs(t, err, scheduler.ErrDuplicateJob)
+
+	s.Start()
+	status := func(name string) scheduler.JobStatus {
+		for _, st := range s.Status() {
+			if st.Name == name {
+				return st
+			}
+		}
+		t.Fatalf("任务 %s 不存在", name)
+		return scheduler.JobStatus{}
+	}
+	waitFor(t, func() bool {
+		return status("ok").Runs >= 2 && status("panic").Failures >= 2 && status("timeout").Failures >= 1 && atomic.LoadInt32(&slowDone) >= 2
+	})
+
+	require.NoError(t, s.Stop(context.Background()))
+	assert.Contains(t, status("panic").LastError, "boom")
+	assert.Empty(t, status("ok").LastError)
+	assert.Contains(t, status("timeout").LastError, "deadline")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&maxRunning))
+	assert.Zero(t, atomic.LoadInt32(&running))
+	assert.False(t, status("slow").Running)
+
+	// 停止后不再执行
+	runs := atomic.LoadInt32(&ok)
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, runs, atomic.LoadInt32(&ok))
+	assert.ErrorIs(t, s.Register(scheduler.Job{Name: "late", Schedule: scheduler.Every(time.Second), Run: func(ctx context.Context) error { return nil }}), scheduler.ErrStarted)
+}
+
+// 测试租约：租约由其他实例持有时跳过执行，过期后可以接管
+func TestSchedulerLease(t *testing.T) {
+	lease := db.NewMemoryJobLease()
+	ctx := context.Background()
+
+	acquired, err := lease.TryAcquire(ctx, "job", "a", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	acquired, err = lease.TryAcquire(ctx, "job", "b", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.False(t, acquired)
+	acquired, err = lease.TryAcquire(ctx, "job", "a", time.Now().Add(-time.Second))
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	acquired, err = lease.TryAcquire(ctx, "job", "b", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	var runs int32
+	s := scheduler.New(scheduler.Options{Locker: lease, Holder: "a"})
+	require.NoError(t, s.Register(scheduler.Job{Name: "job", Schedule: scheduler.Every(5 * time.Millisecond),
+		Run: func(ctx context.Context) error { atomic.AddInt32(&runs, 1); return nil }}))
+	s.Start()
+	waitFor(t, func() bool { return s.Status()[0].LastSkipped != "" })
+	require.NoError(t, s.Stop(ctx))
+	assert.Zero(t, atomic.LoadInt32(&runs))
+}
+
+// 测试 Stop 在任务未响应取消时按 ctx 超时返回
+func TestSchedulerStopTimeout(t *testing.T) {
+	s := scheduler.New(scheduler.Options{})
+	started, release := make(chan struct{}), make(chan struct{})
+	require.NoError(t, s.Register(scheduler.Job{Name: "stuck", Schedule: scheduler.Every(time.Millisecond),
+		Run: func(ctx context.Context) error {
+			close(started)
+			<-release
+			return nil
+		}}))
+	s.Start()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.True(t, errors.Is(s.Stop(ctx), context.DeadlineExceeded))
+	close(release)
+	require.NoError(t, s.Stop(context.Background()))
+}
+
+// 测试每日统计汇总
+func TestDailyStatsRollup(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	freights := newMemoryFreightService(repo)
+	ctx := context.Background()
+
+	for _, price := range []float64{1000, 2000, 3000} {
+		order := &models.FreightOrder{OriginCode: "310100", DestinationCode: "330100", Price: price, ShipperID: 1, OrderDate: utils.NewDate(2025, 3, 1)}
+		require.NoError(t, freights.CreateFreight(ctx, order))
+		if price == 3000 {
+			require.NoError(t, freights.TransitionOrder(ctx, order.ID, 1, models.FreightStatusCancelled, ""))
+			continue
+		}
+		require.NoError(t, freights.AcceptOrder(ctx, order.ID, 2, 0))
+		require.NoError(t, freights.TransitionOrder(ctx, order.ID, 2, models.FreightStatusPickedUp, ""))
+		require.NoError(t, freights.TransitionOrder(ctx, order.ID, 2, models.FreightStatusShipping, ""))
+		require.NoError(t, freights.CompleteOrder(ctx, order.ID, 2))
+		if price == 1000 {
+			require.NoError(t, freights.TransitionOrder(ctx, order.ID, 1, models.FreightStatusConfirmed, ""))
+		}
+	}
+
+	stats := services.NewStatsService(db.NewMemoryStatsRepository(repo))
+	today, err := stats.RollupDay(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 3, today.OrdersCreated)
+	assert.Equal(t, 2, today.OrdersAccepted)
+	assert.Equal(t, 2, today.OrdersDelivered)
+	assert.Equal(t, 1, today.OrdersConfirmed)
+	assert.Equal(t, 1, today.OrdersCancelled)
+	assert.Equal(t, 1000.0, today.GMV)
+	assert.Equal(t, utils.FromTimeToDate(time.Now()).String(), today.Date.String())
+
+	yesterday, err := stats.RollupDay(ctx, time.Now().AddDate(0, 0, -1))
+	require.NoError(t, err)
+	assert.Zero(t, yesterday.OrdersCreated)
+}
+
+// 测试定时任务状态接口仅管理员可访问
+func TestJobStatusRoute(t *testing.T) {
+	s := scheduler.New(scheduler.Options{})
+	require.NoError(t, s.Register(scheduler.Job{Name: "purge_tokens", Schedule: scheduler.MustParse("30 3 * * *"),
+		Run: func(ctx context.Context) error { return nil }}))
+	router := routes.SetupRoutes(routes.Deps{
+		Jobs:           s,
+		AuthMiddleware: middleware.NewAuthMiddleware(testJWTSecret, nil),
+	})
+
+	get := func(role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/admin/jobs", nil)
+		req.Header.Set("Authorization", "Bearer "+tokenFor(t, 1, role))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+	assert.Equal(t, http.StatusForbidden, get(models.RoleDispatcher).Code)
+
+	recorder := get(models.RoleAdmin)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var body struct {
+		Data []scheduler.JobStatus `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	require.Len(t, body.Data, 1)
+	assert.Equal(t, "purge_tokens", body.Data[0].Name)
+	assert.Equal(t, "30 3 * * *", body.Data[0].Schedule)
+}
@@ -0,0 +1,102 @@
+package handlers_freight_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/db"
+	"freight/models"
+	"freight/services"
+	"freight/utils"
+)
+
+// 创建带时间窗口的订单（上海 → 杭州）
+func windowOrder(remark string, pickupEarliest, pickupLatest, deliveryEarliest, deliveryLatest *time.Time) *models.FreightOrder {
+	return &models.FreightOrder{
+		Remark: remark, OriginCode: "310100", DestinationCode: "330100", Price: 1000, ShipperID: 1,
+		OrderDate: utils.NewDate(2025, 3, 1), PickupEarliest: pickupEarliest, PickupLatest: pickupLatest,
+		DeliveryEarliest: deliveryEarliest, DeliveryLatest: deliveryLatest,
+	}
+}
+
+// 测试发布订单时校验取货和送达时间窗口
+func TestTimeWindowValidation(t *testing.T) {
+	service := newMemoryFreightService(db.NewMemoryFreightRepository())
+	ctx := context.Background()
+	shanghai := time.FixedZone("CST", 8*3600)
+	at := func(d time.Duration) *time.Time {
+		v := time.Now().Add(d).In(shanghai)
+		return &v
+	}
+
+	valid := windowOrder("", at(2*time.Hour), at(4*time.Hour), at(12*time.Hour), at(24*time.Hour))
+	require.NoError(t, service.CreateFreight(ctx, valid))
+	assert.Greater(t, valid.EstimatedHours, 1.0)
+
+	var fieldErr *services.FreightFieldError
+	for _, tc := range []struct {
+		name  string
+		order *models.FreightOrder
+		field string
+	}{
+		{"取货窗口不完整", windowOrder("", at(time.Hour), nil, nil, nil), "pickup_latest"},
+		{"取货时间已过", windowOrder("", at(-time.Hour), at(time.Hour), nil, nil), "pickup_earliest"},
+		{"取货起止颠倒", windowOrder("", at(3*time.Hour), at(2*time.Hour), nil, nil), "pickup_latest"},
+		{"送达早于取货", windowOrder("", at(5*time.Hour), at(6*time.Hour), at(4*time.Hour), at(24*time.Hour)), "delivery_earliest"},
+		{"来不及送达", windowOrder("", at(2*time.Hour), at(3*time.Hour), at(2*time.Hour), at(2*time.Hour+30*time.Minute)), "delivery_latest"},
+		{"无取货窗口时来不及送达", windowOrder("", nil, nil, at(10*time.Minute), at(30*time.Minute)), "delivery_latest"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.ErrorAs(t, service.CreateFreight(ctx, tc.order), &fieldErr)
+			assert.Equal(t, tc.field, fieldErr.Field)
+		})
+	}
+}
+
+// 测试大厅按取货时间筛选，以及超过取货时间的订单过期
+func TestPickupWindowHallAndExpiry(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	service := newMemoryFreightService(repo)
+	ctx := context.Background()
+	at := func(d time.Duration) *time.Time {
+		v := time.Now().Add(d)
+		return &v
+	}
+
+	for _, o := range []*models.FreightOrder{
+		windowOrder("今天", at(2*time.Hour), at(6*time.Hour), nil, nil),
+		windowOrder("下周", at(7*24*time.Hour), at(8*24*time.Hour), nil, nil),
+		windowOrder("不限", nil, nil, nil, nil),
+	} {
+		require.NoError(t, service.CreateFreight(ctx, o))
+	}
+	page, err := service.ListFreights(ctx, models.FreightFilter{PickupWithinHours: 24})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "今天", page.Items[0].Remark)
+
+	// 直接写入仓储，模拟取货时间已过仍未接单的订单
+	overdue := windowOrder("过期", at(-3*time.Hour), at(-time.Hour), nil, nil)
+	require.NoError(t, repo.Create(ctx, overdue))
+	assert.ErrorIs(t, service.AcceptOrder(ctx, overdue.ID, 2, 0), services.ErrPickupWindowClosed)
+
+	expired, err := service.ExpireOverdueOrders(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	stored, err := service.GetFreightByID(ctx, overdue.ID)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(models.FreightStatusExpired), stored.Status)
+	history, err := service.GetOrderHistory(ctx, overdue.ID, overdue.ShipperID, false)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, services.SystemActorID, history[0].ActorID)
+
+	// 已处理的订单不会重复过期
+	expired, err = service.ExpireOverdueOrders(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, expired)
+}
@@ -0,0 +1,98 @@
+package handlers_freight_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/api/middleware"
+	"freight/api/routes"
+	"freight/db"
+	"freight/models"
+	"freight/services"
+)
+
+func newTestTokenService() services.TokenService {
+	users := db.NewMemoryUserRepository()
+	_ = users.Create(&models.User{Username: "tester", Email: "tester@example.com", Role: models.RoleUser})
+	return services.NewTokenService(db.NewMemoryTokenRepository(), users, testJWTSecret, time.Minute, time.Hour)
+}
+
+// 刷新令牌轮换：旧令牌重复使用会吊销整个令牌族
+func TestRefreshTokenRotation(t *testing.T) {
+	tokens := newTestTokenService()
+
+	first, err := tokens.Issue(&models.User{ID: 1, Username: "tester"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(60), first.ExpiresIn)
+
+	second, err := tokens.Refresh(first.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+
+	// 重复使用已轮换的令牌
+	_, err = tokens.Refresh(first.RefreshToken)
+	assert.ErrorIs(t, err, services.ErrRefreshTokenReused)
+
+	// 同一族中最新的令牌也随之失效
+	_, err = tokens.Refresh(second.RefreshToken)
+	assert.ErrorIs(t, err, services.ErrRefreshTokenReused)
+
+	_, err = tokens.Refresh("not-a-token")
+	assert.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+}
+
+// 登出后访问令牌被拒绝，刷新令牌也不能再使用
+func TestLogoutRevokesTokens(t *testing.T) {
+	tokens := newTestTokenService()
+	router := routes.SetupRoutes(routes.Deps{
+		ConfigService:  &testConfigService{},
+		TokenService:   tokens,
+		AuthMiddleware: middleware.NewAuthMiddleware(testJWTSecret, tokens),
+	})
+
+	pair, err := tokens.Issue(&models.User{ID: 1, Username: "tester"})
+	require.NoError(t, err)
+
+	do := func(method, path, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusOK, do("GET", "/api/configs?key=a", ""))
+	assert.Equal(t, http.StatusOK, do("POST", "/api/users/logout", `{"refresh_token":"`+pair.RefreshToken+`"}`))
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/api/configs?key=a", ""))
+	assert.Equal(t, http.StatusUnauthorized, do("POST", "/api/users/refresh", `{"refresh_token":"`+pair.RefreshToken+`"}`))
+}
+
+// 登出时提交他人的刷新令牌不会吊销其令牌族
+func TestLogoutIgnoresOtherUsersRefreshToken(t *testing.T) {
+	tokens := newTestTokenService()
+	router := routes.SetupRoutes(routes.Deps{
+		ConfigService:  &testConfigService{},
+		TokenService:   tokens,
+		AuthMiddleware: middleware.NewAuthMiddleware(testJWTSecret, tokens),
+	})
+
+	victim, err := tokens.Issue(&models.User{ID: 1, Username: "tester"})
+	require.NoError(t, err)
+	attacker, err := tokens.Issue(&models.User{ID: 2, Username: "mallory"})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("POST", "/api/users/logout", strings.NewReader(`{"refresh_token":"`+victim.RefreshToken+`"}`))
+	req.Header.Set("Authorization", "Bearer "+attacker.AccessToken)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	_, err = tokens.Refresh(victim.RefreshToken)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,185 @@
+package handlers_freight_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/api/middleware"
+	"freight/api/routes"
+	"freight/db"
+	"freight/models"
+	"freight/region"
+	"freight/services"
+	"freight/utils"
+)
+
+// 创建一个由承运方2运输中的订单（上海 → 杭州）
+func newShippingOrder(t *testing.T, freights services.FreightService) *models.FreightOrder {
+	ctx := context.Background()
+	order := &models.FreightOrder{OriginCode: "310100", DestinationCode: "330100", Price: 1000, ShipperID: 1, OrderDate: utils.NewDate(2025, 3, 1)}
+	require.NoError(t, freights.CreateFreight(ctx, order))
+	require.NoError(t, freights.AcceptOrder(ctx, order.ID, 2, 0))
+	require.NoError(t, freights.TransitionOrder(ctx, order.ID, 2, models.FreightStatusPickedUp, ""))
+	require.NoError(t, freights.TransitionOrder(ctx, order.ID, 2, models.FreightStatusShipping, ""))
+	return order
+}
+
+func ping(lat, lng float64, at time.Time) *models.LocationPing {
+	return &models.LocationPing{Lat: lat, Lng: lng, SpeedKmh: 80, Heading: 225, RecordedAt: at}
+}
+
+// 测试定位上报校验、最新位置、预计到达时间和轨迹清理
+func TestTracking(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	freights := newMemoryFreightService(repo)
+	locations := db.NewMemoryLocationRepository(repo)
+	regions := region.Default()
+	tracking := services.NewTrackingService(locations, repo, services.NewHaversineEstimator(regions, services.RouteOptions{}), nil)
+	ctx := context.Background()
+	order := newShippingOrder(t, freights)
+	now := time.Now().Truncate(time.Second)
+
+	_, err := tracking.RecordPings(ctx, order.ID, 3, []*models.LocationPing{ping(31, 121, now)})
+	assert.ErrorIs(t, err, services.ErrPermissionDenied)
+	_, err = tracking.LatestPosition(ctx, order.ID, 1, false)
+	assert.ErrorIs(t, err, services.ErrNoLocation)
+
+	var fieldErr *services.FreightFieldError
+	for _, tc := range []struct {
+		ping  *models.LocationPing
+		field string
+	}{
+		{ping(91, 121, now), "pings[1].lat"},
+		{ping(31, 121, now.Add(time.Hour)), "pings[1].timestamp"},
+		{ping(31, 121, now.AddDate(0, 0, -30)), "pings[1].timestamp"},
+		{&models.LocationPing{Lat: 31, Lng: 121, Heading: 360, RecordedAt: now}, "pings[1].heading"},
+	} {
+		_, err := tracking.RecordPings(ctx, order.ID, 2, []*models.LocationPing{ping(31, 121, now), tc.ping})
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, tc.field, fieldErr.Field)
+	}
+
+	// 离线补传：乱序上报，重复的定位时间只保存一次
+	saved, err := tracking.RecordPings(ctx, order.ID, 2, []*models.LocationPing{
+		ping(30.8, 120.8, now.Add(-10*time.Minute)),
+		ping(31.1, 121.2, now.Add(-30*time.Minute)),
+		ping(30.6, 120.5, now),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, saved)
+	saved, err = tracking.RecordPings(ctx, order.ID, 2, []*models.LocationPing{ping(30.6, 120.5, now)})
+	require.NoError(t, err)
+	assert.Zero(t, saved)
+
+	position, err := tracking.LatestPosition(ctx, order.ID, 1, false)
+	require.NoError(t, err)
+	assert.Equal(t, 30.6, position.Location.Lat)
+	require.NotNil(t, position.ETA)
+	assert.Greater(t, *position.RemainingKm, 0.0)
+	estimate, err := services.NewHaversineEstimator(regions, services.RouteOptions{}).Estimate(ctx, "310100", "330100")
+	require.NoError(t, err)
+	assert.Less(t, *position.RemainingKm, estimate.DistanceKm)
+	assert.True(t, position.ETA.After(now))
+
+	_, err = tracking.Trail(ctx, order.ID, 3, false)
+	assert.ErrorIs(t, err, services.ErrPermissionDenied)
+	trail, err := tracking.Trail(ctx, order.ID, 3, true)
+	require.NoError(t, err)
+	require.Len(t, trail, 3)
+	assert.Equal(t, 31.1, trail[0].Lat)
+
+	// 送达后不能再上报；确认收货超过保留期后清理定位
+	require.NoError(t, freights.CompleteOrder(ctx, order.ID, 2))
+	_, err = tracking.RecordPings(ctx, order.ID, 2, []*models.LocationPing{ping(30.3, 120.2, now)})
+	assert.ErrorIs(t, err, services.ErrTrackingInactive)
+	purged, err := locations.PurgeFinished(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+	require.NoError(t, freights.TransitionOrder(ctx, order.ID, 1, models.FreightStatusConfirmed, ""))
+	purged, err = locations.PurgeFinished(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, purged)
+}
+
+// 测试定位上报和GeoJSON轨迹接口
+func TestTrackingRoutes(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	freights := newMemoryFreightService(repo)
+	regions := region.Default()
+	tracking := services.NewTrackingService(db.NewMemoryLocationRepository(repo), repo, services.NewHaversineEstimator(regions, services.RouteOptions{}), nil)
+	router := routes.SetupRoutes(routes.Deps{
+		FreightService:  freights,
+		TrackingService: tracking,
+		Regions:         regions,
+		AuthMiddleware:  middleware.NewAuthMiddleware(testJWTSecret, nil),
+	})
+	order := newShippingOrder(t, freights)
+
+	do := func(method, path, body string, userID int64, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+tokenFor(t, userID, role))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+	path := fmt.Sprintf("/api/freights/%d/locations", order.ID)
+	at := func(d time.Duration) string { return time.Now().Add(d).Format(time.RFC3339) }
+
+	single := `{"lat":31.0,"lng":121.0,"speed":60,"heading":180,"timestamp":"` + at(-20*time.Minute) + `"}`
+	assert.Equal(t, http.StatusForbidden, do("POST", path, single, 1, models.RoleShipper).Code)
+	recorder := do("POST", path, single, 2, models.RoleCarrier)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+
+	recorder = do("GET", path+"/latest", "", 1, models.RoleShipper)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"eta"`)
+
+	// 只有一个定位时轨迹只包含最新位置
+	recorder = do("GET", path, "", 1, models.RoleShipper)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/geo+json", recorder.Header().Get("Content-Type"))
+	var collection struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Geometry struct {
+				Type        string          `json:"type"`
+				Coordinates json.RawMessage `json:"coordinates"`
+			} `json:"geometry"`
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"features"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &collection))
+	require.Len(t, collection.Features, 1)
+	assert.Equal(t, "Point", collection.Features[0].Geometry.Type)
+
+	batch := `[{"lat":30.8,"lng":120.8,"timestamp":"` + at(-10*time.Minute) + `"},{"lat":30.6,"lng":120.5,"timestamp":"` + at(0) + `"}]`
+	recorder = do("POST", path, batch, 2, models.RoleCarrier)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"saved":2`)
+	recorder = do("POST", path, `[{"lat":300,"lng":120,"timestamp":"`+at(0)+`"}]`, 2, models.RoleCarrier)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"field":"pings[0].lat"`)
+
+	recorder = do("GET", path, "", 1, models.RoleShipper)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &collection))
+	require.Len(t, collection.Features, 2)
+	line := collection.Features[0]
+	assert.Equal(t, "LineString", line.Geometry.Type)
+	var coordinates [][2]float64
+	require.NoError(t, json.Unmarshal(line.Geometry.Coordinates, &coordinates))
+	assert.Equal(t, [][2]float64{{121, 31}, {120.8, 30.8}, {120.5, 30.6}}, coordinates)
+	assert.Len(t, line.Properties["timestamps"], 3)
+
+	assert.Equal(t, http.StatusForbidden, do("GET", path, "", 3, models.RoleCarrier).Code)
+	assert.Equal(t, http.StatusOK, do("GET", path, "", 3, models.RoleDispatcher).Code)
+}
@@ -0,0 +1,159 @@
+package handlers_freight_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/api/middleware"
+	"freight/db"
+	"freight/mail"
+	"freight/models"
+	"freight/services"
+	"freight/utils"
+)
+
+// 创建基于内存仓储的用户服务，并预置一个用户
+func newTestProfileService(t *testing.T) (services.UserService, *db.MemoryUserRepository, *models.User, *mail.LogMailer) {
+	users := db.NewMemoryUserRepository()
+	tokenRepo := db.NewMemoryTokenRepository()
+	tokens := services.NewTokenService(tokenRepo, users, testJWTSecret, time.Minute, time.Hour)
+	guard := services.NewLoginGuard(db.NewMemoryLoginAttemptRepository(), db.NewMemoryAuditRepository(), services.LoginGuardOptions{})
+	mailer := mail.NewLogMailer(io.Discard)
+	service := services.NewUserServiceImpl(users, tokens, tokenRepo, mailer, guard, services.AccountOptions{})
+
+	hash, err := utils.HashPassword("secret1")
+	require.NoError(t, err)
+	user := &models.User{Username: "alice", Password: hash, Email: "alice@example.com"}
+	require.NoError(t, users.Create(user))
+	require.NoError(t, users.Create(&models.User{Username: "bob", Password: hash, Email: "bob@example.com"}))
+	return service, users, user, mailer
+}
+
+func strPtr(s string) *string { return &s }
+
+// 测试资料修改的字段校验和用户名唯一
+func TestUpdateProfile(t *testing.T) {
+	service, _, user, _ := newTestProfileService(t)
+
+	updated, err := service.UpdateProfile(user.ID, services.ProfileUpdate{
+		Phone:     strPtr("+8613800000000"),
+		AvatarURL: strPtr("https://cdn.example.com/a.png"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", updated.Username)
+	assert.Equal(t, "+8613800000000", updated.Phone)
+
+	got, err := service.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "https://cdn.example.com/a.png", got.AvatarURL)
+
+	_, err = service.UpdateProfile(user.ID, services.ProfileUpdate{Username: strPtr("bob")})
+	assert.ErrorIs(t, err, services.ErrUsernameTaken)
+
+	var fieldErr *services.ProfileFieldError
+	_, err = service.UpdateProfile(user.ID, services.ProfileUpdate{Phone: strPtr("abc")})
+	require.ErrorAs(t, err, &fieldErr)
+	assert.Equal(t, "phone", fieldErr.Field)
+
+	_, err = service.UpdateProfile(user.ID, services.ProfileUpdate{AvatarURL: strPtr("javascript:alert(1)")})
+	require.ErrorAs(t, err, &fieldErr)
+	assert.Equal(t, "avatar_url", fieldErr.Field)
+
+	_, err = service.UpdateProfile(999, services.ProfileUpdate{})
+	assert.ErrorIs(t, err, services.ErrUserNotFound)
+}
+
+// 测试修改密码需校验原密码
+func TestChangePassword(t *testing.T) {
+	service, users, user, _ := newTestProfileService(t)
+
+	assert.ErrorIs(t, service.ChangePassword(user.ID, "wrong", "secret2"), services.ErrWrongPassword)
+	assert.ErrorIs(t, service.ChangePassword(user.ID, "secret1", "123"), services.ErrPasswordTooShort)
+	require.NoError(t, service.ChangePassword(user.ID, "secret1", "secret2"))
+
+	stored, err := users.FindByID(user.ID)
+	require.NoError(t, err)
+	assert.True(t, utils.CheckPasswordHash("secret2", stored.Password))
+}
+
+// 测试注销账号
+func TestDeactivateAccount(t *testing.T) {
+	service, users, user, _ := newTestProfileService(t)
+
+	assert.ErrorIs(t, service.Deactivate(user.ID, "wrong"), services.ErrWrongPassword)
+	require.NoError(t, service.Deactivate(user.ID, "secret1"))
+
+	stored, err := users.FindByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.UserStatusDeactivated, stored.Status)
+}
+
+// 测试校验当前密码与登录共用失败计数，达到阈值后锁定，正确密码也被拒绝
+func TestPasswordCheckLockout(t *testing.T) {
+	service, _, user, _ := newTestProfileService(t)
+
+	for i := 0; i < 4; i++ {
+		assert.ErrorIs(t, service.ChangePassword(user.ID, "wrong", "secret2"), services.ErrWrongPassword)
+	}
+	assert.ErrorIs(t, service.Deactivate(user.ID, "wrong"), services.ErrWrongPassword)
+
+	assert.ErrorIs(t, service.ChangePassword(user.ID, "secret1", "secret2"), services.ErrTooManyAttempts)
+	assert.ErrorIs(t, service.Deactivate(user.ID, "secret1"), services.ErrTooManyAttempts)
+	_, _, err := service.Login("alice", "secret1", "10.0.0.1")
+	assert.ErrorIs(t, err, services.ErrTooManyAttempts)
+
+	unlocked, err := service.UnlockAccount(user.ID, 99)
+	require.NoError(t, err)
+	assert.True(t, unlocked)
+	require.NoError(t, service.ChangePassword(user.ID, "secret1", "secret2"))
+}
+
+// 测试修改角色后吊销用户的刷新令牌，须重新登录获取新角色
+func TestUpdateRoleRevokesRefreshTokens(t *testing.T) {
+	users := db.NewMemoryUserRepository()
+	tokenRepo := db.NewMemoryTokenRepository()
+	tokens := services.NewTokenService(tokenRepo, users, testJWTSecret, time.Minute, time.Hour)
+	guard := services.NewLoginGuard(db.NewMemoryLoginAttemptRepository(), db.NewMemoryAuditRepository(), services.LoginGuardOptions{})
+	service := services.NewUserServiceImpl(users, tokens, tokenRepo, mail.NewLogMailer(io.Discard), guard, services.AccountOptions{})
+
+	hash, err := utils.HashPassword("secret1")
+	require.NoError(t, err)
+	user := &models.User{Username: "carol", Password: hash, Email: "carol@example.com", Role: models.RoleDispatcher}
+	require.NoError(t, users.Create(user))
+
+	auth := middleware.NewAuthMiddleware(testJWTSecret, tokens)
+	status := func(accessToken string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/users/me", nil)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		recorder := httptest.NewRecorder()
+		auth.Handler(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })(recorder, req)
+		return recorder.Code
+	}
+
+	_, pair, err := service.Login("carol", "secret1", "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, status(pair.AccessToken))
+	_, err = service.UpdateRole(user.ID, models.RoleShipper)
+	require.NoError(t, err)
+
+	// 旧访问令牌仍携带原角色，角色变更后立即失效
+	assert.Equal(t, http.StatusUnauthorized, status(pair.AccessToken))
+	_, err = tokens.Refresh(pair.RefreshToken)
+	assert.ErrorIs(t, err, services.ErrRefreshTokenReused)
+	_, relogin, err := service.Login("carol", "secret1", "")
+	require.NoError(t, err)
+	claims, err := utils.ParseJWT(relogin.AccessToken, testJWTSecret)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleShipper, claims["role"])
+	assert.Equal(t, http.StatusOK, status(relogin.AccessToken))
+
+	// 注销账号后访问令牌同样立即失效
+	require.NoError(t, service.Deactivate(user.ID, "secret1"))
+	assert.Equal(t, http.StatusUnauthorized, status(relogin.AccessToken))
+}
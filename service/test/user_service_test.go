@@ -0,0 +1,84 @@
+package handlers_freight_test
+
+import (
+	"io"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/db"
+	"freight/mail"
+	"freight/services"
+)
+
+var mailTokenPattern = regexp.MustCompile(`token=([0-9a-f]+)`)
+
+// 从最近一封邮件中取出令牌
+func lastMailToken(t *testing.T, mailer *mail.LogMailer) string {
+	messages := mailer.Messages()
+	require.NotEmpty(t, messages)
+	match := mailTokenPattern.FindStringSubmatch(messages[len(messages)-1].Body)
+	require.Len(t, match, 2)
+	return match[1]
+}
+
+// 测试注册、邮箱验证、登录的完整流程（要求验证邮箱）
+func TestRegisterVerifyLogin(t *testing.T) {
+	users := db.NewMemoryUserRepository()
+	tokenRepo := db.NewMemoryTokenRepository()
+	tokens := services.NewTokenService(tokenRepo, users, testJWTSecret, time.Minute, time.Hour)
+	guard := services.NewLoginGuard(db.NewMemoryLoginAttemptRepository(), db.NewMemoryAuditRepository(), services.LoginGuardOptions{})
+	mailer := mail.NewLogMailer(io.Discard)
+	service := services.NewUserServiceImpl(users, tokens, tokenRepo, mailer, guard, services.AccountOptions{
+		RequireEmailVerification: true,
+	})
+
+	user, err := service.Register("carol", "secret1", "carol@example.com")
+	require.NoError(t, err)
+	assert.NotZero(t, user.ID)
+
+	_, err = service.Register("carol", "secret1", "other@example.com")
+	assert.ErrorIs(t, err, services.ErrUsernameTaken)
+
+	// 验证前不能登录
+	_, _, err = service.Login("carol", "secret1", "127.0.0.1")
+	assert.ErrorIs(t, err, services.ErrEmailNotVerified)
+
+	token := lastMailToken(t, mailer)
+	require.NoError(t, service.VerifyEmail(token))
+	assert.ErrorIs(t, service.VerifyEmail(token), services.ErrInvalidUserToken)
+
+	// 按邮箱登录
+	loggedIn, pair, err := service.Login("carol@example.com", "secret1", "127.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, loggedIn.ID)
+	assert.NotEmpty(t, pair.AccessToken)
+
+	// 账号不存在和密码错误返回相同的错误
+	_, _, err = service.Login("carol", "wrong", "127.0.0.1")
+	assert.ErrorIs(t, err, services.ErrInvalidCredentials)
+	_, _, err = service.Login("nobody", "secret1", "127.0.0.1")
+	assert.ErrorIs(t, err, services.ErrInvalidCredentials)
+}
+
+// 测试忘记密码和重置密码（令牌只能使用一次）
+func TestForgotAndResetPassword(t *testing.T) {
+	service, _, _, mailer := newTestProfileService(t)
+
+	// 邮箱不存在时静默成功，不发送邮件
+	require.NoError(t, service.ForgotPassword("nobody@example.com"))
+	assert.Empty(t, mailer.Messages())
+
+	require.NoError(t, service.ForgotPassword("alice@example.com"))
+	token := lastMailToken(t, mailer)
+
+	assert.ErrorIs(t, service.ResetPassword(token, "123"), services.ErrPasswordTooShort)
+	require.NoError(t, service.ResetPassword(token, "secret2"))
+	assert.ErrorIs(t, service.ResetPassword(token, "secret3"), services.ErrInvalidUserToken)
+
+	_, _, err := service.Login("alice", "secret2", "")
+	require.NoError(t, err)
+}
@@ -0,0 +1,166 @@
+package handlers_freight_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/api/middleware"
+	"freight/api/routes"
+	"freight/db"
+	"freight/models"
+	"freight/services"
+	"freight/utils"
+)
+
+// 测试车辆登记校验和归属
+func TestVehicleRegistry(t *testing.T) {
+	service := services.NewVehicleService(db.NewMemoryVehicleRepository())
+	ctx := context.Background()
+
+	van := &models.Vehicle{Plate: "沪a·12345", Type: models.VehicleTypeVan, CapacityKg: 5000, CapacityM3: 30, LengthM: 6.8}
+	require.NoError(t, service.CreateVehicle(ctx, 2, van))
+	assert.Equal(t, "沪A12345", van.Plate)
+	assert.Equal(t, uint64(2), van.OwnerID)
+
+	var fieldErr *services.FreightFieldError
+	for _, tc := range []struct {
+		vehicle models.Vehicle
+		field   string
+	}{
+		{models.Vehicle{Plate: "ABC123", Type: models.VehicleTypeVan, CapacityKg: 1, CapacityM3: 1, LengthM: 1}, "plate"},
+		{models.Vehicle{Plate: "浙B12345", Type: "tractor", CapacityKg: 1, CapacityM3: 1, LengthM: 1}, "type"},
+		{models.Vehicle{Plate: "浙B12345", Type: models.VehicleTypeVan, CapacityM3: 1, LengthM: 1}, "capacity_kg"},
+	} {
+		require.ErrorAs(t, service.CreateVehicle(ctx, 2, &tc.vehicle), &fieldErr)
+		assert.Equal(t, tc.field, fieldErr.Field)
+	}
+	duplicate := &models.Vehicle{Plate: "沪A12345", Type: models.VehicleTypeFlatbed, CapacityKg: 1, CapacityM3: 1, LengthM: 1}
+	assert.ErrorIs(t, service.CreateVehicle(ctx, 3, duplicate), services.ErrPlateTaken)
+
+	_, err := service.GetVehicle(ctx, van.ID, 3)
+	assert.ErrorIs(t, err, services.ErrVehicleNotFound)
+	assert.ErrorIs(t, service.DeleteVehicle(ctx, van.ID, 3), services.ErrVehicleNotFound)
+
+	van.CapacityKg = 8000
+	require.NoError(t, service.UpdateVehicle(ctx, 2, van))
+	stored, err := service.GetVehicle(ctx, van.ID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 8000.0, stored.CapacityKg)
+
+	require.NoError(t, service.DeleteVehicle(ctx, van.ID, 2))
+	vehicles, err := service.ListVehicles(ctx, 2)
+	require.NoError(t, err)
+	assert.Empty(t, vehicles)
+}
+
+// 测试接单时校验车辆，以及大厅按车辆筛选可承运的订单
+func TestVehicleMatching(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	vehicleRepo := db.NewMemoryVehicleRepository()
+	freights := newMemoryFreightServiceWithVehicles(repo, vehicleRepo)
+	vehicles := services.NewVehicleService(vehicleRepo)
+	ctx := context.Background()
+
+	van := &models.Vehicle{Plate: "浙A12345", Type: models.VehicleTypeVan, CapacityKg: 5000, CapacityM3: 30, LengthM: 6.8}
+	reefer := &models.Vehicle{Plate: "浙AD12345", Type: models.VehicleTypeRefrigerated, CapacityKg: 10000, CapacityM3: 40, LengthM: 9.6}
+	require.NoError(t, vehicles.CreateVehicle(ctx, 2, van))
+	require.NoError(t, vehicles.CreateVehicle(ctx, 2, reefer))
+
+	orders := map[string]*models.FreightOrder{
+		"普货": {WeightKg: 3000, VolumeM3: 20},
+		"重货": {WeightKg: 8000, VolumeM3: 25},
+		"长货": {WeightKg: 1000, VolumeM3: 10, LengthM: 8, WidthM: 1, HeightM: 1},
+		"冷链": {WeightKg: 2000, VolumeM3: 10, TempMinC: celsius(-18), TempMaxC: celsius(-12)},
+	}
+	for remark, o := range orders {
+		o.Remark, o.OriginCode, o.DestinationCode, o.Price, o.ShipperID, o.OrderDate = remark, "310100", "330100", 1000, 1, utils.NewDate(2025, 3, 1)
+		require.NoError(t, freights.CreateFreight(ctx, o))
+	}
+
+	hall := func(vehicleID uint64, filter models.FreightFilter) []string {
+		filter.VehicleID, filter.VehicleOwnerID = vehicleID, 2
+		filter.SortField, filter.SortOrder = "created_at", "asc"
+		page, err := freights.ListFreights(ctx, filter)
+		require.NoError(t, err)
+		var remarks []string
+		for _, o := range page.Items {
+			remarks = append(remarks, o.Remark)
+		}
+		return remarks
+	}
+	yes := true
+	assert.ElementsMatch(t, []string{"普货"}, hall(van.ID, models.FreightFilter{}))
+	assert.ElementsMatch(t, []string{"普货", "重货", "长货", "冷链"}, hall(reefer.ID, models.FreightFilter{}))
+	assert.ElementsMatch(t, []string{"冷链"}, hall(reefer.ID, models.FreightFilter{ColdChain: &yes}))
+	assert.Empty(t, hall(van.ID, models.FreightFilter{ColdChain: &yes}))
+	_, err := freights.ListFreights(ctx, models.FreightFilter{VehicleID: van.ID, VehicleOwnerID: 3})
+	assert.ErrorIs(t, err, services.ErrVehicleNotFound)
+
+	var mismatch *services.VehicleMismatchError
+	assert.ErrorAs(t, freights.AcceptOrder(ctx, orders["重货"].ID, 2, van.ID), &mismatch)
+	assert.ErrorAs(t, freights.AcceptOrder(ctx, orders["冷链"].ID, 2, van.ID), &mismatch)
+	assert.ErrorIs(t, freights.AcceptOrder(ctx, orders["普货"].ID, 3, van.ID), services.ErrVehicleNotFound)
+
+	require.NoError(t, freights.AcceptOrder(ctx, orders["冷链"].ID, 2, reefer.ID))
+	accepted, err := freights.GetFreightByID(ctx, orders["冷链"].ID)
+	require.NoError(t, err)
+	assert.Equal(t, reefer.ID, accepted.VehicleID)
+}
+
+// 测试车辆接口和接单时选择车辆
+func TestVehicleRoutes(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	vehicleRepo := db.NewMemoryVehicleRepository()
+	freights := newMemoryFreightServiceWithVehicles(repo, vehicleRepo)
+	router := routes.SetupRoutes(routes.Deps{
+		FreightService: freights,
+		VehicleService: services.NewVehicleService(vehicleRepo),
+		AuthMiddleware: middleware.NewAuthMiddleware(testJWTSecret, nil),
+	})
+
+	do := func(method, path, body string, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+tokenFor(t, 2, role))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	body := `{"plate":"苏E12345","type":"flatbed","capacity_kg":20000,"capacity_m3":60,"length_m":13}`
+	assert.Equal(t, http.StatusForbidden, do("POST", "/api/vehicles", body, models.RoleShipper).Code)
+	recorder := do("POST", "/api/vehicles", body, models.RoleCarrier)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	var created struct {
+		Data models.Vehicle `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &created))
+	assert.Equal(t, http.StatusConflict, do("POST", "/api/vehicles", body, models.RoleCarrier).Code)
+
+	order := &models.FreightOrder{OriginCode: "310100", DestinationCode: "330100", Price: 1000, ShipperID: 1,
+		OrderDate: utils.NewDate(2025, 3, 1), WeightKg: 30000}
+	require.NoError(t, freights.CreateFreight(context.Background(), order))
+
+	hallPath := fmt.Sprintf("/api/freights?vehicle_id=%d", created.Data.ID)
+	recorder = do("GET", hallPath, "", models.RoleCarrier)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"total":0`)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/api/freights?vehicle_id=999", "", models.RoleCarrier).Code)
+
+	acceptPath := fmt.Sprintf("/api/freights/%d/accept", order.ID)
+	recorder = do("POST", acceptPath, fmt.Sprintf(`{"vehicle_id":%d}`, created.Data.ID), models.RoleCarrier)
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	recorder = do("POST", acceptPath, "", models.RoleCarrier)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = do("DELETE", fmt.Sprintf("/api/vehicles/%d", created.Data.ID), "", models.RoleCarrier)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", fmt.Sprintf("/api/vehicles/%d", created.Data.ID), "", models.RoleCarrier).Code)
+}
@@ -0,0 +1,349 @@
+package handlers_freight_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"freight/api/middleware"
+	"freight/api/routes"
+	"freight/db"
+	"freight/events"
+	"freight/models"
+	"freight/region"
+	"freight/services"
+	"freight/utils"
+)
+
+// webhookReceiver 本地接收方，记录收到的请求；failing 不为0时返回500
+type webhookReceiver struct {
+	*httptest.Server
+	failing  int32
+	mu       sync.Mutex
+	requests []*receivedWebhook
+}
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+func newWebhookReceiver(t *testing.T) *webhookReceiver {
+	receiver := &webhookReceiver{}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receiver.mu.Lock()
+		receiver.requests = append(receiver.requests, &receivedWebhook{header: r.Header.Clone(), body: body})
+		receiver.mu.Unlock()
+		if atomic.LoadInt32(&receiver.failing) != 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver
+}
+
+func (r *webhookReceiver) received() []*receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*receivedWebhook(nil), r.requests...)
+}
+
+// 测试事件匹配、签名、指数退避重试、死信和手动重新投递
+func TestWebhookDelivery(t *testing.T) {
+	repo := db.NewMemoryFreightRepository()
+	regions := region.Default()
+	webhookRepo := db.NewMemoryWebhookRepository()
+	webhooks := services.NewWebhookService(webhookRepo, services.WebhookOptions{
+		MaxAttempts: 4, Backoff: time.Minute, MaxBackoff: 3 * time.Minute, AllowPrivateNetworks: true,
+	})
+	freights := services.NewFreightService(repo, repo, regions, services.NewHaversineEstimator(regions, services.RouteOptions{}),
+		db.NewMemoryVehicleRepository(), events.Publishers{events.NewBus(0), webhooks})
+	receiver := newWebhookReceiver(t)
+	ctx := context.Background()
+
+	shipper := services.WebhookActor{UserID: 1}
+	var fieldErr *services.FreightFieldError
+	for _, tc := range []struct {
+		req   services.WebhookSubscriptionRequest
+		field string
+	}{
+		{services.WebhookSubscriptionRequest{URL: "ftp://erp.example.com", EventTypes: []string{events.TypeOrderCreated}}, "url"},
+		{services.WebhookSubscriptionRequest{URL: receiver.URL}, "event_types"},
+		{services.WebhookSubscriptionRequest{URL: receiver.URL, EventTypes: []string{"chat.message"}}, "event_types"},
+		{services.WebhookSubscriptionRequest{URL: receiver.URL, EventTypes: []string{events.TypeOrderCreated}, Secret: "short"}, "secret"},
+	} {
+		_, err := webhooks.CreateSubscription(ctx, shipper, tc.req)
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, tc.field, fieldErr.Field)
+	}
+	_, err := webhooks.CreateSubscription(ctx, shipper, services.WebhookSubscriptionRequest{
+		URL: receiver.URL, EventTypes: []string{events.TypeOrderCreated}, AllOrders: true,
+	})
+	assert.ErrorIs(t, err, services.ErrPermissionDenied)
+
+	sub, err := webhooks.CreateSubscription(ctx, shipper, services.WebhookSubscriptionRequest{
+		URL: receiver.URL + "/erp", EventTypes: []string{events.TypeOrderCreated, events.TypeOrderAccepted, events.TypeOrderCreated},
+	})
+	require.NoError(t, err)
+	assert.Len(t, sub.Secret, 64)
+	assert.Equal(t, []string{events.TypeOrderCreated, events.TypeOrderAccepted}, sub.EventTypes)
+	_, err = webhooks.CreateSubscription(ctx, services.WebhookActor{UserID: 5}, services.WebhookSubscriptionRequest{
+		URL: receiver.URL + "/other", EventTypes: []string{events.TypeOrderCreated},
+	})
+	require.NoError(t, err)
+	monitor, err := webhooks.CreateSubscription(ctx, services.WebhookActor{UserID: 9, ViewAll: true}, services.WebhookSubscriptionRequest{
+		URL: receiver.URL + "/monitor", EventTypes: []string{events.TypeOrderCancelled}, AllOrders: true, Secret: "monitor-secret-0123",
+	})
+	require.NoError(t, err)
+
+	// 发货方订阅收到本人订单的发布和接单；其他用户的订阅收不到
+	order := &models.FreightOrder{OriginCode: "310100", DestinationCode: "330100", Price: 1000, ShipperID: 1, OrderDate: utils.NewDate(2025, 3, 1)}
+	require.NoError(t, freights.CreateFreight(ctx, order))
+	require.NoError(t, freights.AcceptOrder(ctx, order.ID, 2, 0))
+	n, err := webhooks.DeliverDue(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	requests := receiver.received()
+	require.Len(t, requests, 2)
+	types := []string{}
+	for _, req := range requests {
+		timestamp, err := strconv.ParseInt(req.header.Get(services.WebhookTimestampHeader), 10, 64)
+		require.NoError(t, err)
+		assert.Equal(t, services.SignWebhook(sub.Secret, timestamp, req.body), req.header.Get(services.WebhookSignatureHeader))
+		assert.NotEqual(t, services.SignWebhook("wrong-secret-000000", timestamp, req.body), req.header.Get(services.WebhookSignatureHeader))
+		assert.Equal(t, "application/json", req.header.Get("Content-Type"))
+		assert.NotEmpty(t, req.header.Get(services.WebhookDeliveryHeader))
+
+		var payload struct {
+			Type    string `json:"type"`
+			OrderID uint64 `json:"order_id"`
+			Data    struct {
+				CarrierID uint64 `json:"carrier_id"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(req.body, &payload))
+		assert.Equal(t, order.ID, payload.OrderID)
+		assert.Equal(t, req.header.Get(services.WebhookEventHeader), payload.Type)
+		types = append(types, payload.Type)
+	}
+	assert.ElementsMatch(t, []string{events.TypeOrderCreated, events.TypeOrderAccepted}, types)
+
+	// 接收方持续失败：按 1、2、3（上限）分钟退避，第4次失败后转为死信
+	atomic.StoreInt32(&receiver.failing, 1)
+	other := &models.FreightOrder{OriginCode: "310100", DestinationCode: "330100", Price: 800, ShipperID: 7, OrderDate: utils.NewDate(2025, 3, 1)}
+	require.NoError(t, freights.CreateFreight(ctx, other))
+	require.NoError(t, freights.TransitionOrder(ctx, other.ID, 7, models.FreightStatusCancelled, ""))
+	start := time.Now()
+	for i, offset := range []time.Duration{0, time.Minute, 3 * time.Minute, 6 * time.Minute} {
+		n, err = webhooks.DeliverDue(ctx, start.Add(offset))
+		require.NoError(t, err)
+		assert.Equal(t, 1, n, "第 %d 次投递", i+1)
+		n, err = webhooks.DeliverDue(ctx, start.Add(offset+time.Second))
+		require.NoError(t, err)
+		assert.Zero(t, n, "未到重试时间")
+	}
+	dead, err := webhooks.ListDeliveries(ctx, models.WebhookDeliveryFilter{Status: models.WebhookDead})
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, monitor.ID, dead[0].SubscriptionID)
+	assert.Equal(t, 4, dead[0].Attempts)
+	assert.Equal(t, http.StatusInternalServerError, dead[0].LastStatusCode)
+	assert.Contains(t, dead[0].LastError, "500")
+	assert.Nil(t, dead[0].NextAttemptAt)
+
+	atomic.StoreInt32(&receiver.failing, 0)
+	redelivered, err := webhooks.Redeliver(ctx, dead[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookDelivered, redelivered.Status)
+	assert.Equal(t, 1, redelivered.Attempts)
+	assert.NotNil(t, redelivered.DeliveredAt)
+	last := receiver.received()[len(receiver.received())-1]
+	assert.True(t, strings.HasPrefix(last.header.Get(services.WebhookSignatureHeader), "sha256="))
+	timestamp, _ := strconv.ParseInt(last.header.Get(services.WebhookTimestampHeader), 10, 64)
+	assert.Equal(t, services.SignWebhook("monitor-secret-0123", timestamp, last.body), last.header.Get(services.WebhookSignatureHeader))
+	_, err = webhooks.Redeliver(ctx, 999)
+	assert.ErrorIs(t, err, services.ErrDeliveryNotFound)
+
+	// 订阅删除后未完成的投递直接转为死信
+	third := &models.FreightOrder{OriginCode: "310100", DestinationCode: "330100", Price: 900, ShipperID: 1, OrderDate: utils.NewDate(2025, 3, 1)}
+	require.NoError(t, freights.CreateFreight(ctx, third))
+	assert.ErrorIs(t, webhooks.DeleteSubscription(ctx, services.WebhookActor{UserID: 5}, sub.ID), services.ErrPermissionDenied)
+	require.NoError(t, webhooks.DeleteSubscription(ctx, shipper, sub.ID))
+	_, err = webhooks.DeliverDue(ctx, time.Now())
+	require.NoError(t, err)
+	dead, err = webhooks.ListDeliveries(ctx, models.WebhookDeliveryFilter{SubscriptionID: sub.ID, Status: models.WebhookDead})
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, "订阅已删除", dead[0].LastError)
+}
+
+// 测试订阅管理接口和管理员投递记录接口的权限
+func TestWebhookRoutes(t *testing.T) {
+	webhooks := services.NewWebhookService(db.NewMemoryWebhookRepository(), services.WebhookOptions{AllowPrivateNetworks: true})
+	router := routes.SetupRoutes(routes.Deps{
+		WebhookService: webhooks,
+		AuthMiddleware: middleware.NewAuthMiddleware(testJWTSecret, nil),
+	})
+	receiver := newWebhookReceiver(t)
+
+	do := func(method, path, body string, userID int64, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+tokenFor(t, userID, role))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := do("POST", "/api/webhooks", `{"url":"not a url","event_types":["order.created"]}`, 1, models.RoleShipper)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"field":"url"`)
+	recorder = do("POST", "/api/webhooks", `{"url":"`+receiver.URL+`","event_types":["order.created"],"all_orders":true}`, 1, models.RoleShipper)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+
+	recorder = do("POST", "/api/webhooks", `{"url":"`+receiver.URL+`","event_types":["order.created","order.completed"]}`, 1, models.RoleShipper)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	var created struct {
+		Data models.WebhookSubscription `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.Data.Secret)
+	assert.True(t, created.Data.Active)
+	path := fmt.Sprintf("/api/webhooks/%d", created.Data.ID)
+
+	recorder = do("GET", "/api/webhooks", "", 1, models.RoleShipper)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), created.Data.Secret)
+	assert.Equal(t, http.StatusForbidden, do("GET", path, "", 2, models.RoleCarrier).Code)
+	assert.Equal(t, http.StatusOK, do("GET", path, "", 9, models.RoleAdmin).Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/api/webhooks/999", "", 1, models.RoleShipper).Code)
+
+	recorder = do("PUT", path, `{"url":"`+receiver.URL+`/v2","event_types":["order.cancelled"],"active":false}`, 1, models.RoleShipper)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"active":false`)
+	assert.Contains(t, recorder.Body.String(), `/v2"`)
+
+	// 投递记录和重新投递仅管理员
+	assert.Equal(t, http.StatusForbidden, do("GET", "/api/admin/webhooks/deliveries", "", 9, models.RoleDispatcher).Code)
+	recorder = do("GET", "/api/admin/webhooks/dead-letters", "", 9, models.RoleAdmin)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"data":[]`)
+	assert.Equal(t, http.StatusBadRequest, do("GET", "/api/admin/webhooks/deliveries?status=lost", "", 9, models.RoleAdmin).Code)
+	assert.Equal(t, http.StatusNotFound, do("POST", "/api/admin/webhooks/deliveries/5/redeliver", "", 9, models.RoleAdmin).Code)
+
+	assert.Equal(t, http.StatusOK, do("DELETE", path, "", 1, models.RoleShipper).Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", path, "", 1, models.RoleShipper).Code)
+}
+
+// 测试默认拒绝投递到本机和内网地址，且不跟随重定向
+func TestWebhookTargetRestrictions(t *testing.T) {
+	receiver := newWebhookReceiver(t)
+	ctx := context.Background()
+	owner := services.WebhookActor{UserID: 1}
+	order := events.Event{Type: events.TypeOrderCreated, OrderID: 1, ShipperID: 1}
+
+	webhookRepo := db.NewMemoryWebhookRepository()
+	webhooks := services.NewWebhookService(webhookRepo, services.WebhookOptions{})
+	var fieldErr *services.FreightFieldError
+	for _, target := range []string{receiver.URL, "http://10.1.2.3/hook", "http://169.254.169.254/latest/meta-data", "http://[::1]:8080/", "http://0.0.0.0/"} {
+		_, err := webhooks.CreateSubscription(ctx, owner, services.WebhookSubscriptionRequest{URL: target, EventTypes: []string{events.TypeOrderCreated}})
+		require.ErrorAs(t, err, &fieldErr, target)
+		assert.Equal(t, "url", fieldErr.Field)
+	}
+
+	// 域名在连接时校验解析结果，指向本机的投递直接转为死信
+	_, port, err := net.SplitHostPort(receiver.Listener.Addr().String())
+	require.NoError(t, err)
+	_, err = webhooks.CreateSubscription(ctx, owner, services.WebhookSubscriptionRequest{
+		URL: "http://localhost:" + port + "/hook", EventTypes: []string{events.TypeOrderCreated},
+	})
+	require.NoError(t, err)
+	webhooks.Publish(order)
+	n, err := webhooks.DeliverDue(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Empty(t, receiver.received())
+	dead, err := webhooks.ListDeliveries(ctx, models.WebhookDeliveryFilter{Status: models.WebhookDead})
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, 1, dead[0].Attempts)
+	assert.Contains(t, dead[0].LastError, services.ErrWebhookTargetBlocked.Error())
+
+	// 重定向响应按失败处理，不请求跳转地址
+	redirects := 0
+	redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirects++
+		http.Redirect(w, r, receiver.URL+"/internal", http.StatusFound)
+	}))
+	defer redirector.Close()
+	local := services.NewWebhookService(db.NewMemoryWebhookRepository(), services.WebhookOptions{AllowPrivateNetworks: true})
+	_, err = local.CreateSubscription(ctx, owner, services.WebhookSubscriptionRequest{URL: redirector.URL, EventTypes: []string{events.TypeOrderCreated}})
+	require.NoError(t, err)
+	local.Publish(order)
+	_, err = local.DeliverDue(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, redirects)
+	assert.Empty(t, receiver.received())
+	pending, err := local.ListDeliveries(ctx, models.WebhookDeliveryFilter{Status: models.WebhookPending})
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, http.StatusFound, pending[0].LastStatusCode)
+}
+
+// 统计订阅查询和投递记录保存次数的 Webhook 仓储
+type countingWebhookRepo struct {
+	*db.MemoryWebhookRepository
+	lists, creates int32
+}
+
+func (r *countingWebhookRepo) ListActiveSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error) {
+	atomic.AddInt32(&r.lists, 1)
+	return r.MemoryWebhookRepository.ListActiveSubscriptions(ctx)
+}
+
+func (r *countingWebhookRepo) CreateDeliveries(ctx context.Context, deliveries []*models.WebhookDelivery) error {
+	atomic.AddInt32(&r.creates, 1)
+	return r.MemoryWebhookRepository.CreateDeliveries(ctx, deliveries)
+}
+
+// 测试发布事件使用订阅缓存：无人订阅的定位上报不访问仓储，匹配的事件由后台协程保存
+func TestWebhookPublishUsesCache(t *testing.T) {
+	ctx := context.Background()
+	repo := &countingWebhookRepo{MemoryWebhookRepository: db.NewMemoryWebhookRepository()}
+	webhooks := services.NewWebhookService(repo, services.WebhookOptions{AllowPrivateNetworks: true})
+	_, err := webhooks.CreateSubscription(ctx, services.WebhookActor{UserID: 1}, services.WebhookSubscriptionRequest{
+		URL: "http://127.0.0.1:1/hook", EventTypes: []string{events.TypeOrderCreated},
+	})
+	require.NoError(t, err)
+	lists := atomic.LoadInt32(&repo.lists)
+
+	for i := 0; i < 100; i++ {
+		webhooks.Publish(events.Event{Type: events.TypeOrderLocation, OrderID: 1, ShipperID: 1, CarrierID: 2})
+	}
+	webhooks.Publish(events.Event{Type: events.TypeOrderCreated, OrderID: 2, ShipperID: 3})
+	webhooks.Publish(events.Event{Type: events.TypeOrderCreated, OrderID: 1, ShipperID: 1})
+	require.NoError(t, webhooks.Flush(ctx))
+	assert.Equal(t, lists, atomic.LoadInt32(&repo.lists))
+	assert.EqualValues(t, 1, atomic.LoadInt32(&repo.creates))
+
+	deliveries, err := webhooks.ListDeliveries(ctx, models.WebhookDeliveryFilter{})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, uint64(1), deliveries[0].OrderID)
+}
@@ -2,10 +2,16 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
@@ -27,6 +33,14 @@ func ResponseError(w http.ResponseWriter, statusCode int, message string) {
 	ResponseJSON(w, statusCode, map[string]string{"error": message})
 }
 
+// ResponseForbidden 返回统一格式的403响应（detail 说明缺少的角色/权限或拒绝原因）
+func ResponseForbidden(w http.ResponseWriter, detail string) {
+	ResponseJSON(w, http.StatusForbidden, map[string]string{
+		"error":  "权限不足",
+		"detail": detail,
+	})
+}
+
 // HashPassword 生成密码哈希（使用成本因子14）
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
@@ -39,17 +53,17 @@ func HashPassword(password string) (string, error) {
 
 // CheckPasswordHash 验证密码哈希
 func CheckPasswordHash(password, hash string) bool {
-	log.Printf("验证密码 - 输入: %s, 哈希: %s", password, hash)
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	// 不记录输入的密码和哈希，避免敏感信息写入日志
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// GenerateJWT 生成JWT token（携带用户角色和唯一ID jti，ttl 为有效期），返回 token 和 jti
+func GenerateJWT(userID int64, username, role, secret string, ttl time.Duration) (string, string, error) {
+	jti, err := RandomToken(16)
 	if err != nil {
-		log.Printf("密码验证失败: %v", err)
-		return false
+		return "", "", err
 	}
-	return true
-}
 
-// GenerateJWT 生成JWT token
-func GenerateJWT(userID int64, username, secret string) (string, error) {
 	// 创建token
 	token := jwt.New(jwt.SigningMethodHS256)
 
@@ -57,15 +71,101 @@ func GenerateJWT(userID int64, username, secret string) (string, error) {
 	claims := token.Claims.(jwt.MapClaims)
 	claims["user_id"] = userID
 	claims["username"] = username
-	claims["exp"] = time.Now().Add(time.Hour * 24).Unix() // 24小时过期
+	claims["role"] = role
+	now := time.Now()
+	claims["jti"] = jti
+	// iat 精确到毫秒，用于与按用户吊销的时间点比较
+	claims["iat"] = float64(now.UnixMilli()) / 1000
+	claims["exp"] = now.Add(ttl).Unix()
 
 	// 生成签名
 	tokenString, err := token.SignedString([]byte(secret))
 	if err != nil {
+		return "", "", err
+	}
+
+	return tokenString, jti, nil
+}
+
+// RandomToken 生成 n 字节的随机令牌（十六进制字符串）
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
 		return "", err
 	}
+	return hex.EncodeToString(b), nil
+}
+
+// HashToken 计算令牌的 SHA-256 哈希（数据库只保存哈希值）
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// TrustedProxies 受信任的反向代理地址，只有来自这些地址的请求才采用代理设置的客户端IP请求头
+type TrustedProxies []*net.IPNet
 
-	return tokenString, nil
+// ParseTrustedProxies 解析受信任代理列表，每项为 IP 或 CIDR
+func ParseTrustedProxies(entries []string) (TrustedProxies, error) {
+	proxies := make(TrustedProxies, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("无效的代理地址 %q", entry)
+			}
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			entry = fmt.Sprintf("%s/%d", entry, bits)
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("无效的代理地址 %q", entry)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+// trusts 判断地址是否为受信任代理
+func (p TrustedProxies) trusts(ip net.IP) bool {
+	for _, network := range p {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP 获取客户端IP：默认取连接地址（客户端可随意伪造请求头）；仅当连接来自受信任代理时，
+// 采用 X-Forwarded-For 中从右往左第一个非受信任代理的地址，其次是 X-Real-IP
+func (p TrustedProxies) ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	peer := net.ParseIP(host)
+	if peer == nil || !p.trusts(peer) {
+		return host
+	}
+
+	forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if ip == nil {
+			break
+		}
+		if !p.trusts(ip) {
+			return ip.String()
+		}
+	}
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	return host
 }
 
 // ParseJWT 解析JWT token